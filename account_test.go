@@ -0,0 +1,38 @@
+package main
+
+import (
+	"UASBE/app/service"
+	"reflect"
+	"testing"
+)
+
+// TestProtectedProfileFields menguji penolakan field yang hanya boleh diubah admin
+func TestProtectedProfileFields(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"editable fields only", `{"full_name":"Budi","email":"budi@unair.ac.id","program_study":"Informatika"}`, nil},
+		{"role", `{"full_name":"Budi","role":"admin"}`, []string{"role"}},
+		{"student fields", `{"student_id":"123","advisor_id":"x","academic_year":"2024"}`, []string{"advisor_id", "student_id"}},
+		{"case insensitive", `{"Is_Active":true}`, []string{"Is_Active"}},
+		{"password", `{"password":"secret123"}`, []string{"password"}},
+		{"empty object", `{}`, nil},
+	}
+
+	for _, tt := range tests {
+		got, err := service.ProtectedProfileFields([]byte(tt.body))
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ProtectedProfileFields = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := service.ProtectedProfileFields([]byte(`not json`)); err == nil {
+		t.Error("expected an error for an invalid body")
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"UASBE/app/policy"
+	"UASBE/app/repository"
+	"UASBE/app/service"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TestNormalizeAdminScopes menguji normalisasi scope admin program studi
+func TestNormalizeAdminScopes(t *testing.T) {
+	scopes, err := service.NormalizeAdminScopes([]string{" Teknik  Informatika ", "teknik informatika", "", "Sistem Informasi"})
+	if err != nil {
+		t.Fatalf("NormalizeAdminScopes: %v", err)
+	}
+	if want := []string{"Teknik Informatika", "Sistem Informasi"}; !reflect.DeepEqual(scopes, want) {
+		t.Errorf("scopes = %v, want %v", scopes, want)
+	}
+
+	empty, err := service.NormalizeAdminScopes(nil)
+	if err != nil || empty == nil || len(empty) != 0 {
+		t.Errorf("no scopes should normalize to an empty list, got %v, %v", empty, err)
+	}
+
+	if _, err := service.NormalizeAdminScopes([]string{strings.Repeat("a", 256)}); err == nil {
+		t.Error("a scope longer than 255 characters should be rejected")
+	}
+}
+
+// TestInScope menguji pencocokan program studi / departemen dengan scope admin
+func TestInScope(t *testing.T) {
+	scopes := []string{"Teknik Informatika", " Sistem Informasi "}
+
+	tests := map[string]bool{
+		"Teknik Informatika":   true,
+		"teknik informatika ":  true,
+		"SISTEM INFORMASI":     true,
+		"Teknik Elektro":       false,
+		"":                     false,
+		"Teknik Informatika 2": false,
+	}
+
+	for value, want := range tests {
+		if got := policy.InScope(scopes, value); got != want {
+			t.Errorf("InScope(%q) = %v, want %v", value, got, want)
+		}
+	}
+
+	if policy.InScope(nil, "Teknik Informatika") {
+		t.Error("nothing is in an empty scope")
+	}
+}
+
+// TestCreateUserRequestOutOfScope menguji bahwa admin program studi hanya bisa
+// membuat mahasiswa dan dosen dengan program studi atau departemen dalam scope-nya
+func TestCreateUserRequestOutOfScope(t *testing.T) {
+	f := newAuthFixture(t)
+	users := service.NewUserService(repository.NewUserRepository(), repository.NewStudentRepository(),
+		repository.NewLecturerRepository(), f.auth, nil)
+
+	app := fiber.New()
+	app.Post("/users", func(c *fiber.Ctx) error {
+		c.Locals("role", policy.RoleDepartmentAdmin)
+		c.Locals("username", "staff")
+		c.Locals("admin_scopes", []string{"Teknik Informatika"})
+		return c.Next()
+	}, users.CreateUserRequest)
+
+	tests := []struct {
+		name string
+		body map[string]string
+	}{
+		{"student of another program study", map[string]string{"role": "student", "program_study": "Sistem Informasi"}},
+		{"student without program study", map[string]string{"role": "student"}},
+		{"lecturer of another department", map[string]string{"role": "lecturer", "lecturer_id": "L-1", "department": "Sistem Informasi"}},
+		{"lecturer without department", map[string]string{"role": "lecturer", "lecturer_id": "L-1"}},
+	}
+
+	for _, tt := range tests {
+		body := map[string]string{"username": "budi", "email": "budi@example.ac.id", "password": "Rahasia-123", "full_name": "Budi",
+			"student_id": "S-1", "academic_year": "2024"}
+		for key, value := range tt.body {
+			body[key] = value
+		}
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest("POST", "/users", strings.NewReader(string(payload)))
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test() error = %v", err)
+		}
+		var result struct {
+			Code string `json:"code"`
+		}
+		json.NewDecoder(resp.Body).Decode(&result)
+		if resp.StatusCode != fiber.StatusForbidden || result.Code != "OUT_OF_SCOPE" {
+			t.Errorf("%s: got %d %s, want 403 OUT_OF_SCOPE", tt.name, resp.StatusCode, result.Code)
+		}
+	}
+}
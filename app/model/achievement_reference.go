@@ -9,11 +9,44 @@ type AchievementReference struct {
 	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	StudentID    string             `bson:"student_id" json:"student_id"` // PostgreSQL UUID as string
 	AchievementID string            `bson:"achievement_id" json:"achievement_id"`
-	Status       string             `bson:"status" json:"status"` // draft, submitted, verified, rejected
+	Status       string             `bson:"status" json:"status"` // draft, submitted, verified, rejected, revision_requested
 	SubmittedAt  time.Time          `bson:"submitted_at,omitempty" json:"submitted_at,omitempty"`
 	VerifiedAt   time.Time          `bson:"verified_at,omitempty" json:"verified_at,omitempty"`
 	VerifiedBy   string             `bson:"verified_by,omitempty" json:"verified_by,omitempty"` // PostgreSQL UUID as string
+	VerifiedOnBehalfOf string       `bson:"verified_on_behalf_of,omitempty" json:"verified_on_behalf_of,omitempty"` // advisor user ID when a delegate verified
 	RejectionNote string            `bson:"rejection_note,omitempty" json:"rejection_note,omitempty"`
+	StatusHistory []StatusChange    `bson:"status_history,omitempty" json:"status_history,omitempty"`
+	VerificationChain []string      `bson:"verification_chain,omitempty" json:"verification_chain,omitempty"` // stages, set on every submission
+	CurrentStage int                `bson:"current_stage" json:"current_stage"` // index in verification_chain
+	Approvals    []StageApproval    `bson:"approvals,omitempty" json:"approvals,omitempty"`
+	RemindedAt   time.Time          `bson:"reminded_at,omitempty" json:"reminded_at,omitempty"`   // last SLA reminder to the verifiers
+	EscalatedAt  time.Time          `bson:"escalated_at,omitempty" json:"escalated_at,omitempty"` // last SLA escalation to the administrators
 	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
-}
\ No newline at end of file
+}
+
+// StatusChange is one entry of the status history of a reference
+type StatusChange struct {
+	From  string    `bson:"from" json:"from"` // empty when the reference was created
+	To    string    `bson:"to" json:"to"`
+	Actor string    `bson:"actor" json:"actor"` // user ID (PostgreSQL UUID as string)
+	Note  string    `bson:"note,omitempty" json:"note,omitempty"`
+	At    time.Time `bson:"at" json:"at"`
+
+	// OnBehalfOf is the user ID of the advisor when their delegate made the change
+	OnBehalfOf string `bson:"on_behalf_of,omitempty" json:"on_behalf_of,omitempty"`
+
+	// Feedback are the points to address, given when returning for revision
+	Feedback []string `bson:"feedback,omitempty" json:"feedback,omitempty"`
+}
+
+// StageApproval is the approval of one stage of the verification chain
+type StageApproval struct {
+	Stage string    `bson:"stage" json:"stage"`
+	Round int       `bson:"round" json:"round"` // the submission it approved
+	By    string    `bson:"by" json:"by"`       // user ID (PostgreSQL UUID as string)
+	At    time.Time `bson:"at" json:"at"`
+
+	// OnBehalfOf is the user ID of the advisor when their delegate approved
+	OnBehalfOf string `bson:"on_behalf_of,omitempty" json:"on_behalf_of,omitempty"`
+}
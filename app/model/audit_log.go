@@ -0,0 +1,36 @@
+package model
+
+import (
+	"time"
+)
+
+// AuditLog records a sensitive action. For impersonation the actor is the admin
+// and the subject is the user being viewed.
+type AuditLog struct {
+	ID            string    `json:"id" db:"id"`
+	ActorID       string    `json:"actor_id,omitempty" db:"actor_id"`
+	SubjectUserID string    `json:"subject_user_id,omitempty" db:"subject_user_id"`
+	Action        string    `json:"action" db:"action"`
+	Method        string    `json:"method,omitempty" db:"method"`
+	Path          string    `json:"path,omitempty" db:"path"`
+	StatusCode    int       `json:"status_code,omitempty" db:"status_code"`
+	IPAddress     string    `json:"ip_address,omitempty" db:"ip_address"`
+	UserAgent     string    `json:"user_agent,omitempty" db:"user_agent"`
+	Details       string    `json:"details,omitempty" db:"details"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// Audit log actions
+const (
+	AuditActionImpersonationStart   = "impersonation.start"
+	AuditActionImpersonationRequest = "impersonation.request"
+)
+
+// AuditLogFilter narrows an audit log listing; empty fields match everything
+type AuditLogFilter struct {
+	ActorID       string
+	SubjectUserID string
+	Action        string
+	Limit         int
+	Offset        int
+}
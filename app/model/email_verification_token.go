@@ -0,0 +1,17 @@
+package model
+
+import (
+	"time"
+)
+
+// EmailVerificationToken is a single-use token mailed to confirm that a user owns
+// an email address. Only the SHA-256 hash of the token is stored.
+type EmailVerificationToken struct {
+	ID        string     `json:"id" db:"id"`
+	UserID    string     `json:"user_id" db:"user_id"`
+	Email     string     `json:"email" db:"email"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty" db:"used_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
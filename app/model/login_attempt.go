@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+)
+
+// LoginAttempt is one login, successful or not. UserID is empty when the
+// credential did not match any account.
+type LoginAttempt struct {
+	ID            string    `json:"id" db:"id"`
+	UserID        string    `json:"user_id,omitempty" db:"user_id"`
+	Credential    string    `json:"credential" db:"credential"`
+	IPAddress     string    `json:"ip_address" db:"ip_address"`
+	UserAgent     string    `json:"user_agent" db:"user_agent"`
+	Success       bool      `json:"success" db:"success"`
+	FailureReason string    `json:"failure_reason,omitempty" db:"failure_reason"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+
+	// IPRange is the network of the IP (/24 for IPv4, /48 for IPv6) and Device
+	// a label of the user agent; together they tell whether a login is new
+	IPRange string `json:"ip_range,omitempty" db:"ip_range"`
+	Device  string `json:"device,omitempty" db:"device"`
+}
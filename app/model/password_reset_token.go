@@ -0,0 +1,17 @@
+package model
+
+import (
+	"time"
+)
+
+// PasswordResetToken is a single-use token mailed to a user who forgot their
+// password. Only the SHA-256 hash of the token is stored.
+type PasswordResetToken struct {
+	ID          string     `json:"id" db:"id"`
+	UserID      string     `json:"user_id" db:"user_id"`
+	TokenHash   string     `json:"-" db:"token_hash"`
+	ExpiresAt   time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt      *time.Time `json:"used_at,omitempty" db:"used_at"`
+	RequestedIP string     `json:"requested_ip" db:"requested_ip"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
@@ -6,4 +6,9 @@ type Permission struct {
 	Resource    string `json:"resource" db:"resource"`
 	Action      string `json:"action" db:"action"`
 	Description string `json:"description" db:"description"`
+}
+
+// Key returns the permission as checked by RBACMiddleware ("resource:action")
+func (p Permission) Key() string {
+	return p.Resource + ":" + p.Action
 }
\ No newline at end of file
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+)
+
+// RefreshToken is a long-lived, server-side token that can be exchanged for a
+// new access token. Tokens issued from the same login share a FamilyID so that
+// reuse of a rotated token can revoke the whole chain.
+type RefreshToken struct {
+	ID         string     `json:"id" db:"id"`
+	UserID     string     `json:"user_id" db:"user_id"`
+	FamilyID   string     `json:"family_id" db:"family_id"`
+	TokenHash  string     `json:"-" db:"token_hash"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	ReplacedBy string     `json:"replaced_by,omitempty" db:"replaced_by"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+
+	// Expired is evaluated by the database (expires_at <= NOW()) so the check
+	// does not depend on the application server's clock or time zone
+	Expired bool `json:"-" db:"-"`
+}
@@ -8,11 +8,21 @@ type Role struct {
 	ID          string    `json:"id" db:"id"`
 	Name        string    `json:"name" db:"name"`
 	Description string    `json:"description" db:"description"`
+	IsSystem    bool      `json:"is_system" db:"is_system"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
 }
 
 type RolePermission struct {
 	ID           string `json:"id" db:"id"`
 	RoleID       string `json:"role_id" db:"role_id"`
 	PermissionID string `json:"permission_id" db:"permission_id"`
-}
\ No newline at end of file
+}
+// UserRole is an additional role granted to a user on top of users.role_id
+type UserRole struct {
+	UserID    string    `json:"user_id" db:"user_id"`
+	RoleID    string    `json:"role_id" db:"role_id"`
+	RoleName  string    `json:"role_name" db:"role_name"`
+	GrantedBy string    `json:"granted_by,omitempty" db:"granted_by"`
+	GrantedAt time.Time `json:"granted_at" db:"granted_at"`
+}
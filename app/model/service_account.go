@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+)
+
+// ServiceAccount is a non-human user for system integrations. It has no usable
+// password and authenticates only with API keys.
+type ServiceAccount struct {
+	UserID      string    `json:"id" db:"user_id"`
+	Username    string    `json:"username" db:"username"`
+	Name        string    `json:"name" db:"full_name"`
+	RoleID      string    `json:"role_id" db:"role_id"`
+	RoleName    string    `json:"role" db:"role_name"`
+	IsActive    bool      `json:"is_active" db:"is_active"`
+	Description string    `json:"description,omitempty" db:"description"`
+	CreatedBy   string    `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	ActiveKeys  int       `json:"active_keys" db:"-"`
+}
+
+// APIKey authenticates a service account. Only the SHA-256 hash of the key is
+// stored; the key itself is shown once when it is created. Scopes are permission
+// names ("resource:action") and can only narrow what the account's role allows.
+type APIKey struct {
+	ID         string     `json:"id" db:"id"`
+	UserID     string     `json:"service_account_id" db:"user_id"`
+	Name       string     `json:"name" db:"name"`
+	KeyPrefix  string     `json:"key_prefix" db:"key_prefix"`
+	KeyHash    string     `json:"-" db:"key_hash"`
+	Scopes     []string   `json:"scopes" db:"scopes"`
+	CreatedBy  string     `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip,omitempty" db:"last_used_ip"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+}
@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+)
+
+// Session is one login of a user on a device. Its ID is the FamilyID of the
+// refresh tokens issued from that login and the "sid" claim of its access
+// tokens, so revoking the session ends both.
+type Session struct {
+	ID            string     `json:"id" db:"id"`
+	UserID        string     `json:"user_id" db:"user_id"`
+	UserAgent     string     `json:"user_agent" db:"user_agent"`
+	Device        string     `json:"device" db:"-"`
+	IPAddress     string     `json:"ip_address" db:"ip_address"`
+	LastIPAddress string     `json:"last_ip_address" db:"last_ip_address"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	LastSeenAt    time.Time  `json:"last_seen_at" db:"last_seen_at"`
+	ExpiresAt     time.Time  `json:"expires_at" db:"expires_at"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	Current       bool       `json:"current" db:"-"`
+}
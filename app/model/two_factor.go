@@ -0,0 +1,18 @@
+package model
+
+import (
+	"time"
+)
+
+// UserTOTP is a user's TOTP (RFC 6238) enrolment. The secret is stored encrypted
+// and stays pending (Enabled=false) until the user confirms it with a valid code.
+type UserTOTP struct {
+	UserID         string     `json:"user_id" db:"user_id"`
+	Secret         string     `json:"-" db:"secret_encrypted"`
+	Enabled        bool       `json:"enabled" db:"enabled"`
+	ConfirmedAt    *time.Time `json:"confirmed_at,omitempty" db:"confirmed_at"`
+	LastUsedStep   int64      `json:"-" db:"last_used_step"`
+	FailedAttempts int        `json:"-" db:"failed_attempts"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
@@ -5,13 +5,33 @@ import (
 )
 
 type User struct {
-	ID        string    `json:"id" db:"id"`
-	Username  string    `json:"username" db:"username"`
-	Email     string    `json:"email" db:"email"`
-	Password  string    `json:"password" db:"password_hash"`
-	FullName  string    `json:"full_name" db:"full_name"`
-	RoleID    string    `json:"role_id" db:"role_id"`
-	IsActive  bool      `json:"is_active" db:"is_active"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
-}
\ No newline at end of file
+	ID              string     `json:"id" db:"id"`
+	Username        string     `json:"username" db:"username"`
+	Email           string     `json:"email" db:"email"`
+	Password        string     `json:"password" db:"password_hash"`
+	FullName        string     `json:"full_name" db:"full_name"`
+	RoleID          string     `json:"role_id" db:"role_id"`
+	IsActive        bool       `json:"is_active" db:"is_active"`
+	ApprovalStatus  string     `json:"approval_status" db:"approval_status"`
+	SelfRegistered  bool       `json:"-" db:"self_registered"` // written on insert only
+	EmailVerifiedAt *time.Time `json:"email_verified_at" db:"email_verified_at"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Approval states of an account. Self-registered accounts start pending and can
+// only log in once an admin approves them; accounts created by an admin are approved.
+const (
+	ApprovalStatusPending  = "pending"
+	ApprovalStatusApproved = "approved"
+	ApprovalStatusRejected = "rejected"
+)
+
+// Registration is a self-registered account awaiting or after review
+type Registration struct {
+	User            User       `json:"user"`
+	Student         *Student   `json:"student,omitempty"`
+	ReviewedBy      *string    `json:"reviewed_by,omitempty"`
+	ReviewedAt      *time.Time `json:"reviewed_at,omitempty"`
+	RejectionReason *string    `json:"rejection_reason,omitempty"`
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+)
+
+// UserIdentity links a local user to an account at an external OpenID Connect
+// identity provider. The provider's issuer and subject identify the account; the
+// email is only a snapshot from the last login.
+type UserIdentity struct {
+	ID          string     `json:"id" db:"id"`
+	UserID      string     `json:"user_id" db:"user_id"`
+	Issuer      string     `json:"issuer" db:"issuer"`
+	Subject     string     `json:"subject" db:"subject"`
+	Email       string     `json:"email" db:"email"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty" db:"last_login_at"`
+}
+
+// OIDCState is a pending authorization request, stored until the identity
+// provider redirects back. Only the SHA-256 hash of the state is stored.
+type OIDCState struct {
+	ID           string    `json:"id" db:"id"`
+	StateHash    string    `json:"-" db:"state_hash"`
+	Nonce        string    `json:"-" db:"nonce"`
+	CodeVerifier string    `json:"-" db:"code_verifier"`
+	ExpiresAt    time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
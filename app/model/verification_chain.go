@@ -0,0 +1,17 @@
+package model
+
+import (
+	"time"
+)
+
+// VerificationChain lists the stages that approve, in order, the achievements
+// of a category and competition level (empty for any level)
+type VerificationChain struct {
+	ID               string    `json:"id" db:"id"`
+	Category         string    `json:"category" db:"category"`
+	CompetitionLevel string    `json:"competition_level" db:"competition_level"`
+	Stages           []string  `json:"stages" db:"stages"` // "advisor" or role names
+	UpdatedBy        string    `json:"updated_by,omitempty" db:"updated_by"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+}
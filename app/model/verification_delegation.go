@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+)
+
+// VerificationDelegation lets a lecturer (the delegate) verify the achievements
+// of another lecturer's (the delegator's) advisees for a period
+type VerificationDelegation struct {
+	ID            string     `json:"id" db:"id"`
+	DelegatorID   string     `json:"delegator_id" db:"delegator_id"` // lecturers.id
+	DelegatorName string     `json:"delegator_name" db:"delegator_name"`
+	DelegateID    string     `json:"delegate_id" db:"delegate_id"` // lecturers.id
+	DelegateName  string     `json:"delegate_name" db:"delegate_name"`
+	StartsAt      time.Time  `json:"starts_at" db:"starts_at"`
+	EndsAt        time.Time  `json:"ends_at" db:"ends_at"`
+	Reason        string     `json:"reason,omitempty" db:"reason"`
+	CreatedBy     string     `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	Status        string     `json:"status" db:"-"` // see StatusAt
+}
+
+// StatusAt is "revoked", "scheduled", "active" or "expired" at the given time
+func (d *VerificationDelegation) StatusAt(now time.Time) string {
+	switch {
+	case d.RevokedAt != nil:
+		return "revoked"
+	case now.Before(d.StartsAt):
+		return "scheduled"
+	case now.Before(d.EndsAt):
+		return "active"
+	}
+	return "expired"
+}
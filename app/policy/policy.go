@@ -0,0 +1,221 @@
+// Package policy answers "may this user do this action on this resource". It
+// holds no database access: callers load the facts (who the user is, who owns
+// the resource) and the rules decide, so every rule can be tested in isolation.
+package policy
+
+import (
+	"errors"
+	"strings"
+)
+
+// Canonical role names. Older data and clients also use the Indonesian names.
+const (
+	RoleAdmin    = "admin"
+	RoleStudent  = "student"
+	RoleLecturer = "lecturer"
+	RoleService  = "service"
+
+	// RoleDepartmentAdmin administers only the program studies and departments
+	// in its scopes
+	RoleDepartmentAdmin = "department_admin"
+)
+
+var roleAliases = map[string]string{
+	"mahasiswa":  RoleStudent,
+	"dosen":      RoleLecturer,
+	"dosen wali": RoleLecturer,
+}
+
+// CanonicalRole maps a role name, including its aliases, to the canonical name
+func CanonicalRole(name string) string {
+	lowered := strings.ToLower(strings.TrimSpace(name))
+	if canonical, ok := roleAliases[lowered]; ok {
+		return canonical
+	}
+	return lowered
+}
+
+// Action is something a subject wants to do with a resource
+type Action string
+
+const (
+	ActionView   Action = "view"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+	ActionSubmit Action = "submit"
+	ActionVerify Action = "verify"
+)
+
+// Resource types
+const (
+	ResourceAchievement = "achievement"
+)
+
+// Subject is the user asking
+type Subject struct {
+	UserID string
+	Roles  []string
+
+	// LecturerID is the lecturers.id of the subject, empty if not a lecturer
+	LecturerID string
+
+	// DelegatorIDs are the lecturers.id of the advisors who currently let the
+	// subject verify for them
+	DelegatorIDs []string
+
+	// Scopes are the departments or program studies the subject administers
+	Scopes []string
+}
+
+// HasRole reports whether the subject holds the role (or one of its aliases)
+func (s Subject) HasRole(role string) bool {
+	role = CanonicalRole(role)
+	for _, held := range s.Roles {
+		if CanonicalRole(held) == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Resource describes what is being acted on and whose it is
+type Resource struct {
+	Type string
+	ID   string
+
+	// OwnerID is the user ID of the student the resource belongs to
+	OwnerID string
+	// OwnerAdvisorID is the lecturers.id of the owner's academic advisor
+	OwnerAdvisorID string
+	// OwnerProgramStudy is the owner's program study, matched against admin scopes
+	OwnerProgramStudy string
+
+	// VerificationStage is the stage of the verification chain the resource
+	// waits for; empty when it is not in verification
+	VerificationStage string
+}
+
+// Rule decides one way a subject can be allowed
+type Rule func(subject Subject, resource Resource) bool
+
+// Owner allows the student the resource belongs to
+func Owner(subject Subject, resource Resource) bool {
+	return subject.UserID != "" && subject.UserID == resource.OwnerID
+}
+
+// AdvisorOfOwner allows the academic advisor of the owner
+func AdvisorOfOwner(subject Subject, resource Resource) bool {
+	return subject.LecturerID != "" && subject.LecturerID == resource.OwnerAdvisorID
+}
+
+// DelegateOfAdvisor allows a lecturer the owner's advisor delegated verification to
+func DelegateOfAdvisor(subject Subject, resource Resource) bool {
+	if resource.OwnerAdvisorID == "" {
+		return false
+	}
+	for _, delegatorID := range subject.DelegatorIDs {
+		if delegatorID == resource.OwnerAdvisorID {
+			return true
+		}
+	}
+	return false
+}
+
+// StageAdvisor is the verification stage of the owner's academic advisor. Every
+// other stage is the name of the role whose holders approve it.
+const StageAdvisor = "advisor"
+
+// StageVerifier allows whoever approves the verification stage the resource
+// waits for: the advisor of the owner or their delegate for the advisor stage
+// (and outside a chain), else holders of the stage's role, limited to their
+// scopes if they have any
+func StageVerifier(subject Subject, resource Resource) bool {
+	if resource.VerificationStage == "" || resource.VerificationStage == StageAdvisor {
+		return AdvisorOfOwner(subject, resource) || DelegateOfAdvisor(subject, resource)
+	}
+	if !subject.HasRole(resource.VerificationStage) {
+		return false
+	}
+	return len(subject.Scopes) == 0 || InScope(subject.Scopes, resource.OwnerProgramStudy)
+}
+
+// DepartmentAdmin allows department admins scoped to the owner's program study.
+// Scopes alone grant nothing: they outlive the role and other roles have them too.
+func DepartmentAdmin(subject Subject, resource Resource) bool {
+	return subject.HasRole(RoleDepartmentAdmin) && InScope(subject.Scopes, resource.OwnerProgramStudy)
+}
+
+// InScope reports whether a program study or department is one of the scopes,
+// ignoring case and surrounding spaces. An empty value is in no scope.
+func InScope(scopes []string, value string) bool {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return false
+	}
+	for _, scope := range scopes {
+		if strings.EqualFold(strings.TrimSpace(scope), value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Admin allows system administrators
+func Admin(subject Subject, resource Resource) bool {
+	return subject.HasRole(RoleAdmin)
+}
+
+// ErrForbidden is returned by Authorize when no rule allows the action
+var ErrForbidden = errors.New("forbidden")
+
+// Policy maps each resource type and action to the rules that allow it. An
+// action is allowed when any of its rules allows it; an action without rules is
+// denied.
+type Policy struct {
+	rules map[string]map[Action][]Rule
+}
+
+func New() *Policy {
+	return &Policy{rules: make(map[string]map[Action][]Rule)}
+}
+
+// Allow adds rules that permit an action on a resource type
+func (p *Policy) Allow(resourceType string, action Action, rules ...Rule) *Policy {
+	if p.rules[resourceType] == nil {
+		p.rules[resourceType] = make(map[Action][]Rule)
+	}
+	p.rules[resourceType][action] = append(p.rules[resourceType][action], rules...)
+	return p
+}
+
+// Can reports whether the subject may perform the action on the resource
+func (p *Policy) Can(subject Subject, action Action, resource Resource) bool {
+	for _, rule := range p.rules[resource.Type][action] {
+		if rule(subject, resource) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize is Can returning ErrForbidden on denial
+func (p *Policy) Authorize(subject Subject, action Action, resource Resource) error {
+	if !p.Can(subject, action, resource) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// Default is the application policy. Achievements are edited, deleted and
+// submitted by their owner only; the advisor (or their delegate), or the
+// verifier of the stage of the verification chain they are at, verifies them;
+// owner, advisor, delegate, the verifier of the current stage and
+// administrators (system-wide or of the owner's program study) may view them.
+func Default() *Policy {
+	return New().
+		Allow(ResourceAchievement, ActionView, Owner, AdvisorOfOwner, DelegateOfAdvisor, StageVerifier, DepartmentAdmin, Admin).
+		Allow(ResourceAchievement, ActionUpdate, Owner).
+		Allow(ResourceAchievement, ActionDelete, Owner).
+		Allow(ResourceAchievement, ActionSubmit, Owner).
+		Allow(ResourceAchievement, ActionVerify, StageVerifier)
+}
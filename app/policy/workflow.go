@@ -0,0 +1,145 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Achievement reference statuses
+const (
+	StatusDraft     = "draft"
+	StatusSubmitted = "submitted"
+	StatusVerified  = "verified"
+	StatusRejected  = "rejected"
+	StatusDeleted   = "deleted"
+
+	// StatusRevisionRequested is an achievement returned to the student with
+	// feedback to address before submitting it again
+	StatusRevisionRequested = "revision_requested"
+)
+
+// Transition is one allowed change of status
+type Transition struct {
+	From string
+	To   string
+
+	// Action is what the subject must be allowed to do on the resource to
+	// trigger the transition, e.g. only who may verify may reject
+	Action Action
+
+	// RequiresNote makes the transition fail without a note, e.g. the reason
+	// of a rejection
+	RequiresNote bool
+}
+
+// ErrNoteRequired is returned by Check when the transition needs a note
+var ErrNoteRequired = errors.New("a note is required for this status change")
+
+// TransitionError is returned by Check when the workflow has no transition
+// between the two statuses
+type TransitionError struct {
+	From string
+	To   string
+
+	// Allowed are the statuses the resource can move to from its current one
+	Allowed []string
+}
+
+func (e *TransitionError) Error() string {
+	if len(e.Allowed) == 0 {
+		return fmt.Sprintf("status cannot change from '%s'", e.From)
+	}
+	return fmt.Sprintf("status cannot change from '%s' to '%s', allowed: %s", e.From, e.To, strings.Join(e.Allowed, ", "))
+}
+
+// Workflow is a state machine over statuses: which transitions exist, who may
+// trigger them and what they require. Like Policy it only decides; the caller
+// loads and stores the resource.
+type Workflow struct {
+	transitions map[string]map[string]Transition
+	notes       map[string]bool
+	order       map[string][]string
+	editable    map[string]bool
+}
+
+func NewWorkflow() *Workflow {
+	return &Workflow{
+		transitions: make(map[string]map[string]Transition),
+		notes:       make(map[string]bool),
+		order:       make(map[string][]string),
+		editable:    make(map[string]bool),
+	}
+}
+
+// Allow adds transitions to a status from each of the given statuses, triggered
+// by subjects allowed the action
+func (w *Workflow) Allow(action Action, to string, from ...string) *Workflow {
+	for _, status := range from {
+		if w.transitions[status] == nil {
+			w.transitions[status] = make(map[string]Transition)
+		}
+		if _, exists := w.transitions[status][to]; !exists {
+			w.order[status] = append(w.order[status], to)
+		}
+		w.transitions[status][to] = Transition{From: status, To: to, Action: action}
+	}
+	return w
+}
+
+// RequireNote makes every transition to the status require a note
+func (w *Workflow) RequireNote(to string) *Workflow {
+	w.notes[to] = true
+	return w
+}
+
+// AllowEdit lets the owner edit the content of resources in the statuses
+func (w *Workflow) AllowEdit(statuses ...string) *Workflow {
+	for _, status := range statuses {
+		w.editable[status] = true
+	}
+	return w
+}
+
+// Editable reports whether the content of a resource may change in the status
+func (w *Workflow) Editable(status string) bool {
+	return w.editable[status]
+}
+
+// Next returns the statuses reachable from a status, in the order they were added
+func (w *Workflow) Next(from string) []string {
+	return append([]string{}, w.order[from]...)
+}
+
+// Check returns the transition between two statuses. It fails with a
+// *TransitionError when there is none and with ErrNoteRequired when the
+// transition needs a note and the note is blank. Whether the subject may
+// trigger it is left to the policy, using the transition's Action.
+func (w *Workflow) Check(from, to, note string) (Transition, error) {
+	transition, ok := w.transitions[from][to]
+	if !ok {
+		return Transition{}, &TransitionError{From: from, To: to, Allowed: w.Next(from)}
+	}
+
+	transition.RequiresNote = w.notes[to]
+	if transition.RequiresNote && strings.TrimSpace(note) == "" {
+		return Transition{}, ErrNoteRequired
+	}
+	return transition, nil
+}
+
+// DefaultWorkflow is the life cycle of an achievement: the owner submits a
+// draft or deletes it, the advisor verifies a submission, rejects it or returns
+// it for revision, giving the reason or feedback. The owner edits rejected and
+// returned achievements and submits them again. Verified and deleted are final.
+func DefaultWorkflow() *Workflow {
+	return NewWorkflow().
+		Allow(ActionSubmit, StatusSubmitted, StatusDraft, StatusRejected, StatusRevisionRequested).
+		Allow(ActionDelete, StatusDeleted, StatusDraft).
+		Allow(ActionVerify, StatusVerified, StatusSubmitted).
+		Allow(ActionVerify, StatusRejected, StatusSubmitted).
+		Allow(ActionVerify, StatusRevisionRequested, StatusSubmitted).
+		RequireNote(StatusRejected).
+		RequireNote(StatusRevisionRequested).
+		AllowEdit(StatusDraft, StatusRejected, StatusRevisionRequested)
+}
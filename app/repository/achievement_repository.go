@@ -169,16 +169,78 @@ func (r *AchievementRepository) Update(achievement *model.Achievement) error {
 	return err
 }
 
+// UpdateReference saves a reference. The verification outcome fields are left
+// out of $set when empty (omitempty), so they are unset to clear them.
+// reminded_at and escalated_at are only written by MarkReferenceReminded and
+// MarkReferenceEscalated: a reference loaded before a reminder must not roll
+// them back.
 func (r *AchievementRepository) UpdateReference(ref *model.AchievementReference) error {
 	ref.UpdatedAt = time.Now()
+
+	raw, err := bson.Marshal(ref)
+	if err != nil {
+		return err
+	}
+	var set bson.M
+	if err := bson.Unmarshal(raw, &set); err != nil {
+		return err
+	}
+	delete(set, "reminded_at")
+	delete(set, "escalated_at")
 	
 	filter := bson.M{"_id": ref.ID}
-	update := bson.M{"$set": ref}
+	update := bson.M{"$set": set}
+
+	unset := bson.M{}
+	if ref.VerifiedAt.IsZero() {
+		unset["verified_at"] = ""
+	}
+	if ref.VerifiedBy == "" {
+		unset["verified_by"] = ""
+	}
+	if ref.VerifiedOnBehalfOf == "" {
+		unset["verified_on_behalf_of"] = ""
+	}
+	if ref.RejectionNote == "" {
+		unset["rejection_note"] = ""
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
 	
-	_, err := r.referenceCollection.UpdateOne(context.Background(), filter, update)
+	_, err = r.referenceCollection.UpdateOne(context.Background(), filter, update)
 	return err
 }
 
+// MarkReferenceReminded records when the verifiers of a reference were reminded,
+// without overwriting the rest of the reference. It only marks a reference still
+// waiting at the given stage; false means it was decided or moved on meanwhile.
+func (r *AchievementRepository) MarkReferenceReminded(id primitive.ObjectID, stage int, at time.Time) (bool, error) {
+	return r.markReferenceAtStage(id, stage, "reminded_at", at)
+}
+
+// MarkReferenceEscalated records when a reference was escalated to the
+// administrators, without overwriting the rest of the reference. Like
+// MarkReferenceReminded it only marks a reference still waiting at the stage.
+func (r *AchievementRepository) MarkReferenceEscalated(id primitive.ObjectID, stage int, at time.Time) (bool, error) {
+	return r.markReferenceAtStage(id, stage, "escalated_at", at)
+}
+
+func (r *AchievementRepository) markReferenceAtStage(id primitive.ObjectID, stage int, field string, at time.Time) (bool, error) {
+	var currentStage interface{} = stage
+	if stage == 0 {
+		// References submitted before verification chains have no current_stage
+		currentStage = bson.M{"$in": bson.A{0, nil}}
+	}
+
+	filter := bson.M{"_id": id, "status": "submitted", "current_stage": currentStage}
+	result, err := r.referenceCollection.UpdateOne(context.Background(), filter, bson.M{"$set": bson.M{field: at}})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
+}
+
 func (r *AchievementRepository) Delete(id primitive.ObjectID) error {
 	_, err := r.collection.DeleteOne(context.Background(), bson.M{"_id": id})
 	return err
@@ -319,7 +381,8 @@ func (r *AchievementRepository) CountByStudentIDs(studentIDs []string) (int64, e
 }
 
 // FR-010: Get all achievement references with filters and pagination
-func (r *AchievementRepository) GetAllReferencesWithFilters(page, limit int, status, studentID string) ([]model.AchievementReference, int, error) {
+// studentIDs, when not nil, limits the results to those students (scoped admins)
+func (r *AchievementRepository) GetAllReferencesWithFilters(page, limit int, status, studentID string, studentIDs []string) ([]model.AchievementReference, int, error) {
 	var references []model.AchievementReference
 	
 	// Build filter
@@ -330,6 +393,13 @@ func (r *AchievementRepository) GetAllReferencesWithFilters(page, limit int, sta
 	if studentID != "" {
 		filter["student_id"] = studentID
 	}
+	if studentIDs != nil {
+		studentFilter := bson.M{"$in": studentIDs}
+		if studentID != "" {
+			studentFilter["$eq"] = studentID
+		}
+		filter["student_id"] = studentFilter
+	}
 	
 	// Calculate offset
 	offset := (page - 1) * limit
@@ -356,6 +426,36 @@ func (r *AchievementRepository) GetAllReferencesWithFilters(page, limit int, sta
 	return references, int(total), err
 }
 
+// GetVerifiedReferences pages through verified achievements, oldest verification
+// first, so integrations can sync incrementally with the verified_at of the last item
+func (r *AchievementRepository) GetVerifiedReferences(page, limit int, verifiedSince time.Time) ([]model.AchievementReference, int, error) {
+	var references []model.AchievementReference
+	
+	filter := bson.M{"status": "verified"}
+	if !verifiedSince.IsZero() {
+		filter["verified_at"] = bson.M{"$gt": verifiedSince}
+	}
+	
+	total, err := r.referenceCollection.CountDocuments(context.Background(), filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	
+	opts := options.Find()
+	opts.SetLimit(int64(limit))
+	opts.SetSkip(int64((page - 1) * limit))
+	opts.SetSort(bson.D{{Key: "verified_at", Value: 1}, {Key: "_id", Value: 1}})
+	
+	cursor, err := r.referenceCollection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(context.Background())
+	
+	err = cursor.All(context.Background(), &references)
+	return references, int(total), err
+}
+
 // FR-010: Get achievements by IDs with filters and sorting
 func (r *AchievementRepository) GetAchievementsByIDsWithFilters(achievementIDs []string, category, sortBy, sortOrder string) ([]model.Achievement, error) {
 	var achievements []model.Achievement
@@ -411,10 +511,19 @@ func (r *AchievementRepository) GetAchievementsByIDsWithFilters(achievementIDs [
 	err = cursor.All(context.Background(), &achievements)
 	return achievements, err
 }
-// FR-010: Get achievement statistics for admin dashboard
-func (r *AchievementRepository) GetAchievementStatistics() (fiber.Map, error) {
+// FR-010: Get achievement statistics for admin dashboard. studentIDs, when not
+// nil, limits the statistics to those students (scoped admins).
+func (r *AchievementRepository) GetAchievementStatistics(studentIDs []string) (fiber.Map, error) {
+	studentMatch := bson.M{}
+	if studentIDs != nil {
+		studentMatch["student_id"] = bson.M{"$in": studentIDs}
+	}
+
 	// Get status statistics from references
 	statusPipeline := []bson.M{
+		{
+			"$match": studentMatch,
+		},
 		{
 			"$group": bson.M{
 				"_id":   "$status",
@@ -442,11 +551,13 @@ func (r *AchievementRepository) GetAchievementStatistics() (fiber.Map, error) {
 	}
 	
 	// Get category statistics from achievements
+	categoryMatch := bson.M{"deleted_at": bson.M{"$exists": false}}
+	if studentIDs != nil {
+		categoryMatch["student_id"] = bson.M{"$in": studentIDs}
+	}
 	categoryPipeline := []bson.M{
 		{
-			"$match": bson.M{
-				"deleted_at": bson.M{"$exists": false},
-			},
+			"$match": categoryMatch,
 		},
 		{
 			"$group": bson.M{
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"UASBE/database"
+	"database/sql"
+)
+
+// AdminScopeRepository stores the program studies and departments a department
+// administrator manages (admin_scopes)
+type AdminScopeRepository struct {
+	db *sql.DB
+}
+
+func NewAdminScopeRepository() *AdminScopeRepository {
+	return &AdminScopeRepository{
+		db: database.GetPostgresDB(),
+	}
+}
+
+// GetScopes returns the scopes of a user, empty when the user has none
+func (r *AdminScopeRepository) GetScopes(userID string) ([]string, error) {
+	rows, err := r.db.Query("SELECT scope FROM admin_scopes WHERE user_id = $1 ORDER BY scope", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scopes := []string{}
+	for rows.Next() {
+		var scope string
+		if err := rows.Scan(&scope); err != nil {
+			return nil, err
+		}
+		scopes = append(scopes, scope)
+	}
+
+	return scopes, rows.Err()
+}
+
+// SetScopes replaces the scopes of a user
+func (r *AdminScopeRepository) SetScopes(userID string, scopes []string, grantedBy string) error {
+	var granter interface{}
+	if grantedBy != "" {
+		granter = grantedBy
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM admin_scopes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+
+	for _, scope := range scopes {
+		_, err := tx.Exec(`
+			INSERT INTO admin_scopes (user_id, scope, granted_by, granted_at) VALUES ($1, $2, $3, NOW())
+			ON CONFLICT DO NOTHING
+		`, userID, scope, granter)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"UASBE/app/model"
+	"UASBE/database"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AuditLogRepository struct {
+	db *sql.DB
+}
+
+func NewAuditLogRepository() *AuditLogRepository {
+	return &AuditLogRepository{
+		db: database.GetPostgresDB(),
+	}
+}
+
+// nullableString stores empty strings as NULL
+func nullableString(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+func (r *AuditLogRepository) Create(entry *model.AuditLog) error {
+	entry.ID = uuid.New().String()
+	entry.CreatedAt = time.Now()
+
+	var statusCode interface{}
+	if entry.StatusCode != 0 {
+		statusCode = entry.StatusCode
+	}
+
+	query := `
+		INSERT INTO audit_logs (id, actor_id, subject_user_id, action, method, path, status_code, ip_address, user_agent, details, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+
+	_, err := r.db.Exec(query, entry.ID, nullableString(entry.ActorID), nullableString(entry.SubjectUserID),
+		entry.Action, nullableString(entry.Method), nullableString(entry.Path), statusCode,
+		nullableString(entry.IPAddress), nullableString(entry.UserAgent), nullableString(entry.Details), entry.CreatedAt)
+
+	return err
+}
+
+// List returns matching entries, newest first, and the total number of matches
+func (r *AuditLogRepository) List(filter model.AuditLogFilter) ([]model.AuditLog, int, error) {
+	var conditions []string
+	var args []interface{}
+	addCondition := func(column, value string) {
+		if value == "" {
+			return
+		}
+		args = append(args, value)
+		conditions = append(conditions, column+" = $"+strconv.Itoa(len(args)))
+	}
+	addCondition("actor_id", filter.ActorID)
+	addCondition("subject_user_id", filter.SubjectUserID)
+	addCondition("action", filter.Action)
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM audit_logs"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, filter.Limit, filter.Offset)
+	query := `
+		SELECT id, actor_id, subject_user_id, action, method, path, status_code, ip_address, user_agent, details, created_at
+		FROM audit_logs` + where + `
+		ORDER BY created_at DESC
+		LIMIT $` + strconv.Itoa(len(args)-1) + ` OFFSET $` + strconv.Itoa(len(args))
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var entries []model.AuditLog
+	for rows.Next() {
+		var entry model.AuditLog
+		var actorID, subjectUserID, method, path, ipAddress, userAgent, details sql.NullString
+		var statusCode sql.NullInt64
+
+		err := rows.Scan(&entry.ID, &actorID, &subjectUserID, &entry.Action, &method, &path,
+			&statusCode, &ipAddress, &userAgent, &details, &entry.CreatedAt)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		entry.ActorID = actorID.String
+		entry.SubjectUserID = subjectUserID.String
+		entry.Method = method.String
+		entry.Path = path.String
+		entry.StatusCode = int(statusCode.Int64)
+		entry.IPAddress = ipAddress.String
+		entry.UserAgent = userAgent.String
+		entry.Details = details.String
+		entries = append(entries, entry)
+	}
+
+	return entries, total, rows.Err()
+}
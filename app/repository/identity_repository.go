@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"UASBE/app/model"
+	"UASBE/database"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type IdentityRepository struct {
+	db *sql.DB
+}
+
+func NewIdentityRepository() *IdentityRepository {
+	return &IdentityRepository{
+		db: database.GetPostgresDB(),
+	}
+}
+
+// CreateState stores a pending authorization request
+func (r *IdentityRepository) CreateState(state *model.OIDCState) error {
+	state.ID = uuid.New().String()
+	state.CreatedAt = time.Now()
+
+	_, err := r.db.Exec(`
+		INSERT INTO oidc_states (id, state_hash, nonce, code_verifier, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, state.ID, state.StateHash, state.Nonce, state.CodeVerifier, state.ExpiresAt, state.CreatedAt)
+	return err
+}
+
+// ConsumeState deletes and returns an unexpired authorization request, so each
+// state can complete exactly one login; sql.ErrNoRows means unknown or expired.
+func (r *IdentityRepository) ConsumeState(stateHash string) (*model.OIDCState, error) {
+	var state model.OIDCState
+
+	err := r.db.QueryRow(`
+		DELETE FROM oidc_states
+		WHERE state_hash = $1 AND expires_at > NOW()
+		RETURNING id, state_hash, nonce, code_verifier, expires_at, created_at
+	`, stateHash).Scan(
+		&state.ID, &state.StateHash, &state.Nonce, &state.CodeVerifier, &state.ExpiresAt, &state.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+// DeleteExpiredStates prunes authorization requests that were never completed
+func (r *IdentityRepository) DeleteExpiredStates() error {
+	_, err := r.db.Exec("DELETE FROM oidc_states WHERE expires_at < NOW()")
+	return err
+}
+
+func (r *IdentityRepository) GetByIssuerSubject(issuer, subject string) (*model.UserIdentity, error) {
+	var identity model.UserIdentity
+	var email sql.NullString
+
+	err := r.db.QueryRow(`
+		SELECT id, user_id, issuer, subject, email, created_at, last_login_at
+		FROM user_identities WHERE issuer = $1 AND subject = $2
+	`, issuer, subject).Scan(
+		&identity.ID, &identity.UserID, &identity.Issuer, &identity.Subject,
+		&email, &identity.CreatedAt, &identity.LastLoginAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	identity.Email = email.String
+	return &identity, nil
+}
+
+// Create links an external identity to a user
+func (r *IdentityRepository) Create(identity *model.UserIdentity) error {
+	identity.ID = uuid.New().String()
+	identity.CreatedAt = time.Now()
+
+	_, err := r.db.Exec(`
+		INSERT INTO user_identities (id, user_id, issuer, subject, email, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, identity.ID, identity.UserID, identity.Issuer, identity.Subject, identity.Email, identity.CreatedAt)
+	return err
+}
+
+// TouchLogin records a login through the identity and refreshes the email snapshot
+func (r *IdentityRepository) TouchLogin(id, email string) error {
+	_, err := r.db.Exec(`
+		UPDATE user_identities SET last_login_at = NOW(), email = $2 WHERE id = $1
+	`, id, email)
+	return err
+}
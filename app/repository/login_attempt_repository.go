@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"UASBE/app/model"
+	"UASBE/database"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Throttle scopes
+const (
+	ThrottleScopeAccount = "account"
+	ThrottleScopeIP      = "ip"
+)
+
+type LoginAttemptRepository struct {
+	db *sql.DB
+}
+
+func NewLoginAttemptRepository() *LoginAttemptRepository {
+	return &LoginAttemptRepository{
+		db: database.GetPostgresDB(),
+	}
+}
+
+func (r *LoginAttemptRepository) Create(attempt *model.LoginAttempt) error {
+	attempt.ID = uuid.New().String()
+	attempt.CreatedAt = time.Now()
+
+	var userID interface{}
+	if attempt.UserID != "" {
+		userID = attempt.UserID
+	}
+
+	query := `
+		INSERT INTO login_attempts (id, user_id, credential, ip_address, user_agent, success, failure_reason, ip_range, device, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	_, err := r.db.Exec(query, attempt.ID, userID, attempt.Credential, attempt.IPAddress,
+		attempt.UserAgent, attempt.Success, attempt.FailureReason, attempt.IPRange, attempt.Device, attempt.CreatedAt)
+
+	return err
+}
+
+// GetByUserID pages through the login attempts of a user, newest first
+func (r *LoginAttemptRepository) GetByUserID(userID string, limit, offset int) ([]model.LoginAttempt, int, error) {
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM login_attempts WHERE user_id = $1", userID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.Query(`
+		SELECT id, user_id, credential, COALESCE(ip_address, ''), COALESCE(user_agent, ''), success,
+		       COALESCE(failure_reason, ''), COALESCE(ip_range, ''), COALESCE(device, ''), created_at
+		FROM login_attempts
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`, userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	attempts := []model.LoginAttempt{}
+	for rows.Next() {
+		var attempt model.LoginAttempt
+		err := rows.Scan(&attempt.ID, &attempt.UserID, &attempt.Credential, &attempt.IPAddress, &attempt.UserAgent,
+			&attempt.Success, &attempt.FailureReason, &attempt.IPRange, &attempt.Device, &attempt.CreatedAt)
+		if err != nil {
+			return nil, 0, err
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, total, rows.Err()
+}
+
+// GetLoginFootprint tells whether the user has logged in successfully before and
+// whether the IP range and the device were among those earlier logins. Attempts
+// recorded before ranges and devices were stored do not count.
+func (r *LoginAttemptRepository) GetLoginFootprint(userID, ipRange, device string) (hasHistory, ipRangeSeen, deviceSeen bool, err error) {
+	query := `
+		SELECT COUNT(*) > 0,
+		       COALESCE(BOOL_OR(ip_range = $2), false),
+		       COALESCE(BOOL_OR(device = $3), false)
+		FROM login_attempts
+		WHERE user_id = $1 AND success AND device IS NOT NULL
+	`
+	err = r.db.QueryRow(query, userID, ipRange, device).Scan(&hasHistory, &ipRangeSeen, &deviceSeen)
+	return hasHistory, ipRangeSeen, deviceSeen, err
+}
+
+// GetThrottle returns the failed-login state of a scope. Durations are computed
+// by the database relative to NOW(); an unknown key has a zero state.
+func (r *LoginAttemptRepository) GetThrottle(scope, key string) (failures int, sinceLastFailure, lockRemaining time.Duration, err error) {
+	var since, remaining float64
+
+	query := `
+		SELECT failed_count,
+		       COALESCE(EXTRACT(EPOCH FROM NOW() - last_failed_at), 0),
+		       COALESCE(GREATEST(EXTRACT(EPOCH FROM locked_until - NOW()), 0), 0)
+		FROM login_throttles WHERE scope = $1 AND key = $2
+	`
+
+	err = r.db.QueryRow(query, scope, key).Scan(&failures, &since, &remaining)
+	if err == sql.ErrNoRows {
+		return 0, 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	return failures, time.Duration(since * float64(time.Second)), time.Duration(remaining * float64(time.Second)), nil
+}
+
+// RecordThrottleFailure atomically counts a failure and returns the new count.
+// Failures older than window are forgotten first.
+func (r *LoginAttemptRepository) RecordThrottleFailure(scope, key string, window time.Duration) (int, error) {
+	var failures int
+
+	query := `
+		INSERT INTO login_throttles (scope, key, failed_count, last_failed_at)
+		VALUES ($1, $2, 1, NOW())
+		ON CONFLICT (scope, key) DO UPDATE SET
+			failed_count = CASE
+				WHEN login_throttles.last_failed_at < NOW() - $3::float8 * INTERVAL '1 second' THEN 1
+				ELSE login_throttles.failed_count + 1
+			END,
+			last_failed_at = NOW()
+		RETURNING failed_count
+	`
+
+	err := r.db.QueryRow(query, scope, key, window.Seconds()).Scan(&failures)
+	return failures, err
+}
+
+// LockThrottle locks a scope for the given duration and starts counting afresh
+func (r *LoginAttemptRepository) LockThrottle(scope, key string, duration time.Duration) error {
+	query := `
+		UPDATE login_throttles
+		SET locked_until = NOW() + $3::float8 * INTERVAL '1 second', failed_count = 0, lockout_count = lockout_count + 1
+		WHERE scope = $1 AND key = $2
+	`
+	_, err := r.db.Exec(query, scope, key, duration.Seconds())
+	return err
+}
+
+// ResetThrottle clears failures and any lock of a scope (successful login, admin unlock)
+func (r *LoginAttemptRepository) ResetThrottle(scope, key string) error {
+	_, err := r.db.Exec("DELETE FROM login_throttles WHERE scope = $1 AND key = $2", scope, key)
+	return err
+}
+
+// IsLocked reports whether a scope is currently locked out
+func (r *LoginAttemptRepository) IsLocked(scope, key string) (bool, error) {
+	var locked bool
+	query := `
+		SELECT EXISTS (SELECT 1 FROM login_throttles WHERE scope = $1 AND key = $2 AND locked_until > NOW())
+	`
+	err := r.db.QueryRow(query, scope, key).Scan(&locked)
+	return locked, err
+}
@@ -39,7 +39,7 @@ func (r *NotificationRepository) GetByUserID(userID string) ([]model.Notificatio
 	defer cancel()
 
 	filter := bson.M{"user_id": userID}
-	opts := options.Find().SetSort(bson.D{{"created_at", -1}})
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
 
 	cursor, err := r.collection.Find(ctx, filter, opts)
 	if err != nil {
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"UASBE/app/model"
+	"UASBE/database"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+type PermissionRepository struct {
+	db *sql.DB
+}
+
+func NewPermissionRepository() *PermissionRepository {
+	return &PermissionRepository{
+		db: database.GetPostgresDB(),
+	}
+}
+
+const permissionColumns = `p.id, p.name, p.resource, p.action, COALESCE(p.description, '')`
+
+func scanPermissions(rows *sql.Rows) ([]model.Permission, error) {
+	permissions := []model.Permission{}
+	for rows.Next() {
+		var permission model.Permission
+		err := rows.Scan(&permission.ID, &permission.Name, &permission.Resource, &permission.Action, &permission.Description)
+		if err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, permission)
+	}
+	return permissions, rows.Err()
+}
+
+func (r *PermissionRepository) GetAll() ([]model.Permission, error) {
+	rows, err := r.db.Query("SELECT " + permissionColumns + " FROM permissions p ORDER BY p.resource, p.action")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanPermissions(rows)
+}
+
+func (r *PermissionRepository) GetByID(id string) (*model.Permission, error) {
+	var permission model.Permission
+	err := r.db.QueryRow("SELECT "+permissionColumns+" FROM permissions p WHERE p.id = $1", id).Scan(
+		&permission.ID, &permission.Name, &permission.Resource, &permission.Action, &permission.Description,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &permission, nil
+}
+
+func (r *PermissionRepository) GetByResourceAction(resource, action string) (*model.Permission, error) {
+	var permission model.Permission
+	err := r.db.QueryRow("SELECT "+permissionColumns+" FROM permissions p WHERE p.resource = $1 AND p.action = $2", resource, action).Scan(
+		&permission.ID, &permission.Name, &permission.Resource, &permission.Action, &permission.Description,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &permission, nil
+}
+
+func (r *PermissionRepository) Create(permission *model.Permission) error {
+	permission.ID = uuid.New().String()
+	permission.Name = permission.Key()
+
+	query := `
+		INSERT INTO permissions (id, name, resource, action, description, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+	`
+
+	_, err := r.db.Exec(query, permission.ID, permission.Name, permission.Resource, permission.Action, permission.Description)
+	return err
+}
+
+// UpdateDescription changes the description only; resource and action identify
+// the permission in code and are immutable
+func (r *PermissionRepository) UpdateDescription(id, description string) error {
+	_, err := r.db.Exec("UPDATE permissions SET description = $2 WHERE id = $1", id, description)
+	return err
+}
+
+// Delete removes the permission and, through the foreign key, every grant of it
+func (r *PermissionRepository) Delete(id string) error {
+	_, err := r.db.Exec("DELETE FROM permissions WHERE id = $1", id)
+	return err
+}
+
+// GetRoleIDsWithPermission lists the roles that hold the permission
+func (r *PermissionRepository) GetRoleIDsWithPermission(permissionID string) ([]string, error) {
+	rows, err := r.db.Query("SELECT role_id FROM role_permissions WHERE permission_id = $1", permissionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roleIDs []string
+	for rows.Next() {
+		var roleID string
+		if err := rows.Scan(&roleID); err != nil {
+			return nil, err
+		}
+		roleIDs = append(roleIDs, roleID)
+	}
+	return roleIDs, rows.Err()
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"UASBE/app/model"
+	"UASBE/database"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type RoleRepository struct {
+	db *sql.DB
+}
+
+func NewRoleRepository() *RoleRepository {
+	return &RoleRepository{
+		db: database.GetPostgresDB(),
+	}
+}
+
+const roleColumns = `id, name, COALESCE(description, ''), is_system, created_at, COALESCE(updated_at, created_at)`
+
+func scanRole(row interface{ Scan(...interface{}) error }) (*model.Role, error) {
+	var role model.Role
+	err := row.Scan(&role.ID, &role.Name, &role.Description, &role.IsSystem, &role.CreatedAt, &role.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *RoleRepository) GetByID(id string) (*model.Role, error) {
+	return scanRole(r.db.QueryRow("SELECT "+roleColumns+" FROM roles WHERE id = $1", id))
+}
+
+func (r *RoleRepository) GetByName(name string) (*model.Role, error) {
+	return scanRole(r.db.QueryRow("SELECT "+roleColumns+" FROM roles WHERE name = $1", name))
+}
+
+func (r *RoleRepository) GetAll() ([]model.Role, error) {
+	rows, err := r.db.Query("SELECT " + roleColumns + " FROM roles ORDER BY is_system DESC, name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []model.Role{}
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, *role)
+	}
+
+	return roles, rows.Err()
+}
+
+func (r *RoleRepository) Create(role *model.Role) error {
+	role.ID = uuid.New().String()
+	role.CreatedAt = time.Now()
+	role.UpdatedAt = time.Now()
+
+	query := `
+		INSERT INTO roles (id, name, description, is_system, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := r.db.Exec(query, role.ID, role.Name, role.Description, role.IsSystem, role.CreatedAt, role.UpdatedAt)
+	return err
+}
+
+func (r *RoleRepository) Update(role *model.Role) error {
+	role.UpdatedAt = time.Now()
+
+	query := "UPDATE roles SET name = $2, description = $3, updated_at = $4 WHERE id = $1"
+	_, err := r.db.Exec(query, role.ID, role.Name, role.Description, role.UpdatedAt)
+	return err
+}
+
+func (r *RoleRepository) Delete(id string) error {
+	_, err := r.db.Exec("DELETE FROM roles WHERE id = $1 AND is_system = false", id)
+	return err
+}
+
+// CountUsers returns how many users currently have the role, as their primary
+// role or as an additional one
+func (r *RoleRepository) CountUsers(roleID string) (int, error) {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*) FROM users u
+		WHERE u.role_id = $1
+		   OR EXISTS (SELECT 1 FROM user_roles ur WHERE ur.user_id = u.id AND ur.role_id = $1)
+	`, roleID).Scan(&count)
+	return count, err
+}
+
+// GetPermissionsByRoleID returns the permissions granted to a role
+func (r *RoleRepository) GetPermissionsByRoleID(roleID string) ([]model.Permission, error) {
+	query := `
+		SELECT ` + permissionColumns + `
+		FROM permissions p
+		JOIN role_permissions rp ON p.id = rp.permission_id
+		WHERE rp.role_id = $1
+		ORDER BY p.resource, p.action
+	`
+
+	rows, err := r.db.Query(query, roleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanPermissions(rows)
+}
+
+// SetPermissions replaces every grant of the role with the given permissions
+func (r *RoleRepository) SetPermissions(roleID string, permissionIDs []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM role_permissions WHERE role_id = $1", roleID); err != nil {
+		return err
+	}
+
+	for _, permissionID := range permissionIDs {
+		_, err := tx.Exec(`
+			INSERT INTO role_permissions (role_id, permission_id) VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`, roleID, permissionID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *RoleRepository) GrantPermission(roleID, permissionID string) error {
+	_, err := r.db.Exec(`
+		INSERT INTO role_permissions (role_id, permission_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, roleID, permissionID)
+	return err
+}
+
+func (r *RoleRepository) RevokePermission(roleID, permissionID string) error {
+	_, err := r.db.Exec("DELETE FROM role_permissions WHERE role_id = $1 AND permission_id = $2", roleID, permissionID)
+	return err
+}
@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"UASBE/app/model"
+	"UASBE/database"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ServiceAccountRepository stores service accounts and their API keys
+type ServiceAccountRepository struct {
+	db *sql.DB
+}
+
+func NewServiceAccountRepository() *ServiceAccountRepository {
+	return &ServiceAccountRepository{
+		db: database.GetPostgresDB(),
+	}
+}
+
+const serviceAccountQuery = `
+	SELECT u.id, u.username, u.full_name, u.role_id, r.name, u.is_active,
+	       COALESCE(sa.description, ''), COALESCE(sa.created_by::text, ''), sa.created_at,
+	       (SELECT COUNT(*) FROM api_keys k
+	        WHERE k.user_id = sa.user_id AND k.revoked_at IS NULL
+	          AND (k.expires_at IS NULL OR k.expires_at > NOW()))
+	FROM service_accounts sa
+	JOIN users u ON u.id = sa.user_id
+	JOIN roles r ON r.id = u.role_id
+`
+
+func scanServiceAccount(row interface{ Scan(...interface{}) error }) (*model.ServiceAccount, error) {
+	var account model.ServiceAccount
+	err := row.Scan(&account.UserID, &account.Username, &account.Name, &account.RoleID, &account.RoleName,
+		&account.IsActive, &account.Description, &account.CreatedBy, &account.CreatedAt, &account.ActiveKeys)
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// Create marks an existing user as a service account
+func (r *ServiceAccountRepository) Create(userID, description, createdBy string) error {
+	var creator interface{}
+	if createdBy != "" {
+		creator = createdBy
+	}
+
+	_, err := r.db.Exec(`
+		INSERT INTO service_accounts (user_id, description, created_by, created_at)
+		VALUES ($1, $2, $3, $4)
+	`, userID, description, creator, time.Now())
+	return err
+}
+
+func (r *ServiceAccountRepository) GetAll() ([]model.ServiceAccount, error) {
+	rows, err := r.db.Query(serviceAccountQuery + " ORDER BY u.username")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []model.ServiceAccount
+	for rows.Next() {
+		account, err := scanServiceAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, *account)
+	}
+
+	return accounts, rows.Err()
+}
+
+func (r *ServiceAccountRepository) GetByUserID(userID string) (*model.ServiceAccount, error) {
+	return scanServiceAccount(r.db.QueryRow(serviceAccountQuery+" WHERE sa.user_id = $1", userID))
+}
+
+const apiKeyColumns = "id, user_id, name, key_prefix, key_hash, scopes, created_by, created_at, expires_at, last_used_at, last_used_ip, revoked_at"
+
+func scanAPIKey(row interface{ Scan(...interface{}) error }) (*model.APIKey, error) {
+	var key model.APIKey
+	var createdBy, lastUsedIP sql.NullString
+
+	err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.KeyPrefix, &key.KeyHash, pq.Array(&key.Scopes),
+		&createdBy, &key.CreatedAt, &key.ExpiresAt, &key.LastUsedAt, &lastUsedIP, &key.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	key.CreatedBy = createdBy.String
+	key.LastUsedIP = lastUsedIP.String
+	return &key, nil
+}
+
+func (r *ServiceAccountRepository) CreateAPIKey(key *model.APIKey) error {
+	key.ID = uuid.New().String()
+	key.CreatedAt = time.Now()
+
+	var createdBy interface{}
+	if key.CreatedBy != "" {
+		createdBy = key.CreatedBy
+	}
+
+	_, err := r.db.Exec(`
+		INSERT INTO api_keys (id, user_id, name, key_prefix, key_hash, scopes, created_by, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, key.ID, key.UserID, key.Name, key.KeyPrefix, key.KeyHash, pq.Array(key.Scopes),
+		createdBy, key.CreatedAt, key.ExpiresAt)
+	return err
+}
+
+// GetAPIKeys lists every key of a service account, including revoked ones
+func (r *ServiceAccountRepository) GetAPIKeys(userID string) ([]model.APIKey, error) {
+	rows, err := r.db.Query("SELECT "+apiKeyColumns+" FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []model.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+
+	return keys, rows.Err()
+}
+
+// GetActiveAPIKeyByHash finds a key that is neither revoked nor expired
+func (r *ServiceAccountRepository) GetActiveAPIKeyByHash(keyHash string) (*model.APIKey, error) {
+	return scanAPIKey(r.db.QueryRow(`
+		SELECT `+apiKeyColumns+` FROM api_keys
+		WHERE key_hash = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+	`, keyHash))
+}
+
+// RevokeAPIKey revokes a key of the given service account. It returns false when
+// there was no such active key.
+func (r *ServiceAccountRepository) RevokeAPIKey(id, userID string) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE api_keys SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, id, userID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// TouchAPIKey records that a key was used. Like sessions, the row is written at
+// most once a minute so busy integrations do not cause a write per request.
+func (r *ServiceAccountRepository) TouchAPIKey(id, ipAddress string) error {
+	_, err := r.db.Exec(`
+		UPDATE api_keys SET last_used_at = NOW(), last_used_ip = $2
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute' OR last_used_ip IS DISTINCT FROM $2)
+	`, id, ipAddress)
+	return err
+}
+
+// Deactivate disables the account and revokes all of its keys in one step
+func (r *ServiceAccountRepository) Deactivate(userID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE users SET is_active = false, updated_at = NOW() WHERE id = $1", userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE api_keys SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL", userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"UASBE/app/model"
+	"UASBE/database"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SessionRepository struct {
+	db *sql.DB
+}
+
+func NewSessionRepository() *SessionRepository {
+	return &SessionRepository{
+		db: database.GetPostgresDB(),
+	}
+}
+
+const sessionColumns = "id, user_id, user_agent, ip_address, last_ip_address, created_at, last_seen_at, expires_at, revoked_at"
+
+func scanSession(row interface{ Scan(...interface{}) error }) (*model.Session, error) {
+	var session model.Session
+	var userAgent, ipAddress, lastIPAddress sql.NullString
+
+	err := row.Scan(
+		&session.ID, &session.UserID, &userAgent, &ipAddress, &lastIPAddress,
+		&session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt, &session.RevokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	session.UserAgent = userAgent.String
+	session.IPAddress = ipAddress.String
+	session.LastIPAddress = lastIPAddress.String
+	return &session, nil
+}
+
+// Create stores a new session; the ID becomes the family of its refresh tokens
+func (r *SessionRepository) Create(session *model.Session) error {
+	session.ID = uuid.New().String()
+	session.CreatedAt = time.Now()
+	session.LastSeenAt = session.CreatedAt
+	session.LastIPAddress = session.IPAddress
+
+	_, err := r.db.Exec(`
+		INSERT INTO sessions (id, user_id, user_agent, ip_address, last_ip_address, created_at, last_seen_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, session.ID, session.UserID, session.UserAgent, session.IPAddress, session.LastIPAddress,
+		session.CreatedAt, session.LastSeenAt, session.ExpiresAt)
+	return err
+}
+
+func (r *SessionRepository) GetByID(id string) (*model.Session, error) {
+	return scanSession(r.db.QueryRow("SELECT "+sessionColumns+" FROM sessions WHERE id = $1", id))
+}
+
+// GetActiveByUserID lists sessions that are neither revoked nor expired, most recently used first
+func (r *SessionRepository) GetActiveByUserID(userID string) ([]model.Session, error) {
+	rows, err := r.db.Query(`
+		SELECT `+sessionColumns+` FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_seen_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []model.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// IsActive reports whether the session of an access token may still be used and
+// records the activity. last_seen_at is written at most once a minute to keep
+// authenticated requests from turning into writes.
+func (r *SessionRepository) IsActive(id, userID string) (bool, error) {
+	var active bool
+
+	err := r.db.QueryRow(`
+		WITH existing AS (
+			SELECT revoked_at IS NULL AND expires_at > NOW() AS active
+			FROM sessions WHERE id = $1 AND user_id = $2
+		), touched AS (
+			UPDATE sessions SET last_seen_at = NOW()
+			WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+			  AND last_seen_at < NOW() - INTERVAL '1 minute'
+		)
+		SELECT active FROM existing
+	`, id, userID).Scan(&active)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return active, err
+}
+
+// Extend records a refresh: the session lives as long as its newest refresh token
+func (r *SessionRepository) Extend(id, ipAddress string, expiresAt time.Time) error {
+	_, err := r.db.Exec(`
+		UPDATE sessions SET last_seen_at = NOW(), last_ip_address = $2, expires_at = $3
+		WHERE id = $1 AND revoked_at IS NULL
+	`, id, ipAddress, expiresAt)
+	return err
+}
+
+// Revoke ends a session; false means it did not exist or was already revoked
+func (r *SessionRepository) Revoke(id string) (bool, error) {
+	result, err := r.db.Exec("UPDATE sessions SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL", id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// RevokeAllForUser ends every session of a user
+func (r *SessionRepository) RevokeAllForUser(userID string) error {
+	_, err := r.db.Exec("UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL", userID)
+	return err
+}
+
+// RevokeOthersForUser ends every session of a user except keepID
+func (r *SessionRepository) RevokeOthersForUser(userID, keepID string) error {
+	_, err := r.db.Exec("UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL", userID, keepID)
+	return err
+}
+
+// DeleteStale prunes sessions that ended more than 30 days ago
+func (r *SessionRepository) DeleteStale() error {
+	_, err := r.db.Exec(`
+		DELETE FROM sessions
+		WHERE COALESCE(revoked_at, expires_at) < NOW() - INTERVAL '30 days'
+	`)
+	return err
+}
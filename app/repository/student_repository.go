@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type StudentRepository struct {
@@ -159,6 +160,34 @@ func (r *StudentRepository) GetByAdvisorID(advisorID string) ([]model.Student, e
 	return students, nil
 }
 
+// GetUserIDsByProgramStudies returns the user IDs of the students of the program
+// studies, matched case-insensitively
+func (r *StudentRepository) GetUserIDsByProgramStudies(programStudies []string) ([]string, error) {
+	lowered := make([]string, 0, len(programStudies))
+	for _, programStudy := range programStudies {
+		lowered = append(lowered, strings.ToLower(strings.TrimSpace(programStudy)))
+	}
+
+	rows, err := r.db.Query(`
+		SELECT user_id FROM students WHERE LOWER(TRIM(program_study)) = ANY($1)
+	`, pq.Array(lowered))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	userIDs := []string{}
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	return userIDs, rows.Err()
+}
+
 func (r *StudentRepository) Update(student *model.Student) error {
 	query := `
 		UPDATE students 
@@ -0,0 +1,290 @@
+package repository
+
+import (
+	"UASBE/app/model"
+	"UASBE/database"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TokenRepository struct {
+	db *sql.DB
+}
+
+func NewTokenRepository() *TokenRepository {
+	return &TokenRepository{
+		db: database.GetPostgresDB(),
+	}
+}
+
+func (r *TokenRepository) CreateRefreshToken(token *model.RefreshToken) error {
+	token.ID = uuid.New().String()
+	token.CreatedAt = time.Now()
+	if token.FamilyID == "" {
+		token.FamilyID = uuid.New().String()
+	}
+
+	query := `
+		INSERT INTO refresh_tokens (id, user_id, family_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := r.db.Exec(query, token.ID, token.UserID, token.FamilyID,
+		token.TokenHash, token.ExpiresAt, token.CreatedAt)
+
+	return err
+}
+
+func (r *TokenRepository) GetRefreshTokenByHash(tokenHash string) (*model.RefreshToken, error) {
+	var token model.RefreshToken
+	var revokedAt sql.NullTime
+	var replacedBy sql.NullString
+
+	query := `
+		SELECT id, user_id, family_id, token_hash, expires_at, revoked_at, replaced_by, created_at,
+		       expires_at <= NOW()
+		FROM refresh_tokens WHERE token_hash = $1
+	`
+
+	err := r.db.QueryRow(query, tokenHash).Scan(
+		&token.ID, &token.UserID, &token.FamilyID, &token.TokenHash,
+		&token.ExpiresAt, &revokedAt, &replacedBy, &token.CreatedAt, &token.Expired,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+	if replacedBy.Valid {
+		token.ReplacedBy = replacedBy.String
+	}
+
+	return &token, nil
+}
+
+// MarkRefreshTokenRotated revokes a refresh token in favour of its successor.
+// It only succeeds for a token that is still active, so two concurrent refreshes
+// with the same token cannot both win; the caller treats false as reuse.
+func (r *TokenRepository) MarkRefreshTokenRotated(id, replacedBy string) (bool, error) {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW(), replaced_by = $2
+		WHERE id = $1 AND revoked_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, id, replacedBy)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+// RevokeRefreshTokenFamily revokes every still-active token issued from the same login
+func (r *TokenRepository) RevokeRefreshTokenFamily(familyID string) error {
+	query := `
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE family_id = $1 AND revoked_at IS NULL
+	`
+	_, err := r.db.Exec(query, familyID)
+	return err
+}
+
+// RevokeAllUserRefreshTokens revokes every active refresh token of a user
+func (r *TokenRepository) RevokeAllUserRefreshTokens(userID string) error {
+	query := `
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`
+	_, err := r.db.Exec(query, userID)
+	return err
+}
+
+// RevokeOtherUserRefreshTokens revokes every active refresh token of a user except
+// those of the given family (the session that stays logged in)
+func (r *TokenRepository) RevokeOtherUserRefreshTokens(userID, keepFamilyID string) error {
+	query := `
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE user_id = $1 AND family_id <> $2 AND revoked_at IS NULL
+	`
+	_, err := r.db.Exec(query, userID, keepFamilyID)
+	return err
+}
+
+// RevokeAccessToken adds a single access token (by jti) to the revocation list.
+// The entry is only needed until the token would have expired anyway.
+func (r *TokenRepository) RevokeAccessToken(jti, userID string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO revoked_tokens (jti, user_id, expires_at, revoked_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (jti) DO NOTHING
+	`
+	_, err := r.db.Exec(query, jti, userID, expiresAt)
+	return err
+}
+
+// RevokeAllUserAccessTokens invalidates every access token issued to the user
+// before the current second. The cutoff is kept in unix seconds, the precision of
+// the iat claim, so a token issued right after (e.g. the login following a
+// password reset) is not caught by it; tokens issued earlier in the same second
+// end with their session.
+func (r *TokenRepository) RevokeAllUserAccessTokens(userID string) error {
+	query := `
+		INSERT INTO user_token_revocations (user_id, revoked_before, revoked_before_epoch)
+		VALUES ($1, NOW(), FLOOR(EXTRACT(EPOCH FROM NOW()))::BIGINT)
+		ON CONFLICT (user_id) DO UPDATE
+		SET revoked_before = EXCLUDED.revoked_before, revoked_before_epoch = EXCLUDED.revoked_before_epoch
+	`
+	_, err := r.db.Exec(query, userID)
+	return err
+}
+
+// IsAccessTokenRevoked reports whether the token was revoked individually (logout)
+// or was issued before a user-wide revocation (deactivation, role change, delete).
+// The iat is compared in unix seconds, so neither side depends on a time zone.
+func (r *TokenRepository) IsAccessTokenRevoked(jti, userID string, issuedAt time.Time) (bool, error) {
+	var revoked bool
+
+	query := `
+		SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)
+		    OR EXISTS (SELECT 1 FROM user_token_revocations WHERE user_id = $2 AND revoked_before_epoch > $3)
+	`
+
+	err := r.db.QueryRow(query, jti, userID, issuedAt.Unix()).Scan(&revoked)
+	return revoked, err
+}
+
+// DeleteExpiredRevokedTokens prunes revocation entries for tokens that have expired
+func (r *TokenRepository) DeleteExpiredRevokedTokens() error {
+	_, err := r.db.Exec("DELETE FROM revoked_tokens WHERE expires_at < NOW()")
+	return err
+}
+
+// CreatePasswordResetToken stores a new reset token and invalidates any earlier,
+// still-unused token of the same user so only the latest emailed link works.
+func (r *TokenRepository) CreatePasswordResetToken(token *model.PasswordResetToken) error {
+	token.ID = uuid.New().String()
+	token.CreatedAt = time.Now()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE password_reset_tokens SET used_at = NOW()
+		WHERE user_id = $1 AND used_at IS NULL
+	`, token.UserID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO password_reset_tokens (id, user_id, token_hash, expires_at, requested_ip, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, token.ID, token.UserID, token.TokenHash, token.ExpiresAt, token.RequestedIP, token.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ConsumePasswordResetToken marks an unused, unexpired reset token as used and
+// returns its owner. The single UPDATE makes the token usable exactly once even
+// under concurrent requests; sql.ErrNoRows means invalid, used or expired.
+func (r *TokenRepository) ConsumePasswordResetToken(tokenHash string) (string, error) {
+	var userID string
+
+	query := `
+		UPDATE password_reset_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id
+	`
+
+	err := r.db.QueryRow(query, tokenHash).Scan(&userID)
+	return userID, err
+}
+
+// DeleteExpiredPasswordResetTokens prunes reset tokens that can no longer be used
+func (r *TokenRepository) DeleteExpiredPasswordResetTokens() error {
+	_, err := r.db.Exec("DELETE FROM password_reset_tokens WHERE expires_at < NOW() - INTERVAL '1 day'")
+	return err
+}
+
+// CreateEmailVerificationToken stores a new verification token and invalidates the
+// user's earlier unused tokens so only the latest emailed link works.
+func (r *TokenRepository) CreateEmailVerificationToken(token *model.EmailVerificationToken) error {
+	token.ID = uuid.New().String()
+	token.CreatedAt = time.Now()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE email_verification_tokens SET used_at = NOW()
+		WHERE user_id = $1 AND used_at IS NULL
+	`, token.UserID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO email_verification_tokens (id, user_id, email, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, token.ID, token.UserID, token.Email, token.TokenHash, token.ExpiresAt, token.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// HasRecentEmailVerificationToken reports whether a verification email was sent to
+// the user within the given duration, to rate-limit resends
+func (r *TokenRepository) HasRecentEmailVerificationToken(userID string, within time.Duration) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM email_verification_tokens
+			WHERE user_id = $1 AND created_at > NOW() - $2::float8 * INTERVAL '1 second'
+		)
+	`, userID, within.Seconds()).Scan(&exists)
+	return exists, err
+}
+
+// ConsumeEmailVerificationToken marks an unused, unexpired verification token as
+// used and returns its owner and the address it was sent to; sql.ErrNoRows means
+// invalid, used or expired.
+func (r *TokenRepository) ConsumeEmailVerificationToken(tokenHash string) (string, string, error) {
+	var userID, email string
+
+	query := `
+		UPDATE email_verification_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id, email
+	`
+
+	err := r.db.QueryRow(query, tokenHash).Scan(&userID, &email)
+	return userID, email, err
+}
+
+// DeleteExpiredEmailVerificationTokens prunes verification tokens that can no longer be used
+func (r *TokenRepository) DeleteExpiredEmailVerificationTokens() error {
+	_, err := r.db.Exec("DELETE FROM email_verification_tokens WHERE expires_at < NOW() - INTERVAL '1 day'")
+	return err
+}
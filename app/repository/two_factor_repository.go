@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"UASBE/app/model"
+	"UASBE/database"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TwoFactorRepository struct {
+	db *sql.DB
+}
+
+func NewTwoFactorRepository() *TwoFactorRepository {
+	return &TwoFactorRepository{
+		db: database.GetPostgresDB(),
+	}
+}
+
+func (r *TwoFactorRepository) GetByUserID(userID string) (*model.UserTOTP, error) {
+	var totp model.UserTOTP
+	var confirmedAt sql.NullTime
+
+	query := `
+		SELECT user_id, secret_encrypted, enabled, confirmed_at, last_used_step, failed_attempts, created_at, updated_at
+		FROM user_totp WHERE user_id = $1
+	`
+
+	err := r.db.QueryRow(query, userID).Scan(
+		&totp.UserID, &totp.Secret, &totp.Enabled, &confirmedAt,
+		&totp.LastUsedStep, &totp.FailedAttempts, &totp.CreatedAt, &totp.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if confirmedAt.Valid {
+		totp.ConfirmedAt = &confirmedAt.Time
+	}
+
+	return &totp, nil
+}
+
+// SavePendingSecret stores a new, not yet confirmed secret. An enabled enrolment
+// is never overwritten; it has to be disabled first.
+func (r *TwoFactorRepository) SavePendingSecret(userID, encryptedSecret string) error {
+	query := `
+		INSERT INTO user_totp (user_id, secret_encrypted, enabled, last_used_step, failed_attempts, created_at, updated_at)
+		VALUES ($1, $2, false, 0, 0, $3, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret_encrypted = EXCLUDED.secret_encrypted, last_used_step = 0, failed_attempts = 0, updated_at = EXCLUDED.updated_at
+		WHERE user_totp.enabled = false
+	`
+	_, err := r.db.Exec(query, userID, encryptedSecret, time.Now())
+	return err
+}
+
+// Enable confirms the pending secret and replaces the recovery codes in one transaction
+func (r *TwoFactorRepository) Enable(userID string, step int64, recoveryCodeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE user_totp
+		SET enabled = true, confirmed_at = NOW(), last_used_step = $2, failed_attempts = 0, updated_at = NOW()
+		WHERE user_id = $1
+	`, userID, step)
+	if err != nil {
+		return err
+	}
+
+	if err := replaceRecoveryCodes(tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Disable removes the enrolment and its recovery codes
+func (r *TwoFactorRepository) Disable(userID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM totp_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM user_totp WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// MarkStepUsed records the time step of an accepted code. It fails for a step that
+// is not newer than the last accepted one, so a code cannot be replayed.
+func (r *TwoFactorRepository) MarkStepUsed(userID string, step int64) (bool, error) {
+	query := `
+		UPDATE user_totp SET last_used_step = $2, failed_attempts = 0, updated_at = NOW()
+		WHERE user_id = $1 AND last_used_step < $2
+	`
+
+	result, err := r.db.Exec(query, userID, step)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+// IncrementFailedAttempts counts a wrong code and returns the new count
+func (r *TwoFactorRepository) IncrementFailedAttempts(userID string) (int, error) {
+	var attempts int
+	query := `
+		UPDATE user_totp SET failed_attempts = failed_attempts + 1, updated_at = NOW()
+		WHERE user_id = $1
+		RETURNING failed_attempts
+	`
+	err := r.db.QueryRow(query, userID).Scan(&attempts)
+	return attempts, err
+}
+
+func (r *TwoFactorRepository) ResetFailedAttempts(userID string) error {
+	_, err := r.db.Exec("UPDATE user_totp SET failed_attempts = 0 WHERE user_id = $1", userID)
+	return err
+}
+
+// ReplaceRecoveryCodes discards all previous recovery codes of the user
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(userID string, codeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(tx *sql.Tx, userID string, codeHashes []string) error {
+	if _, err := tx.Exec("DELETE FROM totp_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+
+	for _, hash := range codeHashes {
+		_, err := tx.Exec(`
+			INSERT INTO totp_recovery_codes (id, user_id, code_hash, created_at)
+			VALUES ($1, $2, $3, NOW())
+		`, uuid.New().String(), userID, hash)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ConsumeRecoveryCode marks an unused recovery code as used; false means it is
+// unknown or was already used.
+func (r *TwoFactorRepository) ConsumeRecoveryCode(userID, codeHash string) (bool, error) {
+	query := `
+		UPDATE totp_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+func (r *TwoFactorRepository) CountRemainingRecoveryCodes(userID string) (int, error) {
+	var count int
+	err := r.db.QueryRow(
+		"SELECT COUNT(*) FROM totp_recovery_codes WHERE user_id = $1 AND used_at IS NULL", userID,
+	).Scan(&count)
+	return count, err
+}
@@ -23,14 +23,17 @@ func (r *UserRepository) Create(user *model.User) error {
 	user.ID = uuid.New().String()
 	user.CreatedAt = time.Now()
 	user.UpdatedAt = time.Now()
+	if user.ApprovalStatus == "" {
+		user.ApprovalStatus = model.ApprovalStatusApproved
+	}
 	
 	query := `
-		INSERT INTO users (id, username, email, password_hash, full_name, role_id, is_active, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO users (id, username, email, password_hash, full_name, role_id, is_active, approval_status, self_registered, email_verified_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 	`
 	
 	_, err := r.db.Exec(query, user.ID, user.Username, user.Email, user.Password, 
-		user.FullName, user.RoleID, user.IsActive, user.CreatedAt, user.UpdatedAt)
+		user.FullName, user.RoleID, user.IsActive, user.ApprovalStatus, user.SelfRegistered, user.EmailVerifiedAt, user.CreatedAt, user.UpdatedAt)
 	
 	return err
 }
@@ -39,13 +42,13 @@ func (r *UserRepository) GetByID(id string) (*model.User, error) {
 	var user model.User
 	
 	query := `
-		SELECT id, username, email, password_hash, full_name, role_id, is_active, created_at, updated_at
+		SELECT id, username, email, password_hash, full_name, role_id, is_active, approval_status, email_verified_at, created_at, updated_at
 		FROM users WHERE id = $1
 	`
 	
 	err := r.db.QueryRow(query, id).Scan(
 		&user.ID, &user.Username, &user.Email, &user.Password,
-		&user.FullName, &user.RoleID, &user.IsActive, &user.CreatedAt, &user.UpdatedAt,
+		&user.FullName, &user.RoleID, &user.IsActive, &user.ApprovalStatus, &user.EmailVerifiedAt, &user.CreatedAt, &user.UpdatedAt,
 	)
 	
 	if err != nil {
@@ -59,13 +62,13 @@ func (r *UserRepository) GetByUsername(username string) (*model.User, error) {
 	var user model.User
 	
 	query := `
-		SELECT id, username, email, password_hash, full_name, role_id, is_active, created_at, updated_at
+		SELECT id, username, email, password_hash, full_name, role_id, is_active, approval_status, email_verified_at, created_at, updated_at
 		FROM users WHERE username = $1
 	`
 	
 	err := r.db.QueryRow(query, username).Scan(
 		&user.ID, &user.Username, &user.Email, &user.Password,
-		&user.FullName, &user.RoleID, &user.IsActive, &user.CreatedAt, &user.UpdatedAt,
+		&user.FullName, &user.RoleID, &user.IsActive, &user.ApprovalStatus, &user.EmailVerifiedAt, &user.CreatedAt, &user.UpdatedAt,
 	)
 	
 	if err != nil {
@@ -79,13 +82,13 @@ func (r *UserRepository) GetByEmail(email string) (*model.User, error) {
 	var user model.User
 	
 	query := `
-		SELECT id, username, email, password_hash, full_name, role_id, is_active, created_at, updated_at
+		SELECT id, username, email, password_hash, full_name, role_id, is_active, approval_status, email_verified_at, created_at, updated_at
 		FROM users WHERE email = $1
 	`
 	
 	err := r.db.QueryRow(query, email).Scan(
 		&user.ID, &user.Username, &user.Email, &user.Password,
-		&user.FullName, &user.RoleID, &user.IsActive, &user.CreatedAt, &user.UpdatedAt,
+		&user.FullName, &user.RoleID, &user.IsActive, &user.ApprovalStatus, &user.EmailVerifiedAt, &user.CreatedAt, &user.UpdatedAt,
 	)
 	
 	if err != nil {
@@ -101,12 +104,12 @@ func (r *UserRepository) Update(user *model.User) error {
 	query := `
 		UPDATE users 
 		SET username = $2, email = $3, password_hash = $4, full_name = $5, 
-		    role_id = $6, is_active = $7, updated_at = $8
+		    role_id = $6, is_active = $7, updated_at = $8, email_verified_at = $9
 		WHERE id = $1
 	`
 	
 	_, err := r.db.Exec(query, user.ID, user.Username, user.Email, user.Password,
-		user.FullName, user.RoleID, user.IsActive, user.UpdatedAt)
+		user.FullName, user.RoleID, user.IsActive, user.UpdatedAt, user.EmailVerifiedAt)
 	
 	return err
 }
@@ -121,7 +124,7 @@ func (r *UserRepository) GetAll() ([]model.User, error) {
 	var users []model.User
 	
 	query := `
-		SELECT id, username, email, password_hash, full_name, role_id, is_active, created_at, updated_at
+		SELECT id, username, email, password_hash, full_name, role_id, is_active, approval_status, email_verified_at, created_at, updated_at
 		FROM users ORDER BY created_at DESC
 	`
 	
@@ -135,7 +138,7 @@ func (r *UserRepository) GetAll() ([]model.User, error) {
 		var user model.User
 		err := rows.Scan(
 			&user.ID, &user.Username, &user.Email, &user.Password,
-			&user.FullName, &user.RoleID, &user.IsActive, &user.CreatedAt, &user.UpdatedAt,
+			&user.FullName, &user.RoleID, &user.IsActive, &user.ApprovalStatus, &user.EmailVerifiedAt, &user.CreatedAt, &user.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -146,6 +149,32 @@ func (r *UserRepository) GetAll() ([]model.User, error) {
 	return users, nil
 }
 
+// GetActiveUserIDsByRole returns the active users holding a role, as their
+// primary role or as an additional one
+func (r *UserRepository) GetActiveUserIDsByRole(role string) ([]string, error) {
+	rows, err := r.db.Query(`
+		SELECT DISTINCT u.id FROM users u
+		LEFT JOIN user_roles ur ON ur.user_id = u.id
+		JOIN roles ro ON ro.id = u.role_id OR ro.id = ur.role_id
+		WHERE LOWER(ro.name) = LOWER($1) AND u.is_active = true
+	`, role)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	return userIDs, rows.Err()
+}
+
 func (r *UserRepository) GetDB() *sql.DB {
 	return r.db
 }
@@ -155,13 +184,13 @@ func (r *UserRepository) GetByUsernameOrEmail(credential string) (*model.User, e
 	var user model.User
 	
 	query := `
-		SELECT id, username, email, password_hash, full_name, role_id, is_active, created_at, updated_at
+		SELECT id, username, email, password_hash, full_name, role_id, is_active, approval_status, email_verified_at, created_at, updated_at
 		FROM users WHERE username = $1 OR email = $1
 	`
 	
 	err := r.db.QueryRow(query, credential).Scan(
 		&user.ID, &user.Username, &user.Email, &user.Password,
-		&user.FullName, &user.RoleID, &user.IsActive, &user.CreatedAt, &user.UpdatedAt,
+		&user.FullName, &user.RoleID, &user.IsActive, &user.ApprovalStatus, &user.EmailVerifiedAt, &user.CreatedAt, &user.UpdatedAt,
 	)
 	
 	if err != nil {
@@ -221,4 +250,157 @@ func (r *UserRepository) GetUserWithRoleAndPermissions(userID string) (*model.Us
 	}
 
 	return user, &role, permissions, nil
-}
\ No newline at end of file
+}
+// UpdatePassword replaces only the password hash of a user
+func (r *UserRepository) UpdatePassword(id string, passwordHash string) error {
+	query := "UPDATE users SET password_hash = $2, updated_at = $3 WHERE id = $1"
+	_, err := r.db.Exec(query, id, passwordHash, time.Now())
+	return err
+}
+
+// ReplacePassword sets a new password hash and keeps the previous one in the
+// password history, trimmed to the newest keep entries
+func (r *UserRepository) ReplacePassword(id, previousHash, newHash string, keep int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE users SET password_hash = $2, updated_at = $3 WHERE id = $1", id, newHash, time.Now()); err != nil {
+		return err
+	}
+
+	if keep > 0 && previousHash != "" {
+		if _, err := tx.Exec("INSERT INTO password_history (user_id, password_hash, created_at) VALUES ($1, $2, NOW())", id, previousHash); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM password_history
+		WHERE user_id = $1 AND id NOT IN (
+			SELECT id FROM password_history WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2
+		)
+	`, id, keep)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetPasswordHistory returns the newest previous password hashes of a user
+func (r *UserRepository) GetPasswordHistory(id string, limit int) ([]string, error) {
+	rows, err := r.db.Query("SELECT password_hash FROM password_history WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2", id, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+
+	return hashes, rows.Err()
+}
+
+// UpdateProfile writes only the self-editable fields of a user (name and email)
+func (r *UserRepository) UpdateProfile(user *model.User) error {
+	user.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE users SET full_name = $2, email = $3, email_verified_at = $4, updated_at = $5
+		WHERE id = $1
+	`
+	_, err := r.db.Exec(query, user.ID, user.FullName, user.Email, user.EmailVerifiedAt, user.UpdatedAt)
+	return err
+}
+
+// MarkEmailVerified records that the user confirmed the given address. It returns
+// sql.ErrNoRows when the address has changed since the verification email was sent.
+func (r *UserRepository) MarkEmailVerified(id string, email string) error {
+	result, err := r.db.Exec(`
+		UPDATE users SET email_verified_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND email = $2
+	`, id, email)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetRegistrations lists self-registered accounts in the given approval status
+func (r *UserRepository) GetRegistrations(status string) ([]model.Registration, error) {
+	query := `
+		SELECT id, username, email, password_hash, full_name, role_id, is_active, approval_status, email_verified_at, created_at, updated_at,
+		       reviewed_by, reviewed_at, rejection_reason
+		FROM users WHERE self_registered = true AND approval_status = $1
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.Query(query, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var registrations []model.Registration
+	for rows.Next() {
+		var registration model.Registration
+		user := &registration.User
+		err := rows.Scan(
+			&user.ID, &user.Username, &user.Email, &user.Password,
+			&user.FullName, &user.RoleID, &user.IsActive, &user.ApprovalStatus, &user.EmailVerifiedAt, &user.CreatedAt, &user.UpdatedAt,
+			&registration.ReviewedBy, &registration.ReviewedAt, &registration.RejectionReason,
+		)
+		if err != nil {
+			return nil, err
+		}
+		user.Password = ""
+		registrations = append(registrations, registration)
+	}
+
+	return registrations, rows.Err()
+}
+
+// ReviewRegistration moves a pending registration to approved or rejected. It
+// returns sql.ErrNoRows when the account is not (or no longer) pending, so two
+// admins cannot review the same registration twice.
+func (r *UserRepository) ReviewRegistration(id, status, reviewerID, reason string) error {
+	var rejectionReason interface{}
+	if reason != "" {
+		rejectionReason = reason
+	}
+
+	result, err := r.db.Exec(`
+		UPDATE users
+		SET approval_status = $2, reviewed_by = $3, reviewed_at = NOW(), rejection_reason = $4, updated_at = NOW()
+		WHERE id = $1 AND approval_status = 'pending'
+	`, id, status, reviewerID, rejectionReason)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
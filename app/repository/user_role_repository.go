@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"UASBE/app/model"
+	"UASBE/database"
+	"database/sql"
+)
+
+// UserRoleRepository stores the additional roles of users (user_roles). The
+// primary role stays in users.role_id.
+type UserRoleRepository struct {
+	db *sql.DB
+}
+
+func NewUserRoleRepository() *UserRoleRepository {
+	return &UserRoleRepository{
+		db: database.GetPostgresDB(),
+	}
+}
+
+// GetRoleIDs returns the IDs of the user's additional roles
+func (r *UserRoleRepository) GetRoleIDs(userID string) ([]string, error) {
+	rows, err := r.db.Query("SELECT role_id FROM user_roles WHERE user_id = $1 ORDER BY granted_at", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roleIDs []string
+	for rows.Next() {
+		var roleID string
+		if err := rows.Scan(&roleID); err != nil {
+			return nil, err
+		}
+		roleIDs = append(roleIDs, roleID)
+	}
+
+	return roleIDs, rows.Err()
+}
+
+// GetByUserID lists the user's additional roles with their names
+func (r *UserRoleRepository) GetByUserID(userID string) ([]model.UserRole, error) {
+	rows, err := r.db.Query(`
+		SELECT ur.user_id, ur.role_id, r.name, COALESCE(ur.granted_by::text, ''), ur.granted_at
+		FROM user_roles ur
+		JOIN roles r ON r.id = ur.role_id
+		WHERE ur.user_id = $1
+		ORDER BY ur.granted_at
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []model.UserRole
+	for rows.Next() {
+		var role model.UserRole
+		if err := rows.Scan(&role.UserID, &role.RoleID, &role.RoleName, &role.GrantedBy, &role.GrantedAt); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+// Grant adds a role to the user. It returns false when the user already had it.
+func (r *UserRoleRepository) Grant(userID, roleID, grantedBy string) (bool, error) {
+	var granter interface{}
+	if grantedBy != "" {
+		granter = grantedBy
+	}
+
+	result, err := r.db.Exec(`
+		INSERT INTO user_roles (user_id, role_id, granted_by, granted_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT DO NOTHING
+	`, userID, roleID, granter)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// Revoke removes an additional role. It returns false when the user did not have it.
+func (r *UserRoleRepository) Revoke(userID, roleID string) (bool, error) {
+	result, err := r.db.Exec("DELETE FROM user_roles WHERE user_id = $1 AND role_id = $2", userID, roleID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"UASBE/app/model"
+	"UASBE/database"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// VerificationChainRepository stores the verification chains per achievement
+// category and competition level (verification_chains)
+type VerificationChainRepository struct {
+	db *sql.DB
+}
+
+func NewVerificationChainRepository() *VerificationChainRepository {
+	return &VerificationChainRepository{
+		db: database.GetPostgresDB(),
+	}
+}
+
+const verificationChainQuery = `
+	SELECT id, category, competition_level, stages, COALESCE(updated_by::text, ''), created_at, updated_at
+	FROM verification_chains
+`
+
+func scanVerificationChain(row interface{ Scan(...interface{}) error }) (*model.VerificationChain, error) {
+	var chain model.VerificationChain
+	err := row.Scan(&chain.ID, &chain.Category, &chain.CompetitionLevel, pq.Array(&chain.Stages),
+		&chain.UpdatedBy, &chain.CreatedAt, &chain.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &chain, nil
+}
+
+func (r *VerificationChainRepository) GetAll() ([]model.VerificationChain, error) {
+	rows, err := r.db.Query(verificationChainQuery + " ORDER BY category, competition_level")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	chains := []model.VerificationChain{}
+	for rows.Next() {
+		chain, err := scanVerificationChain(rows)
+		if err != nil {
+			return nil, err
+		}
+		chains = append(chains, *chain)
+	}
+
+	return chains, rows.Err()
+}
+
+// GetForAchievement returns the chains that may apply to an achievement: the
+// one of its category and competition level and the one of its category for
+// any level
+func (r *VerificationChainRepository) GetForAchievement(category, competitionLevel string) ([]model.VerificationChain, error) {
+	rows, err := r.db.Query(verificationChainQuery+`
+		WHERE LOWER(category) = LOWER($1) AND (competition_level = '' OR LOWER(competition_level) = LOWER($2))
+	`, category, competitionLevel)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chains []model.VerificationChain
+	for rows.Next() {
+		chain, err := scanVerificationChain(rows)
+		if err != nil {
+			return nil, err
+		}
+		chains = append(chains, *chain)
+	}
+
+	return chains, rows.Err()
+}
+
+// Save creates the chain of a category and competition level or replaces its stages
+func (r *VerificationChainRepository) Save(category, competitionLevel string, stages []string, updatedBy string) (*model.VerificationChain, error) {
+	var updater interface{}
+	if updatedBy != "" {
+		updater = updatedBy
+	}
+
+	return scanVerificationChain(r.db.QueryRow(`
+		INSERT INTO verification_chains (category, competition_level, stages, updated_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW())
+		ON CONFLICT (category, competition_level)
+		DO UPDATE SET stages = EXCLUDED.stages, updated_by = EXCLUDED.updated_by, updated_at = NOW()
+		RETURNING id, category, competition_level, stages, COALESCE(updated_by::text, ''), created_at, updated_at
+	`, category, competitionLevel, pq.Array(stages), updater))
+}
+
+func (r *VerificationChainRepository) Delete(id string) error {
+	result, err := r.db.Exec("DELETE FROM verification_chains WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
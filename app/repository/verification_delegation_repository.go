@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"UASBE/app/model"
+	"UASBE/database"
+	"database/sql"
+	"time"
+)
+
+// VerificationDelegationRepository stores the verification delegations between
+// lecturers (verification_delegations)
+type VerificationDelegationRepository struct {
+	db *sql.DB
+}
+
+func NewVerificationDelegationRepository() *VerificationDelegationRepository {
+	return &VerificationDelegationRepository{
+		db: database.GetPostgresDB(),
+	}
+}
+
+const verificationDelegationQuery = `
+	SELECT d.id, d.delegator_id, du.full_name, d.delegate_id, eu.full_name, d.starts_at, d.ends_at,
+	       COALESCE(d.reason, ''), COALESCE(d.created_by::text, ''), d.created_at, d.revoked_at
+	FROM verification_delegations d
+	JOIN lecturers dl ON dl.id = d.delegator_id
+	JOIN users du ON du.id = dl.user_id
+	JOIN lecturers el ON el.id = d.delegate_id
+	JOIN users eu ON eu.id = el.user_id
+`
+
+func scanVerificationDelegation(row interface{ Scan(...interface{}) error }) (*model.VerificationDelegation, error) {
+	var delegation model.VerificationDelegation
+	var revokedAt sql.NullTime
+	err := row.Scan(&delegation.ID, &delegation.DelegatorID, &delegation.DelegatorName, &delegation.DelegateID,
+		&delegation.DelegateName, &delegation.StartsAt, &delegation.EndsAt, &delegation.Reason,
+		&delegation.CreatedBy, &delegation.CreatedAt, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+	if revokedAt.Valid {
+		delegation.RevokedAt = &revokedAt.Time
+	}
+	delegation.Status = delegation.StatusAt(time.Now())
+	return &delegation, nil
+}
+
+func (r *VerificationDelegationRepository) queryDelegations(query string, args ...interface{}) ([]model.VerificationDelegation, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	delegations := []model.VerificationDelegation{}
+	for rows.Next() {
+		delegation, err := scanVerificationDelegation(rows)
+		if err != nil {
+			return nil, err
+		}
+		delegations = append(delegations, *delegation)
+	}
+
+	return delegations, rows.Err()
+}
+
+func (r *VerificationDelegationRepository) Create(delegation *model.VerificationDelegation) error {
+	var creator interface{}
+	if delegation.CreatedBy != "" {
+		creator = delegation.CreatedBy
+	}
+
+	delegation.CreatedAt = time.Now()
+	return r.db.QueryRow(`
+		INSERT INTO verification_delegations (delegator_id, delegate_id, starts_at, ends_at, reason, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`, delegation.DelegatorID, delegation.DelegateID, delegation.StartsAt, delegation.EndsAt,
+		delegation.Reason, creator, delegation.CreatedAt).Scan(&delegation.ID)
+}
+
+func (r *VerificationDelegationRepository) GetByID(id string) (*model.VerificationDelegation, error) {
+	return scanVerificationDelegation(r.db.QueryRow(verificationDelegationQuery+" WHERE d.id = $1", id))
+}
+
+// GetAll returns every delegation, newest first
+func (r *VerificationDelegationRepository) GetAll() ([]model.VerificationDelegation, error) {
+	return r.queryDelegations(verificationDelegationQuery + " ORDER BY d.starts_at DESC")
+}
+
+// GetByLecturer returns the delegations a lecturer gave or received, newest first
+func (r *VerificationDelegationRepository) GetByLecturer(lecturerID string) ([]model.VerificationDelegation, error) {
+	return r.queryDelegations(verificationDelegationQuery+`
+		WHERE d.delegator_id = $1 OR d.delegate_id = $1
+		ORDER BY d.starts_at DESC
+	`, lecturerID)
+}
+
+// GetActiveDelegatorIDs returns the lecturers whose advisees the delegate may
+// verify right now. Delegations outside their period or revoked do not count,
+// so they expire without anyone having to end them.
+func (r *VerificationDelegationRepository) GetActiveDelegatorIDs(delegateID string) ([]string, error) {
+	rows, err := r.db.Query(`
+		SELECT DISTINCT delegator_id FROM verification_delegations
+		WHERE delegate_id = $1 AND revoked_at IS NULL AND starts_at <= NOW() AND ends_at > NOW()
+	`, delegateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var delegatorIDs []string
+	for rows.Next() {
+		var delegatorID string
+		if err := rows.Scan(&delegatorID); err != nil {
+			return nil, err
+		}
+		delegatorIDs = append(delegatorIDs, delegatorID)
+	}
+
+	return delegatorIDs, rows.Err()
+}
+
+// GetActiveDelegateUserIDs returns the user IDs of the lecturers verifying for
+// the delegator right now
+func (r *VerificationDelegationRepository) GetActiveDelegateUserIDs(delegatorID string) ([]string, error) {
+	rows, err := r.db.Query(`
+		SELECT DISTINCT l.user_id FROM verification_delegations d
+		JOIN lecturers l ON l.id = d.delegate_id
+		WHERE d.delegator_id = $1 AND d.revoked_at IS NULL AND d.starts_at <= NOW() AND d.ends_at > NOW()
+	`, delegatorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	return userIDs, rows.Err()
+}
+
+// Revoke ends a delegation that has not been revoked yet
+func (r *VerificationDelegationRepository) Revoke(id string) error {
+	result, err := r.db.Exec(`
+		UPDATE verification_delegations SET revoked_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL
+	`, id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,445 @@
+package service
+
+import (
+	"UASBE/app/model"
+	"UASBE/app/policy"
+	"UASBE/app/repository"
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AccountService is the self-service side of user management: every user can
+// view their own account and profile, edit a few fields and change their password.
+// Everything else (role, student/lecturer IDs, advisor, status) stays with admins
+// through /api/users.
+type AccountService struct {
+	authService  *AuthService
+	userRepo     *repository.UserRepository
+	studentRepo  *repository.StudentRepository
+	lecturerRepo *repository.LecturerRepository
+}
+
+func NewAccountService(authService *AuthService, userRepo *repository.UserRepository, studentRepo *repository.StudentRepository, lecturerRepo *repository.LecturerRepository) *AccountService {
+	return &AccountService{
+		authService:  authService,
+		userRepo:     userRepo,
+		studentRepo:  studentRepo,
+		lecturerRepo: lecturerRepo,
+	}
+}
+
+// AccountProfile is the caller's own account as returned by /api/auth/me
+type AccountProfile struct {
+	User          *model.User        `json:"user"`
+	Role          *model.Role        `json:"role"`
+	Roles         []model.Role       `json:"roles"`
+	Permissions   []model.Permission `json:"permissions"`
+	EmailVerified bool               `json:"email_verified"`
+	Student       *model.Student     `json:"student,omitempty"`
+	Lecturer      *model.Lecturer    `json:"lecturer,omitempty"`
+}
+
+// UpdateProfileRequest holds the fields a user may change themselves. Omitted
+// fields are left as they are.
+type UpdateProfileRequest struct {
+	FullName     *string `json:"full_name,omitempty"`
+	Email        *string `json:"email,omitempty"`
+	ProgramStudy *string `json:"program_study,omitempty"` // students only
+	AcademicYear *string `json:"academic_year,omitempty"` // students only
+	Department   *string `json:"department,omitempty"`    // lecturers only
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// protectedProfileFields can only be changed by an admin (or, for the password,
+// through the change-password endpoint)
+var protectedProfileFields = map[string]bool{
+	"id":                true,
+	"username":          true,
+	"password":          true,
+	"role":              true,
+	"role_id":           true,
+	"is_active":         true,
+	"approval_status":   true,
+	"email_verified_at": true,
+	"student_id":        true,
+	"advisor_id":        true,
+	"lecturer_id":       true,
+}
+
+// ProtectedFieldsError lists fields of a profile update that the user may not change
+type ProtectedFieldsError struct {
+	Fields []string
+}
+
+func (e *ProtectedFieldsError) Error() string {
+	return "protected fields cannot be changed: " + strings.Join(e.Fields, ", ")
+}
+
+// ProtectedProfileFields returns the protected fields present in a JSON profile
+// update, sorted. The request is refused as a whole rather than silently
+// ignoring them, so a client never believes such a change was applied.
+func ProtectedProfileFields(body []byte) ([]string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+
+	var protected []string
+	for field := range fields {
+		if protectedProfileFields[strings.ToLower(field)] {
+			protected = append(protected, field)
+		}
+	}
+	sort.Strings(protected)
+	return protected, nil
+}
+
+// GetProfile loads the user's account, role, permissions and student or lecturer profile
+func (s *AccountService) GetProfile(userID string) (*AccountProfile, error) {
+	user, err := s.authService.GetActiveUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := s.authService.ResolveUserRoles(user)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &AccountProfile{
+		User:          user,
+		Role:          roles.Primary.Role,
+		Roles:         roles.RoleList(),
+		Permissions:   roles.Permissions(),
+		EmailVerified: user.EmailVerifiedAt != nil,
+	}
+
+	// A user holding several roles may have both profiles
+	if roles.HasRole(policy.RoleStudent) {
+		if student, err := s.studentRepo.GetByUserID(user.ID); err == nil {
+			profile.Student = student
+		}
+	}
+	if roles.HasRole(policy.RoleLecturer) {
+		if lecturer, err := s.lecturerRepo.GetByUserID(user.ID); err == nil {
+			profile.Lecturer = lecturer
+		}
+	}
+
+	// Remove password from response
+	user.Password = ""
+	return profile, nil
+}
+
+// UpdateProfile applies a self-service update. A new email address has to be
+// verified again; students are held to the registration email domains.
+func (s *AccountService) UpdateProfile(userID string, req *UpdateProfileRequest) (*AccountProfile, error) {
+	profile, err := s.GetProfile(userID)
+	if err != nil {
+		return nil, err
+	}
+	user := profile.User
+
+	if (req.ProgramStudy != nil || req.AcademicYear != nil) && profile.Student == nil {
+		return nil, errors.New("program_study and academic_year can only be changed by students")
+	}
+	if req.Department != nil && profile.Lecturer == nil {
+		return nil, errors.New("department can only be changed by lecturers")
+	}
+
+	userChanged := false
+	emailChanged := false
+
+	if req.FullName != nil {
+		fullName := strings.TrimSpace(*req.FullName)
+		if fullName == "" {
+			return nil, errors.New("full_name cannot be empty")
+		}
+		if fullName != user.FullName {
+			user.FullName = fullName
+			userChanged = true
+		}
+	}
+
+	if req.Email != nil {
+		email := strings.TrimSpace(*req.Email)
+		if !EmailDomainAllowed(email, nil) {
+			return nil, errors.New("invalid email address")
+		}
+		if !strings.EqualFold(email, user.Email) {
+			if profile.Student != nil && !EmailDomainAllowed(email, s.authService.registrationEmailDomains) {
+				return nil, errors.New("email domain is not allowed")
+			}
+			if _, err := s.userRepo.GetByEmail(email); err == nil {
+				return nil, errors.New("email already exists")
+			}
+			user.Email = email
+			user.EmailVerifiedAt = nil
+			userChanged = true
+			emailChanged = true
+		}
+	}
+
+	if profile.Student != nil && (req.ProgramStudy != nil || req.AcademicYear != nil) {
+		student := profile.Student
+		if req.ProgramStudy != nil {
+			if strings.TrimSpace(*req.ProgramStudy) == "" {
+				return nil, errors.New("program_study cannot be empty")
+			}
+			student.ProgramStudy = strings.TrimSpace(*req.ProgramStudy)
+		}
+		if req.AcademicYear != nil {
+			if strings.TrimSpace(*req.AcademicYear) == "" {
+				return nil, errors.New("academic_year cannot be empty")
+			}
+			student.AcademicYear = strings.TrimSpace(*req.AcademicYear)
+		}
+		if err := s.studentRepo.Update(student); err != nil {
+			return nil, err
+		}
+	}
+
+	if profile.Lecturer != nil && req.Department != nil {
+		if strings.TrimSpace(*req.Department) == "" {
+			return nil, errors.New("department cannot be empty")
+		}
+		profile.Lecturer.Department = strings.TrimSpace(*req.Department)
+		if err := s.lecturerRepo.Update(profile.Lecturer); err != nil {
+			return nil, err
+		}
+	}
+
+	if userChanged {
+		if err := s.userRepo.UpdateProfile(user); err != nil {
+			return nil, err
+		}
+	}
+
+	// The new address has to be confirmed again
+	if emailChanged {
+		profile.EmailVerified = false
+		if err := s.authService.SendEmailVerification(user); err != nil {
+			log.Printf("Failed to send verification email to user %s: %v", user.ID, err)
+		}
+	}
+
+	return profile, nil
+}
+
+// accountErrorResponse maps the errors of this service to HTTP responses
+func accountErrorResponse(c *fiber.Ctx, err error) error {
+	var protected *ProtectedFieldsError
+	if errors.As(err, &protected) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"success": false,
+			"error": err.Error(),
+			"message": "These fields can only be changed by an administrator",
+			"code": "PROTECTED_FIELDS",
+			"fields": protected.Fields,
+		})
+	}
+
+	if isPasswordRejection(err) {
+		return passwordRejectedResponse(c, err)
+	}
+
+	var throttled *LoginThrottledError
+	if errors.As(err, &throttled) {
+		retryAfter := int(throttled.RetryAfter.Seconds() + 0.999)
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"success": false,
+			"error": err.Error(),
+			"message": "Too many failed attempts. Please wait before trying again",
+			"code": "TOO_MANY_LOGIN_ATTEMPTS",
+			"retry_after_seconds": retryAfter,
+			"locked": throttled.Locked,
+		})
+	}
+
+	switch err.Error() {
+	case "current password is incorrect":
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error": err.Error(),
+			"message": "The current password is incorrect",
+			"code": "INVALID_CURRENT_PASSWORD",
+		})
+	case "email already exists":
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"success": false,
+			"error": err.Error(),
+			"message": "This email address is already used by another account",
+			"code": "EMAIL_ALREADY_EXISTS",
+		})
+	case "email domain is not allowed":
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error": err.Error(),
+			"message": "Please use your institutional email address",
+			"code": "EMAIL_DOMAIN_NOT_ALLOWED",
+		})
+	case "invalid email address",
+		"full_name cannot be empty",
+		"program_study cannot be empty",
+		"academic_year cannot be empty",
+		"department cannot be empty",
+		"program_study and academic_year can only be changed by students",
+		"department can only be changed by lecturers":
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error": "Validation failed",
+			"message": err.Error(),
+			"code": "VALIDATION_ERROR",
+		})
+	case "user not found", "account is deactivated", "account is pending approval", "registration was rejected":
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"success": false,
+			"error": err.Error(),
+			"message": "Your account is no longer available",
+			"code": "ACCOUNT_UNAVAILABLE",
+		})
+	}
+
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"success": false,
+		"error": "Account operation failed",
+		"message": err.Error(),
+		"code": "ACCOUNT_OPERATION_FAILED",
+	})
+}
+
+// GetMeRequest returns the caller's own account
+// @Summary Get My Account
+// @Description The current user with role, permissions and student or lecturer profile
+// @Tags Account
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} AccountProfile "Account"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /auth/me [get]
+func (s *AccountService) GetMeRequest(c *fiber.Ctx) error {
+	profile, err := s.GetProfile(c.Locals("user_id").(string))
+	if err != nil {
+		return accountErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": profile,
+	})
+}
+
+// UpdateMeRequest updates the caller's own account
+// @Summary Update My Account
+// @Description Change full name, email (must be verified again) and, for students, program study and academic year or, for lecturers, department. Role, username, student/lecturer ID, advisor and status can only be changed by an admin
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body UpdateProfileRequest true "Fields to change"
+// @Success 200 {object} AccountProfile "Updated account"
+// @Failure 400 {object} map[string]interface{} "Validation failed"
+// @Failure 403 {object} map[string]interface{} "Protected fields"
+// @Failure 409 {object} map[string]interface{} "Email already exists"
+// @Router /auth/me [put]
+func (s *AccountService) UpdateMeRequest(c *fiber.Ctx) error {
+	protected, err := ProtectedProfileFields(c.Body())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error": "Invalid request body",
+			"message": "Please provide valid JSON data",
+			"code": "INVALID_REQUEST_BODY",
+		})
+	}
+	if len(protected) > 0 {
+		return accountErrorResponse(c, &ProtectedFieldsError{Fields: protected})
+	}
+
+	var req UpdateProfileRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error": "Invalid request body",
+			"message": "Please provide valid JSON data",
+			"code": "INVALID_REQUEST_BODY",
+		})
+	}
+
+	profile, err := s.UpdateProfile(c.Locals("user_id").(string), &req)
+	if err != nil {
+		return accountErrorResponse(c, err)
+	}
+
+	response := fiber.Map{
+		"success": true,
+		"message": "Account updated",
+		"code": "ACCOUNT_UPDATED",
+		"data": profile,
+	}
+	if req.Email != nil && !profile.EmailVerified {
+		response["next_steps"] = []string{
+			"A verification link was sent to " + profile.User.Email + "; open it to confirm the new address",
+		}
+	}
+
+	return c.JSON(response)
+}
+
+// ChangePasswordRequest changes the caller's password
+// @Summary Change My Password
+// @Description Set a new password; the current one is required. Other sessions are signed out, this one stays logged in
+// @Tags Account
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} map[string]interface{} "Password changed"
+// @Failure 400 {object} map[string]interface{} "Wrong current password or weak new password"
+// @Failure 429 {object} map[string]interface{} "Too many failed attempts"
+// @Router /auth/me/password [put]
+func (s *AccountService) ChangePasswordRequest(c *fiber.Ctx) error {
+	var req ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error": "Invalid request body",
+			"message": "Please provide valid JSON data",
+			"code": "INVALID_REQUEST_BODY",
+		})
+	}
+
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error": "Validation failed",
+			"message": "current_password and new_password are required",
+			"code": "MISSING_FIELDS",
+		})
+	}
+
+	userID := c.Locals("user_id").(string)
+	sessionID, _ := c.Locals("session_id").(string)
+
+	err := s.authService.ChangePassword(userID, sessionID, req.CurrentPassword, req.NewPassword, ClientInfo{IP: c.IP(), UserAgent: c.Get("User-Agent")})
+	if err != nil {
+		return accountErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Password changed. Your other sessions were signed out",
+		"code": "PASSWORD_CHANGED",
+	})
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"UASBE/app/model"
+	"UASBE/app/policy"
 	"UASBE/app/repository"
 	"errors"
 	"fmt"
@@ -17,6 +18,12 @@ type AchievementService struct {
 	studentRepo       *repository.StudentRepository
 	lecturerRepo      *repository.LecturerRepository
 	notificationService *NotificationService
+	adminScopeService   *AdminScopeService
+	policy              *policy.Policy
+	workflow            *policy.Workflow
+	verificationChainService *VerificationChainService
+	delegationService        *DelegationService
+	sla                      VerificationSLAConfig
 }
 
 type CreateAchievementRequest struct {
@@ -30,19 +37,79 @@ type CreateAchievementRequest struct {
 }
 
 type VerifyAchievementRequest struct {
-	Status        string `json:"status"` // "verified" or "rejected"
+	Status        string `json:"status"` // "verified", "rejected" or "revision_requested"
 	RejectionNote string `json:"rejection_note,omitempty"`
+	// Feedback are the points the student has to address, required when
+	// returning an achievement for revision
+	Feedback []string `json:"feedback,omitempty"`
 }
 
-func NewAchievementService(achievementRepo *repository.AchievementRepository, studentRepo *repository.StudentRepository, lecturerRepo *repository.LecturerRepository, notificationService *NotificationService) *AchievementService {
+func NewAchievementService(achievementRepo *repository.AchievementRepository, studentRepo *repository.StudentRepository, lecturerRepo *repository.LecturerRepository, notificationService *NotificationService, adminScopeService *AdminScopeService, accessPolicy *policy.Policy, workflow *policy.Workflow, verificationChainService *VerificationChainService, delegationService *DelegationService, sla VerificationSLAConfig) *AchievementService {
 	return &AchievementService{
 		achievementRepo:     achievementRepo,
 		studentRepo:         studentRepo,
 		lecturerRepo:        lecturerRepo,
 		notificationService: notificationService,
+		adminScopeService:   adminScopeService,
+		policy:              accessPolicy,
+		workflow:            workflow,
+		verificationChainService: verificationChainService,
+		delegationService:        delegationService,
+		sla:                      sla,
 	}
 }
 
+// PolicySubject builds the policy subject for a user holding the given roles
+func (s *AchievementService) PolicySubject(userID string, roles ...string) policy.Subject {
+	subject := policy.Subject{UserID: userID, Roles: roles}
+	if subject.HasRole(policy.RoleLecturer) {
+		if lecturer, err := s.lecturerRepo.GetByUserID(userID); err == nil {
+			subject.LecturerID = lecturer.ID
+			subject.DelegatorIDs = s.delegationService.ActiveDelegatorIDs(lecturer.ID)
+		}
+	}
+	// Department admins and verifiers of chain stages are limited to their scopes
+	if subject.HasRole(policy.RoleDepartmentAdmin) || holdsStaffRole(subject) {
+		if scopes, err := s.adminScopeService.GetScopes(userID); err == nil {
+			subject.Scopes = scopes
+		}
+	}
+	return subject
+}
+
+// achievementResource loads the ownership facts the policy needs about an
+// achievement (or its reference) owned by the given student
+func (s *AchievementService) achievementResource(id, ownerID string) policy.Resource {
+	resource := policy.Resource{Type: policy.ResourceAchievement, ID: id, OwnerID: ownerID}
+	if student, err := s.studentRepo.GetByUserID(ownerID); err == nil {
+		resource.OwnerAdvisorID = student.AdvisorID
+		resource.OwnerProgramStudy = student.ProgramStudy
+	}
+	return resource
+}
+
+// AuthorizeAchievement checks whether the subject may perform the action on an
+// active achievement. It returns policy.ErrForbidden when the policy denies it.
+func (s *AchievementService) AuthorizeAchievement(subject policy.Subject, action policy.Action, achievementID string) error {
+	id, err := primitive.ObjectIDFromHex(achievementID)
+	if err != nil {
+		return errors.New("invalid achievement ID")
+	}
+
+	achievement, err := s.achievementRepo.GetByIDActive(id)
+	if err != nil {
+		return errors.New("achievement not found")
+	}
+
+	// A submitted achievement is at a verification stage, whose verifier may view it
+	resource := s.achievementResource(achievement.ID.Hex(), achievement.StudentID)
+	if reference, err := s.achievementRepo.GetReferenceByAchievementID(achievement.ID.Hex()); err == nil {
+		resource.VerificationStage = VerificationStage(reference)
+	}
+
+	return s.policy.Authorize(subject, action, resource)
+}
+
 // FR-010: View All Achievements - Admin dapat melihat semua prestasi
 // GetAllAchievementsRequest handles admin view of all achievements
 // @Summary Get All Achievements (Admin)
@@ -53,7 +120,7 @@ func NewAchievementService(achievementRepo *repository.AchievementRepository, st
 // @Security BearerAuth
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(10)
-// @Param status query string false "Filter by status" Enums(draft, submitted, verified, rejected)
+// @Param status query string false "Filter by status" Enums(draft, submitted, verified, rejected, revision_requested)
 // @Param category query string false "Filter by category" Enums(competition, research, community_service, academic, organization)
 // @Param student_id query string false "Filter by student ID"
 // @Param sort_by query string false "Sort field" Enums(created_at, updated_at, title, category) default(created_at)
@@ -63,15 +130,26 @@ func NewAchievementService(achievementRepo *repository.AchievementRepository, st
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /achievements/admin/all [get]
 func (s *AchievementService) GetAllAchievementsRequest(c *fiber.Ctx) error {
-	userRole := c.Locals("role").(string)
-	
-	// Only admin can view all achievements
-	if userRole != "admin" {
+	// Only admin can view all achievements; department admins see the students
+	// of their program studies
+	scopes, scoped := requestAdminScopes(c)
+	if scoped && len(scopes) == 0 {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": "Only admin can view all achievements",
 		})
 	}
 
+	var scopeStudentIDs []string
+	if scoped {
+		var err error
+		scopeStudentIDs, err = s.adminScopeService.StudentIDsInScope(scopes)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to get students in scope",
+			})
+		}
+	}
+
 	// Get query parameters for filtering and pagination
 	page := c.QueryInt("page", 1)
 	limit := c.QueryInt("limit", 10)
@@ -90,7 +168,7 @@ func (s *AchievementService) GetAllAchievementsRequest(c *fiber.Ctx) error {
 	}
 
 	// FR-010 Step 1: Get all achievement references with filters
-	references, total, err := s.achievementRepo.GetAllReferencesWithFilters(page, limit, status, studentID)
+	references, total, err := s.achievementRepo.GetAllReferencesWithFilters(page, limit, status, studentID, scopeStudentIDs)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to get achievement references",
@@ -253,7 +331,7 @@ func (s *AchievementService) GetAllAchievementsRequest(c *fiber.Ctx) error {
 	}
 
 	// Get statistics
-	stats, _ := s.achievementRepo.GetAchievementStatistics()
+	stats, _ := s.achievementRepo.GetAchievementStatistics(scopeStudentIDs)
 
 	return c.JSON(fiber.Map{
 		"success": true,
@@ -267,6 +345,7 @@ func (s *AchievementService) GetAllAchievementsRequest(c *fiber.Ctx) error {
 				"category":   category,
 				"student_id": studentID,
 			},
+			"admin_scopes": scopes,
 		},
 		"data": result,
 		"pagination": fiber.Map{
@@ -288,7 +367,7 @@ func (s *AchievementService) GetAllAchievementsRequest(c *fiber.Ctx) error {
 				"sort_order": sortOrder,
 			},
 			"available": fiber.Map{
-				"statuses":    []string{"draft", "submitted", "verified", "rejected", "deleted"},
+				"statuses":    []string{"draft", "submitted", "verified", "rejected", "revision_requested", "deleted"},
 				"categories":  []string{"competition", "research", "community_service", "academic", "organization"},
 				"sort_fields": []string{"created_at", "updated_at", "title", "category"},
 			},
@@ -316,7 +395,7 @@ func (s *AchievementService) CreateAchievementRequest(c *fiber.Ctx) error {
 	username := c.Locals("username").(string)
 	
 	// FR-003 Precondition: User terautentikasi sebagai mahasiswa
-	if userRole != "student" && userRole != "Mahasiswa" {
+	if !requestHasRole(c, policy.RoleStudent) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"success": false,
 			"error": "Access denied",
@@ -523,7 +602,7 @@ func (s *AchievementService) UpdateAchievementRequest(c *fiber.Ctx) error {
 	}
 
 	// Process update
-	achievement, err := s.UpdateAchievement(userID, id, &req)
+	achievement, err := s.UpdateAchievement(s.PolicySubject(userID, requestRoles(c)...), id, &req)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
@@ -539,22 +618,9 @@ func (s *AchievementService) UpdateAchievementRequest(c *fiber.Ctx) error {
 // FR-005: Hapus Prestasi - Service method untuk soft delete prestasi mahasiswa
 func (s *AchievementService) DeleteAchievementRequest(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
-	userRole := c.Locals("role").(string)
 	username := c.Locals("username").(string)
 	idParam := c.Params("id")
 
-	// FR-005 Precondition: Hanya mahasiswa yang bisa hapus prestasi mereka sendiri
-	if userRole != "student" && userRole != "Mahasiswa" {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"success": false,
-			"error": "Access denied",
-			"message": "Only students can delete their own achievements",
-			"code": "INSUFFICIENT_PERMISSIONS",
-			"user_role": userRole,
-			"required_role": "student",
-		})
-	}
-
 	// Validate and convert ID
 	id, err := primitive.ObjectIDFromHex(idParam)
 	if err != nil {
@@ -601,7 +667,10 @@ func (s *AchievementService) DeleteAchievementRequest(c *fiber.Ctx) error {
 	}
 
 	// FR-005 Flow: Soft delete prestasi
-	err = s.SoftDeleteAchievement(userID, id)
+	err = s.SoftDeleteAchievement(s.PolicySubject(userID, requestRoles(c)...), id)
+	if transitionErr, ok := transitionError(err); ok {
+		return invalidStatusResponse(c, transitionErr)
+	}
 	if err != nil {
 		var errorCode string
 		var message string
@@ -671,22 +740,9 @@ func (s *AchievementService) DeleteAchievementRequest(c *fiber.Ctx) error {
 // FR-004: Submit untuk Verifikasi - Service method untuk submit prestasi untuk verifikasi
 func (s *AchievementService) SubmitAchievementRequest(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
-	userRole := c.Locals("role").(string)
 	username := c.Locals("username").(string)
 	achievementID := c.Params("achievement_id")
 
-	// FR-004 Precondition: Hanya mahasiswa yang bisa submit
-	if userRole != "student" {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"success": false,
-			"error": "Access denied",
-			"message": "Only students can submit achievements for verification",
-			"code": "INSUFFICIENT_PERMISSIONS",
-			"user_role": userRole,
-			"required_role": "student",
-		})
-	}
-
 	if achievementID == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"success": false,
@@ -730,6 +786,9 @@ func (s *AchievementService) SubmitAchievementRequest(c *fiber.Ctx) error {
 
 	// FR-004 Flow: Submit prestasi untuk verifikasi
 	updatedReference, err := s.SubmitAchievementForVerification(userID, achievementID)
+	if transitionErr, ok := transitionError(err); ok {
+		return invalidStatusResponse(c, transitionErr)
+	}
 	if err != nil {
 		var errorCode string
 		var message string
@@ -790,10 +849,11 @@ func (s *AchievementService) SubmitAchievementRequest(c *fiber.Ctx) error {
 			"advisor_info": advisorInfo,
 		},
 		"status_info": fiber.Map{
-			"previous_status": "draft",
-			"current_status": "submitted",
+			"previous_status": previousStatus(updatedReference),
+			"current_status": updatedReference.Status,
 			"description": "Achievement is now pending verification by your advisor",
 			"available_actions": []string{"view", "wait_for_verification"},
+			"verification_chain": updatedReference.VerificationChain,
 		},
 		"next_steps": []string{
 			"Your achievement has been submitted to your advisor for verification",
@@ -813,7 +873,7 @@ func (s *AchievementService) SubmitAchievementRequest(c *fiber.Ctx) error {
 func (s *AchievementService) GetPendingVerificationsRequest(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
 
-	references, err := s.GetPendingVerifications(userID)
+	references, err := s.GetPendingVerifications(s.PolicySubject(userID, requestRoles(c)...))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
@@ -829,16 +889,8 @@ func (s *AchievementService) GetPendingVerificationsRequest(c *fiber.Ctx) error
 // FR-007: Service method untuk get detail prestasi yang akan diverifikasi
 func (s *AchievementService) GetVerificationDetailRequest(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
-	userRole := c.Locals("role").(string)
 	refIDParam := c.Params("reference_id")
 
-	// FR-007 Precondition: Hanya dosen wali yang bisa akses
-	if userRole != "lecturer" && userRole != "Dosen" && userRole != "Dosen Wali" {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "Only lecturers can view verification details",
-		})
-	}
-
 	// Validate and convert ID
 	refID, err := primitive.ObjectIDFromHex(refIDParam)
 	if err != nil {
@@ -848,7 +900,7 @@ func (s *AchievementService) GetVerificationDetailRequest(c *fiber.Ctx) error {
 	}
 
 	// Get verification detail
-	detail, err := s.GetVerificationDetail(userID, refID)
+	detail, err := s.GetVerificationDetail(s.PolicySubject(userID, requestRoles(c)...), refID)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
@@ -868,12 +920,12 @@ func (s *AchievementService) VerifyAchievementRequest(c *fiber.Ctx) error {
 	username := c.Locals("username").(string)
 	refIDParam := c.Params("reference_id")
 
-	// FR-007 Precondition: Hanya dosen wali yang bisa verify
-	if userRole != "lecturer" && userRole != "Dosen" && userRole != "Dosen Wali" {
+	// FR-007 Precondition: Hanya dosen wali (atau verifikator tahap rantai verifikasi) yang bisa verify
+	if !requestHasRole(c, policy.RoleLecturer) && !holdsStaffRole(policy.Subject{Roles: requestRoles(c)}) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"success": false,
 			"error": "Access denied",
-			"message": "Only lecturers can verify achievements",
+			"message": "Only lecturers and verifiers of a verification stage can verify achievements",
 			"code": "INSUFFICIENT_PERMISSIONS",
 			"user_role": userRole,
 			"required_role": "lecturer",
@@ -894,8 +946,8 @@ func (s *AchievementService) VerifyAchievementRequest(c *fiber.Ctx) error {
 	// FR-007/FR-008 Step 1 & 2: Enhanced validation
 	validationErrors := make(map[string]string)
 	
-	if req.Status != "verified" && req.Status != "rejected" {
-		validationErrors["status"] = "Status must be 'verified' or 'rejected'"
+	if req.Status != "verified" && req.Status != "rejected" && req.Status != "revision_requested" {
+		validationErrors["status"] = "Status must be 'verified', 'rejected' or 'revision_requested'"
 	}
 
 	// FR-008 Step 1: Dosen input rejection note (required when rejecting)
@@ -903,6 +955,12 @@ func (s *AchievementService) VerifyAchievementRequest(c *fiber.Ctx) error {
 		validationErrors["rejection_note"] = "Rejection note is required when rejecting"
 	}
 
+	// Returning for revision needs the points the student has to address
+	req.Feedback = NormalizeFeedback(req.Feedback)
+	if req.Status == "revision_requested" && len(req.Feedback) == 0 {
+		validationErrors["feedback"] = "Feedback is required when requesting a revision"
+	}
+
 	if len(validationErrors) > 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"success": false,
@@ -910,7 +968,7 @@ func (s *AchievementService) VerifyAchievementRequest(c *fiber.Ctx) error {
 			"message": "Please correct the following errors",
 			"code": "VALIDATION_ERROR",
 			"details": validationErrors,
-			"valid_statuses": []string{"verified", "rejected"},
+			"valid_statuses": []string{"verified", "rejected", "revision_requested"},
 		})
 	}
 
@@ -958,18 +1016,20 @@ func (s *AchievementService) VerifyAchievementRequest(c *fiber.Ctx) error {
 		})
 	}
 
-	// Get lecturer info
-	lecturer, err := s.lecturerRepo.GetByUserID(userID)
-	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"success": false,
-			"error": "Lecturer profile not found",
-			"code": "LECTURER_NOT_FOUND",
-		})
+	// Get lecturer info (verifiers of later stages need not be lecturers)
+	verifierInfo := fiber.Map{
+		"full_name": username,
+	}
+	if lecturer, err := s.lecturerRepo.GetByUserID(userID); err == nil {
+		verifierInfo["lecturer_id"] = lecturer.LecturerID
+		verifierInfo["department"] = lecturer.Department
 	}
 
 	// FR-007/FR-008 Flow: Process verification/rejection
-	updatedReference, err := s.VerifyAchievementWithDetails(userID, refID, &req)
+	updatedReference, err := s.VerifyAchievementWithDetails(s.PolicySubject(userID, requestRoles(c)...), refID, &req)
+	if transitionErr, ok := transitionError(err); ok {
+		return invalidStatusResponse(c, transitionErr)
+	}
 	if err != nil {
 		var errorCode string
 		var message string
@@ -1013,7 +1073,15 @@ func (s *AchievementService) VerifyAchievementRequest(c *fiber.Ctx) error {
 	var message, actionType string
 	var nextSteps []string
 	
-	if req.Status == "verified" {
+	if req.Status == "verified" && updatedReference.Status == policy.StatusSubmitted {
+		message = "Verification stage approved successfully"
+		actionType = "STAGE_APPROVED"
+		nextSteps = []string{
+			"Your stage of the verification chain has been approved",
+			"The achievement now waits for the next stage: " + VerificationStage(updatedReference),
+			"It becomes verified once every stage has approved it",
+		}
+	} else if req.Status == "verified" {
 		message = "Achievement verified successfully"
 		actionType = "VERIFICATION_SUCCESS"
 		nextSteps = []string{
@@ -1021,6 +1089,14 @@ func (s *AchievementService) VerifyAchievementRequest(c *fiber.Ctx) error {
 			"Student will receive a notification about the approval",
 			"Achievement is now part of student's verified portfolio",
 		}
+	} else if req.Status == "revision_requested" {
+		message = "Revision requested successfully"
+		actionType = "REVISION_REQUESTED"
+		nextSteps = []string{
+			"Achievement has been returned to the student with your feedback",
+			"Student will receive a notification with the points to address",
+			"The achievement will be back in your pending list once resubmitted",
+		}
 	} else {
 		message = "Achievement rejected successfully"
 		actionType = "REJECTION_SUCCESS"
@@ -1041,11 +1117,8 @@ func (s *AchievementService) VerifyAchievementRequest(c *fiber.Ctx) error {
 			"status": updatedReference.Status,
 			"verified_by": updatedReference.VerifiedBy,
 			"verified_at": updatedReference.VerifiedAt,
-			"lecturer_info": fiber.Map{
-				"lecturer_id": lecturer.LecturerID,
-				"full_name": username,
-				"department": lecturer.Department,
-			},
+			"verified_on_behalf_of": updatedReference.VerifiedOnBehalfOf,
+			"lecturer_info": verifierInfo,
 			"student_info": fiber.Map{
 				"student_id": student.StudentID,
 				"program_study": student.ProgramStudy,
@@ -1062,11 +1135,23 @@ func (s *AchievementService) VerifyAchievementRequest(c *fiber.Ctx) error {
 			"previous_status": "submitted",
 			"current_status": updatedReference.Status,
 			"processing_time": processingTime,
+			"verification_chain": updatedReference.VerificationChain,
+			"verification_stage": VerificationStage(updatedReference),
 		},
 		"next_steps": nextSteps,
 		"timestamp": time.Now(),
 	}
 
+	// Add revision-specific info
+	if req.Status == "revision_requested" {
+		responseData["data"].(fiber.Map)["feedback"] = req.Feedback
+		responseData["notification_sent"] = fiber.Map{
+			"to_student": true,
+			"message": "Your achievement has been returned for revision",
+			"includes_feedback": true,
+		}
+	}
+
 	// Add rejection-specific info
 	if req.Status == "rejected" {
 		responseData["data"].(fiber.Map)["rejection_note"] = updatedReference.RejectionNote
@@ -1179,12 +1264,14 @@ func (s *AchievementService) SubmitAchievement(studentID string, req *CreateAchi
 	}
 
 	// Create achievement reference for tracking (status: draft)
+	now := time.Now()
 	reference := &model.AchievementReference{
 		StudentID:     studentID,
 		AchievementID: achievement.ID.Hex(), // Fix: Use actual MongoDB ID, not ObjectID field
-		Status:        "draft", // FR-003 Step 4: Status awal 'draft'
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
+		Status:        policy.StatusDraft, // FR-003 Step 4: Status awal 'draft'
+		StatusHistory: []model.StatusChange{{To: policy.StatusDraft, Actor: studentID, At: now}},
+		CreatedAt:     now,
+		UpdatedAt:     now,
 	}
 
 	// Save reference to MongoDB
@@ -1223,15 +1310,30 @@ func (s *AchievementService) SubmitAchievementForVerification(studentID string,
 		return nil, errors.New("achievement not found")
 	}
 
-	// FR-004 Precondition: Prestasi berstatus 'draft'
-	if targetRef.Status != "draft" {
-		return nil, errors.New("only draft achievements can be submitted for verification")
+	// FR-004 Precondition & Step 2: Prestasi berstatus 'draft' (atau dikembalikan/ditolak
+	// untuk diajukan ulang), update status menjadi 'submitted'
+	if err := s.changeStatus(s.PolicySubject(studentID, policy.RoleStudent), targetRef, policy.StatusSubmitted, "", nil); err != nil {
+		return nil, err
 	}
+	targetRef.SubmittedAt = targetRef.UpdatedAt
+
+	// The decision on the previous round (rejection or return for revision) does
+	// not carry over; it stays in the status history
+	targetRef.RejectionNote = ""
+	targetRef.VerifiedBy = ""
+	targetRef.VerifiedAt = time.Time{}
+	targetRef.VerifiedOnBehalfOf = ""
 
-	// FR-004 Step 2: Update status menjadi 'submitted'
-	targetRef.Status = "submitted"
-	targetRef.SubmittedAt = time.Now()
-	targetRef.UpdatedAt = time.Now()
+	// Every submission goes through the verification chain of the achievement from the start
+	achievement, err := s.achievementRepo.GetByObjectID(achievementID)
+	if err != nil {
+		return nil, errors.New("achievement not found")
+	}
+	targetRef.VerificationChain, err = s.verificationChainService.ChainFor(achievement)
+	if err != nil {
+		return nil, errors.New("failed to load verification chain: " + err.Error())
+	}
+	targetRef.CurrentStage = 0
 
 	err = s.achievementRepo.UpdateReference(targetRef)
 	if err != nil {
@@ -1285,41 +1387,38 @@ func (s *AchievementService) createNotificationForAdvisor(studentID, achievement
 }
 
 // FR-007: VerifyAchievementWithDetails - Main method untuk verify prestasi dengan return details
-func (s *AchievementService) VerifyAchievementWithDetails(lecturerID string, referenceID primitive.ObjectID, req *VerifyAchievementRequest) (*model.AchievementReference, error) {
+func (s *AchievementService) VerifyAchievementWithDetails(subject policy.Subject, referenceID primitive.ObjectID, req *VerifyAchievementRequest) (*model.AchievementReference, error) {
 	// FR-007 Step 1: Get the reference untuk review prestasi detail
 	reference, err := s.achievementRepo.GetReferenceByID(referenceID)
 	if err != nil {
 		return nil, errors.New("achievement reference not found")
 	}
 
-	// FR-007 Precondition: Status harus 'submitted'
-	if reference.Status != "submitted" {
-		return nil, fmt.Errorf("only submitted achievements can be verified. Current status: '%s'", reference.Status)
-	}
-
-	// Check if lecturer is the advisor of the student
-	student, err := s.studentRepo.GetByUserID(reference.StudentID)
-	if err != nil {
-		return nil, errors.New("student not found")
+	// FR-007 Precondition: Status harus 'submitted', hanya verifikator tahap saat ini
+	// (dosen wali mahasiswa atau pemegang role tahap berikutnya) yang bisa verify
+	// FR-007/FR-008 Step 2 & 3: Update status menjadi 'verified' (setelah semua tahap) atau 'rejected'
+	if req.Status == policy.StatusVerified {
+		err = s.approveStage(subject, reference)
+	} else {
+		err = s.changeStatus(subject, reference, req.Status, req.RejectionNote, req.Feedback)
 	}
-
-	lecturer, err := s.lecturerRepo.GetByUserID(lecturerID)
 	if err != nil {
-		return nil, errors.New("lecturer not found")
+		if errors.Is(err, policy.ErrForbidden) {
+			return nil, errors.New("you can only verify achievements waiting for your verification stage")
+		}
+		return nil, err
 	}
 
-	if student.AdvisorID != lecturer.ID {
-		return nil, errors.New("you can only verify achievements of your advisees")
+	// Only a verified achievement has a verifier; a stage approval, rejection or
+	// return for revision is recorded in the status history
+	if reference.Status == policy.StatusVerified {
+		reference.VerifiedBy = subject.UserID
+		reference.VerifiedAt = reference.UpdatedAt
+		reference.VerifiedOnBehalfOf = reference.StatusHistory[len(reference.StatusHistory)-1].OnBehalfOf
 	}
-
-	// FR-007/FR-008 Step 2 & 3: Update status menjadi 'verified' atau 'rejected'
-	reference.Status = req.Status
-	reference.VerifiedBy = lecturerID
-	reference.VerifiedAt = time.Now()
-	reference.UpdatedAt = time.Now()
 	
 	// FR-008 Step 3: Save rejection_note (if rejected)
-	if req.Status == "rejected" {
+	if req.Status == policy.StatusRejected {
 		reference.RejectionNote = req.RejectionNote
 	}
 
@@ -1337,6 +1436,14 @@ func (s *AchievementService) VerifyAchievementWithDetails(lecturerID string, ref
 		}
 	}
 
+	// Notify mahasiswa of the points to address (if returned for revision)
+	if req.Status == "revision_requested" && s.notificationService != nil {
+		err = s.createRevisionNotification(reference.StudentID, reference.AchievementID, req.RejectionNote, req.Feedback)
+		if err != nil {
+			fmt.Printf("Warning: Failed to create revision notification: %v\n", err)
+		}
+	}
+
 	// FR-007 Step 5 / FR-008 Step 5: Return updated status
 	return reference, nil
 }
@@ -1368,7 +1475,7 @@ func (s *AchievementService) createRejectionNotification(studentID, achievementI
 
 // Legacy method for backward compatibility
 func (s *AchievementService) VerifyAchievement(lecturerID string, referenceID primitive.ObjectID, req *VerifyAchievementRequest) error {
-	_, err := s.VerifyAchievementWithDetails(lecturerID, referenceID, req)
+	_, err := s.VerifyAchievementWithDetails(s.PolicySubject(lecturerID, policy.RoleLecturer), referenceID, req)
 	return err
 }
 
@@ -1380,35 +1487,64 @@ func (s *AchievementService) GetStudentAchievementReferences(studentID string) (
 	return s.achievementRepo.GetReferencesByStudentID(studentID)
 }
 
-func (s *AchievementService) GetPendingVerifications(lecturerID string) ([]model.AchievementReference, error) {
-	// Get lecturer info
-	lecturer, err := s.lecturerRepo.GetByUserID(lecturerID)
-	if err != nil {
+// GetPendingVerifications returns the submitted achievements waiting for a
+// stage the subject verifies: the advisor stage for the subject's advisees and
+// those of the advisors who delegated to the subject, and the stages named
+// after the subject's roles
+func (s *AchievementService) GetPendingVerifications(subject policy.Subject) ([]model.AchievementReference, error) {
+	if subject.LecturerID == "" && !holdsStaffRole(subject) {
 		return nil, errors.New("lecturer not found")
 	}
 
-	// Get students under this lecturer
-	students, err := s.studentRepo.GetByAdvisorID(lecturer.ID)
-	if err != nil {
-		return nil, err
-	}
+	var candidates []model.AchievementReference
 
-	var allReferences []model.AchievementReference
-	for _, student := range students {
-		references, err := s.achievementRepo.GetReferencesByStudentID(student.UserID)
+	// Advisor stage: submissions of the students under this lecturer and under
+	// the lecturers who delegated to them
+	var advisorIDs []string
+	if subject.LecturerID != "" {
+		advisorIDs = append([]string{subject.LecturerID}, subject.DelegatorIDs...)
+	}
+	for _, advisorID := range advisorIDs {
+		students, err := s.studentRepo.GetByAdvisorID(advisorID)
 		if err != nil {
-			continue
+			return nil, err
 		}
 
-		// Filter only submitted achievements
+		for _, student := range students {
+			references, err := s.achievementRepo.GetReferencesByStudentID(student.UserID)
+			if err != nil {
+				continue
+			}
+
+			// Filter only submitted achievements
+			for _, ref := range references {
+				if ref.Status == policy.StatusSubmitted && VerificationStage(&ref) == policy.StageAdvisor {
+					candidates = append(candidates, ref)
+				}
+			}
+		}
+	}
+
+	// Later stages: every submission waiting for a role of the subject
+	if holdsStaffRole(subject) {
+		references, err := s.achievementRepo.GetReferencesByStatus(policy.StatusSubmitted)
+		if err != nil {
+			return nil, err
+		}
 		for _, ref := range references {
-			if ref.Status == "submitted" {
-				allReferences = append(allReferences, ref)
+			if stage := VerificationStage(&ref); stage != policy.StageAdvisor && subject.HasRole(stage) {
+				candidates = append(candidates, ref)
 			}
 		}
 	}
 
-	return allReferences, nil
+	pending := []model.AchievementReference{}
+	for i := range candidates {
+		if s.policy.Can(subject, policy.ActionVerify, s.referenceResource(&candidates[i])) {
+			pending = append(pending, candidates[i])
+		}
+	}
+	return pending, nil
 }
 
 // FR-007: GetVerificationDetail - Get detail prestasi untuk review oleh dosen
@@ -1416,28 +1552,26 @@ type VerificationDetail struct {
 	Reference    *model.AchievementReference `json:"reference"`
 	Achievement  *model.Achievement          `json:"achievement"`
 	StudentInfo  StudentBasicInfo            `json:"student_info"`
+	// FeedbackRounds is the feedback of earlier submissions, to check a
+	// resubmission against
+	FeedbackRounds []FeedbackRound `json:"feedback_rounds"`
 }
 
-func (s *AchievementService) GetVerificationDetail(lecturerID string, referenceID primitive.ObjectID) (*VerificationDetail, error) {
+func (s *AchievementService) GetVerificationDetail(subject policy.Subject, referenceID primitive.ObjectID) (*VerificationDetail, error) {
 	// Get the reference
 	reference, err := s.achievementRepo.GetReferenceByID(referenceID)
 	if err != nil {
 		return nil, errors.New("achievement reference not found")
 	}
 
-	// Check if lecturer is the advisor of the student
 	student, err := s.studentRepo.GetByUserID(reference.StudentID)
 	if err != nil {
 		return nil, errors.New("student not found")
 	}
 
-	lecturer, err := s.lecturerRepo.GetByUserID(lecturerID)
-	if err != nil {
-		return nil, errors.New("lecturer not found")
-	}
-
-	if student.AdvisorID != lecturer.ID {
-		return nil, errors.New("you can only view achievements of your advisees")
+	// Only whoever may verify the achievement reviews its detail
+	if err := s.policy.Authorize(subject, policy.ActionVerify, s.referenceResource(reference)); err != nil {
+		return nil, errors.New("you can only view achievements waiting for your verification stage")
 	}
 
 	// Get achievement detail from MongoDB
@@ -1462,6 +1596,7 @@ func (s *AchievementService) GetVerificationDetail(lecturerID string, referenceI
 		Reference:   reference,
 		Achievement: achievement,
 		StudentInfo: studentInfo,
+		FeedbackRounds: FeedbackRounds(reference.StatusHistory),
 	}, nil
 }
 
@@ -1473,7 +1608,7 @@ func (s *AchievementService) GetActiveAchievementByID(id primitive.ObjectID) (*m
 	return s.achievementRepo.GetByIDActive(id)
 }
 
-func (s *AchievementService) UpdateAchievement(studentID string, achievementID primitive.ObjectID, req *CreateAchievementRequest) (*model.Achievement, error) {
+func (s *AchievementService) UpdateAchievement(subject policy.Subject, achievementID primitive.ObjectID, req *CreateAchievementRequest) (*model.Achievement, error) {
 	// Get existing achievement
 	achievement, err := s.achievementRepo.GetByID(achievementID)
 	if err != nil {
@@ -1481,7 +1616,7 @@ func (s *AchievementService) UpdateAchievement(studentID string, achievementID p
 	}
 
 	// Check ownership
-	if achievement.StudentID != studentID {
+	if err := s.policy.Authorize(subject, policy.ActionUpdate, s.achievementResource(achievementID.Hex(), achievement.StudentID)); err != nil {
 		return nil, errors.New("unauthorized")
 	}
 
@@ -1490,6 +1625,11 @@ func (s *AchievementService) UpdateAchievement(studentID string, achievementID p
 		return nil, errors.New("cannot update deleted achievement")
 	}
 
+	// Only drafts and achievements sent back to the student can be edited
+	if reference, err := s.GetReferenceByAchievementIDSafe(achievementID.Hex()); err == nil && !s.workflow.Editable(reference.Status) {
+		return nil, fmt.Errorf("cannot update an achievement with status '%s'", reference.Status)
+	}
+
 	// Update fields
 	achievement.Category = req.Category
 	achievement.Title = req.Title
@@ -1510,7 +1650,7 @@ func (s *AchievementService) UpdateAchievement(studentID string, achievementID p
 }
 
 // FR-005: SoftDeleteAchievement - Soft delete prestasi dengan validasi status draft
-func (s *AchievementService) SoftDeleteAchievement(studentID string, achievementID primitive.ObjectID) error {
+func (s *AchievementService) SoftDeleteAchievement(subject policy.Subject, achievementID primitive.ObjectID) error {
 	// FR-005 Step 1: Get existing achievement
 	achievement, err := s.achievementRepo.GetByID(achievementID)
 	if err != nil {
@@ -1518,7 +1658,7 @@ func (s *AchievementService) SoftDeleteAchievement(studentID string, achievement
 	}
 
 	// FR-005 Step 2: Check ownership
-	if achievement.StudentID != studentID {
+	if err := s.policy.Authorize(subject, policy.ActionDelete, s.achievementResource(achievementID.Hex(), achievement.StudentID)); err != nil {
 		return errors.New("unauthorized: you can only delete your own achievements")
 	}
 
@@ -1534,12 +1674,15 @@ func (s *AchievementService) SoftDeleteAchievement(studentID string, achievement
 	}
 
 	// FR-005 Precondition: Hanya prestasi dengan status 'draft' yang bisa dihapus
-	if targetRef.Status != "draft" {
-		return errors.New("only draft achievements can be deleted")
+	if err := s.changeStatus(subject, targetRef, policy.StatusDeleted, "", nil); err != nil {
+		if errors.Is(err, policy.ErrForbidden) {
+			return errors.New("unauthorized: you can only delete your own achievements")
+		}
+		return err
 	}
 
 	// FR-005 Step 5: Perform soft delete
-	now := time.Now()
+	now := targetRef.UpdatedAt
 	achievement.DeletedAt = &now
 	achievement.UpdatedAt = now
 
@@ -1548,10 +1691,7 @@ func (s *AchievementService) SoftDeleteAchievement(studentID string, achievement
 		return errors.New("failed to delete achievement: " + err.Error())
 	}
 
-	// FR-005 Step 6: Update reference status to 'deleted'
-	targetRef.Status = "deleted"
-	targetRef.UpdatedAt = now
-
+	// FR-005 Step 6: Store reference status 'deleted'
 	err = s.achievementRepo.UpdateReference(targetRef)
 	if err != nil {
 		// Log error but don't fail the deletion since achievement is already soft deleted
@@ -1563,7 +1703,7 @@ func (s *AchievementService) SoftDeleteAchievement(studentID string, achievement
 
 // Legacy method for backward compatibility (now deprecated)
 func (s *AchievementService) DeleteAchievement(studentID string, achievementID primitive.ObjectID) error {
-	return s.SoftDeleteAchievement(studentID, achievementID)
+	return s.SoftDeleteAchievement(policy.Subject{UserID: studentID, Roles: []string{policy.RoleStudent}}, achievementID)
 }
 
 // FR-006: View Prestasi Mahasiswa Bimbingan - Service method untuk dosen wali
@@ -1573,7 +1713,7 @@ func (s *AchievementService) GetAdviseeAchievementsRequest(c *fiber.Ctx) error {
 	username := c.Locals("username").(string)
 
 	// FR-006 Precondition: Hanya dosen yang bisa akses
-	if userRole != "lecturer" && userRole != "Dosen" && userRole != "Dosen Wali" {
+	if !requestHasRole(c, policy.RoleLecturer) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"success": false,
 			"error": "Access denied",
@@ -1714,7 +1854,7 @@ func (s *AchievementService) GetAdviseeAchievementsRequest(c *fiber.Ctx) error {
 				"category": category,
 			},
 			"available": fiber.Map{
-				"statuses": []string{"draft", "submitted", "verified", "rejected"},
+				"statuses": []string{"draft", "submitted", "verified", "rejected", "revision_requested"},
 				"categories": []string{"competition", "research", "community_service", "academic", "organization"},
 			},
 		},
@@ -1922,6 +2062,7 @@ func (s *AchievementService) mapDetailsToAchievement(achievement *model.Achievem
 // @Description - Student: own statistics
 // @Description - Lecturer: advisee statistics  
 // @Description - Admin: all statistics
+// @Description Each includes verification_sla: submitted achievements waiting past the reminder and escalation thresholds
 // @Tags Statistics
 // @Accept json
 // @Produce json
@@ -1956,8 +2097,17 @@ func (s *AchievementService) GetAchievementStatisticsRequest(c *fiber.Ctx) error
 	var stats fiber.Map
 	var err error
 
-	switch userRole {
-	case "student", "Mahasiswa":
+	// Users holding several roles get the widest statistics they are entitled to
+	statisticsRole := policy.CanonicalRole(userRole)
+	for _, role := range []string{policy.RoleAdmin, policy.RoleDepartmentAdmin, policy.RoleLecturer, policy.RoleStudent} {
+		if requestHasRole(c, role) {
+			statisticsRole = role
+			break
+		}
+	}
+
+	switch statisticsRole {
+	case policy.RoleStudent:
 		// FR-011: Mahasiswa melihat statistik prestasi sendiri
 		stats, err = s.GetStudentStatistics(userID, period, year, month)
 		if err != nil {
@@ -1969,7 +2119,7 @@ func (s *AchievementService) GetAchievementStatisticsRequest(c *fiber.Ctx) error
 			})
 		}
 
-	case "lecturer", "Dosen", "Dosen Wali":
+	case policy.RoleLecturer:
 		// FR-011: Dosen Wali melihat statistik prestasi mahasiswa bimbingan
 		stats, err = s.GetLecturerStatistics(userID, period, year, month)
 		if err != nil {
@@ -1981,9 +2131,36 @@ func (s *AchievementService) GetAchievementStatisticsRequest(c *fiber.Ctx) error
 			})
 		}
 
-	case "admin":
+	case policy.RoleAdmin:
 		// FR-011: Admin melihat statistik semua prestasi
-		stats, err = s.GetAdminStatistics(period, year, month)
+		stats, err = s.GetAdminStatistics(period, year, month, nil)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"success": false,
+				"error": "Failed to get admin statistics",
+				"message": err.Error(),
+				"code": "ADMIN_STATS_FAILED",
+			})
+		}
+
+	case policy.RoleDepartmentAdmin:
+		// Admin program studi melihat statistik mahasiswa program studinya
+		var scopes, studentIDs []string
+		scopes, err = s.adminScopeService.GetScopes(userID)
+		if err == nil && len(scopes) == 0 {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"success": false,
+				"error": "Access denied",
+				"message": "No program study or department has been assigned to you yet",
+				"code": "NO_ADMIN_SCOPE",
+			})
+		}
+		if err == nil {
+			studentIDs, err = s.adminScopeService.StudentIDsInScope(scopes)
+		}
+		if err == nil {
+			stats, err = s.GetAdminStatistics(period, year, month, studentIDs)
+		}
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"success": false,
@@ -1992,6 +2169,7 @@ func (s *AchievementService) GetAchievementStatisticsRequest(c *fiber.Ctx) error
 				"code": "ADMIN_STATS_FAILED",
 			})
 		}
+		stats["admin_scopes"] = scopes
 
 	default:
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
@@ -2093,6 +2271,7 @@ func (s *AchievementService) GetStudentStatistics(userID string, period string,
 		"by_competition_level": levelCounts,
 		"monthly_trend": monthlyStats,
 		"period_stats": periodStats,
+		"verification_sla": s.sla.Summarize(references, time.Now()),
 		"student_info": fiber.Map{
 			"student_id": student.StudentID,
 			"program_study": student.ProgramStudy,
@@ -2172,6 +2351,7 @@ func (s *AchievementService) GetLecturerStatistics(lecturerID string, period str
 	}
 
 	// Get references for status counts
+	var references []model.AchievementReference
 	for _, studentID := range studentIDs {
 		refs, err := s.achievementRepo.GetReferencesByStudentID(studentID)
 		if err != nil {
@@ -2180,6 +2360,7 @@ func (s *AchievementService) GetLecturerStatistics(lecturerID string, period str
 		for _, ref := range refs {
 			statusCounts[ref.Status]++
 		}
+		references = append(references, refs...)
 	}
 
 	// Get top performing students
@@ -2196,6 +2377,7 @@ func (s *AchievementService) GetLecturerStatistics(lecturerID string, period str
 		"by_status": statusCounts,
 		"by_competition_level": levelCounts,
 		"top_students": topStudents,
+		"verification_sla": s.sla.Summarize(references, time.Now()),
 		"lecturer_info": fiber.Map{
 			"lecturer_id": lecturer.LecturerID,
 			"department": lecturer.Department,
@@ -2209,8 +2391,9 @@ func (s *AchievementService) GetLecturerStatistics(lecturerID string, period str
 	}, nil
 }
 
-// FR-011: Get Admin Statistics - Statistik semua prestasi
-func (s *AchievementService) GetAdminStatistics(period string, year, month int) (fiber.Map, error) {
+// FR-011: Get Admin Statistics - Statistik semua prestasi. studentIDs, when not
+// nil, limits the statistics to those students (department admins).
+func (s *AchievementService) GetAdminStatistics(period string, year, month int, studentIDs []string) (fiber.Map, error) {
 	// Get all achievements
 	achievements, err := s.achievementRepo.GetAll(0, 0)
 	if err != nil {
@@ -2223,6 +2406,29 @@ func (s *AchievementService) GetAdminStatistics(period string, year, month int)
 		return nil, err
 	}
 
+	if studentIDs != nil {
+		inScope := make(map[string]bool, len(studentIDs))
+		for _, id := range studentIDs {
+			inScope[id] = true
+		}
+
+		var scopedAchievements []model.Achievement
+		for _, achievement := range achievements {
+			if inScope[achievement.StudentID] {
+				scopedAchievements = append(scopedAchievements, achievement)
+			}
+		}
+		achievements = scopedAchievements
+
+		var scopedStudents []model.Student
+		for _, student := range students {
+			if inScope[student.UserID] {
+				scopedStudents = append(scopedStudents, student)
+			}
+		}
+		students = scopedStudents
+	}
+
 	// Calculate comprehensive statistics
 	totalAchievements := len(achievements)
 	categoryCounts := make(map[string]int)
@@ -2246,7 +2452,7 @@ func (s *AchievementService) GetAdminStatistics(period string, year, month int)
 	}
 
 	// Get status statistics from repository
-	statusStats, err := s.achievementRepo.GetAchievementStatistics()
+	statusStats, err := s.achievementRepo.GetAchievementStatistics(studentIDs)
 	if err != nil {
 		return nil, err
 	}
@@ -2254,6 +2460,26 @@ func (s *AchievementService) GetAdminStatistics(period string, year, month int)
 	// Get top performing students
 	topStudents := s.getTopStudentsAdmin(studentStats, students, 10)
 
+	// Submitted achievements waiting for verification, for the SLA breaches
+	pending, err := s.achievementRepo.GetReferencesByStatus(policy.StatusSubmitted)
+	if err != nil {
+		return nil, err
+	}
+	if studentIDs != nil {
+		inScope := make(map[string]bool, len(studentIDs))
+		for _, id := range studentIDs {
+			inScope[id] = true
+		}
+
+		var scopedPending []model.AchievementReference
+		for _, ref := range pending {
+			if inScope[ref.StudentID] {
+				scopedPending = append(scopedPending, ref)
+			}
+		}
+		pending = scopedPending
+	}
+
 	// Calculate period-specific statistics
 	var periodStats fiber.Map
 	if period == "year" {
@@ -2273,6 +2499,12 @@ func (s *AchievementService) GetAdminStatistics(period string, year, month int)
 		byStatusMap = make(map[string]int)
 	}
 
+	// A scope without students would otherwise divide by zero
+	averagePerStudent := 0.0
+	if len(students) > 0 {
+		averagePerStudent = float64(totalAchievements) / float64(len(students))
+	}
+
 	return fiber.Map{
 		"overview": fiber.Map{
 			"total_achievements": totalAchievements,
@@ -2286,8 +2518,9 @@ func (s *AchievementService) GetAdminStatistics(period string, year, month int)
 		"monthly_trend": monthlyStats,
 		"period_stats": periodStats,
 		"top_students": topStudents,
+		"verification_sla": s.sla.Summarize(pending, time.Now()),
 		"system_performance": fiber.Map{
-			"average_achievements_per_student": float64(totalAchievements) / float64(len(students)),
+			"average_achievements_per_student": averagePerStudent,
 			"most_popular_category": s.getMostActiveCategory(categoryCounts),
 			"most_common_level": s.getMostActiveLevel(levelCounts),
 			"achievement_growth": s.calculateGrowthRate(monthlyStats),
@@ -2631,9 +2864,8 @@ func (s *AchievementService) FixDataMismatchesRequest(c *fiber.Ctx) error {
 // Debug advisor relationship
 func (s *AchievementService) DebugAdvisorRequest(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
-	userRole := c.Locals("role").(string)
 	
-	if userRole != "lecturer" && userRole != "Dosen" && userRole != "Dosen Wali" {
+	if !requestHasRole(c, policy.RoleLecturer) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": "Only lecturers can access this debug endpoint",
 		})
@@ -2687,10 +2919,8 @@ func (s *AchievementService) DebugAdvisorRequest(c *fiber.Ctx) error {
 }
 // Fix advisor relationship - assign students to lecturers
 func (s *AchievementService) FixAdvisorRelationshipRequest(c *fiber.Ctx) error {
-	userRole := c.Locals("role").(string)
-	
 	// Only admin can fix advisor relationships
-	if userRole != "admin" {
+	if !requestHasRole(c, policy.RoleAdmin) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": "Only admin can fix advisor relationships",
 		})
@@ -2768,10 +2998,8 @@ func (s *AchievementService) FixAdvisorRelationshipRequest(c *fiber.Ctx) error {
 }
 // Quick fix - assign current student to current lecturer
 func (s *AchievementService) AssignStudentToLecturerRequest(c *fiber.Ctx) error {
-	userRole := c.Locals("role").(string)
-	
 	// Only admin can assign students to lecturers
-	if userRole != "admin" {
+	if !requestHasRole(c, policy.RoleAdmin) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": "Only admin can assign students to lecturers",
 		})
@@ -2906,10 +3134,8 @@ func (s *AchievementService) DebugAllDataRequest(c *fiber.Ctx) error {
 }
 // Temporary endpoint to show all submitted achievements for lecturer
 func (s *AchievementService) GetAllSubmittedAchievementsRequest(c *fiber.Ctx) error {
-	userRole := c.Locals("role").(string)
-	
 	// Only lecturers can access
-	if userRole != "lecturer" && userRole != "Dosen" && userRole != "Dosen Wali" {
+	if !requestHasRole(c, policy.RoleLecturer) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": "Only lecturers can access this endpoint",
 		})
@@ -2973,9 +3199,8 @@ func (s *AchievementService) GetAllSubmittedAchievementsRequest(c *fiber.Ctx) er
 // Debug GetByAdvisorID method
 func (s *AchievementService) DebugGetByAdvisorIDRequest(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(string)
-	userRole := c.Locals("role").(string)
 	
-	if userRole != "lecturer" && userRole != "Dosen" && userRole != "Dosen Wali" {
+	if !requestHasRole(c, policy.RoleLecturer) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": "Only lecturers can access this endpoint",
 		})
@@ -3466,10 +3691,8 @@ func (s *AchievementService) CleanLegacyObjectIDRequest(c *fiber.Ctx) error {
 }
 // DebugAdminAchievementsRequest - Debug admin view all achievements
 func (s *AchievementService) DebugAdminAchievementsRequest(c *fiber.Ctx) error {
-	userRole := c.Locals("role").(string)
-	
 	// Only admin can debug
-	if userRole != "admin" {
+	if !requestHasRole(c, policy.RoleAdmin) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": "Only admin can debug achievements",
 		})
@@ -3482,7 +3705,7 @@ func (s *AchievementService) DebugAdminAchievementsRequest(c *fiber.Ctx) error {
 	studentID := c.Query("student_id", "")
 
 	// Step 1: Get achievement references
-	references, total, err := s.achievementRepo.GetAllReferencesWithFilters(page, limit, status, studentID)
+	references, total, err := s.achievementRepo.GetAllReferencesWithFilters(page, limit, status, studentID, nil)
 	if err != nil {
 		return c.JSON(fiber.Map{
 			"step": "get_references",
@@ -3542,4 +3765,121 @@ func min(a, b int) int {
 		return a
 	}
 	return b
-}
\ No newline at end of file
+}
+// GetVerifiedAchievementsRequest exports verified achievements for campus systems
+// @Summary Export Verified Achievements
+// @Description Verified achievements with their student, oldest verification first. Meant for service accounts using an API key with the achievements:export scope; pass the verified_at of the last item as verified_since to fetch only newer ones
+// @Tags Achievements
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param verified_since query string false "Only achievements verified after this time (RFC 3339)"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(50)
+// @Success 200 {object} map[string]interface{} "Verified achievements"
+// @Failure 400 {object} map[string]interface{} "Invalid verified_since"
+// @Failure 403 {object} map[string]interface{} "Missing achievements:export permission"
+// @Router /achievements/verified [get]
+func (s *AchievementService) GetVerifiedAchievementsRequest(c *fiber.Ctx) error {
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 50)
+
+	// Validate pagination
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	var verifiedSince time.Time
+	if since := c.Query("verified_since"); since != "" {
+		parsed, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error": "Validation failed",
+				"message": "verified_since must be an RFC 3339 time, e.g. 2024-01-31T00:00:00Z",
+				"code": "VALIDATION_ERROR",
+			})
+		}
+		verifiedSince = parsed
+	}
+
+	references, total, err := s.achievementRepo.GetVerifiedReferences(page, limit, verifiedSince)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get achievement references",
+		})
+	}
+
+	var achievementIDs, studentIDs []string
+	seenStudents := make(map[string]bool)
+	for _, ref := range references {
+		achievementIDs = append(achievementIDs, ref.AchievementID)
+		if ref.StudentID != "" && !seenStudents[ref.StudentID] {
+			seenStudents[ref.StudentID] = true
+			studentIDs = append(studentIDs, ref.StudentID)
+		}
+	}
+
+	achievements, err := s.achievementRepo.GetAchievementsByIDsWithFilters(achievementIDs, "", "created_at", "desc")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get achievement details",
+		})
+	}
+	achievementMap := make(map[string]*model.Achievement)
+	for i, achievement := range achievements {
+		achievementMap[achievement.ID.Hex()] = &achievements[i]
+	}
+
+	studentMap := make(map[string]*model.Student)
+	if len(studentIDs) > 0 {
+		students, err := s.studentRepo.GetStudentsByUserIDs(studentIDs)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to get student information",
+			})
+		}
+		for i, student := range students {
+			studentMap[student.UserID] = &students[i]
+		}
+	}
+
+	result := []fiber.Map{}
+	for _, ref := range references {
+		achievement := achievementMap[ref.AchievementID]
+		if achievement == nil {
+			continue
+		}
+
+		var studentInfo fiber.Map
+		if student := studentMap[ref.StudentID]; student != nil {
+			studentInfo = fiber.Map{
+				"student_id":    student.StudentID,
+				"program_study": student.ProgramStudy,
+				"academic_year": student.AcademicYear,
+				"user_id":       student.UserID,
+			}
+		}
+
+		result = append(result, fiber.Map{
+			"achievement":  achievement,
+			"verified_at":  ref.VerifiedAt,
+			"verified_by":  ref.VerifiedBy,
+			"student_info": studentInfo,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    result,
+		"pagination": fiber.Map{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": (total + limit - 1) / limit,
+		},
+	})
+}
@@ -0,0 +1,278 @@
+package service
+
+import (
+	"UASBE/app/model"
+	"UASBE/app/policy"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// changeStatus moves a reference to another status through the workflow: the
+// transition must exist, the subject must be allowed its action on the
+// achievement and a required note must be given (feedback counts as a note).
+// The change is appended to the status history; storing the reference is left
+// to the caller, which usually sets more fields (submitted_at, verified_by, ...)
+// first.
+func (s *AchievementService) changeStatus(subject policy.Subject, reference *model.AchievementReference, to, note string, feedback []string) error {
+	given := note
+	if strings.TrimSpace(given) == "" {
+		given = strings.Join(feedback, "\n")
+	}
+
+	transition, err := s.workflow.Check(reference.Status, to, given)
+	if err != nil {
+		return err
+	}
+
+	resource := s.referenceResource(reference)
+	if err := s.policy.Authorize(subject, transition.Action, resource); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	reference.StatusHistory = append(reference.StatusHistory, model.StatusChange{
+		From:       reference.Status,
+		To:         to,
+		Actor:      subject.UserID,
+		Note:       note,
+		At:         now,
+		OnBehalfOf: s.actingFor(subject, resource),
+		Feedback:   feedback,
+	})
+	reference.Status = to
+	reference.UpdatedAt = now
+	return nil
+}
+
+// approveStage records the approval of the verification stage a submitted
+// reference waits for and moves it to the next stage. The approval of the last
+// stage verifies the achievement.
+func (s *AchievementService) approveStage(subject policy.Subject, reference *model.AchievementReference) error {
+	stage := VerificationStage(reference)
+	onBehalfOf := s.actingFor(subject, s.referenceResource(reference))
+
+	if reference.CurrentStage+1 >= len(reference.VerificationChain) {
+		if err := s.changeStatus(subject, reference, policy.StatusVerified, "", nil); err != nil {
+			return err
+		}
+	} else {
+		// The reference stays submitted; the workflow still decides whether it can be approved
+		if _, err := s.workflow.Check(reference.Status, policy.StatusVerified, ""); err != nil {
+			return err
+		}
+		if err := s.policy.Authorize(subject, policy.ActionVerify, s.referenceResource(reference)); err != nil {
+			return err
+		}
+		reference.UpdatedAt = time.Now()
+	}
+
+	reference.CurrentStage++
+	reference.Approvals = append(reference.Approvals, model.StageApproval{
+		Stage:      stage,
+		Round:      submissionRound(reference),
+		By:         subject.UserID,
+		At:         reference.UpdatedAt,
+		OnBehalfOf: onBehalfOf,
+	})
+	return nil
+}
+
+// actingFor returns the user ID of the advisor the subject verifies for as
+// their delegate, empty when the subject acts for themselves
+func (s *AchievementService) actingFor(subject policy.Subject, resource policy.Resource) string {
+	if resource.VerificationStage != policy.StageAdvisor || policy.AdvisorOfOwner(subject, resource) || !policy.DelegateOfAdvisor(subject, resource) {
+		return ""
+	}
+	advisor, err := s.lecturerRepo.GetByID(resource.OwnerAdvisorID)
+	if err != nil {
+		log.Printf("Failed to load advisor %s: %v", resource.OwnerAdvisorID, err)
+		return ""
+	}
+	return advisor.UserID
+}
+
+// referenceResource is the policy resource of a reference, at its verification stage
+func (s *AchievementService) referenceResource(reference *model.AchievementReference) policy.Resource {
+	resource := s.achievementResource(reference.AchievementID, reference.StudentID)
+	resource.VerificationStage = VerificationStage(reference)
+	return resource
+}
+
+// VerificationStage returns the stage of the verification chain a submitted
+// reference waits for, empty when it is not submitted. References submitted
+// before chains existed wait for the advisor.
+func VerificationStage(reference *model.AchievementReference) string {
+	if reference.Status != policy.StatusSubmitted {
+		return ""
+	}
+	if reference.CurrentStage >= 0 && reference.CurrentStage < len(reference.VerificationChain) {
+		return reference.VerificationChain[reference.CurrentStage]
+	}
+	return policy.StageAdvisor
+}
+
+// submissionRound counts the submissions of a reference so far
+func submissionRound(reference *model.AchievementReference) int {
+	round := 0
+	for _, change := range reference.StatusHistory {
+		if change.To == policy.StatusSubmitted {
+			round++
+		}
+	}
+	return round
+}
+
+// holdsStaffRole reports whether the subject holds a role other than the built-in
+// student, lecturer, admin and service ones, e.g. the role of a verification stage
+func holdsStaffRole(subject policy.Subject) bool {
+	for _, role := range subject.Roles {
+		switch policy.CanonicalRole(role) {
+		case policy.RoleStudent, policy.RoleLecturer, policy.RoleAdmin, policy.RoleService:
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeFeedback trims the feedback points and drops empty ones
+func NormalizeFeedback(feedback []string) []string {
+	var normalized []string
+	for _, point := range feedback {
+		if point = strings.TrimSpace(point); point != "" {
+			normalized = append(normalized, point)
+		}
+	}
+	return normalized
+}
+
+// FeedbackRound is what the advisor sent back after one submission
+type FeedbackRound struct {
+	Round    int       `json:"round"`
+	Status   string    `json:"status"` // rejected or revision_requested
+	Actor    string    `json:"actor"`
+	Note     string    `json:"note,omitempty"`
+	Feedback []string  `json:"feedback,omitempty"`
+	At       time.Time `json:"at"`
+}
+
+// FeedbackRounds lists the rejections and revision requests in a status history,
+// numbered by the submission they answered
+func FeedbackRounds(history []model.StatusChange) []FeedbackRound {
+	rounds := []FeedbackRound{}
+	submissions := 0
+	for _, change := range history {
+		switch change.To {
+		case policy.StatusSubmitted:
+			submissions++
+		case policy.StatusRejected, policy.StatusRevisionRequested:
+			rounds = append(rounds, FeedbackRound{
+				Round:    submissions,
+				Status:   change.To,
+				Actor:    change.Actor,
+				Note:     change.Note,
+				Feedback: change.Feedback,
+				At:       change.At,
+			})
+		}
+	}
+	return rounds
+}
+
+// previousStatus is the status a reference had before its last change
+func previousStatus(reference *model.AchievementReference) string {
+	if n := len(reference.StatusHistory); n > 0 {
+		return reference.StatusHistory[n-1].From
+	}
+	return ""
+}
+
+// createRevisionNotification tells the student an achievement came back with feedback
+func (s *AchievementService) createRevisionNotification(studentID, achievementID, note string, feedback []string) error {
+	achievement, err := s.achievementRepo.GetByObjectID(achievementID)
+	if err != nil {
+		return err
+	}
+
+	title := "Achievement Needs Revision"
+	message := fmt.Sprintf("Your advisor returned your achievement '%s' with %d point(s) to address. Edit it and submit it again.", achievement.Title, len(feedback))
+
+	data := map[string]interface{}{
+		"achievement_id":    achievementID,
+		"achievement_title": achievement.Title,
+		"note":              note,
+		"feedback":          feedback,
+		"action_required":   "revise_and_resubmit",
+		"type":              "achievement_revision_requested",
+	}
+
+	return s.notificationService.CreateNotification(studentID, "achievement_revision_requested", title, message, data)
+}
+
+// invalidStatusResponse answers a status change the workflow does not allow
+func invalidStatusResponse(c *fiber.Ctx, err *policy.TransitionError) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"success": false,
+		"error": err.Error(),
+		"message": "This action is not possible while the achievement is " + err.From,
+		"code": "INVALID_STATUS",
+		"current_status": err.From,
+		"allowed_status": err.Allowed,
+	})
+}
+
+// GetAchievementHistoryRequest shows every status change of an achievement
+// @Summary Get Achievement Status History
+// @Description Status changes of an achievement with who made them, when and the note or feedback given, plus the feedback of each round (owner, advisor and admins)
+// @Tags Achievements
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Achievement ID"
+// @Success 200 {object} map[string]interface{} "Status history"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Achievement not found"
+// @Router /achievements/{id}/history [get]
+func (s *AchievementService) GetAchievementHistoryRequest(c *fiber.Ctx) error {
+	reference, err := s.GetReferenceByAchievementIDSafe(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error": "Achievement reference not found",
+			"code": "REFERENCE_NOT_FOUND",
+		})
+	}
+
+	history := reference.StatusHistory
+	if history == nil {
+		history = []model.StatusChange{}
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"achievement_id": reference.AchievementID,
+			"reference_id": reference.ID.Hex(),
+			"status": reference.Status,
+			"next_statuses": s.workflow.Next(reference.Status),
+			"verification_chain": reference.VerificationChain,
+			"verification_stage": VerificationStage(reference),
+			"approvals": reference.Approvals,
+			"history": history,
+			"feedback_rounds": FeedbackRounds(history),
+		},
+	})
+}
+
+// transitionError unwraps the workflow error of a failed status change
+func transitionError(err error) (*policy.TransitionError, bool) {
+	var transitionErr *policy.TransitionError
+	if errors.As(err, &transitionErr) {
+		return transitionErr, true
+	}
+	return nil, false
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"UASBE/app/model"
+	"UASBE/app/policy"
+	"UASBE/app/repository"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdminScopeService manages department administrators: users holding the
+// department_admin role who administer only the students of some program
+// studies and the lecturers of some departments (their scopes).
+type AdminScopeService struct {
+	authService    *AuthService
+	userRepo       *repository.UserRepository
+	studentRepo    *repository.StudentRepository
+	lecturerRepo   *repository.LecturerRepository
+	adminScopeRepo *repository.AdminScopeRepository
+}
+
+func NewAdminScopeService(authService *AuthService, userRepo *repository.UserRepository, studentRepo *repository.StudentRepository, lecturerRepo *repository.LecturerRepository, adminScopeRepo *repository.AdminScopeRepository) *AdminScopeService {
+	return &AdminScopeService{
+		authService:    authService,
+		userRepo:       userRepo,
+		studentRepo:    studentRepo,
+		lecturerRepo:   lecturerRepo,
+		adminScopeRepo: adminScopeRepo,
+	}
+}
+
+type AdminScopesRequest struct {
+	Scopes []string `json:"scopes"` // program studies and departments, e.g. "Teknik Informatika"
+}
+
+const maxAdminScopeLength = 255
+
+// NormalizeAdminScopes trims the scopes and drops empty ones and duplicates
+// (ignoring case). An empty result is allowed and removes all scopes.
+func NormalizeAdminScopes(scopes []string) ([]string, error) {
+	seen := make(map[string]bool)
+	normalized := []string{}
+	for _, scope := range scopes {
+		scope = strings.Join(strings.Fields(scope), " ")
+		if scope == "" || seen[strings.ToLower(scope)] {
+			continue
+		}
+		if len(scope) > maxAdminScopeLength {
+			return nil, errors.New("scope must be at most 255 characters")
+		}
+		seen[strings.ToLower(scope)] = true
+		normalized = append(normalized, scope)
+	}
+	return normalized, nil
+}
+
+// GetScopes returns the scopes of a user
+func (s *AdminScopeService) GetScopes(userID string) ([]string, error) {
+	return s.adminScopeRepo.GetScopes(userID)
+}
+
+// SetScopes replaces the scopes of a user. Scopes only take effect while the
+// user holds the department_admin role.
+func (s *AdminScopeService) SetScopes(userID string, scopes []string, adminID string) ([]string, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	normalized, err := NormalizeAdminScopes(scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.adminScopeRepo.SetScopes(user.ID, normalized, adminID); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// StudentIDsInScope returns the user IDs of the students of the program studies
+// in the scopes; never nil, so it can be used as a filter that matches nobody
+func (s *AdminScopeService) StudentIDsInScope(scopes []string) ([]string, error) {
+	return s.studentRepo.GetUserIDsByProgramStudies(scopes)
+}
+
+// UserInScope reports whether a department admin with the scopes may manage the
+// user: a student of one of the program studies or a lecturer of one of the
+// departments. Administrators are never in anyone's scope.
+func (s *AdminScopeService) UserInScope(user *model.User, scopes []string) bool {
+	roles, err := s.authService.ResolveUserRoles(user)
+	if err != nil || roles.HasRole(policy.RoleAdmin) || roles.HasRole(policy.RoleDepartmentAdmin) {
+		return false
+	}
+
+	if student, err := s.studentRepo.GetByUserID(user.ID); err == nil && policy.InScope(scopes, student.ProgramStudy) {
+		return true
+	}
+	if lecturer, err := s.lecturerRepo.GetByUserID(user.ID); err == nil && policy.InScope(scopes, lecturer.Department) {
+		return true
+	}
+	return false
+}
+
+// FilterUsersInScope keeps the users a department admin with the scopes may manage
+func (s *AdminScopeService) FilterUsersInScope(users []model.User, scopes []string) []model.User {
+	var inScope []model.User
+	for i := range users {
+		if s.UserInScope(&users[i], scopes) {
+			inScope = append(inScope, users[i])
+		}
+	}
+	return inScope
+}
+
+// requestAdminScopes returns the scopes a request of an administrator is limited
+// to. Admins are not limited (scoped is false). For anybody else scoped is true
+// with the scopes set by ScopedAdminMiddleware, which are only there for
+// department admins; no scopes means no access.
+func requestAdminScopes(c *fiber.Ctx) (scopes []string, scoped bool) {
+	if requestHasRole(c, policy.RoleAdmin) {
+		return nil, false
+	}
+	scopes, _ = c.Locals("admin_scopes").([]string)
+	return scopes, true
+}
+
+// outOfScopeResponse answers a department admin asking for a user outside their scopes
+func outOfScopeResponse(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+		"success": false,
+		"error": "Access denied",
+		"message": "This user is outside the program studies and departments you administer",
+		"code": "OUT_OF_SCOPE",
+	})
+}
+
+// GetAdminScopesRequest shows the scopes of a user
+// @Summary Get Admin Scopes
+// @Description Program studies and departments a department admin manages (admin only)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]interface{} "Scopes"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Router /admin/users/{id}/scopes [get]
+func (s *AdminScopeService) GetAdminScopesRequest(c *fiber.Ctx) error {
+	user, err := s.userRepo.GetByID(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error": "User not found",
+			"code": "USER_NOT_FOUND",
+		})
+	}
+
+	scopes, err := s.GetScopes(user.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error": "Failed to get scopes",
+			"message": err.Error(),
+			"code": "FETCH_FAILED",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"user_id": user.ID,
+			"scopes": scopes,
+		},
+	})
+}
+
+// SetAdminScopesRequest replaces the scopes of a user
+// @Summary Set Admin Scopes
+// @Description Bind a department admin to program studies and departments; an empty list removes all (admin only)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param body body AdminScopesRequest true "Scopes"
+// @Success 200 {object} map[string]interface{} "Scopes updated"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Router /admin/users/{id}/scopes [put]
+func (s *AdminScopeService) SetAdminScopesRequest(c *fiber.Ctx) error {
+	var req AdminScopesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error": "Invalid request body",
+			"message": "Please provide valid JSON data",
+			"code": "INVALID_REQUEST_BODY",
+		})
+	}
+
+	adminID, _ := c.Locals("user_id").(string)
+
+	scopes, err := s.SetScopes(c.Params("id"), req.Scopes, adminID)
+	if err != nil {
+		switch {
+		case err.Error() == "user not found":
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"success": false,
+				"error": "User not found",
+				"code": "USER_NOT_FOUND",
+			})
+		case strings.HasPrefix(err.Error(), "scope must"):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error": "Validation failed",
+				"message": err.Error(),
+				"code": "VALIDATION_ERROR",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error": "Failed to update scopes",
+			"message": err.Error(),
+			"code": "UPDATE_FAILED",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Scopes updated",
+		"code": "SCOPES_UPDATED",
+		"data": fiber.Map{
+			"user_id": c.Params("id"),
+			"scopes": scopes,
+		},
+	})
+}
@@ -2,84 +2,497 @@ package service
 
 import (
 	"UASBE/app/model"
+	"UASBE/app/policy"
 	"UASBE/app/repository"
+	"UASBE/mail"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/google/uuid"
 )
 
 type AuthService struct {
-	userRepo     *repository.UserRepository
-	studentRepo  *repository.StudentRepository
-	lecturerRepo *repository.LecturerRepository
-	jwtSecret    string
+	userRepo        *repository.UserRepository
+	studentRepo     *repository.StudentRepository
+	lecturerRepo    *repository.LecturerRepository
+	tokenRepo        *repository.TokenRepository
+	twoFactorRepo    *repository.TwoFactorRepository
+	loginAttemptRepo *repository.LoginAttemptRepository
+	sessionRepo      *repository.SessionRepository
+	serviceAccountRepo *repository.ServiceAccountRepository
+	userRoleRepo     *repository.UserRoleRepository
+	notificationService *NotificationService
+	mailer           mail.Mailer
+	keys             *KeySet
+	permissions      *PermissionResolver
+	accessTokenTTL   time.Duration
+	refreshTokenTTL  time.Duration
+	passwordResetTTL time.Duration
+	passwordResetURL string
+
+	// Email domains allowed to self-register; empty allows any domain
+	registrationEmailDomains []string
+
+	// Email verification; unverified accounts can log in but, when
+	// requireVerifiedEmail is set, cannot submit achievements for verification
+	emailVerificationTTL time.Duration
+	emailVerificationURL string
+	requireVerifiedEmail bool
+
+	// Roles that must use TOTP; their users cannot get a token without it
+	twoFactorRequiredRoles []string
+	twoFactorTokenTTL      time.Duration
+
+	// Password rules, hashing (argon2id or bcrypt) and how many recent passwords
+	// cannot be reused
+	passwordPolicy      *PasswordPolicy
+	passwordHasher      *PasswordHasher
+	passwordHistorySize int
+
+	// Lifetime of the read-only tokens admins use to view the system as a user
+	impersonationTokenTTL time.Duration
+
+	// Brute-force protection, see ThrottlePolicy
+	accountThrottle   ThrottlePolicy
+	ipThrottle        ThrottlePolicy
+	dummyPasswordHash string
+
+	// Notify users of logins from a device or network not seen before
+	loginAlertsEnabled bool
 }
 
+// ClientInfo identifies where a login request came from
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// Values of the "typ" claim. Only access tokens are accepted by AuthMiddleware;
+// the two-factor tokens can only be used on their own endpoints.
+const (
+	tokenTypeAccess             = "access"
+	tokenTypeTwoFactorChallenge = "2fa_challenge"
+	tokenTypeTwoFactorSetup     = "2fa_setup"
+)
+
+// accessTokenVersion is the "ver" claim of the current access token layout
+// (user_id and role_id only; no username, role name or permissions)
+const accessTokenVersion = 3
+
 type LoginRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
 }
 
 type LoginResponse struct {
-	Token       string             `json:"token"`
-	User        *model.User        `json:"user"`
-	Role        *model.Role        `json:"role"`
-	Permissions []model.Permission `json:"permissions"`
-	ExpiresAt   time.Time          `json:"expires_at"`
+	Token            string             `json:"token"`
+	RefreshToken     string             `json:"refresh_token"`
+	User             *model.User        `json:"user"`
+	Role             *model.Role        `json:"role"`
+	Roles            []model.Role       `json:"roles"` // primary role first, then additional roles
+	Permissions      []model.Permission `json:"permissions"` // union of all roles
+	ExpiresAt        time.Time          `json:"expires_at"`
+	RefreshExpiresAt time.Time          `json:"refresh_expires_at"`
+	SessionID        string             `json:"session_id"`
+
+	// Set instead of the tokens above when the password step succeeded but a
+	// second factor is still needed (TwoFactorRequired) or must first be
+	// enrolled (TwoFactorSetupRequired)
+	TwoFactorRequired      bool      `json:"two_factor_required,omitempty"`
+	TwoFactorSetupRequired bool      `json:"two_factor_setup_required,omitempty"`
+	ChallengeToken         string    `json:"challenge_token,omitempty"`
+	ChallengeExpiresAt     time.Time `json:"challenge_expires_at,omitempty"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
 }
 
+// RegisterRequest is a public self-registration. Only students can register
+// themselves; lecturer and admin accounts are created by an admin.
 type RegisterRequest struct {
 	Username     string `json:"username"`
 	Email        string `json:"email"`
 	Password     string `json:"password"`
 	FullName     string `json:"full_name"`
-	Role         string `json:"role"` // "admin", "student", "lecturer"
-	StudentID    string `json:"student_id,omitempty"`
-	LecturerID   string `json:"lecturer_id,omitempty"`
-	ProgramStudy string `json:"program_study,omitempty"`
-	AcademicYear string `json:"academic_year,omitempty"`
-	Department   string `json:"department,omitempty"`
+	Role         string `json:"role,omitempty"` // optional, must be "student"
+	StudentID    string `json:"student_id"`
+	ProgramStudy string `json:"program_study"`
+	AcademicYear string `json:"academic_year"`
 }
 
-func NewAuthService(userRepo *repository.UserRepository, studentRepo *repository.StudentRepository, lecturerRepo *repository.LecturerRepository, jwtSecret string) *AuthService {
+func NewAuthService(userRepo *repository.UserRepository, studentRepo *repository.StudentRepository, lecturerRepo *repository.LecturerRepository, tokenRepo *repository.TokenRepository, twoFactorRepo *repository.TwoFactorRepository, loginAttemptRepo *repository.LoginAttemptRepository, sessionRepo *repository.SessionRepository, serviceAccountRepo *repository.ServiceAccountRepository, userRoleRepo *repository.UserRoleRepository, notificationService *NotificationService, mailer mail.Mailer, keys *KeySet, permissions *PermissionResolver) *AuthService {
+	passwordResetURL := os.Getenv("PASSWORD_RESET_URL")
+	if passwordResetURL == "" {
+		passwordResetURL = "http://localhost:3000/reset-password"
+	}
+
+	emailVerificationURL := os.Getenv("EMAIL_VERIFICATION_URL")
+	if emailVerificationURL == "" {
+		emailVerificationURL = "http://localhost:3000/verify-email"
+	}
+
+	// Compared against when the credential matches no user, so a login for an
+	// unknown account costs the same hashing time as a wrong password
+	passwordHasher := PasswordHasherFromEnv()
+	dummyPasswordHash, _ := passwordHasher.Hash(uuid.New().String())
+
+	lockoutDuration := getEnvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute)
+	backoffBase := getEnvDuration("LOGIN_BACKOFF_BASE", time.Second)
+	backoffMax := getEnvDuration("LOGIN_BACKOFF_MAX", 30*time.Second)
+	attemptWindow := getEnvDuration("LOGIN_ATTEMPT_WINDOW", 15*time.Minute)
+
 	return &AuthService{
-		userRepo:     userRepo,
-		studentRepo:  studentRepo,
-		lecturerRepo: lecturerRepo,
-		jwtSecret:    jwtSecret,
+		userRepo:         userRepo,
+		studentRepo:      studentRepo,
+		lecturerRepo:     lecturerRepo,
+		tokenRepo:        tokenRepo,
+		twoFactorRepo:    twoFactorRepo,
+		loginAttemptRepo: loginAttemptRepo,
+		sessionRepo:      sessionRepo,
+		serviceAccountRepo: serviceAccountRepo,
+		userRoleRepo:     userRoleRepo,
+		notificationService: notificationService,
+		mailer:           mailer,
+		keys:             keys,
+		permissions:      permissions,
+		accessTokenTTL:   getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		refreshTokenTTL:  getEnvDuration("REFRESH_TOKEN_TTL", 7*24*time.Hour),
+		passwordResetTTL: getEnvDuration("PASSWORD_RESET_TTL", 30*time.Minute),
+		passwordResetURL: passwordResetURL,
+
+		registrationEmailDomains: getEnvList("REGISTRATION_EMAIL_DOMAINS"),
+
+		emailVerificationTTL: getEnvDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+		emailVerificationURL: emailVerificationURL,
+		requireVerifiedEmail: getEnvBool("REQUIRE_VERIFIED_EMAIL_FOR_SUBMISSION", true),
+
+		twoFactorRequiredRoles: getEnvList("TWO_FACTOR_REQUIRED_ROLES"),
+		twoFactorTokenTTL:      getEnvDuration("TWO_FACTOR_TOKEN_TTL", 5*time.Minute),
+
+		passwordPolicy:      PasswordPolicyFromEnv(),
+		passwordHasher:      passwordHasher,
+		passwordHistorySize: getEnvInt("PASSWORD_HISTORY_SIZE", 5),
+
+		impersonationTokenTTL: getEnvDuration("IMPERSONATION_TOKEN_TTL", 10*time.Minute),
+
+		accountThrottle: ThrottlePolicy{
+			FreeAttempts:    getEnvInt("LOGIN_ACCOUNT_FREE_ATTEMPTS", 2),
+			Threshold:       getEnvInt("LOGIN_ACCOUNT_LOCKOUT_THRESHOLD", 5),
+			LockoutDuration: lockoutDuration,
+			BackoffBase:     backoffBase,
+			BackoffMax:      backoffMax,
+			Window:          attemptWindow,
+		},
+		// Many users can share an IP (campus NAT), so the IP scope is more lenient
+		ipThrottle: ThrottlePolicy{
+			FreeAttempts:    getEnvInt("LOGIN_IP_FREE_ATTEMPTS", 10),
+			Threshold:       getEnvInt("LOGIN_IP_LOCKOUT_THRESHOLD", 50),
+			LockoutDuration: lockoutDuration,
+			BackoffBase:     backoffBase,
+			BackoffMax:      backoffMax,
+			Window:          attemptWindow,
+		},
+		dummyPasswordHash: dummyPasswordHash,
+
+		loginAlertsEnabled: getEnvBool("LOGIN_ALERTS_ENABLED", true),
 	}
 }
 
-func (s *AuthService) Login(req *LoginRequest) (*LoginResponse, error) {
+func (s *AuthService) Login(req *LoginRequest, client ClientInfo) (*LoginResponse, error) {
 	// FR-001 Step 1: User mengirim kredensial
 	// Support login with username or email
-	user, err := s.getUserByCredential(req.Username)
-	if err != nil {
-		return nil, errors.New("invalid credentials")
+	user, lookupErr := s.getUserByCredential(req.Username)
+
+	// Brute-force protection. Unknown credentials are throttled exactly like real
+	// accounts, so neither the lockout nor the error reveals which accounts exist.
+	accountKey := throttleAccountKey(user, req.Username)
+	if err := s.checkLoginThrottle(accountKey, client.IP); err != nil {
+		s.recordLoginAttempt(user, req.Username, client, false, "throttled")
+		return nil, err
 	}
 
 	// FR-001 Step 2: Sistem memvalidasi kredensial
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
-	if err != nil {
+	passwordHash := s.dummyPasswordHash
+	if lookupErr == nil {
+		passwordHash = user.Password
+	}
+	match, needsRehash := s.passwordHasher.Verify(passwordHash, req.Password)
+	if !match || lookupErr != nil {
+		s.recordLoginFailure(accountKey, client.IP)
+		s.recordLoginAttempt(user, req.Username, client, false, "invalid_credentials")
 		return nil, errors.New("invalid credentials")
 	}
 
 	// FR-001 Step 3: Sistem mengecek status aktif user
-	if !user.IsActive {
-		return nil, errors.New("account is deactivated")
+	if err := checkAccountStatus(user); err != nil {
+		reason := "account_deactivated"
+		if user.IsActive {
+			reason = "approval_" + user.ApprovalStatus
+		}
+		s.recordLoginAttempt(user, req.Username, client, false, reason)
+		return nil, err
 	}
 
-	// Get user role and permissions
-	role, permissions, err := s.getUserRoleAndPermissions(user.RoleID)
+	s.recordLoginAttempt(user, req.Username, client, true, "")
+
+	// Upgrade hashes made with an older algorithm or cost while the password is known
+	if needsRehash {
+		s.rehashPassword(user, req.Password)
+	}
+
+	return s.loginAuthenticatedUser(user, client)
+}
+
+// LoginExternal logs in a user whose identity was established by an external
+// identity provider (single sign-on) instead of a local password
+func (s *AuthService) LoginExternal(user *model.User, credential string, client ClientInfo) (*LoginResponse, error) {
+	if err := checkAccountStatus(user); err != nil {
+		s.recordLoginAttempt(user, credential, client, false, "sso_account_unusable")
+		return nil, err
+	}
+
+	s.recordLoginAttempt(user, credential, client, true, "")
+	return s.loginAuthenticatedUser(user, client)
+}
+
+// loginAuthenticatedUser continues a login whose first factor has passed (password
+// or single sign-on): it asks for the second factor when needed, otherwise issues tokens.
+func (s *AuthService) loginAuthenticatedUser(user *model.User, client ClientInfo) (*LoginResponse, error) {
+	// Get user roles and permissions
+	roles, err := s.ResolveUserRoles(user)
 	if err != nil {
 		return nil, errors.New("failed to get user role")
 	}
 
+	// Second factor: an enrolled user gets a challenge to exchange with a TOTP code,
+	// a user whose role requires TOTP but has not enrolled gets a setup token
+	enrolment, err := s.twoFactorRepo.GetByUserID(user.ID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, errors.New("failed to check two-factor status")
+	}
+	if err == nil && enrolment.Enabled {
+		return s.twoFactorPending(user, tokenTypeTwoFactorChallenge)
+	}
+	if s.RequiresTwoFactor(roles.Names()...) {
+		return s.twoFactorPending(user, tokenTypeTwoFactorSetup)
+	}
+
 	// FR-001 Step 4: Sistem generate JWT token dengan role dan permissions
-	token, expiresAt, err := s.generateTokenWithRoleAndPermissions(user.ID, user.Username, role, permissions)
+	// plus a refresh token that starts a new token family.
+	// With two-factor the counter is only reset once the second step passes.
+	s.loginAttemptRepo.ResetThrottle(repository.ThrottleScopeAccount, user.ID)
+	return s.issueTokens(user, roles, client)
+}
+
+// throttleAccountKey is the account-scope throttle key: the user ID for a known
+// account, otherwise the normalized credential that was tried.
+func throttleAccountKey(user *model.User, credential string) string {
+	if user != nil {
+		return user.ID
+	}
+	return "credential:" + strings.ToLower(strings.TrimSpace(credential))
+}
+
+// checkLoginThrottle refuses the attempt while the account or the client IP is
+// backing off or locked out.
+func (s *AuthService) checkLoginThrottle(accountKey, ip string) error {
+	scopes := []struct {
+		scope  string
+		key    string
+		policy ThrottlePolicy
+	}{
+		{repository.ThrottleScopeAccount, accountKey, s.accountThrottle},
+		{repository.ThrottleScopeIP, ip, s.ipThrottle},
+	}
+
+	for _, sc := range scopes {
+		if sc.key == "" {
+			continue
+		}
+
+		failures, since, lockRemaining, err := s.loginAttemptRepo.GetThrottle(sc.scope, sc.key)
+		if err != nil {
+			return errors.New("login failed")
+		}
+
+		state := ThrottleState{Failures: failures, SinceLastFailure: since, LockRemaining: lockRemaining}
+		if wait := sc.policy.RetryAfter(state); wait > 0 {
+			return &LoginThrottledError{RetryAfter: wait, Locked: lockRemaining > 0}
+		}
+	}
+
+	return nil
+}
+
+// recordLoginFailure counts a failed password or second factor for the account
+// and the IP, locking either one out once its threshold is reached.
+func (s *AuthService) recordLoginFailure(accountKey, ip string) {
+	if failures, err := s.loginAttemptRepo.RecordThrottleFailure(repository.ThrottleScopeAccount, accountKey, s.accountThrottle.Window); err == nil && s.accountThrottle.ShouldLock(failures) {
+		s.loginAttemptRepo.LockThrottle(repository.ThrottleScopeAccount, accountKey, s.accountThrottle.LockoutDuration)
+		log.Printf("Login locked for account %s after %d failed attempts", accountKey, failures)
+	}
+
+	if ip == "" {
+		return
+	}
+	if failures, err := s.loginAttemptRepo.RecordThrottleFailure(repository.ThrottleScopeIP, ip, s.ipThrottle.Window); err == nil && s.ipThrottle.ShouldLock(failures) {
+		s.loginAttemptRepo.LockThrottle(repository.ThrottleScopeIP, ip, s.ipThrottle.LockoutDuration)
+		log.Printf("Login locked for IP %s after %d failed attempts", ip, failures)
+	}
+}
+
+// recordLoginAttempt stores a login attempt for the login history. A successful
+// one from a new device or network raises an alert first.
+func (s *AuthService) recordLoginAttempt(user *model.User, credential string, client ClientInfo, success bool, reason string) {
+	attempt := &model.LoginAttempt{
+		Credential:    credential,
+		IPAddress:     client.IP,
+		UserAgent:     client.UserAgent,
+		Success:       success,
+		FailureReason: reason,
+		IPRange:       IPRange(client.IP),
+		Device:        DescribeUserAgent(client.UserAgent),
+	}
+	if user != nil {
+		attempt.UserID = user.ID
+		if success {
+			s.alertNewLoginLocation(user, attempt)
+		}
+	}
+
+	if err := s.loginAttemptRepo.Create(attempt); err != nil {
+		log.Printf("Failed to record login attempt: %v", err)
+	}
+}
+
+// UnlockAccount lifts a lockout of the user's account and forgets its failed attempts
+func (s *AuthService) UnlockAccount(userID string) (bool, error) {
+	locked, err := s.loginAttemptRepo.IsLocked(repository.ThrottleScopeAccount, userID)
+	if err != nil {
+		return false, err
+	}
+
+	if err := s.loginAttemptRepo.ResetThrottle(repository.ThrottleScopeAccount, userID); err != nil {
+		return false, err
+	}
+
+	return locked, nil
+}
+
+// RequiresTwoFactor reports whether TOTP is mandatory for any of the roles (TWO_FACTOR_REQUIRED_ROLES)
+func (s *AuthService) RequiresTwoFactor(roleNames ...string) bool {
+	for _, required := range s.twoFactorRequiredRoles {
+		for _, roleName := range roleNames {
+			if required == roleName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// twoFactorPending answers a correct password with a short-lived token of the given
+// type instead of an access token
+func (s *AuthService) twoFactorPending(user *model.User, tokenType string) (*LoginResponse, error) {
+	token, expiresAt, err := s.generateTwoFactorToken(user.ID, tokenType)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+
+	user.Password = ""
+
+	return &LoginResponse{
+		User:                   user,
+		TwoFactorRequired:      tokenType == tokenTypeTwoFactorChallenge,
+		TwoFactorSetupRequired: tokenType == tokenTypeTwoFactorSetup,
+		ChallengeToken:         token,
+		ChallengeExpiresAt:     expiresAt,
+	}, nil
+}
+
+// CompleteLogin issues the real token pair once every login step has passed
+func (s *AuthService) CompleteLogin(userID string, client ClientInfo) (*LoginResponse, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, errors.New("invalid credentials")
+	}
+
+	if err := checkAccountStatus(user); err != nil {
+		return nil, err
+	}
+
+	roles, err := s.ResolveUserRoles(user)
+	if err != nil {
+		return nil, errors.New("failed to get user role")
+	}
+
+	s.loginAttemptRepo.ResetThrottle(repository.ThrottleScopeAccount, user.ID)
+	return s.issueTokens(user, roles, client)
+}
+
+// RecordSecondFactorFailure counts a wrong TOTP or recovery code against the
+// account, so a known password cannot be used to brute-force the second factor
+// with fresh challenges.
+func (s *AuthService) RecordSecondFactorFailure(userID string, client ClientInfo) {
+	s.recordLoginFailure(userID, client.IP)
+
+	if user, err := s.userRepo.GetByID(userID); err == nil {
+		s.recordLoginAttempt(user, user.Username, client, false, "invalid_second_factor")
+	}
+}
+
+// issueTokens starts a new session, i.e. a fresh login: a session record, an
+// access token bound to it and a refresh token that starts its token family.
+func (s *AuthService) issueTokens(user *model.User, roles *ResolvedRoles, client ClientInfo) (*LoginResponse, error) {
+	session := &model.Session{
+		UserID:    user.ID,
+		UserAgent: client.UserAgent,
+		IPAddress: client.IP,
+		ExpiresAt: time.Now().Add(s.refreshTokenTTL),
+	}
+	if err := s.sessionRepo.Create(session); err != nil {
+		return nil, errors.New("failed to create session")
+	}
+
+	refreshToken, refreshRecord, err := s.createRefreshToken(user.ID, session.ID)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+
+	// Housekeeping: drop sessions that ended long ago
+	s.sessionRepo.DeleteStale()
+
+	return s.buildLoginResponse(user, roles, refreshToken, refreshRecord)
+}
+
+func (s *AuthService) buildLoginResponse(user *model.User, roles *ResolvedRoles, refreshToken string, refreshRecord *model.RefreshToken) (*LoginResponse, error) {
+	token, expiresAt, err := s.generateAccessToken(user.ID, roles.Primary.Role.ID, refreshRecord.FamilyID)
 	if err != nil {
 		return nil, errors.New("failed to generate token")
 	}
@@ -89,14 +502,97 @@ func (s *AuthService) Login(req *LoginRequest) (*LoginResponse, error) {
 	user.Password = ""
 
 	return &LoginResponse{
-		Token:       token,
-		User:        user,
-		Role:        role,
-		Permissions: permissions,
-		ExpiresAt:   expiresAt,
+		Token:            token,
+		RefreshToken:     refreshToken,
+		User:             user,
+		Role:             roles.Primary.Role,
+		Roles:            roles.RoleList(),
+		Permissions:      roles.Permissions(),
+		ExpiresAt:        expiresAt,
+		RefreshExpiresAt: refreshRecord.ExpiresAt,
+		SessionID:        refreshRecord.FamilyID,
 	}, nil
 }
 
+func (s *AuthService) createRefreshToken(userID, familyID string) (string, *model.RefreshToken, error) {
+	plain, err := generateOpaqueToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	record := &model.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(plain),
+		ExpiresAt: time.Now().Add(s.refreshTokenTTL),
+	}
+
+	if err := s.tokenRepo.CreateRefreshToken(record); err != nil {
+		return "", nil, err
+	}
+
+	return plain, record, nil
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh token.
+// Every refresh token is single-use: presenting one that has already been rotated
+// is treated as theft and revokes the whole family, logging out every holder.
+func (s *AuthService) Refresh(refreshToken string, client ClientInfo) (*LoginResponse, error) {
+	current, err := s.tokenRepo.GetRefreshTokenByHash(hashToken(refreshToken))
+	if err != nil {
+		return nil, errors.New("invalid refresh token")
+	}
+
+	if current.RevokedAt != nil {
+		if current.ReplacedBy != "" {
+			s.RevokeSession(current.FamilyID)
+			return nil, errors.New("refresh token reuse detected")
+		}
+		return nil, errors.New("invalid refresh token")
+	}
+
+	if current.Expired {
+		return nil, errors.New("refresh token expired")
+	}
+
+	user, err := s.userRepo.GetByID(current.UserID)
+	if err != nil {
+		return nil, errors.New("invalid refresh token")
+	}
+
+	if err := checkAccountStatus(user); err != nil {
+		s.RevokeSession(current.FamilyID)
+		return nil, err
+	}
+
+	roles, err := s.ResolveUserRoles(user)
+	if err != nil {
+		return nil, errors.New("failed to get user role")
+	}
+
+	newRefreshToken, successor, err := s.createRefreshToken(user.ID, current.FamilyID)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+
+	// Retire the presented token; losing this race means it was used twice
+	rotated, err := s.tokenRepo.MarkRefreshTokenRotated(current.ID, successor.ID)
+	if err != nil {
+		return nil, errors.New("failed to rotate refresh token")
+	}
+	if !rotated {
+		s.RevokeSession(current.FamilyID)
+		return nil, errors.New("refresh token reuse detected")
+	}
+
+	// The session lives as long as its newest refresh token
+	if err := s.sessionRepo.Extend(current.FamilyID, client.IP, successor.ExpiresAt); err != nil {
+		return nil, errors.New("failed to update session")
+	}
+
+	return s.buildLoginResponse(user, roles, newRefreshToken, successor)
+}
+
 // Helper method to get user by username or email
 func (s *AuthService) getUserByCredential(credential string) (*model.User, error) {
 	// Try username first
@@ -111,49 +607,55 @@ func (s *AuthService) getUserByCredential(credential string) (*model.User, error
 	return user, nil
 }
 
-// Helper method to get user role and permissions
-func (s *AuthService) getUserRoleAndPermissions(roleID string) (*model.Role, []model.Permission, error) {
-	db := s.userRepo.GetDB()
-	if db == nil {
-		return nil, nil, errors.New("database connection not available")
-	}
-
-	// Get role
-	var role model.Role
-	roleQuery := `SELECT id, name, description, created_at FROM roles WHERE id = $1`
-	err := db.QueryRow(roleQuery, roleID).Scan(&role.ID, &role.Name, &role.Description, &role.CreatedAt)
+// ResolveUserRoles returns the user's primary role and additional roles with
+// their current permissions. Additional roles are read on every call, so granting
+// or revoking one takes effect on the next request.
+func (s *AuthService) ResolveUserRoles(user *model.User) (*ResolvedRoles, error) {
+	additional, err := s.userRoleRepo.GetRoleIDs(user.ID)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
+	return s.ResolveRoles(append([]string{user.RoleID}, additional...))
+}
 
-	// Get permissions
-	permissionsQuery := `
-		SELECT p.id, p.name, p.resource, p.action, p.description
-		FROM permissions p
-		JOIN role_permissions rp ON p.id = rp.permission_id
-		WHERE rp.role_id = $1
-	`
-	
-	rows, err := db.Query(permissionsQuery, roleID)
-	if err != nil {
-		return &role, nil, nil // Return role even if permissions fail
-	}
-	defer rows.Close()
+// ResolveRoles resolves several roles, the first being the primary one
+func (s *AuthService) ResolveRoles(roleIDs []string) (*ResolvedRoles, error) {
+	return s.permissions.ResolveAll(roleIDs)
+}
 
-	var permissions []model.Permission
-	for rows.Next() {
-		var permission model.Permission
-		err := rows.Scan(&permission.ID, &permission.Name, &permission.Resource, &permission.Action, &permission.Description)
-		if err != nil {
-			continue
-		}
-		permissions = append(permissions, permission)
+// requestRoles returns the names of every role AuthMiddleware resolved for the request
+func requestRoles(c *fiber.Ctx) []string {
+	if roles, ok := c.Locals("roles").([]string); ok {
+		return roles
+	}
+	if role, ok := c.Locals("role").(string); ok && role != "" {
+		return []string{role}
 	}
+	return nil
+}
 
-	return &role, permissions, nil
+// requestHasRole reports whether the requesting user holds the role, as primary
+// or additional role
+func requestHasRole(c *fiber.Ctx, role string) bool {
+	return policy.Subject{Roles: requestRoles(c)}.HasRole(role)
 }
 
+// Register creates a student account from public self-registration. The account
+// stays pending until an admin approves it, see RegistrationService.
 func (s *AuthService) Register(req *RegisterRequest) (*model.User, error) {
+	if req.Role != "" && req.Role != "student" {
+		return nil, errors.New("self-registration is only available for students")
+	}
+
+	req.Email = strings.TrimSpace(req.Email)
+	if !EmailDomainAllowed(req.Email, s.registrationEmailDomains) {
+		return nil, errors.New("email domain is not allowed")
+	}
+
+	if err := s.passwordPolicy.Validate(req.Password, req.Username, req.Email); err != nil {
+		return nil, err
+	}
+
 	// Check if username already exists
 	_, err := s.userRepo.GetByUsername(req.Username)
 	if err == nil {
@@ -166,132 +668,766 @@ func (s *AuthService) Register(req *RegisterRequest) (*model.User, error) {
 		return nil, errors.New("email already exists")
 	}
 
+	// Check if student ID already exists
+	_, err = s.studentRepo.GetByStudentID(req.StudentID)
+	if err == nil {
+		return nil, errors.New("student ID already exists")
+	}
+
 	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := s.passwordHasher.Hash(req.Password)
 	if err != nil {
 		return nil, err
 	}
 
-	// Get role ID based on role name
-	roleID, err := s.getRoleIDByName(req.Role)
+	roleID, err := s.getRoleIDByName("student")
 	if err != nil {
 		return nil, err
 	}
 
-	// Create user
-	user := &model.User{
-		Username:  req.Username,
-		Email:     req.Email,
-		Password:  string(hashedPassword),
-		FullName:  req.FullName,
-		RoleID:    roleID,
-		IsActive:  true,
+	// Create user
+	user := &model.User{
+		Username:       req.Username,
+		Email:          req.Email,
+		Password:       hashedPassword,
+		FullName:       req.FullName,
+		RoleID:         roleID,
+		IsActive:       true,
+		ApprovalStatus: model.ApprovalStatusPending,
+		SelfRegistered: true,
+	}
+
+	err = s.userRepo.Create(user)
+	if err != nil {
+		return nil, err
+	}
+
+	student := &model.Student{
+		UserID:       user.ID,
+		StudentID:    req.StudentID,
+		ProgramStudy: req.ProgramStudy,
+		AcademicYear: req.AcademicYear,
+	}
+	err = s.studentRepo.Create(student)
+	if err != nil {
+		// Rollback user creation if student creation fails
+		s.userRepo.Delete(user.ID)
+		return nil, err
+	}
+
+	if err := s.SendEmailVerification(user); err != nil {
+		log.Printf("Failed to send verification email to user %s: %v", user.ID, err)
+	}
+
+	// Remove password from response
+	user.Password = ""
+	return user, nil
+}
+
+// EmailDomainAllowed reports whether the email's domain is one of the allowed
+// domains or a subdomain of one (so "unair.ac.id" also allows
+// "student.unair.ac.id"). An empty list allows every domain.
+func EmailDomainAllowed(email string, domains []string) bool {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return false
+	}
+	if len(domains) == 0 {
+		return true
+	}
+
+	domain := strings.ToLower(email[at+1:])
+	for _, allowed := range domains {
+		allowed = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(allowed), "@"))
+		if allowed == "" {
+			continue
+		}
+		if domain == allowed || strings.HasSuffix(domain, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// generateAccessToken issues a small access token: who the user is and which role
+// they had at login. Username, role name and permissions are resolved live on every
+// request, so permission changes take effect without waiting for token expiry.
+func (s *AuthService) generateAccessToken(userID string, roleID string, sessionID string) (string, time.Time, error) {
+	expiresAt := time.Now().Add(s.accessTokenTTL)
+
+	claims := jwt.MapClaims{
+		"jti":     uuid.New().String(),
+		"typ":     tokenTypeAccess,
+		"ver":     accessTokenVersion,
+		"user_id": userID,
+		"role_id": roleID,
+		"sid":     sessionID,
+		"exp":     expiresAt.Unix(),
+		"iat":     time.Now().Unix(),
+	}
+
+	tokenString, err := s.keys.Sign(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return tokenString, expiresAt, nil
+}
+
+// IssueImpersonationToken issues a short-lived access token for the target user
+// on behalf of an admin. The "imp" claim names the admin and "sid" is the admin's
+// own session, so the token ends when the admin logs out. It is never paired
+// with a refresh token and AuthMiddleware only lets it make read-only requests.
+func (s *AuthService) IssueImpersonationToken(user *model.User, impersonatorID, impersonatorSessionID string) (string, time.Time, error) {
+	expiresAt := time.Now().Add(s.impersonationTokenTTL)
+
+	claims := jwt.MapClaims{
+		"jti":     uuid.New().String(),
+		"typ":     tokenTypeAccess,
+		"ver":     accessTokenVersion,
+		"user_id": user.ID,
+		"role_id": user.RoleID,
+		"sid":     impersonatorSessionID,
+		"imp":     impersonatorID,
+		"exp":     expiresAt.Unix(),
+		"iat":     time.Now().Unix(),
+	}
+
+	tokenString, err := s.keys.Sign(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return tokenString, expiresAt, nil
+}
+
+// generateTwoFactorToken issues a short-lived token that only proves the password
+// step passed. It carries no role or permissions and is rejected by ValidateToken.
+func (s *AuthService) generateTwoFactorToken(userID string, tokenType string) (string, time.Time, error) {
+	expiresAt := time.Now().Add(s.twoFactorTokenTTL)
+
+	claims := jwt.MapClaims{
+		"jti":     uuid.New().String(),
+		"typ":     tokenType,
+		"user_id": userID,
+		"exp":     expiresAt.Unix(),
+		"iat":     time.Now().Unix(),
+	}
+
+	tokenString, err := s.keys.Sign(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return tokenString, expiresAt, nil
+}
+
+// ValidateToken validates an access token
+func (s *AuthService) ValidateToken(tokenString string) (*jwt.MapClaims, error) {
+	claims, err := s.validateTokenOfType(tokenString, tokenTypeAccess)
+	if err != nil {
+		return nil, err
+	}
+
+	// Older token layouts (permissions in the token, no session) are not accepted
+	version, _ := (*claims)["ver"].(float64)
+	roleID, _ := (*claims)["role_id"].(string)
+	sessionID, _ := (*claims)["sid"].(string)
+	if int(version) != accessTokenVersion || roleID == "" || sessionID == "" {
+		return nil, errors.New("invalid token claims")
+	}
+
+	// A revoked or expired session ends its access tokens immediately. An
+	// impersonation token lives on the session of the admin who requested it.
+	sessionOwnerID, _ := (*claims)["user_id"].(string)
+	impersonatorID, _ := (*claims)["imp"].(string)
+	if impersonatorID != "" {
+		sessionOwnerID = impersonatorID
+	}
+	active, err := s.sessionRepo.IsActive(sessionID, sessionOwnerID)
+	if err != nil {
+		return nil, errors.New("failed to check session")
+	}
+	if !active {
+		return nil, errors.New("session has been revoked")
+	}
+
+	// The impersonating admin must still be an active admin
+	if impersonatorID != "" {
+		if err := s.checkImpersonator(impersonatorID); err != nil {
+			return nil, err
+		}
+	}
+
+	return claims, nil
+}
+
+// checkImpersonator tells whether a user may (still) impersonate others
+func (s *AuthService) checkImpersonator(userID string) error {
+	user, err := s.GetActiveUser(userID)
+	if err != nil {
+		return errors.New("impersonator is no longer active")
+	}
+	roles, err := s.ResolveUserRoles(user)
+	if err != nil || !roles.HasRole(policy.RoleAdmin) {
+		return errors.New("impersonator is no longer an admin")
+	}
+	return nil
+}
+
+// APIKeyPrincipal is the service account behind a valid API key and what the
+// key may do: the key's scopes that the account's role still holds
+type APIKeyPrincipal struct {
+	Key    *model.APIKey
+	User   *model.User
+	Role   *ResolvedRole
+	Scopes []string
+}
+
+// AuthenticateAPIKey validates an X-API-Key value and records its use
+func (s *AuthService) AuthenticateAPIKey(plainKey, ip string) (*APIKeyPrincipal, error) {
+	key, err := s.serviceAccountRepo.GetActiveAPIKeyByHash(hashToken(plainKey))
+	if err != nil {
+		return nil, errors.New("invalid api key")
+	}
+
+	user, err := s.GetActiveUser(key.UserID)
+	if err != nil {
+		return nil, errors.New("invalid api key")
+	}
+
+	resolved, err := s.ResolveRole(user.RoleID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the primary role counts for keys. Permissions removed from it since
+	// the key was created no longer apply.
+	var scopes []string
+	for _, scope := range key.Scopes {
+		if resolved.Has(scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	if err := s.serviceAccountRepo.TouchAPIKey(key.ID, ip); err != nil {
+		log.Printf("Failed to record use of API key %s: %v", key.ID, err)
+	}
+
+	return &APIKeyPrincipal{Key: key, User: user, Role: resolved, Scopes: scopes}, nil
+}
+
+// ResolveRole returns the role and its current permissions (cached, see PermissionResolver)
+func (s *AuthService) ResolveRole(roleID string) (*ResolvedRole, error) {
+	return s.permissions.Resolve(roleID)
+}
+
+// GetActiveUser loads the user behind a token; deactivated and unapproved users are rejected
+func (s *AuthService) GetActiveUser(userID string) (*model.User, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	if err := checkAccountStatus(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// checkAccountStatus tells whether an account may hold tokens: it must be active
+// and, when self-registered, approved by an admin
+func checkAccountStatus(user *model.User) error {
+	if !user.IsActive {
+		return errors.New("account is deactivated")
+	}
+
+	switch user.ApprovalStatus {
+	case model.ApprovalStatusPending:
+		return errors.New("account is pending approval")
+	case model.ApprovalStatusRejected:
+		return errors.New("registration was rejected")
+	}
+	return nil
+}
+
+// ValidateTwoFactorChallenge validates the token returned by the password step of a
+// login that still needs a TOTP or recovery code
+func (s *AuthService) ValidateTwoFactorChallenge(tokenString string) (*jwt.MapClaims, error) {
+	return s.validateTokenOfType(tokenString, tokenTypeTwoFactorChallenge)
+}
+
+// ValidateTwoFactorSetupToken validates the token given to users who must enrol TOTP
+// before they can log in
+func (s *AuthService) ValidateTwoFactorSetupToken(tokenString string) (*jwt.MapClaims, error) {
+	return s.validateTokenOfType(tokenString, tokenTypeTwoFactorSetup)
+}
+
+func (s *AuthService) validateTokenOfType(tokenString string, tokenType string) (*jwt.MapClaims, error) {
+	claims, err := s.keys.Parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if typ, _ := claims["typ"].(string); typ != tokenType {
+		return nil, errors.New("invalid token type")
+	}
+
+	// Tokens without a jti or expiry cannot be revoked, so they are not accepted
+	jti, _ := claims["jti"].(string)
+	userID, _ := claims["user_id"].(string)
+	issuedAt, iatErr := claims.GetIssuedAt()
+	expiresAt, expErr := claims.GetExpirationTime()
+	if jti == "" || userID == "" || iatErr != nil || issuedAt == nil || expErr != nil || expiresAt == nil {
+		return nil, errors.New("invalid token claims")
+	}
+
+	revoked, err := s.tokenRepo.IsAccessTokenRevoked(jti, userID, issuedAt.Time)
+	if err != nil {
+		return nil, errors.New("failed to check token revocation")
+	}
+	if revoked {
+		return nil, errors.New("token has been revoked")
+	}
+
+	return &claims, nil
+}
+
+// RevokeAllUserTokens logs a user out everywhere: every access token issued so far
+// stops being accepted and every refresh token is revoked.
+func (s *AuthService) RevokeAllUserTokens(userID string) error {
+	if err := s.tokenRepo.RevokeAllUserAccessTokens(userID); err != nil {
+		return err
+	}
+	if err := s.sessionRepo.RevokeAllForUser(userID); err != nil {
+		return err
+	}
+	return s.tokenRepo.RevokeAllUserRefreshTokens(userID)
+}
+
+// RevokeSession ends one session: its access tokens stop being accepted and its
+// refresh token family is revoked. It returns false when the session was not active.
+func (s *AuthService) RevokeSession(sessionID string) (bool, error) {
+	revoked, err := s.sessionRepo.Revoke(sessionID)
+	if err != nil {
+		return false, err
+	}
+	if err := s.tokenRepo.RevokeRefreshTokenFamily(sessionID); err != nil {
+		return false, err
+	}
+	return revoked, nil
+}
+
+// Logout ends the current session (its access and refresh tokens) and, when
+// given, the session of the refresh token. allDevices ends every session instead.
+func (s *AuthService) Logout(userID, sessionID, jti string, expiresAt time.Time, refreshToken string, allDevices bool) error {
+	if allDevices {
+		return s.RevokeAllUserTokens(userID)
+	}
+
+	if err := s.tokenRepo.RevokeAccessToken(jti, userID, expiresAt); err != nil {
+		return err
+	}
+
+	if _, err := s.RevokeSession(sessionID); err != nil {
+		return err
+	}
+
+	if refreshToken != "" {
+		record, err := s.tokenRepo.GetRefreshTokenByHash(hashToken(refreshToken))
+		if err == nil && record.UserID == userID && record.FamilyID != sessionID {
+			if _, err := s.RevokeSession(record.FamilyID); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Housekeeping: entries for expired tokens are no longer needed
+	s.tokenRepo.DeleteExpiredRevokedTokens()
+
+	return nil
+}
+
+// ForgotPassword emails a single-use reset link to the owner of the address.
+// Unknown or deactivated accounts are silently ignored so the caller cannot
+// learn which addresses are registered.
+func (s *AuthService) ForgotPassword(email, requestIP string) error {
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil || !user.IsActive {
+		return nil
+	}
+
+	plain, err := generateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	record := &model.PasswordResetToken{
+		UserID:      user.ID,
+		TokenHash:   hashToken(plain),
+		ExpiresAt:   time.Now().Add(s.passwordResetTTL),
+		RequestedIP: requestIP,
+	}
+	if err := s.tokenRepo.CreatePasswordResetToken(record); err != nil {
+		return err
+	}
+
+	link := s.passwordResetURL + "?token=" + url.QueryEscape(plain)
+	s.sendMail(&mail.Message{
+		To:      []string{user.Email},
+		Subject: "Reset your password",
+		Body: "Hello " + user.FullName + ",\n\n" +
+			"We received a request to reset the password of your account (" + user.Username + ").\n" +
+			"Open the link below to choose a new password:\n\n" + link + "\n\n" +
+			"The link can be used once and expires in " + s.passwordResetTTL.String() + ".\n" +
+			"If you did not request this, you can ignore this email.\n",
+	})
+
+	// Housekeeping: drop reset tokens that expired long ago
+	s.tokenRepo.DeleteExpiredPasswordResetTokens()
+
+	return nil
+}
+
+// emailResendCooldown is the minimum time between two verification emails to the same user
+const emailResendCooldown = time.Minute
+
+// SendEmailVerification emails a single-use link that confirms the user's current
+// address. Earlier links of the user stop working.
+func (s *AuthService) SendEmailVerification(user *model.User) error {
+	plain, err := generateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	record := &model.EmailVerificationToken{
+		UserID:    user.ID,
+		Email:     user.Email,
+		TokenHash: hashToken(plain),
+		ExpiresAt: time.Now().Add(s.emailVerificationTTL),
+	}
+	if err := s.tokenRepo.CreateEmailVerificationToken(record); err != nil {
+		return err
+	}
+
+	link := s.emailVerificationURL + "?token=" + url.QueryEscape(plain)
+	body := "Hello " + user.FullName + ",\n\n" +
+		"Please confirm that " + user.Email + " is your email address by opening the link below:\n\n" + link + "\n\n" +
+		"The link can be used once and expires in " + s.emailVerificationTTL.String() + ".\n"
+	if user.ApprovalStatus == model.ApprovalStatusPending {
+		body += "\nYour registration (" + user.Username + ") is also waiting for administrator approval; " +
+			"you will get another email once you can log in.\n"
+	}
+
+	s.sendMail(&mail.Message{
+		To:      []string{user.Email},
+		Subject: "Verify your email address",
+		Body:    body,
+	})
+
+	// Housekeeping: drop verification tokens that expired long ago
+	s.tokenRepo.DeleteExpiredEmailVerificationTokens()
+
+	return nil
+}
+
+// ResendEmailVerification sends a new verification link to an unverified account.
+// Like ForgotPassword it never reveals whether the address is registered.
+func (s *AuthService) ResendEmailVerification(email string) error {
+	user, err := s.userRepo.GetByEmail(strings.TrimSpace(email))
+	if err != nil || !user.IsActive || user.EmailVerifiedAt != nil || user.ApprovalStatus == model.ApprovalStatusRejected {
+		return nil
+	}
+
+	recent, err := s.tokenRepo.HasRecentEmailVerificationToken(user.ID, emailResendCooldown)
+	if err != nil {
+		return err
+	}
+	if recent {
+		return nil
+	}
+
+	return s.SendEmailVerification(user)
+}
+
+// VerifyEmail consumes a token from SendEmailVerification and marks the address
+// as verified. A link sent to a previous address is rejected.
+func (s *AuthService) VerifyEmail(token string) (*model.User, error) {
+	userID, email, err := s.tokenRepo.ConsumeEmailVerificationToken(hashToken(token))
+	if err != nil {
+		return nil, errors.New("invalid or expired verification token")
+	}
+
+	if err := s.userRepo.MarkEmailVerified(userID, email); err != nil {
+		return nil, errors.New("invalid or expired verification token")
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Password = ""
+	return user, nil
+}
+
+// RequireVerifiedEmailForSubmission tells whether submitting an achievement for
+// verification needs a verified email (REQUIRE_VERIFIED_EMAIL_FOR_SUBMISSION)
+func (s *AuthService) RequireVerifiedEmailForSubmission() bool {
+	return s.requireVerifiedEmail
+}
+
+// ResetPassword sets a new password using a token from ForgotPassword. The token
+// is consumed even if it is presented again later, and every session of the user
+// is revoked so a stolen session does not survive the reset.
+func (s *AuthService) ResetPassword(token, newPassword string) error {
+	userID, err := s.tokenRepo.ConsumePasswordResetToken(hashToken(token))
+	if err != nil {
+		return errors.New("invalid or expired reset token")
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil || !user.IsActive {
+		return errors.New("invalid or expired reset token")
+	}
+
+	if err := s.SetPassword(user, newPassword); err != nil {
+		return err
+	}
+
+	if err := s.RevokeAllUserTokens(user.ID); err != nil {
+		return err
+	}
+
+	s.sendMail(&mail.Message{
+		To:      []string{user.Email},
+		Subject: "Your password was changed",
+		Body: "Hello " + user.FullName + ",\n\n" +
+			"The password of your account (" + user.Username + ") was just reset and all sessions were signed out.\n" +
+			"If this was not you, contact the administrator immediately.\n",
+	})
+
+	return nil
+}
+
+// HashNewPassword checks a password for a new account against the policy and
+// hashes it. personalInfo (username, email) must not appear in the password.
+func (s *AuthService) HashNewPassword(password string, personalInfo ...string) (string, error) {
+	if err := s.passwordPolicy.Validate(password, personalInfo...); err != nil {
+		return "", err
+	}
+	return s.passwordHasher.Hash(password)
+}
+
+// SetPassword gives an existing user a new password. It must meet the policy
+// and differ from the current and the recent passwords; the replaced hash goes
+// into the password history. user.Password is updated to the new hash.
+func (s *AuthService) SetPassword(user *model.User, password string) error {
+	hashedPassword, err := s.HashNewPassword(password, user.Username, user.Email)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkPasswordReuse(user, password); err != nil {
+		return err
+	}
+
+	keep := s.passwordHistorySize - 1 // the current password is the newest of the N
+	if keep < 0 {
+		keep = 0
+	}
+	if err := s.userRepo.ReplacePassword(user.ID, user.Password, hashedPassword, keep); err != nil {
+		return err
+	}
+
+	user.Password = hashedPassword
+	return nil
+}
+
+// checkPasswordReuse refuses the current password and the ones before it, up to
+// PASSWORD_HISTORY_SIZE passwords in total
+func (s *AuthService) checkPasswordReuse(user *model.User, password string) error {
+	if s.passwordHistorySize <= 0 {
+		return nil
 	}
 
-	err = s.userRepo.Create(user)
+	history, err := s.userRepo.GetPasswordHistory(user.ID, s.passwordHistorySize-1)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	// Create additional profile based on role
-	switch req.Role {
-	case "student":
-		student := &model.Student{
-			UserID:       user.ID,
-			StudentID:    req.StudentID,
-			ProgramStudy: req.ProgramStudy,
-			AcademicYear: req.AcademicYear,
-		}
-		err = s.studentRepo.Create(student)
-		if err != nil {
-			// Rollback user creation if student creation fails
-			s.userRepo.Delete(user.ID)
-			return nil, err
-		}
-
-	case "lecturer":
-		lecturer := &model.Lecturer{
-			UserID:     user.ID,
-			LecturerID: req.LecturerID,
-			Department: req.Department,
-		}
-		err = s.lecturerRepo.Create(lecturer)
-		if err != nil {
-			// Rollback user creation if lecturer creation fails
-			s.userRepo.Delete(user.ID)
-			return nil, err
+	for _, previous := range append([]string{user.Password}, history...) {
+		if match, _ := s.passwordHasher.Verify(previous, password); match {
+			return errors.New("password was used recently")
 		}
 	}
-
-	// Remove password from response
-	user.Password = ""
-	return user, nil
+	return nil
 }
 
-func (s *AuthService) generateToken(userID string, username string) (string, time.Time, error) {
-	expiresAt := time.Now().Add(24 * time.Hour)
-
-	claims := jwt.MapClaims{
-		"user_id":  userID,
-		"username": username,
-		"exp":      expiresAt.Unix(),
-		"iat":      time.Now().Unix(),
+// verifyPassword checks a user's password, upgrading an outdated hash on success
+func (s *AuthService) verifyPassword(user *model.User, password string) bool {
+	match, needsRehash := s.passwordHasher.Verify(user.Password, password)
+	if match && needsRehash {
+		s.rehashPassword(user, password)
 	}
+	return match
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(s.jwtSecret))
+// rehashPassword stores the password again with the current algorithm and cost.
+// The password itself is unchanged, so the history is not touched.
+func (s *AuthService) rehashPassword(user *model.User, password string) {
+	hashedPassword, err := s.passwordHasher.Hash(password)
 	if err != nil {
-		return "", time.Time{}, err
+		log.Printf("Failed to rehash password of user %s: %v", user.ID, err)
+		return
 	}
+	if err := s.userRepo.UpdatePassword(user.ID, hashedPassword); err != nil {
+		log.Printf("Failed to store rehashed password of user %s: %v", user.ID, err)
+		return
+	}
+	user.Password = hashedPassword
+}
 
-	return tokenString, expiresAt, nil
+// RevokeOtherSessions logs a user out everywhere except the given session. Access
+// tokens of the other sessions stop working because their session is no longer active.
+func (s *AuthService) RevokeOtherSessions(userID, keepSessionID string) error {
+	if keepSessionID == "" {
+		return s.RevokeAllUserTokens(userID)
+	}
+	if err := s.sessionRepo.RevokeOthersForUser(userID, keepSessionID); err != nil {
+		return err
+	}
+	return s.tokenRepo.RevokeOtherUserRefreshTokens(userID, keepSessionID)
 }
 
-func (s *AuthService) generateTokenWithRoleAndPermissions(userID string, username string, role *model.Role, permissions []model.Permission) (string, time.Time, error) {
-	expiresAt := time.Now().Add(24 * time.Hour)
+// ChangePassword sets a new password for a logged-in user who knows the current one.
+// Wrong current passwords count towards the login throttle, so a stolen session
+// cannot be used to guess the password. Every other session is signed out.
+func (s *AuthService) ChangePassword(userID, sessionID, currentPassword, newPassword string, client ClientInfo) error {
+	user, err := s.GetActiveUser(userID)
+	if err != nil {
+		return err
+	}
 
-	// Create permission strings for JWT
-	var permissionStrings []string
-	for _, perm := range permissions {
-		permissionStrings = append(permissionStrings, perm.Resource+":"+perm.Action)
+	if err := s.checkLoginThrottle(user.ID, client.IP); err != nil {
+		return err
 	}
 
-	claims := jwt.MapClaims{
-		"user_id":     userID,
-		"username":    username,
-		"role":        role.Name,
-		"role_id":     role.ID,
-		"permissions": permissionStrings,
-		"exp":         expiresAt.Unix(),
-		"iat":         time.Now().Unix(),
+	if match, _ := s.passwordHasher.Verify(user.Password, currentPassword); !match {
+		s.recordLoginFailure(user.ID, client.IP)
+		return errors.New("current password is incorrect")
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(s.jwtSecret))
-	if err != nil {
-		return "", time.Time{}, err
+	if err := s.SetPassword(user, newPassword); err != nil {
+		return err
 	}
 
-	return tokenString, expiresAt, nil
-}
+	if err := s.RevokeOtherSessions(user.ID, sessionID); err != nil {
+		return err
+	}
 
-func (s *AuthService) ValidateToken(tokenString string) (*jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("invalid signing method")
-		}
-		return []byte(s.jwtSecret), nil
+	s.sendMail(&mail.Message{
+		To:      []string{user.Email},
+		Subject: "Your password was changed",
+		Body: "Hello " + user.FullName + ",\n\n" +
+			"The password of your account (" + user.Username + ") was just changed and your other sessions were signed out.\n" +
+			"If this was not you, reset your password and contact the administrator immediately.\n",
 	})
 
-	if err != nil {
-		return nil, err
+	return nil
+}
+
+// sendMail delivers in the background so slow SMTP servers do not delay the
+// response, and so response times do not reveal whether an account exists.
+func (s *AuthService) sendMail(msg *mail.Message) {
+	if s.mailer == nil {
+		return
 	}
+	go func() {
+		if err := s.mailer.Send(msg); err != nil {
+			log.Printf("Failed to send email %q: %v", msg.Subject, err)
+		}
+	}()
+}
+
+// isPasswordRejection tells whether err means the new password itself was refused
+func isPasswordRejection(err error) bool {
+	var policyErr *PasswordPolicyError
+	return errors.As(err, &policyErr) || (err != nil && err.Error() == "password was used recently")
+}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		return &claims, nil
+// passwordRejectedResponse explains why a new password was refused
+func passwordRejectedResponse(c *fiber.Ctx, err error) error {
+	var policyErr *PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error": err.Error(),
+			"message": "The password does not meet the password policy",
+			"code": "WEAK_PASSWORD",
+			"violations": policyErr.Violations,
+		})
 	}
 
-	return nil, errors.New("invalid token")
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"success": false,
+		"error": err.Error(),
+		"message": "This password was used recently. Please choose a different one",
+		"code": "PASSWORD_REUSED",
+	})
+}
+
+// PasswordPolicyRequest describes the password rules
+// @Summary Password Policy
+// @Description The rules a new password must meet, for showing next to password fields
+// @Tags Authentication
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Password rules"
+// @Router /auth/password-policy [get]
+func (s *AuthService) PasswordPolicyRequest(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"min_length": s.passwordPolicy.MinLength,
+			"max_length": s.passwordPolicy.MaxLength,
+			"require_uppercase": s.passwordPolicy.RequireUpper,
+			"require_lowercase": s.passwordPolicy.RequireLower,
+			"require_digit": s.passwordPolicy.RequireDigit,
+			"require_symbol": s.passwordPolicy.RequireSymbol,
+			"history_size": s.passwordHistorySize,
+			"rules": s.passwordPolicy.Rules(),
+		},
+	})
+}
+
+// JWKSRequest publishes the public verification keys
+// @Summary JSON Web Key Set
+// @Description Public keys (RS256/EdDSA) that verify tokens issued by this API. Tokens name their key in the kid header
+// @Tags Authentication
+// @Produce json
+// @Success 200 {object} JWKS "Key set"
+// @Router /.well-known/jwks.json [get]
+func (s *AuthService) JWKSRequest(c *fiber.Ctx) error {
+	// Short cache so consumers pick up a newly added key soon after rotation
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(s.keys.JWKS())
+}
+
+// InvalidatePermissionCacheRequest drops all cached role permissions
+// @Summary Invalidate Permission Cache
+// @Description Force permissions to be reloaded from the database, e.g. after editing roles or permissions directly in SQL (admin only)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Cache invalidated"
+// @Router /admin/permission-cache/invalidate [post]
+func (s *AuthService) InvalidatePermissionCacheRequest(c *fiber.Ctx) error {
+	s.permissions.InvalidateAll()
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Permission cache invalidated",
+		"code": "PERMISSION_CACHE_INVALIDATED",
+	})
 }
 
 // LoginRequest handles user login
@@ -331,8 +1467,24 @@ func (s *AuthService) LoginRequest(c *fiber.Ctx) error {
 	}
 
 	// Process login
-	response, err := s.Login(&req)
+	response, err := s.Login(&req, ClientInfo{IP: c.IP(), UserAgent: c.Get("User-Agent")})
 	if err != nil {
+		// Too many failed attempts: same answer whether or not the account exists
+		var throttled *LoginThrottledError
+		if errors.As(err, &throttled) {
+			retryAfter := int(throttled.RetryAfter.Seconds() + 0.999)
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"success": false,
+				"error": err.Error(),
+				"message": "Too many failed login attempts. Please wait before trying again",
+				"code": "TOO_MANY_LOGIN_ATTEMPTS",
+				"retry_after_seconds": retryAfter,
+				"locked": throttled.Locked,
+				"timestamp": time.Now(),
+			})
+		}
+
 		// Enhanced error responses
 		var errorCode string
 		var message string
@@ -344,6 +1496,12 @@ func (s *AuthService) LoginRequest(c *fiber.Ctx) error {
 		case "account is deactivated":
 			errorCode = "ACCOUNT_DEACTIVATED"
 			message = "Your account has been deactivated. Please contact administrator"
+		case "account is pending approval":
+			errorCode = "ACCOUNT_PENDING_APPROVAL"
+			message = "Your registration is waiting for administrator approval"
+		case "registration was rejected":
+			errorCode = "REGISTRATION_REJECTED"
+			message = "Your registration was rejected. Please contact administrator"
 		default:
 			errorCode = "LOGIN_FAILED"
 			message = "Login failed. Please try again"
@@ -358,6 +1516,46 @@ func (s *AuthService) LoginRequest(c *fiber.Ctx) error {
 		})
 	}
 
+	return s.loginSuccessResponse(c, response)
+}
+
+// loginSuccessResponse renders a successful first login step: tokens, or what the
+// user still has to do for the second factor. Shared by password and SSO login.
+func (s *AuthService) loginSuccessResponse(c *fiber.Ctx, response *LoginResponse) error {
+	// First factor accepted, but a second factor is still needed
+	if response.TwoFactorRequired {
+		return c.JSON(fiber.Map{
+			"success": true,
+			"message": "Two-factor authentication required",
+			"code": "TWO_FACTOR_REQUIRED",
+			"data": fiber.Map{
+				"challenge_token": response.ChallengeToken,
+				"expires_at": response.ChallengeExpiresAt,
+			},
+			"next_steps": []string{
+				"Send the challenge token with the code from your authenticator app (or a recovery code) to POST /api/auth/2fa/verify",
+				"The challenge token expires at: " + response.ChallengeExpiresAt.Format("2006-01-02 15:04:05"),
+			},
+		})
+	}
+
+	if response.TwoFactorSetupRequired {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"success": false,
+			"error": "two-factor setup required",
+			"message": "Your role requires two-factor authentication. Set it up to continue",
+			"code": "TWO_FACTOR_SETUP_REQUIRED",
+			"data": fiber.Map{
+				"setup_token": response.ChallengeToken,
+				"expires_at": response.ChallengeExpiresAt,
+			},
+			"next_steps": []string{
+				"Call POST /api/auth/2fa/setup with Authorization: Bearer <setup_token> and scan the returned QR/otpauth URI",
+				"Confirm with POST /api/auth/2fa/enable using the same token and a code from your authenticator app; the response contains your login tokens",
+			},
+		})
+	}
+
 	// Get additional user profile info
 	var profileInfo fiber.Map
 	if response.Role.Name == "student" {
@@ -395,12 +1593,16 @@ func (s *AuthService) LoginRequest(c *fiber.Ctx) error {
 			"token": response.Token,
 			"expires_at": response.ExpiresAt,
 			"expires_in_seconds": int(time.Until(response.ExpiresAt).Seconds()),
+			"refresh_token": response.RefreshToken,
+			"refresh_expires_at": response.RefreshExpiresAt,
+			"session_id": response.SessionID,
 			"user": fiber.Map{
 				"id": response.User.ID,
 				"username": response.User.Username,
 				"email": response.User.Email,
 				"full_name": response.User.FullName,
 				"is_active": response.User.IsActive,
+				"email_verified": response.User.EmailVerifiedAt != nil,
 				"created_at": response.User.CreatedAt,
 			},
 			"role": fiber.Map{
@@ -420,48 +1622,391 @@ func (s *AuthService) LoginRequest(c *fiber.Ctx) error {
 			"Use the token in Authorization header for subsequent requests",
 			"Token format: Bearer <token>",
 			"Token expires at: " + response.ExpiresAt.Format("2006-01-02 15:04:05"),
+			"Exchange the refresh token at POST /api/auth/refresh before it expires; each refresh token can be used only once",
 		},
 	})
 }
 
-func (s *AuthService) RegisterRequest(c *fiber.Ctx) error {
-	var req RegisterRequest
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token,omitempty"`
+	AllDevices   bool   `json:"all_devices,omitempty"`
+}
+
+// LogoutRequest handles user logout
+// @Summary Logout
+// @Description Revoke the current access token, optionally its refresh token, or every token of the user
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body LogoutRequest false "Refresh token to revoke / logout from all devices"
+// @Success 200 {object} map[string]interface{} "Logged out"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /auth/logout [post]
+func (s *AuthService) LogoutRequest(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	sessionID, _ := c.Locals("session_id").(string)
+	jti, _ := c.Locals("jti").(string)
+	expiresAt, _ := c.Locals("token_expires_at").(time.Time)
+
+	// Body is optional
+	var req LogoutRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error": "Invalid request body",
+				"message": "Please provide valid JSON data",
+				"code": "INVALID_REQUEST_BODY",
+			})
+		}
+	}
+
+	if err := s.Logout(userID, sessionID, jti, expiresAt, req.RefreshToken, req.AllDevices); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error": "Failed to logout",
+			"message": err.Error(),
+			"code": "LOGOUT_FAILED",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Logged out successfully",
+		"code": "LOGOUT_SUCCESS",
+		"data": fiber.Map{
+			"all_devices": req.AllDevices,
+			"refresh_token_revoked": true,
+		},
+	})
+}
+
+// RefreshTokenRequest handles access token renewal
+// @Summary Refresh Access Token
+// @Description Exchange a refresh token for a new access token and a rotated refresh token
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param body body RefreshTokenRequest true "Refresh token"
+// @Success 200 {object} map[string]interface{} "Token refreshed"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Invalid, expired or reused refresh token"
+// @Router /auth/refresh [post]
+func (s *AuthService) RefreshTokenRequest(c *fiber.Ctx) error {
+	var req RefreshTokenRequest
+	if err := c.BodyParser(&req); err != nil || req.RefreshToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error": "Invalid request body",
+			"message": "refresh_token is required",
+			"code": "MISSING_REFRESH_TOKEN",
+		})
+	}
+
+	response, err := s.Refresh(req.RefreshToken, ClientInfo{IP: c.IP(), UserAgent: c.Get("User-Agent")})
+	if err != nil {
+		var errorCode string
+		var message string
+
+		switch err.Error() {
+		case "refresh token reuse detected":
+			errorCode = "REFRESH_TOKEN_REUSED"
+			message = "This refresh token was already used. All sessions from this login have been revoked, please log in again"
+		case "refresh token expired":
+			errorCode = "REFRESH_TOKEN_EXPIRED"
+			message = "Refresh token has expired, please log in again"
+		case "account is deactivated":
+			errorCode = "ACCOUNT_DEACTIVATED"
+			message = "Your account has been deactivated. Please contact administrator"
+		default:
+			errorCode = "INVALID_REFRESH_TOKEN"
+			message = "Refresh token is invalid"
+		}
+
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"success": false,
+			"error": err.Error(),
+			"message": message,
+			"code": errorCode,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Token refreshed",
+		"code": "TOKEN_REFRESHED",
+		"data": fiber.Map{
+			"token": response.Token,
+			"expires_at": response.ExpiresAt,
+			"expires_in_seconds": int(time.Until(response.ExpiresAt).Seconds()),
+			"refresh_token": response.RefreshToken,
+			"refresh_expires_at": response.RefreshExpiresAt,
+			"session_id": response.SessionID,
+		},
+	})
+}
+
+// ForgotPasswordRequest starts the password reset flow
+// @Summary Forgot Password
+// @Description Email a single-use password reset link. The response is the same whether or not the address is registered
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param body body ForgotPasswordRequest true "Account email"
+// @Success 200 {object} map[string]interface{} "Reset link sent if the account exists"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Router /auth/forgot-password [post]
+func (s *AuthService) ForgotPasswordRequest(c *fiber.Ctx) error {
+	var req ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil || req.Email == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error": "Invalid request body",
+			"message": "email is required",
+			"code": "MISSING_EMAIL",
+		})
+	}
+
+	if err := s.ForgotPassword(req.Email, c.IP()); err != nil {
+		log.Printf("Forgot password failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error": "Failed to process request",
+			"message": "Please try again later",
+			"code": "FORGOT_PASSWORD_FAILED",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "If an account with that email exists, a password reset link has been sent",
+		"code": "RESET_LINK_SENT",
+	})
+}
+
+// ResetPasswordRequest completes the password reset flow
+// @Summary Reset Password
+// @Description Set a new password using the token from the reset email. Signs the user out everywhere
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param body body ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} map[string]interface{} "Password reset"
+// @Failure 400 {object} map[string]interface{} "Invalid token or password"
+// @Router /auth/reset-password [post]
+func (s *AuthService) ResetPasswordRequest(c *fiber.Ctx) error {
+	var req ResetPasswordRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
 			"error": "Invalid request body",
+			"message": "Please provide valid JSON data",
+			"code": "INVALID_REQUEST_BODY",
 		})
 	}
 
-	// Validate request
-	if req.Username == "" || req.Email == "" || req.Password == "" || req.FullName == "" || req.Role == "" {
+	if req.Token == "" || req.NewPassword == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error": "Validation failed",
+			"message": "token and new_password are required",
+			"code": "MISSING_FIELDS",
+		})
+	}
+
+	if err := s.ResetPassword(req.Token, req.NewPassword); err != nil {
+		if isPasswordRejection(err) {
+			return passwordRejectedResponse(c, err)
+		}
+
+		switch err.Error() {
+		case "invalid or expired reset token":
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error": err.Error(),
+				"message": "The reset link is invalid, expired or was already used. Please request a new one",
+				"code": "INVALID_RESET_TOKEN",
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"success": false,
+				"error": "Failed to reset password",
+				"message": err.Error(),
+				"code": "RESET_PASSWORD_FAILED",
+			})
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Password has been reset. Please log in with your new password",
+		"code": "PASSWORD_RESET",
+	})
+}
+
+// VerifyEmailRequest confirms an email address
+// @Summary Verify Email
+// @Description Confirm an email address with the token from the verification email
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param body body VerifyEmailRequest true "Verification token"
+// @Success 200 {object} map[string]interface{} "Email verified"
+// @Failure 400 {object} map[string]interface{} "Invalid or expired token"
+// @Router /auth/verify-email [post]
+func (s *AuthService) VerifyEmailRequest(c *fiber.Ctx) error {
+	var req VerifyEmailRequest
+	if err := c.BodyParser(&req); err != nil || req.Token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error": "Invalid request body",
+			"message": "token is required",
+			"code": "MISSING_TOKEN",
+		})
+	}
+
+	user, err := s.VerifyEmail(req.Token)
+	if err != nil {
+		if err.Error() == "invalid or expired verification token" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error": err.Error(),
+				"message": "The verification link is invalid, expired or was already used. Please request a new one",
+				"code": "INVALID_VERIFICATION_TOKEN",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error": "Failed to verify email",
+			"message": err.Error(),
+			"code": "VERIFY_EMAIL_FAILED",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Email address verified",
+		"code": "EMAIL_VERIFIED",
+		"data": fiber.Map{
+			"email": user.Email,
+			"email_verified_at": user.EmailVerifiedAt,
+		},
+	})
+}
+
+// ResendVerificationRequest sends a new verification email
+// @Summary Resend Verification Email
+// @Description Send a new verification link to an unverified account. The response is the same whether or not the address is registered
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param body body ForgotPasswordRequest true "Account email"
+// @Success 200 {object} map[string]interface{} "Verification email sent if the account needs one"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Router /auth/verify-email/resend [post]
+func (s *AuthService) ResendVerificationRequest(c *fiber.Ctx) error {
+	var req ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil || req.Email == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "All fields are required",
+			"success": false,
+			"error": "Invalid request body",
+			"message": "email is required",
+			"code": "MISSING_EMAIL",
+		})
+	}
+
+	if err := s.ResendEmailVerification(req.Email); err != nil {
+		log.Printf("Resend verification failed: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error": "Failed to process request",
+			"message": "Please try again later",
+			"code": "RESEND_VERIFICATION_FAILED",
 		})
 	}
 
-	// Validate role-specific fields
-	if req.Role == "student" && (req.StudentID == "" || req.ProgramStudy == "" || req.AcademicYear == "") {
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "If an unverified account with that email exists, a new verification link has been sent",
+		"code": "VERIFICATION_LINK_SENT",
+	})
+}
+
+// RegisterRequest godoc
+// @Summary Student Self-Registration
+// @Description Register a student account. Only students can register themselves, the email domain may be restricted (REGISTRATION_EMAIL_DOMAINS), and the account can log in only after an admin approves it.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param body body RegisterRequest true "Student registration"
+// @Success 201 {object} map[string]interface{} "Registration pending approval"
+// @Failure 400 {object} map[string]interface{} "Validation error"
+// @Failure 403 {object} map[string]interface{} "Role or email domain not allowed"
+// @Failure 409 {object} map[string]interface{} "Username, email or student ID already exists"
+// @Router /auth/register [post]
+func (s *AuthService) RegisterRequest(c *fiber.Ctx) error {
+	var req RegisterRequest
+	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Student ID, program study, and academic year are required for students",
+			"success": false,
+			"error": "Invalid request body",
+			"message": "Please provide valid JSON data",
+			"code": "INVALID_REQUEST_BODY",
 		})
 	}
 
-	if req.Role == "lecturer" && (req.LecturerID == "" || req.Department == "") {
+	// Validate request
+	if req.Username == "" || req.Email == "" || req.Password == "" || req.FullName == "" ||
+		req.StudentID == "" || req.ProgramStudy == "" || req.AcademicYear == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Lecturer ID and department are required for lecturers",
+			"success": false,
+			"error": "Validation failed",
+			"message": "Username, email, password, full name, student ID, program study and academic year are required",
+			"code": "MISSING_REQUIRED_FIELDS",
 		})
 	}
 
 	// Process registration
 	user, err := s.Register(&req)
+	if isPasswordRejection(err) {
+		return passwordRejectedResponse(c, err)
+	}
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		status := fiber.StatusBadRequest
+		var code, message string
+
+		switch err.Error() {
+		case "self-registration is only available for students":
+			status = fiber.StatusForbidden
+			code = "SELF_REGISTRATION_STUDENT_ONLY"
+			message = "Only students can register themselves. Lecturer and admin accounts are created by an administrator"
+		case "email domain is not allowed":
+			status = fiber.StatusForbidden
+			code = "EMAIL_DOMAIN_NOT_ALLOWED"
+			message = "Please register with your institutional email address"
+		case "username already exists", "email already exists", "student ID already exists":
+			status = fiber.StatusConflict
+			code = "ALREADY_REGISTERED"
+			message = "An account with this " + strings.TrimSuffix(err.Error(), " already exists") + " already exists"
+		default:
+			status = fiber.StatusInternalServerError
+			code = "REGISTRATION_FAILED"
+			message = "Registration failed. Please try again"
+		}
+
+		return c.Status(status).JSON(fiber.Map{
+			"success": false,
 			"error": err.Error(),
+			"message": message,
+			"code": code,
 		})
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"success": true,
+		"message": "Registration received. Please verify your email address; an administrator must approve your account before you can log in",
+		"code": "REGISTRATION_PENDING",
 		"data":    user,
 	})
 }
@@ -481,4 +2026,19 @@ func (s *AuthService) getRoleIDByName(roleName string) (string, error) {
 	}
 	
 	return roleID, nil
-}
\ No newline at end of file
+}
+
+// generateOpaqueToken returns a random URL-safe token for refresh/reset links
+func generateOpaqueToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashToken is how opaque tokens are stored, so a database leak does not leak usable tokens
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// getEnvDuration reads a Go duration (e.g. "15m", "168h") from the environment,
+// falling back to the default when the variable is unset or invalid.
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Printf("Warning: invalid %s=%q, using default %s", key, value, fallback)
+		return fallback
+	}
+
+	return duration
+}
+
+// getEnvList reads a comma separated list (e.g. "admin,lecturer"), trimming
+// blanks and dropping empty entries.
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		value = strings.TrimSpace(value)
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getEnvInt reads a non-negative integer from the environment, falling back to the
+// default when the variable is unset or invalid.
+func getEnvInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		log.Printf("Warning: invalid %s=%q, using default %d", key, value, fallback)
+		return fallback
+	}
+
+	return number
+}
+
+// getEnvBool reads a boolean ("true", "false", "1", "0", ...) from the environment,
+// falling back to the default when the variable is unset or invalid.
+func getEnvBool(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Warning: invalid %s=%q, using default %t", key, value, fallback)
+		return fallback
+	}
+
+	return enabled
+}
+
+// getEnvString reads a string from the environment, falling back to the default
+// when the variable is unset.
+func getEnvString(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,313 @@
+package service
+
+import (
+	"UASBE/app/model"
+	"UASBE/app/policy"
+	"UASBE/app/repository"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// DelegationService manages verification delegations: an advisor on leave lets
+// another lecturer verify the achievements of their advisees for a period.
+// Delegations end by themselves when the period is over.
+type DelegationService struct {
+	delegationRepo      *repository.VerificationDelegationRepository
+	lecturerRepo        *repository.LecturerRepository
+	notificationService *NotificationService
+}
+
+func NewDelegationService(delegationRepo *repository.VerificationDelegationRepository, lecturerRepo *repository.LecturerRepository, notificationService *NotificationService) *DelegationService {
+	return &DelegationService{
+		delegationRepo:      delegationRepo,
+		lecturerRepo:        lecturerRepo,
+		notificationService: notificationService,
+	}
+}
+
+type CreateDelegationRequest struct {
+	// DelegatorID is the lecturers.id of the advisor delegating; only admins
+	// set it, lecturers always delegate their own verification
+	DelegatorID string    `json:"delegator_id,omitempty"`
+	DelegateID  string    `json:"delegate_id"` // lecturers.id
+	StartsAt    time.Time `json:"starts_at"`   // defaults to now
+	EndsAt      time.Time `json:"ends_at"`
+	Reason      string    `json:"reason,omitempty"`
+}
+
+const maxDelegationPeriod = 365 * 24 * time.Hour
+
+// ValidateDelegationPeriod checks the period of a new delegation; a missing
+// start is now
+func ValidateDelegationPeriod(startsAt, endsAt, now time.Time) (time.Time, error) {
+	if startsAt.IsZero() {
+		startsAt = now
+	}
+
+	switch {
+	case endsAt.IsZero():
+		return startsAt, errors.New("ends_at is required")
+	case !endsAt.After(startsAt):
+		return startsAt, errors.New("ends_at must be after starts_at")
+	case !endsAt.After(now):
+		return startsAt, errors.New("ends_at must be in the future")
+	case endsAt.Sub(startsAt) > maxDelegationPeriod:
+		return startsAt, errors.New("a delegation can last at most 365 days")
+	}
+	return startsAt, nil
+}
+
+// ActiveDelegatorIDs returns the lecturers whose advisees the lecturer may
+// verify right now on their behalf
+func (s *DelegationService) ActiveDelegatorIDs(lecturerID string) []string {
+	delegatorIDs, err := s.delegationRepo.GetActiveDelegatorIDs(lecturerID)
+	if err != nil {
+		log.Printf("Failed to load delegations of lecturer %s: %v", lecturerID, err)
+		return nil
+	}
+	return delegatorIDs
+}
+
+// CreateDelegation lets the delegate verify for the delegator during the period
+func (s *DelegationService) CreateDelegation(req *CreateDelegationRequest, delegatorID, createdBy string) (*model.VerificationDelegation, error) {
+	startsAt, err := ValidateDelegationPeriod(req.StartsAt, req.EndsAt, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	delegator, err := s.lecturerRepo.GetByID(delegatorID)
+	if err != nil {
+		return nil, errors.New("delegator not found")
+	}
+	delegate, err := s.lecturerRepo.GetByID(req.DelegateID)
+	if err != nil {
+		return nil, errors.New("delegate not found")
+	}
+	if delegate.ID == delegator.ID {
+		return nil, errors.New("cannot delegate to yourself")
+	}
+
+	delegation := &model.VerificationDelegation{
+		DelegatorID: delegator.ID,
+		DelegateID:  delegate.ID,
+		StartsAt:    startsAt,
+		EndsAt:      req.EndsAt,
+		Reason:      strings.TrimSpace(req.Reason),
+		CreatedBy:   createdBy,
+	}
+	if err := s.delegationRepo.Create(delegation); err != nil {
+		return nil, err
+	}
+
+	created, err := s.delegationRepo.GetByID(delegation.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.notificationService != nil {
+		message := fmt.Sprintf("%s delegated the verification of their advisees' achievements to you from %s until %s.",
+			created.DelegatorName, created.StartsAt.Format("02 Jan 2006 15:04"), created.EndsAt.Format("02 Jan 2006 15:04"))
+		data := map[string]interface{}{
+			"delegation_id": created.ID,
+			"delegator_id":  created.DelegatorID,
+			"starts_at":     created.StartsAt,
+			"ends_at":       created.EndsAt,
+			"reason":        created.Reason,
+		}
+		if err := s.notificationService.CreateNotification(delegate.UserID, "verification_delegated", "Verification delegated to you", message, data); err != nil {
+			log.Printf("Failed to notify delegate %s: %v", delegate.ID, err)
+		}
+	}
+
+	return created, nil
+}
+
+// requestLecturerID returns the lecturers.id of the user making the request,
+// empty if they have no lecturer profile
+func (s *DelegationService) requestLecturerID(c *fiber.Ctx) string {
+	userID, _ := c.Locals("user_id").(string)
+	if lecturer, err := s.lecturerRepo.GetByUserID(userID); err == nil {
+		return lecturer.ID
+	}
+	return ""
+}
+
+// GetDelegationsRequest lists delegations
+// @Summary Get Verification Delegations
+// @Description Delegations the lecturer gave or received; admins see all of them, or those of one lecturer with lecturer_id
+// @Tags Delegations
+// @Produce json
+// @Security BearerAuth
+// @Param lecturer_id query string false "Lecturer ID (admin only)"
+// @Success 200 {object} map[string]interface{} "Delegations"
+// @Router /delegations [get]
+func (s *DelegationService) GetDelegationsRequest(c *fiber.Ctx) error {
+	var delegations []model.VerificationDelegation
+	var err error
+
+	switch {
+	case requestHasRole(c, policy.RoleAdmin) && c.Query("lecturer_id") != "":
+		delegations, err = s.delegationRepo.GetByLecturer(c.Query("lecturer_id"))
+	case requestHasRole(c, policy.RoleAdmin):
+		delegations, err = s.delegationRepo.GetAll()
+	default:
+		lecturerID := s.requestLecturerID(c)
+		if lecturerID == "" {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"success": false,
+				"error": "Lecturer profile not found",
+				"code": "LECTURER_NOT_FOUND",
+			})
+		}
+		delegations, err = s.delegationRepo.GetByLecturer(lecturerID)
+	}
+
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error": "Failed to get delegations",
+			"message": err.Error(),
+			"code": "FETCH_FAILED",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": delegations,
+	})
+}
+
+// CreateDelegationRequest delegates verification for a period
+// @Summary Create Verification Delegation
+// @Description Let another lecturer verify your advisees' achievements from starts_at until ends_at. Admins delegate on behalf of a lecturer with delegator_id
+// @Tags Delegations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body CreateDelegationRequest true "Delegation"
+// @Success 201 {object} map[string]interface{} "Delegation created"
+// @Failure 400 {object} map[string]interface{} "Validation error"
+// @Router /delegations [post]
+func (s *DelegationService) CreateDelegationRequest(c *fiber.Ctx) error {
+	var req CreateDelegationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error": "Invalid request body",
+			"message": "Please provide valid JSON data with RFC 3339 dates",
+			"code": "INVALID_REQUEST_BODY",
+		})
+	}
+
+	userID, _ := c.Locals("user_id").(string)
+
+	// Lecturers delegate their own verification; admins that of any lecturer
+	delegatorID := s.requestLecturerID(c)
+	if requestHasRole(c, policy.RoleAdmin) && req.DelegatorID != "" {
+		delegatorID = req.DelegatorID
+	}
+	if delegatorID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error": "Validation failed",
+			"message": "delegator_id is required",
+			"code": "VALIDATION_ERROR",
+		})
+	}
+
+	delegation, err := s.CreateDelegation(&req, delegatorID, userID)
+	if err != nil {
+		switch err.Error() {
+		case "delegator not found", "delegate not found":
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"success": false,
+				"error": "Lecturer not found",
+				"message": err.Error(),
+				"code": "LECTURER_NOT_FOUND",
+			})
+		case "ends_at is required", "ends_at must be after starts_at", "ends_at must be in the future",
+			"a delegation can last at most 365 days", "cannot delegate to yourself":
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error": "Validation failed",
+				"message": err.Error(),
+				"code": "VALIDATION_ERROR",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error": "Failed to create delegation",
+			"message": err.Error(),
+			"code": "CREATE_FAILED",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"message": "Verification delegated",
+		"code": "DELEGATION_CREATED",
+		"data": delegation,
+	})
+}
+
+// RevokeDelegationRequest ends a delegation before its period is over
+// @Summary Revoke Verification Delegation
+// @Description End a delegation early; the delegator, the delegate or an admin may revoke it
+// @Tags Delegations
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Delegation ID"
+// @Success 200 {object} map[string]interface{} "Delegation revoked"
+// @Failure 403 {object} map[string]interface{} "Not your delegation"
+// @Failure 404 {object} map[string]interface{} "Delegation not found"
+// @Router /delegations/{id} [delete]
+func (s *DelegationService) RevokeDelegationRequest(c *fiber.Ctx) error {
+	delegation, err := s.delegationRepo.GetByID(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error": "Delegation not found",
+			"code": "DELEGATION_NOT_FOUND",
+		})
+	}
+
+	if !requestHasRole(c, policy.RoleAdmin) {
+		lecturerID := s.requestLecturerID(c)
+		if lecturerID == "" || (lecturerID != delegation.DelegatorID && lecturerID != delegation.DelegateID) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"success": false,
+				"error": "Access denied",
+				"message": "You can only revoke delegations you gave or received",
+				"code": "FORBIDDEN",
+			})
+		}
+	}
+
+	if err := s.delegationRepo.Revoke(delegation.ID); err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"success": false,
+				"error": "Delegation already revoked",
+				"code": "ALREADY_REVOKED",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error": "Failed to revoke delegation",
+			"message": err.Error(),
+			"code": "REVOKE_FAILED",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Delegation revoked",
+		"code": "DELEGATION_REVOKED",
+	})
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"UASBE/app/model"
+	"UASBE/app/policy"
+	"UASBE/app/repository"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// ImpersonationService lets admins view the system as a given user ("view as
+// user") to investigate reports, and keeps the audit trail of doing so
+type ImpersonationService struct {
+	authService *AuthService
+	userRepo    *repository.UserRepository
+	auditRepo   *repository.AuditLogRepository
+}
+
+func NewImpersonationService(authService *AuthService, userRepo *repository.UserRepository, auditRepo *repository.AuditLogRepository) *ImpersonationService {
+	return &ImpersonationService{
+		authService: authService,
+		userRepo:    userRepo,
+		auditRepo:   auditRepo,
+	}
+}
+
+type ImpersonateRequest struct {
+	Reason string `json:"reason"`
+}
+
+type ImpersonationResponse struct {
+	Token          string      `json:"token"`
+	ExpiresAt      time.Time   `json:"expires_at"`
+	User           *model.User `json:"user"`
+	Role           string      `json:"role"`
+	ImpersonatorID string      `json:"impersonator_id"`
+}
+
+// maxImpersonationReasonLength bounds the reason stored in the audit log
+const maxImpersonationReasonLength = 500
+
+// Impersonate issues a read-only token for the target user. The start is written
+// to the audit log before the token is handed out; without a trail there is no token.
+func (s *ImpersonationService) Impersonate(adminID, adminSessionID, targetID, reason string, client ClientInfo) (*ImpersonationResponse, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, errors.New("reason is required")
+	}
+	if len(reason) > maxImpersonationReasonLength {
+		return nil, errors.New("reason is too long")
+	}
+
+	if targetID == adminID {
+		return nil, errors.New("cannot impersonate yourself")
+	}
+
+	if _, err := s.userRepo.GetByID(targetID); err != nil {
+		return nil, errors.New("user not found")
+	}
+	target, err := s.authService.GetActiveUser(targetID)
+	if err != nil {
+		return nil, errors.New("user is not active")
+	}
+
+	roles, err := s.authService.ResolveUserRoles(target)
+	if err != nil {
+		return nil, err
+	}
+	if roles.HasRole(policy.RoleAdmin) {
+		return nil, errors.New("administrators cannot be impersonated")
+	}
+
+	token, expiresAt, err := s.authService.IssueImpersonationToken(target, adminID, adminSessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &model.AuditLog{
+		ActorID:       adminID,
+		SubjectUserID: target.ID,
+		Action:        model.AuditActionImpersonationStart,
+		IPAddress:     client.IP,
+		UserAgent:     client.UserAgent,
+		Details:       reason,
+	}
+	if err := s.auditRepo.Create(entry); err != nil {
+		return nil, errors.New("failed to write audit log")
+	}
+
+	log.Printf("Admin %s started impersonating user %s: %s", adminID, target.ID, reason)
+
+	// Remove password from response
+	target.Password = ""
+	return &ImpersonationResponse{
+		Token:          token,
+		ExpiresAt:      expiresAt,
+		User:           target,
+		Role:           roles.Primary.Role.Name,
+		ImpersonatorID: adminID,
+	}, nil
+}
+
+// RecordRequest logs one request made with an impersonation token
+func (s *ImpersonationService) RecordRequest(impersonatorID, userID, method, path string, statusCode int, client ClientInfo) {
+	entry := &model.AuditLog{
+		ActorID:       impersonatorID,
+		SubjectUserID: userID,
+		Action:        model.AuditActionImpersonationRequest,
+		Method:        method,
+		Path:          path,
+		StatusCode:    statusCode,
+		IPAddress:     client.IP,
+		UserAgent:     client.UserAgent,
+	}
+	if err := s.auditRepo.Create(entry); err != nil {
+		log.Printf("Failed to record impersonated request %s %s by %s: %v", method, path, impersonatorID, err)
+	}
+}
+
+// GetAuditLogs lists audit log entries, newest first
+func (s *ImpersonationService) GetAuditLogs(filter model.AuditLogFilter) ([]model.AuditLog, int, error) {
+	for _, id := range []string{filter.ActorID, filter.SubjectUserID} {
+		if id == "" {
+			continue
+		}
+		if _, err := uuid.Parse(id); err != nil {
+			return nil, 0, errors.New("invalid user ID")
+		}
+	}
+
+	return s.auditRepo.List(filter)
+}
+
+// impersonationErrorResponse maps the errors of this service to HTTP responses
+func impersonationErrorResponse(c *fiber.Ctx, err error) error {
+	switch err.Error() {
+	case "user not found":
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error": err.Error(),
+			"message": "User not found",
+			"code": "USER_NOT_FOUND",
+		})
+	case "reason is required", "reason is too long", "invalid user ID":
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error": "Validation failed",
+			"message": err.Error(),
+			"code": "VALIDATION_ERROR",
+		})
+	case "cannot impersonate yourself", "administrators cannot be impersonated", "user is not active":
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"success": false,
+			"error": err.Error(),
+			"message": "This user cannot be impersonated",
+			"code": "IMPERSONATION_NOT_ALLOWED",
+		})
+	}
+
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"success": false,
+		"error": "Impersonation failed",
+		"message": err.Error(),
+		"code": "IMPERSONATION_FAILED",
+	})
+}
+
+// ImpersonateUserRequest issues a "view as user" token
+// @Summary Impersonate User
+// @Description Issue a short-lived, read-only access token for a user so an admin can see exactly what they see. The reason is stored in the audit log and every request made with the token is logged. The token ends early when the admin logs out. Admin accounts cannot be impersonated
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param body body ImpersonateRequest true "Why the account is being viewed"
+// @Success 200 {object} ImpersonationResponse "Impersonation token"
+// @Failure 403 {object} map[string]interface{} "User cannot be impersonated"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Router /admin/users/{id}/impersonate [post]
+func (s *ImpersonationService) ImpersonateUserRequest(c *fiber.Ctx) error {
+	var req ImpersonateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error": "Invalid request body",
+			"message": "Please provide valid JSON data",
+			"code": "INVALID_REQUEST_BODY",
+		})
+	}
+
+	adminID := c.Locals("user_id").(string)
+	adminSessionID, _ := c.Locals("session_id").(string)
+
+	response, err := s.Impersonate(adminID, adminSessionID, c.Params("id"), req.Reason, ClientInfo{IP: c.IP(), UserAgent: c.Get("User-Agent")})
+	if err != nil {
+		return impersonationErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Impersonation token issued",
+		"code": "IMPERSONATION_STARTED",
+		"data": response,
+		"next_steps": []string{
+			"Use the token in the Authorization header to view the system as " + response.User.Username,
+			"The token is read-only: POST, PUT, PATCH and DELETE requests are refused",
+			"Every request made with it is recorded in the audit log",
+			"The token expires at: " + response.ExpiresAt.Format("2006-01-02 15:04:05"),
+		},
+	})
+}
+
+// GetAuditLogsRequest lists the audit trail
+// @Summary List Audit Logs
+// @Description Audit log entries, newest first, e.g. impersonation starts and every impersonated request
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param actor_id query string false "Admin who acted"
+// @Param subject_user_id query string false "User acted upon"
+// @Param action query string false "Action, e.g. impersonation.start or impersonation.request"
+// @Param page query int false "Page" default(1)
+// @Param limit query int false "Entries per page" default(50)
+// @Success 200 {object} map[string]interface{} "Audit log entries"
+// @Router /admin/audit-logs [get]
+func (s *ImpersonationService) GetAuditLogsRequest(c *fiber.Ctx) error {
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 50)
+
+	// Validate pagination
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	entries, total, err := s.GetAuditLogs(model.AuditLogFilter{
+		ActorID:       c.Query("actor_id"),
+		SubjectUserID: c.Query("subject_user_id"),
+		Action:        c.Query("action"),
+		Limit:         limit,
+		Offset:        (page - 1) * limit,
+	})
+	if err != nil {
+		return impersonationErrorResponse(c, err)
+	}
+	if entries == nil {
+		entries = []model.AuditLog{}
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": entries,
+		"pagination": fiber.Map{
+			"page": page,
+			"limit": limit,
+			"total": total,
+			"total_pages": (total + limit - 1) / limit,
+		},
+	})
+}
@@ -0,0 +1,272 @@
+package service
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingKey is one key of the key set. Private is nil for retired keys that are
+// only kept so tokens they signed stay valid until they expire.
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// KeySet holds the asymmetric JWT keys: one active key that signs new tokens and
+// every key (active or retired) that is still accepted for verification. Tokens
+// carry the key ID in the "kid" header so keys can be rotated without logging
+// anyone out.
+type KeySet struct {
+	active *signingKey
+	keys   map[string]*signingKey
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517, RFC 8037 for Ed25519).
+// Y is only used by EC keys of external identity providers.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// LoadKeySetFromEnv loads the keys from JWT_KEYS_DIR, signing with JWT_ACTIVE_KID.
+// Without a key directory an ephemeral Ed25519 key is generated, which means
+// tokens do not survive a restart; that is only suitable for development.
+func LoadKeySetFromEnv() *KeySet {
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir != "" {
+		keys, err := LoadKeySet(dir, os.Getenv("JWT_ACTIVE_KID"))
+		if err == nil {
+			log.Printf("JWT signing key %s (%s), %d verification key(s)", keys.active.kid, keys.active.method.Alg(), len(keys.keys))
+			return keys
+		}
+		log.Printf("Warning: failed to load JWT keys from %s: %v", dir, err)
+	}
+
+	keys, err := NewEphemeralKeySet()
+	if err != nil {
+		log.Fatalf("Failed to generate JWT signing key: %v", err)
+	}
+	log.Println("Warning: Using an ephemeral JWT signing key, tokens will not survive a restart. Please set JWT_KEYS_DIR in production.")
+	return keys
+}
+
+// LoadKeySet reads every PEM file in dir. The file name without extension is the
+// kid. Private keys (PKCS#8 or PKCS#1, RSA or Ed25519) can sign; files holding
+// only a public key ("<kid>.pub.pem") are accepted for verification only, which
+// is how a retired key is kept until its last tokens expire. activeKID selects
+// the signing key; when empty, the private key with the greatest kid is used,
+// so date-based kids (e.g. 2025-01) rotate by adding a file.
+func LoadKeySet(dir, activeKID string) (*KeySet, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	set := &KeySet{keys: make(map[string]*signingKey)}
+	var signers []string
+
+	for _, file := range files {
+		kid := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(file), ".pem"), ".pub")
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := parsePEMKey(kid, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", filepath.Base(file), err)
+		}
+
+		if existing, ok := set.keys[kid]; ok && existing.private != nil {
+			continue // keep the private key when both halves are present
+		}
+		set.keys[kid] = key
+		if key.private != nil {
+			signers = append(signers, kid)
+		}
+	}
+
+	if len(signers) == 0 {
+		return nil, errors.New("no private key found")
+	}
+
+	if activeKID == "" {
+		sort.Strings(signers)
+		activeKID = signers[len(signers)-1]
+	}
+
+	active, ok := set.keys[activeKID]
+	if !ok || active.private == nil {
+		return nil, fmt.Errorf("active key %q not found or has no private key", activeKID)
+	}
+	set.active = active
+
+	return set, nil
+}
+
+// NewEphemeralKeySet generates an in-memory Ed25519 key
+func NewEphemeralKeySet() (*KeySet, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &signingKey{
+		kid:     "ephemeral-" + time.Now().Format("20060102150405"),
+		method:  jwt.SigningMethodEdDSA,
+		private: private,
+		public:  private.Public(),
+	}
+
+	return &KeySet{active: key, keys: map[string]*signingKey{key.kid: key}}, nil
+}
+
+func parsePEMKey(kid string, data []byte) (*signingKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY", "RSA PRIVATE KEY":
+		var parsed interface{}
+		var err error
+		if block.Type == "RSA PRIVATE KEY" {
+			parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		} else {
+			parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch private := parsed.(type) {
+		case *rsa.PrivateKey:
+			if private.N.BitLen() < 2048 {
+				return nil, errors.New("RSA keys must be at least 2048 bits")
+			}
+			return &signingKey{kid: kid, method: jwt.SigningMethodRS256, private: private, public: &private.PublicKey}, nil
+		case ed25519.PrivateKey:
+			return &signingKey{kid: kid, method: jwt.SigningMethodEdDSA, private: private, public: private.Public()}, nil
+		}
+		return nil, errors.New("unsupported private key type, use RSA or Ed25519")
+
+	case "PUBLIC KEY":
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		switch public := parsed.(type) {
+		case *rsa.PublicKey:
+			return &signingKey{kid: kid, method: jwt.SigningMethodRS256, public: public}, nil
+		case ed25519.PublicKey:
+			return &signingKey{kid: kid, method: jwt.SigningMethodEdDSA, public: public}, nil
+		}
+		return nil, errors.New("unsupported public key type, use RSA or Ed25519")
+	}
+
+	return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+}
+
+// ActiveKID returns the kid of the key that signs new tokens
+func (k *KeySet) ActiveKID() string {
+	return k.active.kid
+}
+
+// Sign signs the claims with the active key and sets the kid header
+func (k *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.active.method, claims)
+	token.Header["kid"] = k.active.kid
+	return token.SignedString(k.active.private)
+}
+
+// Parse verifies the signature with the key named by the kid header and validates
+// the standard time claims. The algorithm must match the key, so a token cannot
+// switch to another algorithm (e.g. HS256 with the public key as secret).
+func (k *KeySet) Parse(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := k.keys[kid]
+		if !ok {
+			return nil, errors.New("unknown signing key")
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, errors.New("invalid signing method")
+		}
+		return key.public, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}
+
+// JWKS returns the public half of every verification key
+func (k *KeySet) JWKS() JWKS {
+	kids := make([]string, 0, len(k.keys))
+	for kid := range k.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	set := JWKS{Keys: []JWK{}}
+	for _, kid := range kids {
+		key := k.keys[kid]
+		jwk := JWK{Kid: kid, Use: "sig", Alg: key.method.Alg()}
+
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			continue
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
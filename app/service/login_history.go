@@ -0,0 +1,109 @@
+package service
+
+import (
+	"UASBE/app/model"
+	"fmt"
+	"log"
+	"net"
+	"time"
+)
+
+// Size of the network treated as "the same place" when comparing logins. Home
+// and mobile IPs change within their provider's range all the time; a /24 (IPv4)
+// or /48 (IPv6) keeps those from raising alerts.
+const (
+	ipv4RangeBits = 24
+	ipv6RangeBits = 48
+)
+
+// IPRange returns the network of an IP address in CIDR notation, e.g.
+// "10.1.2.0/24" for 10.1.2.3. Anything that is not an IP is returned unchanged.
+func IPRange(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ip
+	}
+
+	if v4 := parsed.To4(); v4 != nil {
+		mask := net.CIDRMask(ipv4RangeBits, 32)
+		return fmt.Sprintf("%s/%d", v4.Mask(mask), ipv4RangeBits)
+	}
+
+	mask := net.CIDRMask(ipv6RangeBits, 128)
+	return fmt.Sprintf("%s/%d", parsed.Mask(mask), ipv6RangeBits)
+}
+
+// LoginHistory is one page of a user's login attempts
+type LoginHistory struct {
+	Attempts []model.LoginAttempt `json:"attempts"`
+	Total    int                  `json:"total"`
+	Page     int                  `json:"page"`
+	Limit    int                  `json:"limit"`
+}
+
+// GetLoginHistory returns the login attempts of a user, newest first
+func (s *AuthService) GetLoginHistory(userID string, page, limit int) (*LoginHistory, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	attempts, total, err := s.loginAttemptRepo.GetByUserID(userID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// Attempts from before devices were stored get a label from the user agent
+	for i := range attempts {
+		if attempts[i].Device == "" {
+			attempts[i].Device = DescribeUserAgent(attempts[i].UserAgent)
+		}
+	}
+
+	return &LoginHistory{Attempts: attempts, Total: total, Page: page, Limit: limit}, nil
+}
+
+// alertNewLoginLocation notifies the user when a successful login comes from a
+// device or an IP range none of the earlier successful logins came from. The
+// first login of an account has nothing to compare with and raises no alert.
+// Called before the attempt itself is stored.
+func (s *AuthService) alertNewLoginLocation(user *model.User, attempt *model.LoginAttempt) {
+	if !s.loginAlertsEnabled || s.notificationService == nil {
+		return
+	}
+
+	hasHistory, ipRangeSeen, deviceSeen, err := s.loginAttemptRepo.GetLoginFootprint(user.ID, attempt.IPRange, attempt.Device)
+	if err != nil {
+		log.Printf("Failed to check login history of user %s: %v", user.ID, err)
+		return
+	}
+	if !hasHistory || (ipRangeSeen && deviceSeen) {
+		return
+	}
+
+	reason := "a new device"
+	switch {
+	case !ipRangeSeen && !deviceSeen:
+		reason = "a new device and network"
+	case !ipRangeSeen:
+		reason = "a new network"
+	}
+
+	message := fmt.Sprintf("Your account was signed in to from %s: %s, IP address %s at %s. If this was not you, change your password and end the session under Sessions.",
+		reason, attempt.Device, attempt.IPAddress, time.Now().Format("02 Jan 2006 15:04 MST"))
+
+	data := map[string]interface{}{
+		"ip_address":  attempt.IPAddress,
+		"ip_range":    attempt.IPRange,
+		"device":      attempt.Device,
+		"user_agent":  attempt.UserAgent,
+		"new_device":  !deviceSeen,
+		"new_network": !ipRangeSeen,
+	}
+
+	if err := s.notificationService.CreateNotification(user.ID, "new_login_location", "New sign-in to your account", message, data); err != nil {
+		log.Printf("Failed to send login alert to user %s: %v", user.ID, err)
+	}
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"time"
+)
+
+// ThrottlePolicy describes how failed logins are slowed down for one scope
+// (an account or a client IP). The first FreeAttempts failures cost nothing;
+// after that every further failure doubles the wait before the next attempt,
+// and reaching Threshold locks the scope for LockoutDuration. Failures older
+// than Window are forgotten.
+type ThrottlePolicy struct {
+	FreeAttempts    int
+	Threshold       int
+	LockoutDuration time.Duration
+	BackoffBase     time.Duration
+	BackoffMax      time.Duration
+	Window          time.Duration
+}
+
+// ThrottleState is the stored state of one scope. Times are relative to the
+// database clock so the policy does not depend on the server's time zone.
+type ThrottleState struct {
+	Failures         int
+	SinceLastFailure time.Duration
+	LockRemaining    time.Duration
+}
+
+// RetryAfter returns how long the scope must wait before the next login attempt
+// is even checked; zero means the attempt may proceed.
+func (p ThrottlePolicy) RetryAfter(state ThrottleState) time.Duration {
+	if state.LockRemaining > 0 {
+		return state.LockRemaining
+	}
+
+	failures := state.Failures
+	if p.Window > 0 && state.SinceLastFailure > p.Window {
+		failures = 0
+	}
+
+	wait := p.Backoff(failures) - state.SinceLastFailure
+	if wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// Backoff is the minimum delay after the given number of consecutive failures
+func (p ThrottlePolicy) Backoff(failures int) time.Duration {
+	if failures <= p.FreeAttempts || p.BackoffBase <= 0 {
+		return 0
+	}
+
+	delay := p.BackoffBase
+	for i := 1; i < failures-p.FreeAttempts; i++ {
+		delay *= 2
+		if p.BackoffMax > 0 && delay >= p.BackoffMax {
+			return p.BackoffMax
+		}
+	}
+
+	if p.BackoffMax > 0 && delay > p.BackoffMax {
+		return p.BackoffMax
+	}
+	return delay
+}
+
+// ShouldLock reports whether the failure count has reached the lockout threshold
+func (p ThrottlePolicy) ShouldLock(failures int) bool {
+	return p.Threshold > 0 && failures >= p.Threshold
+}
+
+// LoginThrottledError is returned by Login while an account or IP is backing off
+// or locked out. It is the same for existing and unknown accounts.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *LoginThrottledError) Error() string {
+	return "too many login attempts"
+}
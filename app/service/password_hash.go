@@ -0,0 +1,151 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hashing algorithms
+const (
+	PasswordAlgorithmArgon2id = "argon2id"
+	PasswordAlgorithmBcrypt   = "bcrypt"
+)
+
+// Argon2Params are the argon2id cost parameters. Memory is in KiB.
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// PasswordHasher hashes new passwords with the configured algorithm and verifies
+// stored hashes of any supported algorithm. Verify reports when a stored hash
+// uses another algorithm or weaker parameters, so it can be upgraded while the
+// plain password is at hand (at login).
+type PasswordHasher struct {
+	Algorithm  string
+	BcryptCost int
+	Argon2     Argon2Params
+}
+
+// PasswordHasherFromEnv builds the hasher from PASSWORD_HASH_ALGORITHM and the
+// ARGON2_* / BCRYPT_COST variables
+func PasswordHasherFromEnv() *PasswordHasher {
+	algorithm := strings.ToLower(getEnvString("PASSWORD_HASH_ALGORITHM", PasswordAlgorithmArgon2id))
+	if algorithm != PasswordAlgorithmArgon2id && algorithm != PasswordAlgorithmBcrypt {
+		log.Printf("Warning: invalid PASSWORD_HASH_ALGORITHM=%q, using %s", algorithm, PasswordAlgorithmArgon2id)
+		algorithm = PasswordAlgorithmArgon2id
+	}
+
+	bcryptCost := getEnvInt("BCRYPT_COST", bcrypt.DefaultCost)
+	if bcryptCost < bcrypt.MinCost || bcryptCost > bcrypt.MaxCost {
+		log.Printf("Warning: invalid BCRYPT_COST=%d, using default %d", bcryptCost, bcrypt.DefaultCost)
+		bcryptCost = bcrypt.DefaultCost
+	}
+
+	parallelism := getEnvInt("ARGON2_PARALLELISM", 2)
+	if parallelism < 1 || parallelism > 255 {
+		parallelism = 2
+	}
+
+	return &PasswordHasher{
+		Algorithm:  algorithm,
+		BcryptCost: bcryptCost,
+		Argon2: Argon2Params{
+			Memory:      uint32(getEnvInt("ARGON2_MEMORY_KIB", 64*1024)),
+			Iterations:  uint32(getEnvInt("ARGON2_ITERATIONS", 3)),
+			Parallelism: uint8(parallelism),
+			SaltLength:  16,
+			KeyLength:   32,
+		},
+	}
+}
+
+// Hash hashes a password with the configured algorithm
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	if h.Algorithm == PasswordAlgorithmBcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.BcryptCost)
+		return string(hash), err
+	}
+
+	salt := make([]byte, h.Argon2.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	p := h.Argon2
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify reports whether the password matches the stored hash and, if so,
+// whether the hash should be replaced by one made with the current settings
+func (h *PasswordHasher) Verify(encodedHash, password string) (match bool, needsRehash bool) {
+	if strings.HasPrefix(encodedHash, "$argon2id$") {
+		params, salt, key, err := decodeArgon2Hash(encodedHash)
+		if err != nil {
+			return false, false
+		}
+
+		computed := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(computed, key) != 1 {
+			return false, false
+		}
+
+		current := h.Argon2
+		outdated := h.Algorithm != PasswordAlgorithmArgon2id ||
+			params.Memory != current.Memory || params.Iterations != current.Iterations ||
+			params.Parallelism != current.Parallelism || uint32(len(key)) != current.KeyLength
+		return true, outdated
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password)) != nil {
+		return false, false
+	}
+
+	cost, err := bcrypt.Cost([]byte(encodedHash))
+	return true, h.Algorithm != PasswordAlgorithmBcrypt || err != nil || cost != h.BcryptCost
+}
+
+// decodeArgon2Hash parses "$argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>"
+func decodeArgon2Hash(encodedHash string) (*Argon2Params, []byte, []byte, error) {
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 {
+		return nil, nil, nil, errors.New("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, nil, nil, errors.New("unsupported argon2 version")
+	}
+
+	params := &Argon2Params{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return nil, nil, nil, errors.New("invalid argon2id parameters")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, errors.New("invalid argon2id salt")
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return nil, nil, nil, errors.New("invalid argon2id key")
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// PasswordPolicy describes what a new password must look like. It is checked
+// whenever a password is set (registration, admin create/update, reset and
+// change); existing passwords are not re-checked at login.
+type PasswordPolicy struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+
+	// Blocklist holds lower-cased common or breached passwords
+	Blocklist map[string]bool
+}
+
+// PasswordPolicyError lists every rule a password breaks, so the user can fix
+// them all at once
+type PasswordPolicyError struct {
+	Violations []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return "password does not meet the policy: " + strings.Join(e.Violations, "; ")
+}
+
+// defaultCommonPasswords is always blocked, on top of PASSWORD_BLOCKLIST_FILE
+var defaultCommonPasswords = []string{
+	"password", "password1", "password123", "passw0rd", "p@ssw0rd", "p@ssword1",
+	"123456", "12345678", "123456789", "1234567890", "qwerty", "qwerty123",
+	"qwertyuiop", "abc123", "abcd1234", "111111", "000000", "iloveyou",
+	"admin", "admin123", "administrator", "welcome", "welcome1", "welcome123",
+	"letmein", "monkey", "dragon", "football", "sunshine", "princess",
+	"changeme", "secret", "student", "student123", "mahasiswa", "dosen123",
+}
+
+// PasswordPolicyFromEnv builds the policy from PASSWORD_* variables
+func PasswordPolicyFromEnv() *PasswordPolicy {
+	policy := &PasswordPolicy{
+		MinLength:     getEnvInt("PASSWORD_MIN_LENGTH", 8),
+		MaxLength:     getEnvInt("PASSWORD_MAX_LENGTH", 128),
+		RequireUpper:  getEnvBool("PASSWORD_REQUIRE_UPPERCASE", true),
+		RequireLower:  getEnvBool("PASSWORD_REQUIRE_LOWERCASE", true),
+		RequireDigit:  getEnvBool("PASSWORD_REQUIRE_DIGIT", true),
+		RequireSymbol: getEnvBool("PASSWORD_REQUIRE_SYMBOL", false),
+		Blocklist:     make(map[string]bool),
+	}
+
+	for _, password := range defaultCommonPasswords {
+		policy.Blocklist[password] = true
+	}
+
+	if path := os.Getenv("PASSWORD_BLOCKLIST_FILE"); path != "" {
+		if err := policy.LoadBlocklist(path); err != nil {
+			log.Printf("Warning: failed to load password blocklist %s: %v", path, err)
+		}
+	}
+
+	return policy
+}
+
+// LoadBlocklist adds the passwords of a file, one per line, to the blocklist.
+// Empty lines and lines starting with # are skipped.
+func (p *PasswordPolicy) LoadBlocklist(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if p.Blocklist == nil {
+		p.Blocklist = make(map[string]bool)
+	}
+
+	scanner := bufio.NewScanner(file)
+	count := 0
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.Blocklist[strings.ToLower(line)] = true
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	log.Printf("Loaded %d passwords into the password blocklist", count)
+	return nil
+}
+
+// Validate checks a new password. personalInfo holds values the password must
+// not contain, such as the username and email address.
+func (p *PasswordPolicy) Validate(password string, personalInfo ...string) error {
+	var violations []string
+
+	length := len([]rune(password))
+	if length < p.MinLength {
+		violations = append(violations, "must be at least "+strconv.Itoa(p.MinLength)+" characters")
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, "must be at most "+strconv.Itoa(p.MaxLength)+" characters")
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireUpper && !hasUpper {
+		violations = append(violations, "must contain an uppercase letter")
+	}
+	if p.RequireLower && !hasLower {
+		violations = append(violations, "must contain a lowercase letter")
+	}
+	if p.RequireDigit && !hasDigit {
+		violations = append(violations, "must contain a digit")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		violations = append(violations, "must contain a symbol")
+	}
+
+	if p.Blocklist[strings.ToLower(password)] {
+		violations = append(violations, "is too common")
+	}
+
+	lowered := strings.ToLower(password)
+	for _, info := range personalInfo {
+		// For an email address only the part before @ is meaningful
+		if at := strings.Index(info, "@"); at > 0 {
+			info = info[:at]
+		}
+		info = strings.ToLower(strings.TrimSpace(info))
+		if len(info) >= 3 && strings.Contains(lowered, info) {
+			violations = append(violations, "must not contain your username or email")
+			break
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// Rules describes the policy in words, for clients that show it next to a
+// password field
+func (p *PasswordPolicy) Rules() []string {
+	rules := []string{"At least " + strconv.Itoa(p.MinLength) + " characters"}
+	if p.MaxLength > 0 {
+		rules = append(rules, "At most "+strconv.Itoa(p.MaxLength)+" characters")
+	}
+	if p.RequireUpper {
+		rules = append(rules, "An uppercase letter")
+	}
+	if p.RequireLower {
+		rules = append(rules, "A lowercase letter")
+	}
+	if p.RequireDigit {
+		rules = append(rules, "A digit")
+	}
+	if p.RequireSymbol {
+		rules = append(rules, "A symbol")
+	}
+	rules = append(rules, "Not a common password", "Not containing your username or email")
+	return rules
+}
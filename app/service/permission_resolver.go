@@ -0,0 +1,211 @@
+package service
+
+import (
+	"UASBE/app/model"
+	"UASBE/app/policy"
+	"errors"
+	"sync"
+	"time"
+)
+
+// RoleLoader loads a role and its permissions from storage (RoleRepository)
+type RoleLoader interface {
+	GetByID(id string) (*model.Role, error)
+	GetPermissionsByRoleID(roleID string) ([]model.Permission, error)
+}
+
+// ResolvedRole is a role with its current permissions
+type ResolvedRole struct {
+	Role        *model.Role
+	Permissions []model.Permission
+	granted     map[string]bool
+}
+
+// Has reports whether the role holds the "resource:action" permission
+func (r *ResolvedRole) Has(permission string) bool {
+	return r.granted[permission]
+}
+
+// PermissionStrings returns the permissions as "resource:action"
+func (r *ResolvedRole) PermissionStrings() []string {
+	permissions := make([]string, 0, len(r.Permissions))
+	for _, p := range r.Permissions {
+		permissions = append(permissions, p.Key())
+	}
+	return permissions
+}
+
+// ResolvedRoles is every role a user holds, the primary role (users.role_id)
+// first, and the union of their permissions
+type ResolvedRoles struct {
+	Primary *ResolvedRole
+	Roles   []*ResolvedRole
+}
+
+// Has reports whether any of the roles holds the "resource:action" permission
+func (r *ResolvedRoles) Has(permission string) bool {
+	for _, role := range r.Roles {
+		if role.Has(permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRole reports whether one of the roles has the name (or one of its aliases)
+func (r *ResolvedRoles) HasRole(name string) bool {
+	return policy.Subject{Roles: r.Names()}.HasRole(name)
+}
+
+// Names returns the role names, primary first
+func (r *ResolvedRoles) Names() []string {
+	names := make([]string, 0, len(r.Roles))
+	for _, role := range r.Roles {
+		names = append(names, role.Role.Name)
+	}
+	return names
+}
+
+// IDs returns the role IDs, primary first
+func (r *ResolvedRoles) IDs() []string {
+	ids := make([]string, 0, len(r.Roles))
+	for _, role := range r.Roles {
+		ids = append(ids, role.Role.ID)
+	}
+	return ids
+}
+
+// RoleList returns the roles themselves, primary first
+func (r *ResolvedRoles) RoleList() []model.Role {
+	roles := make([]model.Role, 0, len(r.Roles))
+	for _, role := range r.Roles {
+		roles = append(roles, *role.Role)
+	}
+	return roles
+}
+
+// Permissions returns the union of the permissions of all roles
+func (r *ResolvedRoles) Permissions() []model.Permission {
+	seen := make(map[string]bool)
+	var permissions []model.Permission
+	for _, role := range r.Roles {
+		for _, p := range role.Permissions {
+			if !seen[p.Key()] {
+				seen[p.Key()] = true
+				permissions = append(permissions, p)
+			}
+		}
+	}
+	return permissions
+}
+
+// PermissionStrings returns the union of the permissions as "resource:action"
+func (r *ResolvedRoles) PermissionStrings() []string {
+	permissions := r.Permissions()
+	keys := make([]string, 0, len(permissions))
+	for _, p := range permissions {
+		keys = append(keys, p.Key())
+	}
+	return keys
+}
+
+type cachedRole struct {
+	resolved  *ResolvedRole
+	expiresAt time.Time
+}
+
+// PermissionResolver resolves a role's permissions from the database for every
+// request instead of trusting permissions frozen into the JWT. Results are cached
+// per role_id; changes made through the API invalidate the cache immediately and
+// the TTL bounds how long other instances (or direct SQL edits) can stay stale.
+type PermissionResolver struct {
+	loader RoleLoader
+	ttl    time.Duration
+
+	mu    sync.RWMutex
+	cache map[string]cachedRole
+}
+
+func NewPermissionResolver(loader RoleLoader) *PermissionResolver {
+	return &PermissionResolver{
+		loader: loader,
+		ttl:    getEnvDuration("PERMISSION_CACHE_TTL", 30*time.Second),
+		cache:  make(map[string]cachedRole),
+	}
+}
+
+// Resolve returns the role and its permissions, from the cache when fresh
+func (r *PermissionResolver) Resolve(roleID string) (*ResolvedRole, error) {
+	r.mu.RLock()
+	entry, ok := r.cache[roleID]
+	r.mu.RUnlock()
+
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.resolved, nil
+	}
+
+	role, err := r.loader.GetByID(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := r.loader.GetPermissionsByRoleID(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := &ResolvedRole{
+		Role:        role,
+		Permissions: permissions,
+		granted:     make(map[string]bool, len(permissions)),
+	}
+	for _, p := range permissions {
+		resolved.granted[p.Key()] = true
+	}
+
+	r.mu.Lock()
+	r.cache[roleID] = cachedRole{resolved: resolved, expiresAt: time.Now().Add(r.ttl)}
+	r.mu.Unlock()
+
+	return resolved, nil
+}
+
+// ResolveAll resolves several roles; the first is the primary role. Duplicate IDs
+// are resolved once.
+func (r *PermissionResolver) ResolveAll(roleIDs []string) (*ResolvedRoles, error) {
+	if len(roleIDs) == 0 {
+		return nil, errors.New("role not found")
+	}
+
+	resolved := &ResolvedRoles{}
+	seen := make(map[string]bool)
+	for _, roleID := range roleIDs {
+		if seen[roleID] {
+			continue
+		}
+		seen[roleID] = true
+
+		role, err := r.Resolve(roleID)
+		if err != nil {
+			return nil, err
+		}
+		resolved.Roles = append(resolved.Roles, role)
+	}
+	resolved.Primary = resolved.Roles[0]
+
+	return resolved, nil
+}
+
+// Invalidate drops one role from the cache, e.g. after its permissions changed
+func (r *PermissionResolver) Invalidate(roleID string) {
+	r.mu.Lock()
+	delete(r.cache, roleID)
+	r.mu.Unlock()
+}
+
+// InvalidateAll empties the cache, e.g. after a permission was renamed or deleted
+func (r *PermissionResolver) InvalidateAll() {
+	r.mu.Lock()
+	r.cache = make(map[string]cachedRole)
+	r.mu.Unlock()
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"UASBE/app/model"
+	"UASBE/app/repository"
+	"UASBE/mail"
+	"database/sql"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RegistrationService lets admins review accounts created through self-registration
+type RegistrationService struct {
+	authService *AuthService
+	userRepo    *repository.UserRepository
+	studentRepo *repository.StudentRepository
+}
+
+type RejectRegistrationRequest struct {
+	Reason string `json:"reason"`
+}
+
+func NewRegistrationService(authService *AuthService, userRepo *repository.UserRepository, studentRepo *repository.StudentRepository) *RegistrationService {
+	return &RegistrationService{
+		authService: authService,
+		userRepo:    userRepo,
+		studentRepo: studentRepo,
+	}
+}
+
+// GetRegistrations lists self-registrations in a status, pending by default
+func (s *RegistrationService) GetRegistrations(status string) ([]model.Registration, error) {
+	if status == "" {
+		status = model.ApprovalStatusPending
+	}
+	if status != model.ApprovalStatusPending && status != model.ApprovalStatusApproved && status != model.ApprovalStatusRejected {
+		return nil, errors.New("invalid status")
+	}
+
+	registrations, err := s.userRepo.GetRegistrations(status)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range registrations {
+		if student, err := s.studentRepo.GetByUserID(registrations[i].User.ID); err == nil {
+			registrations[i].Student = student
+		}
+	}
+
+	return registrations, nil
+}
+
+// Approve lets a pending account log in
+func (s *RegistrationService) Approve(userID, reviewerID string) (*model.User, error) {
+	user, err := s.review(userID, model.ApprovalStatusApproved, reviewerID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	s.authService.sendMail(&mail.Message{
+		To:      []string{user.Email},
+		Subject: "Your registration was approved",
+		Body: "Hello " + user.FullName + ",\n\n" +
+			"Your account " + user.Username + " has been approved. You can now log in.\n",
+	})
+
+	return user, nil
+}
+
+// Reject refuses a pending account; it can never log in
+func (s *RegistrationService) Reject(userID, reviewerID, reason string) (*model.User, error) {
+	user, err := s.review(userID, model.ApprovalStatusRejected, reviewerID, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	body := "Hello " + user.FullName + ",\n\n" +
+		"Your registration as " + user.Username + " was not approved.\n"
+	if reason != "" {
+		body += "Reason: " + reason + "\n"
+	}
+	s.authService.sendMail(&mail.Message{
+		To:      []string{user.Email},
+		Subject: "Your registration was not approved",
+		Body:    body,
+	})
+
+	return user, nil
+}
+
+func (s *RegistrationService) review(userID, status, reviewerID, reason string) (*model.User, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, errors.New("registration not found")
+	}
+
+	err = s.userRepo.ReviewRegistration(user.ID, status, reviewerID, reason)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("registration is not pending")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user.ApprovalStatus = status
+	user.Password = ""
+	return user, nil
+}
+
+func registrationErrorResponse(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	code := "REGISTRATION_REVIEW_FAILED"
+	message := "Operation failed"
+
+	switch err.Error() {
+	case "registration not found":
+		status = fiber.StatusNotFound
+		code = "REGISTRATION_NOT_FOUND"
+		message = "Registration not found"
+	case "registration is not pending":
+		status = fiber.StatusConflict
+		code = "REGISTRATION_ALREADY_REVIEWED"
+		message = "This registration has already been approved or rejected"
+	case "invalid status":
+		status = fiber.StatusBadRequest
+		code = "INVALID_STATUS"
+		message = "Status must be pending, approved or rejected"
+	}
+
+	return c.Status(status).JSON(fiber.Map{
+		"success": false,
+		"error": err.Error(),
+		"message": message,
+		"code": code,
+	})
+}
+
+// GetRegistrationsRequest lists self-registrations
+// @Summary List Registrations
+// @Description List self-registered student accounts by approval status (admin only)
+// @Tags Registration
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "pending (default), approved or rejected"
+// @Success 200 {object} map[string]interface{} "Registrations"
+// @Router /admin/registrations [get]
+func (s *RegistrationService) GetRegistrationsRequest(c *fiber.Ctx) error {
+	registrations, err := s.GetRegistrations(c.Query("status"))
+	if err != nil {
+		return registrationErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": registrations,
+		"total": len(registrations),
+	})
+}
+
+// ApproveRegistrationRequest approves a pending registration
+// @Summary Approve Registration
+// @Tags Registration
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]interface{} "Registration approved"
+// @Failure 409 {object} map[string]interface{} "Already reviewed"
+// @Router /admin/registrations/{id}/approve [post]
+func (s *RegistrationService) ApproveRegistrationRequest(c *fiber.Ctx) error {
+	reviewerID, _ := c.Locals("user_id").(string)
+
+	user, err := s.Approve(c.Params("id"), reviewerID)
+	if err != nil {
+		return registrationErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Registration approved. The user can now log in",
+		"code": "REGISTRATION_APPROVED",
+		"data": user,
+	})
+}
+
+// RejectRegistrationRequest rejects a pending registration
+// @Summary Reject Registration
+// @Tags Registration
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param body body RejectRegistrationRequest false "Reason shown to the applicant"
+// @Success 200 {object} map[string]interface{} "Registration rejected"
+// @Failure 409 {object} map[string]interface{} "Already reviewed"
+// @Router /admin/registrations/{id}/reject [post]
+func (s *RegistrationService) RejectRegistrationRequest(c *fiber.Ctx) error {
+	var req RejectRegistrationRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error": "Invalid request body",
+				"message": "Please provide valid JSON data",
+				"code": "INVALID_REQUEST_BODY",
+			})
+		}
+	}
+
+	reviewerID, _ := c.Locals("user_id").(string)
+
+	user, err := s.Reject(c.Params("id"), reviewerID, req.Reason)
+	if err != nil {
+		return registrationErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Registration rejected",
+		"code": "REGISTRATION_REJECTED",
+		"data": user,
+	})
+}
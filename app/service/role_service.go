@@ -0,0 +1,919 @@
+package service
+
+import (
+	"UASBE/app/model"
+	"UASBE/app/repository"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Role names and permission parts are identifiers used in code and URLs
+var (
+	roleNamePattern       = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+	permissionPartPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,99}$`)
+)
+
+type RoleService struct {
+	roleRepo       *repository.RoleRepository
+	permissionRepo *repository.PermissionRepository
+	userRepo       *repository.UserRepository
+	userRoleRepo   *repository.UserRoleRepository
+	resolver       *PermissionResolver
+}
+
+type RoleRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions,omitempty"` // "resource:action"
+}
+
+type RolePermissionsRequest struct {
+	Permissions []string `json:"permissions"`
+}
+
+type GrantPermissionRequest struct {
+	Permission string `json:"permission"`
+}
+
+type GrantUserRoleRequest struct {
+	Role string `json:"role"` // role ID or name
+}
+
+type PermissionRequest struct {
+	Resource    string `json:"resource"`
+	Action      string `json:"action"`
+	Description string `json:"description"`
+}
+
+// RoleWithPermissions is a role as returned by the admin API
+type RoleWithPermissions struct {
+	model.Role
+	Permissions []model.Permission `json:"permissions"`
+	UserCount   int                `json:"user_count"`
+}
+
+// UserRoles are the primary role and the additional roles of a user
+type UserRoles struct {
+	UserID          string           `json:"user_id"`
+	PrimaryRole     model.Role       `json:"primary_role"`
+	AdditionalRoles []model.UserRole `json:"additional_roles"`
+}
+
+// UnknownPermissionsError lists permission identifiers that do not exist
+type UnknownPermissionsError struct {
+	Permissions []string
+}
+
+func (e *UnknownPermissionsError) Error() string {
+	return "unknown permissions: " + strings.Join(e.Permissions, ", ")
+}
+
+func NewRoleService(roleRepo *repository.RoleRepository, permissionRepo *repository.PermissionRepository, userRepo *repository.UserRepository, userRoleRepo *repository.UserRoleRepository, resolver *PermissionResolver) *RoleService {
+	return &RoleService{
+		roleRepo:       roleRepo,
+		permissionRepo: permissionRepo,
+		userRepo:       userRepo,
+		userRoleRepo:   userRoleRepo,
+		resolver:       resolver,
+	}
+}
+
+func (s *RoleService) GetRoles() ([]RoleWithPermissions, error) {
+	roles, err := s.roleRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]RoleWithPermissions, 0, len(roles))
+	for _, role := range roles {
+		detail, err := s.describeRole(&role)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *detail)
+	}
+
+	return result, nil
+}
+
+func (s *RoleService) GetRole(roleID string) (*RoleWithPermissions, error) {
+	role, err := s.roleRepo.GetByID(roleID)
+	if err != nil {
+		return nil, errors.New("role not found")
+	}
+	return s.describeRole(role)
+}
+
+func (s *RoleService) describeRole(role *model.Role) (*RoleWithPermissions, error) {
+	permissions, err := s.roleRepo.GetPermissionsByRoleID(role.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	userCount, err := s.roleRepo.CountUsers(role.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RoleWithPermissions{Role: *role, Permissions: permissions, UserCount: userCount}, nil
+}
+
+// CreateRole creates a custom role, optionally with an initial set of permissions
+func (s *RoleService) CreateRole(req *RoleRequest) (*RoleWithPermissions, error) {
+	name := strings.ToLower(strings.TrimSpace(req.Name))
+	if !roleNamePattern.MatchString(name) {
+		return nil, errors.New("invalid role name")
+	}
+
+	if _, err := s.roleRepo.GetByName(name); err == nil {
+		return nil, errors.New("role already exists")
+	}
+
+	permissionIDs, err := s.lookupPermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	role := &model.Role{Name: name, Description: req.Description}
+	if err := s.roleRepo.Create(role); err != nil {
+		return nil, err
+	}
+
+	if len(permissionIDs) > 0 {
+		if err := s.roleRepo.SetPermissions(role.ID, permissionIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.describeRole(role)
+}
+
+// UpdateRole changes name and description. System roles keep their name because
+// the code refers to them by name.
+func (s *RoleService) UpdateRole(roleID string, req *RoleRequest) (*RoleWithPermissions, error) {
+	role, err := s.roleRepo.GetByID(roleID)
+	if err != nil {
+		return nil, errors.New("role not found")
+	}
+
+	if req.Name != "" {
+		name := strings.ToLower(strings.TrimSpace(req.Name))
+		if name != role.Name {
+			if role.IsSystem {
+				return nil, errors.New("system role cannot be renamed")
+			}
+			if !roleNamePattern.MatchString(name) {
+				return nil, errors.New("invalid role name")
+			}
+			if _, err := s.roleRepo.GetByName(name); err == nil {
+				return nil, errors.New("role already exists")
+			}
+			role.Name = name
+		}
+	}
+	role.Description = req.Description
+
+	if err := s.roleRepo.Update(role); err != nil {
+		return nil, err
+	}
+
+	s.resolver.Invalidate(role.ID)
+	return s.describeRole(role)
+}
+
+// DeleteRole deletes a custom role that no user has anymore
+func (s *RoleService) DeleteRole(roleID string) error {
+	role, err := s.roleRepo.GetByID(roleID)
+	if err != nil {
+		return errors.New("role not found")
+	}
+	if role.IsSystem {
+		return errors.New("system role cannot be deleted")
+	}
+
+	count, err := s.roleRepo.CountUsers(role.ID)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("role is assigned to users")
+	}
+
+	if err := s.roleRepo.Delete(role.ID); err != nil {
+		return err
+	}
+
+	s.resolver.Invalidate(role.ID)
+	return nil
+}
+
+// SetRolePermissions replaces the permissions of a role
+func (s *RoleService) SetRolePermissions(roleID string, permissions []string) (*RoleWithPermissions, error) {
+	role, err := s.roleRepo.GetByID(roleID)
+	if err != nil {
+		return nil, errors.New("role not found")
+	}
+
+	permissionIDs, err := s.lookupPermissions(permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.roleRepo.SetPermissions(role.ID, permissionIDs); err != nil {
+		return nil, err
+	}
+
+	s.resolver.Invalidate(role.ID)
+	return s.describeRole(role)
+}
+
+func (s *RoleService) GrantPermission(roleID, permission string) (*RoleWithPermissions, error) {
+	role, err := s.roleRepo.GetByID(roleID)
+	if err != nil {
+		return nil, errors.New("role not found")
+	}
+
+	permissionIDs, err := s.lookupPermissions([]string{permission})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.roleRepo.GrantPermission(role.ID, permissionIDs[0]); err != nil {
+		return nil, err
+	}
+
+	s.resolver.Invalidate(role.ID)
+	return s.describeRole(role)
+}
+
+func (s *RoleService) RevokePermission(roleID, permissionID string) (*RoleWithPermissions, error) {
+	role, err := s.roleRepo.GetByID(roleID)
+	if err != nil {
+		return nil, errors.New("role not found")
+	}
+
+	if err := s.roleRepo.RevokePermission(role.ID, permissionID); err != nil {
+		return nil, err
+	}
+
+	s.resolver.Invalidate(role.ID)
+	return s.describeRole(role)
+}
+
+// GetUserRoles returns the primary and additional roles of a user
+func (s *RoleService) GetUserRoles(userID string) (*UserRoles, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	primary, err := s.roleRepo.GetByID(user.RoleID)
+	if err != nil {
+		return nil, err
+	}
+
+	additional, err := s.userRoleRepo.GetByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if additional == nil {
+		additional = []model.UserRole{}
+	}
+
+	return &UserRoles{
+		UserID:          user.ID,
+		PrimaryRole:     *primary,
+		AdditionalRoles: additional,
+	}, nil
+}
+
+// GrantUserRole gives a user an additional role, by role ID or name. The user
+// gets its permissions on the next request; tokens do not carry permissions.
+func (s *RoleService) GrantUserRole(userID, roleRef, grantedBy string) (*UserRoles, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	role, err := s.findRole(roleRef)
+	if err != nil {
+		return nil, err
+	}
+	if role.ID == user.RoleID {
+		return nil, errors.New("user already has this role")
+	}
+
+	granted, err := s.userRoleRepo.Grant(user.ID, role.ID, grantedBy)
+	if err != nil {
+		return nil, err
+	}
+	if !granted {
+		return nil, errors.New("user already has this role")
+	}
+
+	return s.GetUserRoles(user.ID)
+}
+
+// RevokeUserRole removes an additional role. The primary role can only be
+// changed by moving the user to another role.
+func (s *RoleService) RevokeUserRole(userID, roleID string) (*UserRoles, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	if roleID == user.RoleID {
+		return nil, errors.New("primary role cannot be revoked")
+	}
+
+	revoked, err := s.userRoleRepo.Revoke(user.ID, roleID)
+	if err != nil {
+		return nil, err
+	}
+	if !revoked {
+		return nil, errors.New("user does not have this role")
+	}
+
+	return s.GetUserRoles(user.ID)
+}
+
+// findRole looks a role up by ID, then by name
+func (s *RoleService) findRole(ref string) (*model.Role, error) {
+	ref = strings.TrimSpace(ref)
+	if role, err := s.roleRepo.GetByID(ref); err == nil {
+		return role, nil
+	}
+	if role, err := s.roleRepo.GetByName(strings.ToLower(ref)); err == nil {
+		return role, nil
+	}
+	return nil, errors.New("role not found")
+}
+
+func (s *RoleService) CreatePermission(req *PermissionRequest) (*model.Permission, error) {
+	permission := &model.Permission{
+		Resource:    strings.ToLower(strings.TrimSpace(req.Resource)),
+		Action:      strings.ToLower(strings.TrimSpace(req.Action)),
+		Description: req.Description,
+	}
+
+	if !permissionPartPattern.MatchString(permission.Resource) || !permissionPartPattern.MatchString(permission.Action) {
+		return nil, errors.New("invalid permission")
+	}
+
+	if _, err := s.permissionRepo.GetByResourceAction(permission.Resource, permission.Action); err == nil {
+		return nil, errors.New("permission already exists")
+	}
+
+	if err := s.permissionRepo.Create(permission); err != nil {
+		return nil, err
+	}
+
+	return permission, nil
+}
+
+func (s *RoleService) UpdatePermission(permissionID, description string) (*model.Permission, error) {
+	permission, err := s.permissionRepo.GetByID(permissionID)
+	if err != nil {
+		return nil, errors.New("permission not found")
+	}
+
+	if err := s.permissionRepo.UpdateDescription(permission.ID, description); err != nil {
+		return nil, err
+	}
+	permission.Description = description
+
+	s.resolver.InvalidateAll()
+	return permission, nil
+}
+
+// DeletePermission deletes a permission and revokes it from every role
+func (s *RoleService) DeletePermission(permissionID string) error {
+	permission, err := s.permissionRepo.GetByID(permissionID)
+	if err != nil {
+		return errors.New("permission not found")
+	}
+
+	if err := s.permissionRepo.Delete(permission.ID); err != nil {
+		return err
+	}
+
+	s.resolver.InvalidateAll()
+	return nil
+}
+
+// lookupPermissions maps "resource:action" identifiers to permission IDs
+func (s *RoleService) lookupPermissions(keys []string) ([]string, error) {
+	var ids []string
+	var unknown []string
+	seen := make(map[string]bool)
+
+	for _, key := range keys {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		resource, action, ok := strings.Cut(key, ":")
+		if !ok {
+			unknown = append(unknown, key)
+			continue
+		}
+
+		permission, err := s.permissionRepo.GetByResourceAction(resource, action)
+		if err == sql.ErrNoRows {
+			unknown = append(unknown, key)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, permission.ID)
+	}
+
+	if len(unknown) > 0 {
+		return nil, &UnknownPermissionsError{Permissions: unknown}
+	}
+
+	return ids, nil
+}
+
+// roleErrorResponse maps the errors of this service to HTTP responses
+func roleErrorResponse(c *fiber.Ctx, err error) error {
+	var unknown *UnknownPermissionsError
+	if errors.As(err, &unknown) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+			"message": "Create the permissions first with POST /api/admin/permissions",
+			"code":    "UNKNOWN_PERMISSIONS",
+			"details": unknown.Permissions,
+		})
+	}
+
+	status := fiber.StatusBadRequest
+	var code, message string
+
+	switch err.Error() {
+	case "role not found":
+		status = fiber.StatusNotFound
+		code = "ROLE_NOT_FOUND"
+		message = "Role not found"
+	case "permission not found":
+		status = fiber.StatusNotFound
+		code = "PERMISSION_NOT_FOUND"
+		message = "Permission not found"
+	case "invalid role name":
+		code = "INVALID_ROLE_NAME"
+		message = "Role name must be 2-50 lowercase letters, digits or underscores, starting with a letter (e.g. head_of_study_program)"
+	case "invalid permission":
+		code = "INVALID_PERMISSION"
+		message = "Resource and action must be lowercase letters, digits or underscores, starting with a letter"
+	case "role already exists":
+		status = fiber.StatusConflict
+		code = "ROLE_ALREADY_EXISTS"
+		message = "A role with this name already exists"
+	case "permission already exists":
+		status = fiber.StatusConflict
+		code = "PERMISSION_ALREADY_EXISTS"
+		message = "This resource:action permission already exists"
+	case "system role cannot be renamed", "system role cannot be deleted":
+		status = fiber.StatusForbidden
+		code = "SYSTEM_ROLE"
+		message = "Built-in roles (admin, student, lecturer) cannot be renamed or deleted"
+	case "role is assigned to users":
+		status = fiber.StatusConflict
+		code = "ROLE_IN_USE"
+		message = "Move the users to another role before deleting this role"
+	case "user not found":
+		status = fiber.StatusNotFound
+		code = "USER_NOT_FOUND"
+		message = "User not found"
+	case "user already has this role":
+		status = fiber.StatusConflict
+		code = "ROLE_ALREADY_GRANTED"
+		message = "The user already has this role"
+	case "user does not have this role":
+		status = fiber.StatusNotFound
+		code = "ROLE_NOT_GRANTED"
+		message = "The user does not have this additional role"
+	case "primary role cannot be revoked":
+		status = fiber.StatusConflict
+		code = "PRIMARY_ROLE"
+		message = "The primary role cannot be revoked; move the user to another role instead"
+	default:
+		status = fiber.StatusInternalServerError
+		code = "RBAC_OPERATION_FAILED"
+		message = "Operation failed"
+	}
+
+	return c.Status(status).JSON(fiber.Map{
+		"success": false,
+		"error":   err.Error(),
+		"message": message,
+		"code":    code,
+	})
+}
+
+func invalidRoleBody(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"success": false,
+		"error": "Invalid request body",
+		"message": "Please provide valid JSON data",
+		"code": "INVALID_REQUEST_BODY",
+	})
+}
+
+// GetRolesRequest lists roles
+// @Summary List Roles
+// @Description List all roles with their permissions and number of users (admin only)
+// @Tags RBAC
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Roles"
+// @Router /admin/roles [get]
+func (s *RoleService) GetRolesRequest(c *fiber.Ctx) error {
+	roles, err := s.GetRoles()
+	if err != nil {
+		return roleErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": roles,
+		"total": len(roles),
+	})
+}
+
+// GetRoleRequest shows one role
+// @Summary Get Role
+// @Tags RBAC
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Role ID"
+// @Success 200 {object} map[string]interface{} "Role"
+// @Failure 404 {object} map[string]interface{} "Role not found"
+// @Router /admin/roles/{id} [get]
+func (s *RoleService) GetRoleRequest(c *fiber.Ctx) error {
+	role, err := s.GetRole(c.Params("id"))
+	if err != nil {
+		return roleErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": role,
+	})
+}
+
+// CreateRoleRequest creates a custom role
+// @Summary Create Role
+// @Description Create a custom role (e.g. head_of_study_program) with optional resource:action permissions (admin only)
+// @Tags RBAC
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body RoleRequest true "Role"
+// @Success 201 {object} map[string]interface{} "Role created"
+// @Failure 400 {object} map[string]interface{} "Validation error"
+// @Failure 409 {object} map[string]interface{} "Role already exists"
+// @Router /admin/roles [post]
+func (s *RoleService) CreateRoleRequest(c *fiber.Ctx) error {
+	var req RoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return invalidRoleBody(c)
+	}
+
+	role, err := s.CreateRole(&req)
+	if err != nil {
+		return roleErrorResponse(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"message": "Role created",
+		"code": "ROLE_CREATED",
+		"data": role,
+	})
+}
+
+// UpdateRoleRequest renames or re-describes a role
+// @Summary Update Role
+// @Tags RBAC
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Role ID"
+// @Param body body RoleRequest true "Name and description"
+// @Success 200 {object} map[string]interface{} "Role updated"
+// @Router /admin/roles/{id} [put]
+func (s *RoleService) UpdateRoleRequest(c *fiber.Ctx) error {
+	var req RoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return invalidRoleBody(c)
+	}
+
+	role, err := s.UpdateRole(c.Params("id"), &req)
+	if err != nil {
+		return roleErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Role updated",
+		"code": "ROLE_UPDATED",
+		"data": role,
+	})
+}
+
+// DeleteRoleRequest deletes a custom role
+// @Summary Delete Role
+// @Tags RBAC
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Role ID"
+// @Success 200 {object} map[string]interface{} "Role deleted"
+// @Failure 409 {object} map[string]interface{} "Role still assigned to users"
+// @Router /admin/roles/{id} [delete]
+func (s *RoleService) DeleteRoleRequest(c *fiber.Ctx) error {
+	if err := s.DeleteRole(c.Params("id")); err != nil {
+		return roleErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Role deleted",
+		"code": "ROLE_DELETED",
+	})
+}
+
+// SetRolePermissionsRequest replaces a role's permissions
+// @Summary Set Role Permissions
+// @Tags RBAC
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Role ID"
+// @Param body body RolePermissionsRequest true "Complete list of resource:action permissions"
+// @Success 200 {object} map[string]interface{} "Permissions replaced"
+// @Router /admin/roles/{id}/permissions [put]
+func (s *RoleService) SetRolePermissionsRequest(c *fiber.Ctx) error {
+	var req RolePermissionsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return invalidRoleBody(c)
+	}
+
+	role, err := s.SetRolePermissions(c.Params("id"), req.Permissions)
+	if err != nil {
+		return roleErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Role permissions updated",
+		"code": "ROLE_PERMISSIONS_UPDATED",
+		"data": role,
+	})
+}
+
+// GrantRolePermissionRequest grants one permission to a role
+// @Summary Grant Permission
+// @Tags RBAC
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Role ID"
+// @Param body body GrantPermissionRequest true "resource:action"
+// @Success 200 {object} map[string]interface{} "Permission granted"
+// @Router /admin/roles/{id}/permissions [post]
+func (s *RoleService) GrantRolePermissionRequest(c *fiber.Ctx) error {
+	var req GrantPermissionRequest
+	if err := c.BodyParser(&req); err != nil || req.Permission == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error": "Invalid request body",
+			"message": "permission (resource:action) is required",
+			"code": "MISSING_PERMISSION",
+		})
+	}
+
+	role, err := s.GrantPermission(c.Params("id"), req.Permission)
+	if err != nil {
+		return roleErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Permission granted",
+		"code": "PERMISSION_GRANTED",
+		"data": role,
+	})
+}
+
+// RevokeRolePermissionRequest revokes one permission from a role
+// @Summary Revoke Permission
+// @Tags RBAC
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Role ID"
+// @Param permission_id path string true "Permission ID"
+// @Success 200 {object} map[string]interface{} "Permission revoked"
+// @Router /admin/roles/{id}/permissions/{permission_id} [delete]
+func (s *RoleService) RevokeRolePermissionRequest(c *fiber.Ctx) error {
+	role, err := s.RevokePermission(c.Params("id"), c.Params("permission_id"))
+	if err != nil {
+		return roleErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Permission revoked",
+		"code": "PERMISSION_REVOKED",
+		"data": role,
+	})
+}
+
+// GetUserRolesRequest lists the roles of a user
+// @Summary List User Roles
+// @Description Primary role and additional roles of a user (admin only)
+// @Tags RBAC
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]interface{} "User roles"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Router /admin/users/{id}/roles [get]
+func (s *RoleService) GetUserRolesRequest(c *fiber.Ctx) error {
+	roles, err := s.GetUserRoles(c.Params("id"))
+	if err != nil {
+		return roleErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": roles,
+	})
+}
+
+// GrantUserRoleRequest grants an additional role to a user
+// @Summary Grant User Role
+// @Description Give a user an additional role; permissions of all roles are combined (admin only)
+// @Tags RBAC
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param body body GrantUserRoleRequest true "Role ID or name"
+// @Success 200 {object} map[string]interface{} "Role granted"
+// @Failure 409 {object} map[string]interface{} "User already has the role"
+// @Router /admin/users/{id}/roles [post]
+func (s *RoleService) GrantUserRoleRequest(c *fiber.Ctx) error {
+	var req GrantUserRoleRequest
+	if err := c.BodyParser(&req); err != nil || strings.TrimSpace(req.Role) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error": "Invalid request body",
+			"message": "role (ID or name) is required",
+			"code": "MISSING_ROLE",
+		})
+	}
+
+	adminID, _ := c.Locals("user_id").(string)
+
+	roles, err := s.GrantUserRole(c.Params("id"), req.Role, adminID)
+	if err != nil {
+		return roleErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Role granted",
+		"code": "ROLE_GRANTED",
+		"data": roles,
+	})
+}
+
+// RevokeUserRoleRequest revokes an additional role from a user
+// @Summary Revoke User Role
+// @Tags RBAC
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param role_id path string true "Role ID"
+// @Success 200 {object} map[string]interface{} "Role revoked"
+// @Failure 409 {object} map[string]interface{} "Primary role cannot be revoked"
+// @Router /admin/users/{id}/roles/{role_id} [delete]
+func (s *RoleService) RevokeUserRoleRequest(c *fiber.Ctx) error {
+	roles, err := s.RevokeUserRole(c.Params("id"), c.Params("role_id"))
+	if err != nil {
+		return roleErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Role revoked",
+		"code": "ROLE_REVOKED",
+		"data": roles,
+	})
+}
+
+// GetPermissionsRequest lists permissions
+// @Summary List Permissions
+// @Tags RBAC
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Permissions"
+// @Router /admin/permissions [get]
+func (s *RoleService) GetPermissionsRequest(c *fiber.Ctx) error {
+	permissions, err := s.permissionRepo.GetAll()
+	if err != nil {
+		return roleErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": permissions,
+		"total": len(permissions),
+	})
+}
+
+// CreatePermissionRequest defines a new resource:action permission
+// @Summary Create Permission
+// @Tags RBAC
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body PermissionRequest true "Permission"
+// @Success 201 {object} map[string]interface{} "Permission created"
+// @Failure 409 {object} map[string]interface{} "Permission already exists"
+// @Router /admin/permissions [post]
+func (s *RoleService) CreatePermissionRequest(c *fiber.Ctx) error {
+	var req PermissionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return invalidRoleBody(c)
+	}
+
+	permission, err := s.CreatePermission(&req)
+	if err != nil {
+		return roleErrorResponse(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"message": "Permission created",
+		"code": "PERMISSION_CREATED",
+		"data": permission,
+	})
+}
+
+// UpdatePermissionRequest changes a permission's description
+// @Summary Update Permission
+// @Tags RBAC
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Permission ID"
+// @Param body body PermissionRequest true "Description (resource and action cannot change)"
+// @Success 200 {object} map[string]interface{} "Permission updated"
+// @Router /admin/permissions/{id} [put]
+func (s *RoleService) UpdatePermissionRequest(c *fiber.Ctx) error {
+	var req PermissionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return invalidRoleBody(c)
+	}
+
+	permission, err := s.UpdatePermission(c.Params("id"), req.Description)
+	if err != nil {
+		return roleErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Permission updated",
+		"code": "PERMISSION_UPDATED",
+		"data": permission,
+	})
+}
+
+// DeletePermissionRequest deletes a permission and all its grants
+// @Summary Delete Permission
+// @Tags RBAC
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Permission ID"
+// @Success 200 {object} map[string]interface{} "Permission deleted"
+// @Router /admin/permissions/{id} [delete]
+func (s *RoleService) DeletePermissionRequest(c *fiber.Ctx) error {
+	if err := s.DeletePermission(c.Params("id")); err != nil {
+		return roleErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Permission deleted and revoked from all roles",
+		"code": "PERMISSION_DELETED",
+	})
+}
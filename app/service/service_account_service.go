@@ -0,0 +1,500 @@
+package service
+
+import (
+	"UASBE/app/model"
+	"UASBE/app/repository"
+	"database/sql"
+	"errors"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// ServiceAccountService lets admins create non-human accounts for campus systems
+// and issue them API keys. A key is shown once, stored hashed, and may only use
+// the scopes it was issued with.
+type ServiceAccountService struct {
+	authService        *AuthService
+	userRepo           *repository.UserRepository
+	serviceAccountRepo *repository.ServiceAccountRepository
+}
+
+func NewServiceAccountService(authService *AuthService, userRepo *repository.UserRepository, serviceAccountRepo *repository.ServiceAccountRepository) *ServiceAccountService {
+	return &ServiceAccountService{
+		authService:        authService,
+		userRepo:           userRepo,
+		serviceAccountRepo: serviceAccountRepo,
+	}
+}
+
+type CreateServiceAccountRequest struct {
+	Username    string `json:"username"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Role        string `json:"role"` // defaults to "service"
+}
+
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"` // 0 means the key does not expire
+}
+
+// CreatedAPIKey is returned once when a key is issued; only then is Key known
+type CreatedAPIKey struct {
+	Key    string       `json:"key"`
+	APIKey model.APIKey `json:"api_key"`
+}
+
+const (
+	// apiKeyPrefix marks API keys so they are recognisable in configs and secret scanners
+	apiKeyPrefix = "uask_"
+	// apiKeyDisplayLength is how much of a key is kept in plain text to tell keys apart
+	apiKeyDisplayLength = 12
+	// serviceAccountEmailDomain gives service accounts a unique address nobody receives mail at
+	serviceAccountEmailDomain = "service-accounts.invalid"
+	maxAPIKeyLifetimeDays     = 730
+)
+
+var (
+	serviceAccountUsernamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{2,49}$`)
+	apiKeyScopePattern            = regexp.MustCompile(`^[a-z0-9_]+:[a-z0-9_]+$`)
+)
+
+// GenerateAPIKey returns a new random API key and the prefix stored to identify it
+func GenerateAPIKey() (key string, prefix string, err error) {
+	token, err := generateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	key = apiKeyPrefix + token
+	return key, key[:apiKeyDisplayLength], nil
+}
+
+// NormalizeAPIKeyScopes trims, lower-cases and de-duplicates requested scopes and
+// checks that each has the "resource:action" form of a permission
+func NormalizeAPIKeyScopes(scopes []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var normalized []string
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if scope == "" || seen[scope] {
+			continue
+		}
+		if !apiKeyScopePattern.MatchString(scope) {
+			return nil, errors.New("invalid scope: " + scope)
+		}
+		seen[scope] = true
+		normalized = append(normalized, scope)
+	}
+
+	if len(normalized) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	return normalized, nil
+}
+
+// CreateServiceAccount creates the user behind a service account. It cannot log
+// in with a password; it only authenticates with API keys.
+func (s *ServiceAccountService) CreateServiceAccount(req CreateServiceAccountRequest, adminID string) (*model.ServiceAccount, error) {
+	username := strings.ToLower(strings.TrimSpace(req.Username))
+	if !serviceAccountUsernamePattern.MatchString(username) {
+		return nil, errors.New("username must be 3-50 lowercase letters, digits, dots, dashes or underscores")
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = username
+	}
+
+	roleName := strings.TrimSpace(req.Role)
+	if roleName == "" {
+		roleName = "service"
+	}
+	if roleName == "admin" {
+		return nil, errors.New("service accounts cannot be administrators")
+	}
+	roleID, err := s.authService.getRoleIDByName(roleName)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.userRepo.GetByUsername(username); err == nil {
+		return nil, errors.New("username already exists")
+	}
+
+	unusablePassword, err := s.authService.passwordHasher.Hash(uuid.New().String())
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user := &model.User{
+		Username:        username,
+		Email:           username + "@" + serviceAccountEmailDomain,
+		Password:        unusablePassword,
+		FullName:        name,
+		RoleID:          roleID,
+		IsActive:        true,
+		ApprovalStatus:  model.ApprovalStatusApproved,
+		EmailVerifiedAt: &now,
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, err
+	}
+
+	if err := s.serviceAccountRepo.Create(user.ID, strings.TrimSpace(req.Description), adminID); err != nil {
+		// Without the service_accounts row the user would be an orphan nobody manages
+		if delErr := s.userRepo.Delete(user.ID); delErr != nil {
+			log.Printf("Failed to remove user %s of failed service account: %v", user.ID, delErr)
+		}
+		return nil, err
+	}
+
+	log.Printf("Admin %s created service account %s", adminID, username)
+	return s.serviceAccountRepo.GetByUserID(user.ID)
+}
+
+// getServiceAccount loads a service account, mapping a missing one to "service account not found"
+func (s *ServiceAccountService) getServiceAccount(id string) (*model.ServiceAccount, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, errors.New("service account not found")
+	}
+
+	account, err := s.serviceAccountRepo.GetByUserID(id)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("service account not found")
+	}
+	return account, err
+}
+
+// DeactivateServiceAccount disables the account and revokes all its keys. The
+// account is kept so the audit trail and key history stay readable.
+func (s *ServiceAccountService) DeactivateServiceAccount(id, adminID string) error {
+	account, err := s.getServiceAccount(id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.serviceAccountRepo.Deactivate(account.UserID); err != nil {
+		return err
+	}
+
+	log.Printf("Admin %s deactivated service account %s", adminID, account.Username)
+	return nil
+}
+
+// CreateAPIKey issues a key for a service account. Scopes must be permissions the
+// account's role holds; a key can narrow the role but never widen it.
+func (s *ServiceAccountService) CreateAPIKey(accountID string, req CreateAPIKeyRequest, adminID string) (*CreatedAPIKey, error) {
+	account, err := s.getServiceAccount(accountID)
+	if err != nil {
+		return nil, err
+	}
+	if !account.IsActive {
+		return nil, errors.New("service account is deactivated")
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	scopes, err := NormalizeAPIKeyScopes(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved, err := s.authService.ResolveRole(account.RoleID)
+	if err != nil {
+		return nil, err
+	}
+	for _, scope := range scopes {
+		if !resolved.Has(scope) {
+			return nil, errors.New("role " + resolved.Role.Name + " does not have permission " + scope)
+		}
+	}
+
+	if req.ExpiresInDays < 0 || req.ExpiresInDays > maxAPIKeyLifetimeDays {
+		return nil, errors.New("expires_in_days must be between 0 and 730")
+	}
+
+	plainKey, prefix, err := GenerateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	key := &model.APIKey{
+		UserID:    account.UserID,
+		Name:      name,
+		KeyPrefix: prefix,
+		KeyHash:   hashToken(plainKey),
+		Scopes:    scopes,
+		CreatedBy: adminID,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		key.ExpiresAt = &expiresAt
+	}
+
+	if err := s.serviceAccountRepo.CreateAPIKey(key); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Admin %s issued API key %s (%s) for service account %s", adminID, key.ID, key.KeyPrefix, account.Username)
+	return &CreatedAPIKey{Key: plainKey, APIKey: *key}, nil
+}
+
+// GetAPIKeys lists the keys of a service account, without their secrets
+func (s *ServiceAccountService) GetAPIKeys(accountID string) ([]model.APIKey, error) {
+	account, err := s.getServiceAccount(accountID)
+	if err != nil {
+		return nil, err
+	}
+	return s.serviceAccountRepo.GetAPIKeys(account.UserID)
+}
+
+// RevokeAPIKey revokes a key immediately
+func (s *ServiceAccountService) RevokeAPIKey(accountID, keyID, adminID string) error {
+	account, err := s.getServiceAccount(accountID)
+	if err != nil {
+		return err
+	}
+	if _, err := uuid.Parse(keyID); err != nil {
+		return errors.New("api key not found")
+	}
+
+	revoked, err := s.serviceAccountRepo.RevokeAPIKey(keyID, account.UserID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return errors.New("api key not found")
+	}
+
+	log.Printf("Admin %s revoked API key %s of service account %s", adminID, keyID, account.Username)
+	return nil
+}
+
+// serviceAccountErrorResponse maps the errors of this service to HTTP responses
+func serviceAccountErrorResponse(c *fiber.Ctx, err error) error {
+	message := err.Error()
+	switch {
+	case message == "service account not found", message == "api key not found", message == "role not found":
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error": message,
+			"message": "The requested resource does not exist",
+			"code": "NOT_FOUND",
+		})
+	case message == "username already exists":
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"success": false,
+			"error": message,
+			"message": "Please choose a different username",
+			"code": "USERNAME_EXISTS",
+		})
+	case message == "service accounts cannot be administrators", message == "service account is deactivated",
+		strings.HasPrefix(message, "role ") && strings.Contains(message, "does not have permission"):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"success": false,
+			"error": message,
+			"message": "The request is not allowed for this service account",
+			"code": "SERVICE_ACCOUNT_NOT_ALLOWED",
+		})
+	case strings.HasPrefix(message, "username must"), message == "name is required",
+		strings.HasPrefix(message, "invalid scope"), message == "at least one scope is required",
+		strings.HasPrefix(message, "expires_in_days"):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error": "Validation failed",
+			"message": message,
+			"code": "VALIDATION_ERROR",
+		})
+	}
+
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"success": false,
+		"error": "Service account operation failed",
+		"message": message,
+		"code": "SERVICE_ACCOUNT_FAILED",
+	})
+}
+
+// GetServiceAccountsRequest lists service accounts
+// @Summary List Service Accounts
+// @Description Non-human accounts used by campus systems, with their number of active API keys
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Service accounts"
+// @Router /admin/service-accounts [get]
+func (s *ServiceAccountService) GetServiceAccountsRequest(c *fiber.Ctx) error {
+	accounts, err := s.serviceAccountRepo.GetAll()
+	if err != nil {
+		return serviceAccountErrorResponse(c, err)
+	}
+	if accounts == nil {
+		accounts = []model.ServiceAccount{}
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": accounts,
+	})
+}
+
+// CreateServiceAccountRequest creates a service account
+// @Summary Create Service Account
+// @Description Create an account for a campus system. It has no usable password and authenticates only with API keys issued for it. The role defaults to "service" and cannot be admin
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body CreateServiceAccountRequest true "Service account"
+// @Success 201 {object} model.ServiceAccount "Service account created"
+// @Failure 400 {object} map[string]interface{} "Validation failed"
+// @Failure 409 {object} map[string]interface{} "Username already exists"
+// @Router /admin/service-accounts [post]
+func (s *ServiceAccountService) CreateServiceAccountRequest(c *fiber.Ctx) error {
+	var req CreateServiceAccountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error": "Invalid request body",
+			"message": "Please provide valid JSON data",
+			"code": "INVALID_REQUEST_BODY",
+		})
+	}
+
+	account, err := s.CreateServiceAccount(req, c.Locals("user_id").(string))
+	if err != nil {
+		return serviceAccountErrorResponse(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"message": "Service account created",
+		"code": "SERVICE_ACCOUNT_CREATED",
+		"data": account,
+		"next_steps": []string{
+			"Issue an API key with POST /api/admin/service-accounts/" + account.UserID + "/api-keys",
+		},
+	})
+}
+
+// DeactivateServiceAccountRequest deactivates a service account
+// @Summary Deactivate Service Account
+// @Description Deactivate a service account and revoke all of its API keys
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Service account ID"
+// @Success 200 {object} map[string]interface{} "Service account deactivated"
+// @Failure 404 {object} map[string]interface{} "Service account not found"
+// @Router /admin/service-accounts/{id} [delete]
+func (s *ServiceAccountService) DeactivateServiceAccountRequest(c *fiber.Ctx) error {
+	if err := s.DeactivateServiceAccount(c.Params("id"), c.Locals("user_id").(string)); err != nil {
+		return serviceAccountErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Service account deactivated and its API keys revoked",
+		"code": "SERVICE_ACCOUNT_DEACTIVATED",
+	})
+}
+
+// GetAPIKeysRequest lists the API keys of a service account
+// @Summary List API Keys
+// @Description Keys of a service account with their scopes and last use. Secrets are never returned
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Service account ID"
+// @Success 200 {object} map[string]interface{} "API keys"
+// @Failure 404 {object} map[string]interface{} "Service account not found"
+// @Router /admin/service-accounts/{id}/api-keys [get]
+func (s *ServiceAccountService) GetAPIKeysRequest(c *fiber.Ctx) error {
+	keys, err := s.GetAPIKeys(c.Params("id"))
+	if err != nil {
+		return serviceAccountErrorResponse(c, err)
+	}
+	if keys == nil {
+		keys = []model.APIKey{}
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": keys,
+	})
+}
+
+// CreateAPIKeyRequest issues an API key
+// @Summary Create API Key
+// @Description Issue an API key for a service account, limited to the given scopes ("resource:action" permissions held by its role). The key is only shown in this response; store it right away
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Service account ID"
+// @Param body body CreateAPIKeyRequest true "Key name, scopes and lifetime"
+// @Success 201 {object} CreatedAPIKey "API key created"
+// @Failure 400 {object} map[string]interface{} "Validation failed"
+// @Failure 403 {object} map[string]interface{} "Scope not held by the role"
+// @Failure 404 {object} map[string]interface{} "Service account not found"
+// @Router /admin/service-accounts/{id}/api-keys [post]
+func (s *ServiceAccountService) CreateAPIKeyRequest(c *fiber.Ctx) error {
+	var req CreateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error": "Invalid request body",
+			"message": "Please provide valid JSON data",
+			"code": "INVALID_REQUEST_BODY",
+		})
+	}
+
+	created, err := s.CreateAPIKey(c.Params("id"), req, c.Locals("user_id").(string))
+	if err != nil {
+		return serviceAccountErrorResponse(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"message": "API key created",
+		"code": "API_KEY_CREATED",
+		"data": created,
+		"next_steps": []string{
+			"Store the key now; it cannot be shown again",
+			"Send it in the X-API-Key header",
+		},
+	})
+}
+
+// RevokeAPIKeyRequest revokes an API key
+// @Summary Revoke API Key
+// @Description Revoke an API key of a service account; it stops working immediately
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Service account ID"
+// @Param key_id path string true "API key ID"
+// @Success 200 {object} map[string]interface{} "API key revoked"
+// @Failure 404 {object} map[string]interface{} "API key not found"
+// @Router /admin/service-accounts/{id}/api-keys/{key_id} [delete]
+func (s *ServiceAccountService) RevokeAPIKeyRequest(c *fiber.Ctx) error {
+	if err := s.RevokeAPIKey(c.Params("id"), c.Params("key_id"), c.Locals("user_id").(string)); err != nil {
+		return serviceAccountErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "API key revoked",
+		"code": "API_KEY_REVOKED",
+	})
+}
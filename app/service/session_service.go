@@ -0,0 +1,284 @@
+package service
+
+import (
+	"UASBE/app/model"
+	"UASBE/app/repository"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SessionService lets users see where they are logged in and end sessions;
+// admins can do the same for any user
+type SessionService struct {
+	authService *AuthService
+	sessionRepo *repository.SessionRepository
+	userRepo    *repository.UserRepository
+}
+
+func NewSessionService(authService *AuthService, sessionRepo *repository.SessionRepository, userRepo *repository.UserRepository) *SessionService {
+	return &SessionService{
+		authService: authService,
+		sessionRepo: sessionRepo,
+		userRepo:    userRepo,
+	}
+}
+
+// GetSessions lists the active sessions of a user; currentSessionID is flagged
+func (s *SessionService) GetSessions(userID, currentSessionID string) ([]model.Session, error) {
+	sessions, err := s.sessionRepo.GetActiveByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sessions {
+		sessions[i].Device = DescribeUserAgent(sessions[i].UserAgent)
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession ends a session of the given user. Sessions of other users are
+// reported as not found so their IDs cannot be probed.
+func (s *SessionService) RevokeSession(userID, sessionID string) error {
+	session, err := s.sessionRepo.GetByID(sessionID)
+	if err != nil || session.UserID != userID {
+		return errors.New("session not found")
+	}
+
+	revoked, err := s.authService.RevokeSession(session.ID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return errors.New("session already ended")
+	}
+
+	return nil
+}
+
+// DescribeUserAgent turns a User-Agent header into a short device label such as
+// "Chrome on Windows" for the session list
+func DescribeUserAgent(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	ua := strings.ToLower(userAgent)
+
+	// Non-browser clients
+	for _, client := range []struct{ token, name string }{
+		{"postmanruntime", "Postman"},
+		{"insomnia", "Insomnia"},
+		{"curl/", "curl"},
+		{"okhttp", "Android app"},
+		{"python-requests", "Python script"},
+		{"go-http-client", "Go client"},
+	} {
+		if strings.Contains(ua, client.token) {
+			return client.name
+		}
+	}
+
+	// Order matters: Edge and Opera also claim Chrome, Chrome also claims Safari
+	browser := "Browser"
+	switch {
+	case strings.Contains(ua, "edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "opr/") || strings.Contains(ua, "opera"):
+		browser = "Opera"
+	case strings.Contains(ua, "firefox/") || strings.Contains(ua, "fxios/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "chrome/") || strings.Contains(ua, "crios/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "safari/"):
+		browser = "Safari"
+	}
+
+	// Order matters: Android and iOS user agents also mention Linux and Mac OS X
+	os := ""
+	switch {
+	case strings.Contains(ua, "android"):
+		os = "Android"
+	case strings.Contains(ua, "iphone") || strings.Contains(ua, "ipad"):
+		os = "iOS"
+	case strings.Contains(ua, "windows"):
+		os = "Windows"
+	case strings.Contains(ua, "mac os x") || strings.Contains(ua, "macintosh"):
+		os = "macOS"
+	case strings.Contains(ua, "cros"):
+		os = "ChromeOS"
+	case strings.Contains(ua, "linux"):
+		os = "Linux"
+	}
+
+	if os == "" {
+		if browser == "Browser" {
+			return "Unknown device"
+		}
+		return browser
+	}
+	return browser + " on " + os
+}
+
+func sessionErrorResponse(c *fiber.Ctx, err error) error {
+	switch err.Error() {
+	case "session not found", "session already ended":
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error": err.Error(),
+			"message": "Session not found or already ended",
+			"code": "SESSION_NOT_FOUND",
+		})
+	case "user not found":
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error": err.Error(),
+			"message": "User not found",
+			"code": "USER_NOT_FOUND",
+		})
+	}
+
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"success": false,
+		"error": "Session operation failed",
+		"message": err.Error(),
+		"code": "SESSION_OPERATION_FAILED",
+	})
+}
+
+// GetMySessionsRequest lists the caller's sessions
+// @Summary List My Sessions
+// @Description Active logins of the current user with device, IP address and last activity. The session of this request has current=true
+// @Tags Sessions
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Sessions"
+// @Router /auth/sessions [get]
+func (s *SessionService) GetMySessionsRequest(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	sessionID, _ := c.Locals("session_id").(string)
+
+	sessions, err := s.GetSessions(userID, sessionID)
+	if err != nil {
+		return sessionErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": sessions,
+		"total": len(sessions),
+	})
+}
+
+// GetMyLoginHistoryRequest lists the caller's login attempts
+// @Summary My Login History
+// @Description Successful and failed logins of the current user with IP address, network and device, newest first
+// @Tags Sessions
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page (max 100)" default(20)
+// @Success 200 {object} map[string]interface{} "Login history"
+// @Router /auth/login-history [get]
+func (s *SessionService) GetMyLoginHistoryRequest(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	history, err := s.authService.GetLoginHistory(userID, c.QueryInt("page", 1), c.QueryInt("limit", 20))
+	if err != nil {
+		return sessionErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": history.Attempts,
+		"pagination": fiber.Map{
+			"page": history.Page,
+			"limit": history.Limit,
+			"total": history.Total,
+			"total_pages": (history.Total + history.Limit - 1) / history.Limit,
+		},
+	})
+}
+
+// RevokeMySessionRequest ends one of the caller's sessions
+// @Summary Revoke My Session
+// @Description Log out one session (e.g. a lost phone). Revoking the current session is the same as logging out
+// @Tags Sessions
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Session ID"
+// @Success 200 {object} map[string]interface{} "Session revoked"
+// @Failure 404 {object} map[string]interface{} "Session not found"
+// @Router /auth/sessions/{id} [delete]
+func (s *SessionService) RevokeMySessionRequest(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	currentSessionID, _ := c.Locals("session_id").(string)
+
+	if err := s.RevokeSession(userID, c.Params("id")); err != nil {
+		return sessionErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Session revoked",
+		"code": "SESSION_REVOKED",
+		"data": fiber.Map{
+			"session_id": c.Params("id"),
+			"current": c.Params("id") == currentSessionID,
+		},
+	})
+}
+
+// GetUserSessionsRequest lists the sessions of any user
+// @Summary List User Sessions
+// @Description Active logins of a user (admin only)
+// @Tags Sessions
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]interface{} "Sessions"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Router /users/{id}/sessions [get]
+func (s *SessionService) GetUserSessionsRequest(c *fiber.Ctx) error {
+	user, err := s.userRepo.GetByID(c.Params("id"))
+	if err != nil {
+		return sessionErrorResponse(c, errors.New("user not found"))
+	}
+
+	sessions, err := s.GetSessions(user.ID, "")
+	if err != nil {
+		return sessionErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": sessions,
+		"total": len(sessions),
+	})
+}
+
+// RevokeUserSessionRequest ends a session of any user
+// @Summary Revoke User Session
+// @Description Log a user out of one session (admin only)
+// @Tags Sessions
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param session_id path string true "Session ID"
+// @Success 200 {object} map[string]interface{} "Session revoked"
+// @Failure 404 {object} map[string]interface{} "Session not found"
+// @Router /users/{id}/sessions/{session_id} [delete]
+func (s *SessionService) RevokeUserSessionRequest(c *fiber.Ctx) error {
+	if err := s.RevokeSession(c.Params("id"), c.Params("session_id")); err != nil {
+		return sessionErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Session revoked",
+		"code": "SESSION_REVOKED",
+	})
+}
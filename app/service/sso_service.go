@@ -0,0 +1,402 @@
+package service
+
+import (
+	"UASBE/app/model"
+	"UASBE/app/repository"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// SSOService implements single sign-on with the university identity provider
+// (OpenID Connect authorization code flow with PKCE). A provider account is
+// matched to a local user by its linked identity, then by verified email; unknown
+// students are provisioned on their first login.
+type SSOService struct {
+	authService  *AuthService
+	provider     *OIDCProvider
+	identityRepo *repository.IdentityRepository
+	userRepo     *repository.UserRepository
+	studentRepo  *repository.StudentRepository
+	stateTTL     time.Duration
+
+	// Claims that identify and describe students (e.g. "affiliation": "student")
+	roleClaim         string
+	studentRoleValues []string
+	studentIDClaim    string
+	programClaim      string
+	academicYearClaim string
+}
+
+// NewSSOService creates the service; provider may be nil when SSO is not configured
+func NewSSOService(authService *AuthService, provider *OIDCProvider, identityRepo *repository.IdentityRepository, userRepo *repository.UserRepository, studentRepo *repository.StudentRepository) *SSOService {
+	studentRoleValues := getEnvList("OIDC_STUDENT_ROLE_VALUES")
+	if len(studentRoleValues) == 0 {
+		studentRoleValues = []string{"student"}
+	}
+
+	return &SSOService{
+		authService:       authService,
+		provider:          provider,
+		identityRepo:      identityRepo,
+		userRepo:          userRepo,
+		studentRepo:       studentRepo,
+		stateTTL:          getEnvDuration("OIDC_STATE_TTL", 10*time.Minute),
+		roleClaim:         getEnvString("OIDC_ROLE_CLAIM", "affiliation"),
+		studentRoleValues: studentRoleValues,
+		studentIDClaim:    getEnvString("OIDC_STUDENT_ID_CLAIM", "student_id"),
+		programClaim:      getEnvString("OIDC_PROGRAM_STUDY_CLAIM", "program_study"),
+		academicYearClaim: getEnvString("OIDC_ACADEMIC_YEAR_CLAIM", "academic_year"),
+	}
+}
+
+// Enabled tells whether an identity provider is configured
+func (s *SSOService) Enabled() bool {
+	return s.provider != nil
+}
+
+// Authorize starts a login: it stores the state, nonce and PKCE verifier and
+// returns the identity provider URL to send the browser to
+func (s *SSOService) Authorize() (string, error) {
+	if !s.Enabled() {
+		return "", errors.New("sso is not configured")
+	}
+
+	state, err := generateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := generateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	verifier, err := GeneratePKCEVerifier()
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := s.provider.AuthCodeURL(state, nonce, PKCEChallenge(verifier))
+	if err != nil {
+		return "", err
+	}
+
+	record := &model.OIDCState{
+		StateHash:    hashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(s.stateTTL),
+	}
+	if err := s.identityRepo.CreateState(record); err != nil {
+		return "", err
+	}
+
+	// Housekeeping: drop requests that were never completed
+	s.identityRepo.DeleteExpiredStates()
+
+	return authURL, nil
+}
+
+// Callback completes a login with the code and state the provider redirected with
+func (s *SSOService) Callback(code, state string, client ClientInfo) (*LoginResponse, error) {
+	if !s.Enabled() {
+		return nil, errors.New("sso is not configured")
+	}
+
+	pending, err := s.identityRepo.ConsumeState(hashToken(state))
+	if err != nil {
+		return nil, errors.New("invalid or expired sso state")
+	}
+
+	tokens, err := s.provider.Exchange(code, pending.CodeVerifier)
+	if err != nil {
+		log.Printf("SSO code exchange failed: %v", err)
+		return nil, errors.New("sso code exchange failed")
+	}
+
+	identity, err := s.provider.VerifyIDToken(tokens.IDToken, pending.Nonce)
+	if err != nil {
+		log.Printf("SSO id token rejected: %v", err)
+		return nil, errors.New("invalid sso identity")
+	}
+
+	user, err := s.resolveUser(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.authService.LoginExternal(user, "sso:"+identity.Subject, client)
+}
+
+// resolveUser finds or provisions the local user of a provider identity
+func (s *SSOService) resolveUser(identity *OIDCIdentity) (*model.User, error) {
+	// 1. Identity linked at an earlier login
+	linked, err := s.identityRepo.GetByIssuerSubject(identity.Issuer, identity.Subject)
+	if err == nil {
+		user, err := s.userRepo.GetByID(linked.UserID)
+		if err != nil {
+			return nil, errors.New("no account for this identity")
+		}
+		s.identityRepo.TouchLogin(linked.ID, identity.Email)
+		return user, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	// Everything below trusts the email, so the provider must have verified it
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, errors.New("sso email not verified")
+	}
+
+	// 2. Existing local account (created by an admin or self-registered) with the same email
+	user, err := s.userRepo.GetByEmail(identity.Email)
+	if err != nil {
+		// 3. First login of a student: provision the account
+		if !s.isStudent(identity) {
+			return nil, errors.New("no account for this identity")
+		}
+		user, err = s.provisionStudent(identity)
+		if err != nil {
+			return nil, err
+		}
+	} else if user.EmailVerifiedAt == nil {
+		// The provider vouches for the address
+		if err := s.userRepo.MarkEmailVerified(user.ID, user.Email); err == nil {
+			now := time.Now()
+			user.EmailVerifiedAt = &now
+		}
+	}
+
+	link := &model.UserIdentity{
+		UserID:  user.ID,
+		Issuer:  identity.Issuer,
+		Subject: identity.Subject,
+		Email:   identity.Email,
+	}
+	if err := s.identityRepo.Create(link); err != nil {
+		return nil, err
+	}
+	s.identityRepo.TouchLogin(link.ID, identity.Email)
+
+	return user, nil
+}
+
+// isStudent checks the role claim of the identity against OIDC_STUDENT_ROLE_VALUES
+func (s *SSOService) isStudent(identity *OIDCIdentity) bool {
+	for _, value := range identity.ClaimStrings(s.roleClaim) {
+		for _, studentValue := range s.studentRoleValues {
+			if strings.EqualFold(value, studentValue) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// provisionStudent creates an approved, email-verified student account from the
+// identity's claims. The account has no usable password; the student signs in
+// through SSO (or sets a password with the reset flow).
+func (s *SSOService) provisionStudent(identity *OIDCIdentity) (*model.User, error) {
+	studentID := identity.ClaimString(s.studentIDClaim)
+	if studentID == "" {
+		return nil, errors.New("sso identity has no student ID")
+	}
+	if _, err := s.studentRepo.GetByStudentID(studentID); err == nil {
+		return nil, errors.New("student ID already belongs to another account")
+	}
+
+	username := s.availableUsername(identity, studentID)
+	if username == "" {
+		return nil, errors.New("no username available for this identity")
+	}
+
+	roleID, err := s.authService.getRoleIDByName("student")
+	if err != nil {
+		return nil, err
+	}
+
+	unusablePassword, err := s.authService.passwordHasher.Hash(uuid.New().String())
+	if err != nil {
+		return nil, err
+	}
+
+	fullName := identity.Name
+	if fullName == "" {
+		fullName = username
+	}
+
+	now := time.Now()
+	user := &model.User{
+		Username:        username,
+		Email:           identity.Email,
+		Password:        unusablePassword,
+		FullName:        fullName,
+		RoleID:          roleID,
+		IsActive:        true,
+		ApprovalStatus:  model.ApprovalStatusApproved,
+		EmailVerifiedAt: &now,
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, err
+	}
+
+	student := &model.Student{
+		UserID:       user.ID,
+		StudentID:    studentID,
+		ProgramStudy: identity.ClaimString(s.programClaim),
+		AcademicYear: identity.ClaimString(s.academicYearClaim),
+	}
+	if err := s.studentRepo.Create(student); err != nil {
+		// Rollback user creation if student creation fails
+		s.userRepo.Delete(user.ID)
+		return nil, err
+	}
+
+	log.Printf("SSO: provisioned student %s (%s) from %s", user.Username, studentID, identity.Issuer)
+	return user, nil
+}
+
+// availableUsername picks the first free candidate: the provider's username, the
+// local part of the email, then the student ID
+func (s *SSOService) availableUsername(identity *OIDCIdentity, studentID string) string {
+	candidates := []string{identity.PreferredUsername}
+	if at := strings.LastIndex(identity.Email, "@"); at > 0 {
+		candidates = append(candidates, identity.Email[:at])
+	}
+	candidates = append(candidates, studentID)
+
+	for _, candidate := range candidates {
+		candidate = strings.ToLower(strings.TrimSpace(candidate))
+		if candidate == "" || len(candidate) > 100 {
+			continue
+		}
+		if _, err := s.userRepo.GetByUsername(candidate); err != nil {
+			return candidate
+		}
+	}
+	return ""
+}
+
+func ssoErrorResponse(c *fiber.Ctx, err error) error {
+	status := fiber.StatusUnauthorized
+	var code, message string
+
+	switch err.Error() {
+	case "sso is not configured":
+		status = fiber.StatusNotFound
+		code = "SSO_NOT_CONFIGURED"
+		message = "Single sign-on is not enabled on this server"
+	case "invalid or expired sso state":
+		status = fiber.StatusBadRequest
+		code = "INVALID_SSO_STATE"
+		message = "The sign-in request expired or was already used. Please start again"
+	case "sso code exchange failed", "invalid sso identity":
+		code = "SSO_FAILED"
+		message = "The identity provider response could not be verified. Please start again"
+	case "sso email not verified":
+		status = fiber.StatusForbidden
+		code = "SSO_EMAIL_NOT_VERIFIED"
+		message = "Your campus account has no verified email address"
+	case "no account for this identity", "sso identity has no student ID":
+		status = fiber.StatusForbidden
+		code = "SSO_NO_ACCOUNT"
+		message = "There is no account for your campus identity. Please contact administrator"
+	case "student ID already belongs to another account", "no username available for this identity":
+		status = fiber.StatusConflict
+		code = "SSO_ACCOUNT_CONFLICT"
+		message = "Your campus identity conflicts with an existing account. Please contact administrator"
+	case "account is deactivated":
+		code = "ACCOUNT_DEACTIVATED"
+		message = "Your account has been deactivated. Please contact administrator"
+	case "account is pending approval":
+		code = "ACCOUNT_PENDING_APPROVAL"
+		message = "Your registration is waiting for administrator approval"
+	case "registration was rejected":
+		code = "REGISTRATION_REJECTED"
+		message = "Your registration was rejected. Please contact administrator"
+	default:
+		status = fiber.StatusInternalServerError
+		code = "SSO_LOGIN_FAILED"
+		message = "Single sign-on failed. Please try again"
+	}
+
+	return c.Status(status).JSON(fiber.Map{
+		"success": false,
+		"error": err.Error(),
+		"message": message,
+		"code": code,
+	})
+}
+
+// SSOLoginRequest starts single sign-on
+// @Summary SSO Login
+// @Description Redirect to the university identity provider (OpenID Connect, PKCE). With ?mode=json the authorization URL is returned instead of a redirect
+// @Tags Authentication
+// @Produce json
+// @Param mode query string false "json to get the URL instead of a redirect"
+// @Success 302 "Redirect to the identity provider"
+// @Failure 404 {object} map[string]interface{} "SSO not configured"
+// @Router /auth/sso/login [get]
+func (s *SSOService) SSOLoginRequest(c *fiber.Ctx) error {
+	authURL, err := s.Authorize()
+	if err != nil {
+		return ssoErrorResponse(c, err)
+	}
+
+	if c.Query("mode") == "json" {
+		return c.JSON(fiber.Map{
+			"success": true,
+			"data": fiber.Map{
+				"authorization_url": authURL,
+			},
+		})
+	}
+
+	return c.Redirect(authURL, fiber.StatusFound)
+}
+
+// SSOCallbackRequest completes single sign-on
+// @Summary SSO Callback
+// @Description Redirect target of the identity provider. Verifies the response and returns the same data as POST /auth/login
+// @Tags Authentication
+// @Produce json
+// @Param code query string true "Authorization code"
+// @Param state query string true "State from the login request"
+// @Success 200 {object} map[string]interface{} "Login successful"
+// @Failure 400 {object} map[string]interface{} "Invalid or expired request"
+// @Failure 403 {object} map[string]interface{} "No account for this identity"
+// @Router /auth/sso/callback [get]
+func (s *SSOService) SSOCallbackRequest(c *fiber.Ctx) error {
+	// The user cancelled or the provider refused
+	if providerError := c.Query("error"); providerError != "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"success": false,
+			"error": providerError,
+			"message": "The identity provider did not sign you in: " + c.Query("error_description", providerError),
+			"code": "SSO_DENIED",
+		})
+	}
+
+	code := c.Query("code")
+	state := c.Query("state")
+	if code == "" || state == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error": "Validation failed",
+			"message": "code and state are required",
+			"code": "MISSING_FIELDS",
+		})
+	}
+
+	response, err := s.Callback(code, state, ClientInfo{IP: c.IP(), UserAgent: c.Get("User-Agent")})
+	if err != nil {
+		return ssoErrorResponse(c, err)
+	}
+
+	return s.authService.loginSuccessResponse(c, response)
+}
@@ -0,0 +1,603 @@
+package service
+
+import (
+	"UASBE/app/repository"
+	"UASBE/mail"
+	"database/sql"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// recoveryCodeCount is how many one-time recovery codes a user gets on enrolment
+const recoveryCodeCount = 10
+
+// maxTwoFactorAttempts wrong codes invalidate the login challenge
+const maxTwoFactorAttempts = 5
+
+type TwoFactorService struct {
+	authService   *AuthService
+	twoFactorRepo *repository.TwoFactorRepository
+	userRepo      *repository.UserRepository
+	secrets       *secretBox
+	issuer        string
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type TwoFactorVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code,omitempty"`
+	RecoveryCode   string `json:"recovery_code,omitempty"`
+}
+
+type TwoFactorDisableRequest struct {
+	Password     string `json:"password"`
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+func NewTwoFactorService(authService *AuthService, twoFactorRepo *repository.TwoFactorRepository, userRepo *repository.UserRepository) *TwoFactorService {
+	key := os.Getenv("TOTP_ENCRYPTION_KEY")
+	if key == "" {
+		key = "your-totp-encryption-key-change-in-production"
+		log.Println("Warning: Using default TOTP encryption key. Please set TOTP_ENCRYPTION_KEY in production.")
+	}
+
+	secrets, err := newSecretBox(key)
+	if err != nil {
+		log.Fatalf("Failed to initialize TOTP secret encryption: %v", err)
+	}
+
+	issuer := os.Getenv("TOTP_ISSUER")
+	if issuer == "" {
+		issuer = "UAS Achievement System"
+	}
+
+	return &TwoFactorService{
+		authService:   authService,
+		twoFactorRepo: twoFactorRepo,
+		userRepo:      userRepo,
+		secrets:       secrets,
+		issuer:        issuer,
+	}
+}
+
+// Setup generates a new pending secret and returns it with its provisioning URI
+func (s *TwoFactorService) Setup(userID string) (string, string, error) {
+	enrolment, err := s.twoFactorRepo.GetByUserID(userID)
+	if err == nil && enrolment.Enabled {
+		return "", "", errors.New("two-factor already enabled")
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return "", "", errors.New("user not found")
+	}
+
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	sealed, err := s.secrets.Seal(secret)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := s.twoFactorRepo.SavePendingSecret(userID, sealed); err != nil {
+		return "", "", err
+	}
+
+	return secret, TOTPProvisioningURI(s.issuer, user.Username, secret), nil
+}
+
+// Enable confirms the pending secret with a code and returns fresh recovery codes
+func (s *TwoFactorService) Enable(userID, code string) ([]string, error) {
+	enrolment, err := s.twoFactorRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, errors.New("two-factor setup not started")
+	}
+	if enrolment.Enabled {
+		return nil, errors.New("two-factor already enabled")
+	}
+
+	secret, err := s.secrets.Open(enrolment.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	step, ok := ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return nil, errors.New("invalid two-factor code")
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.twoFactorRepo.Enable(userID, step, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Verify completes a two-step login: the challenge from the password step is
+// exchanged, together with a TOTP or recovery code, for the real token pair.
+// The challenge is single-use and is burnt after too many wrong codes.
+func (s *TwoFactorService) Verify(challengeToken, code, recoveryCode string, client ClientInfo) (*LoginResponse, error) {
+	claims, err := s.authService.ValidateTwoFactorChallenge(challengeToken)
+	if err != nil {
+		return nil, errors.New("invalid or expired challenge")
+	}
+
+	userID, _ := (*claims)["user_id"].(string)
+	jti, _ := (*claims)["jti"].(string)
+	expiresAt, _ := claims.GetExpirationTime()
+
+	// Wrong codes count towards the account lockout like wrong passwords
+	if err := s.authService.checkLoginThrottle(userID, client.IP); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkSecondFactor(userID, code, recoveryCode); err != nil {
+		if err.Error() != "invalid two-factor code" {
+			return nil, err
+		}
+
+		s.authService.RecordSecondFactorFailure(userID, client)
+
+		attempts, countErr := s.twoFactorRepo.IncrementFailedAttempts(userID)
+		if countErr == nil && attempts >= maxTwoFactorAttempts {
+			s.authService.tokenRepo.RevokeAccessToken(jti, userID, expiresAt.Time)
+			s.twoFactorRepo.ResetFailedAttempts(userID)
+			return nil, errors.New("too many attempts")
+		}
+		return nil, err
+	}
+
+	// The challenge cannot be exchanged twice
+	if err := s.authService.tokenRepo.RevokeAccessToken(jti, userID, expiresAt.Time); err != nil {
+		return nil, errors.New("failed to complete login")
+	}
+
+	return s.authService.CompleteLogin(userID, client)
+}
+
+// Disable turns TOTP off after re-checking the password and a second factor.
+// Users holding a role that requires TOTP cannot disable it.
+func (s *TwoFactorService) Disable(userID string, roleNames []string, password, code, recoveryCode string) error {
+	if s.authService.RequiresTwoFactor(roleNames...) {
+		return errors.New("two-factor required for role")
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+
+	if !s.authService.verifyPassword(user, password) {
+		return errors.New("invalid password")
+	}
+
+	if err := s.checkSecondFactor(userID, code, recoveryCode); err != nil {
+		return err
+	}
+
+	if err := s.twoFactorRepo.Disable(userID); err != nil {
+		return err
+	}
+
+	s.authService.sendMail(&mail.Message{
+		To:      []string{user.Email},
+		Subject: "Two-factor authentication disabled",
+		Body: "Hello " + user.FullName + ",\n\n" +
+			"Two-factor authentication was just disabled for your account (" + user.Username + ").\n" +
+			"If this was not you, change your password and contact the administrator immediately.\n",
+	})
+
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking a current TOTP code
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID, code string) ([]string, error) {
+	if err := s.checkSecondFactor(userID, code, ""); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.twoFactorRepo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// checkSecondFactor accepts either a TOTP code (each time step only once) or an
+// unused recovery code of an enabled enrolment
+func (s *TwoFactorService) checkSecondFactor(userID, code, recoveryCode string) error {
+	enrolment, err := s.twoFactorRepo.GetByUserID(userID)
+	if err == sql.ErrNoRows || (err == nil && !enrolment.Enabled) {
+		return errors.New("two-factor not enabled")
+	}
+	if err != nil {
+		return err
+	}
+
+	if recoveryCode != "" {
+		used, err := s.twoFactorRepo.ConsumeRecoveryCode(userID, hashToken(NormalizeRecoveryCode(recoveryCode)))
+		if err != nil {
+			return err
+		}
+		if !used {
+			return errors.New("invalid two-factor code")
+		}
+		return nil
+	}
+
+	secret, err := s.secrets.Open(enrolment.Secret)
+	if err != nil {
+		return err
+	}
+
+	step, ok := ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return errors.New("invalid two-factor code")
+	}
+
+	fresh, err := s.twoFactorRepo.MarkStepUsed(userID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		// Same code already used: treat a replay like a wrong code
+		return errors.New("invalid two-factor code")
+	}
+
+	return nil
+}
+
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = hashToken(NormalizeRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// twoFactorErrorResponse maps the errors of this service to HTTP responses
+func twoFactorErrorResponse(c *fiber.Ctx, err error) error {
+	status := fiber.StatusBadRequest
+	var code, message string
+
+	switch err.Error() {
+	case "invalid two-factor code":
+		code = "INVALID_TWO_FACTOR_CODE"
+		message = "The code is invalid or was already used"
+	case "invalid or expired challenge":
+		status = fiber.StatusUnauthorized
+		code = "INVALID_CHALLENGE"
+		message = "The login challenge is invalid or expired, please log in again"
+	case "too many attempts":
+		status = fiber.StatusUnauthorized
+		code = "TOO_MANY_ATTEMPTS"
+		message = "Too many wrong codes, please log in again"
+	case "two-factor already enabled":
+		status = fiber.StatusConflict
+		code = "TWO_FACTOR_ALREADY_ENABLED"
+		message = "Two-factor authentication is already enabled. Disable it first to enrol a new device"
+	case "two-factor setup not started":
+		code = "TWO_FACTOR_SETUP_NOT_STARTED"
+		message = "Call POST /api/auth/2fa/setup first"
+	case "two-factor not enabled":
+		code = "TWO_FACTOR_NOT_ENABLED"
+		message = "Two-factor authentication is not enabled for this account"
+	case "two-factor required for role":
+		status = fiber.StatusForbidden
+		code = "TWO_FACTOR_REQUIRED_FOR_ROLE"
+		message = "Two-factor authentication is mandatory for your role"
+	case "invalid password":
+		status = fiber.StatusUnauthorized
+		code = "INVALID_PASSWORD"
+		message = "Password is incorrect"
+	case "account is deactivated":
+		status = fiber.StatusUnauthorized
+		code = "ACCOUNT_DEACTIVATED"
+		message = "Your account has been deactivated. Please contact administrator"
+	default:
+		status = fiber.StatusInternalServerError
+		code = "TWO_FACTOR_FAILED"
+		message = "Two-factor operation failed"
+	}
+
+	return c.Status(status).JSON(fiber.Map{
+		"success": false,
+		"error":   err.Error(),
+		"message": message,
+		"code":    code,
+	})
+}
+
+// StatusRequest shows the two-factor state of the current user
+// @Summary Two-Factor Status
+// @Description Whether TOTP is enabled or required for the current user and how many recovery codes are left
+// @Tags Two-Factor Authentication
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Two-factor status"
+// @Router /auth/2fa [get]
+func (s *TwoFactorService) StatusRequest(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	roleNames := requestRoles(c)
+
+	enabled := false
+	var confirmedAt *time.Time
+	remaining := 0
+
+	enrolment, err := s.twoFactorRepo.GetByUserID(userID)
+	if err == nil && enrolment.Enabled {
+		enabled = true
+		confirmedAt = enrolment.ConfirmedAt
+		remaining, _ = s.twoFactorRepo.CountRemainingRecoveryCodes(userID)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"enabled": enabled,
+			"required": s.authService.RequiresTwoFactor(roleNames...),
+			"confirmed_at": confirmedAt,
+			"recovery_codes_remaining": remaining,
+		},
+	})
+}
+
+// SetupRequest starts TOTP enrolment
+// @Summary Start Two-Factor Setup
+// @Description Generate a TOTP secret and otpauth:// provisioning URI (render it as a QR code). Accepts an access token or the setup token from login
+// @Tags Two-Factor Authentication
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Secret and provisioning URI"
+// @Failure 409 {object} map[string]interface{} "Already enabled"
+// @Router /auth/2fa/setup [post]
+func (s *TwoFactorService) SetupRequest(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	secret, uri, err := s.Setup(userID)
+	if err != nil {
+		return twoFactorErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Scan the QR code with your authenticator app, then confirm with a code",
+		"code": "TWO_FACTOR_SETUP_STARTED",
+		"data": fiber.Map{
+			"secret": secret,
+			"otpauth_uri": uri,
+			"issuer": s.issuer,
+			"digits": totpDigits,
+			"period": totpPeriod,
+		},
+		"next_steps": []string{
+			"Add the account to your authenticator app by scanning a QR code of otpauth_uri (or enter the secret manually)",
+			"Confirm with POST /api/auth/2fa/enable and the current 6-digit code",
+		},
+	})
+}
+
+// EnableRequest confirms TOTP enrolment
+// @Summary Enable Two-Factor
+// @Description Confirm the pending secret with a TOTP code. Returns one-time recovery codes, and login tokens when called with a setup token
+// @Tags Two-Factor Authentication
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body TwoFactorCodeRequest true "Current TOTP code"
+// @Success 200 {object} map[string]interface{} "Two-factor enabled"
+// @Failure 400 {object} map[string]interface{} "Invalid code"
+// @Router /auth/2fa/enable [post]
+func (s *TwoFactorService) EnableRequest(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error": "Invalid request body",
+			"message": "code is required",
+			"code": "MISSING_CODE",
+		})
+	}
+
+	codes, err := s.Enable(userID, req.Code)
+	if err != nil {
+		return twoFactorErrorResponse(c, err)
+	}
+
+	data := fiber.Map{
+		"recovery_codes": codes,
+	}
+
+	// Enrolment forced at login: the setup token is burnt and the login completes
+	if c.Locals("token_type") == tokenTypeTwoFactorSetup {
+		jti, _ := c.Locals("jti").(string)
+		expiresAt, _ := c.Locals("token_expires_at").(time.Time)
+		s.authService.tokenRepo.RevokeAccessToken(jti, userID, expiresAt)
+
+		response, err := s.authService.CompleteLogin(userID, ClientInfo{IP: c.IP(), UserAgent: c.Get("User-Agent")})
+		if err != nil {
+			return twoFactorErrorResponse(c, err)
+		}
+		data["token"] = response.Token
+		data["expires_at"] = response.ExpiresAt
+		data["refresh_token"] = response.RefreshToken
+		data["refresh_expires_at"] = response.RefreshExpiresAt
+		data["session_id"] = response.SessionID
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Two-factor authentication enabled. Store the recovery codes somewhere safe, they are shown only once",
+		"code": "TWO_FACTOR_ENABLED",
+		"data": data,
+	})
+}
+
+// VerifyRequest is the second step of a two-step login
+// @Summary Verify Two-Factor Login
+// @Description Exchange the challenge token from login and a TOTP code (or a recovery code) for an access and refresh token
+// @Tags Two-Factor Authentication
+// @Accept json
+// @Produce json
+// @Param body body TwoFactorVerifyRequest true "Challenge token and code"
+// @Success 200 {object} map[string]interface{} "Login successful"
+// @Failure 400 {object} map[string]interface{} "Invalid code"
+// @Failure 401 {object} map[string]interface{} "Invalid challenge or too many attempts"
+// @Router /auth/2fa/verify [post]
+func (s *TwoFactorService) VerifyRequest(c *fiber.Ctx) error {
+	var req TwoFactorVerifyRequest
+	if err := c.BodyParser(&req); err != nil || req.ChallengeToken == "" || (req.Code == "" && req.RecoveryCode == "") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error": "Invalid request body",
+			"message": "challenge_token and either code or recovery_code are required",
+			"code": "MISSING_FIELDS",
+		})
+	}
+
+	response, err := s.Verify(req.ChallengeToken, req.Code, req.RecoveryCode, ClientInfo{IP: c.IP(), UserAgent: c.Get("User-Agent")})
+	if err != nil {
+		var throttled *LoginThrottledError
+		if errors.As(err, &throttled) {
+			retryAfter := int(throttled.RetryAfter.Seconds() + 0.999)
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"success": false,
+				"error": err.Error(),
+				"message": "Too many failed login attempts. Please wait before trying again",
+				"code": "TOO_MANY_LOGIN_ATTEMPTS",
+				"retry_after_seconds": retryAfter,
+				"locked": throttled.Locked,
+			})
+		}
+		return twoFactorErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Login successful",
+		"code": "LOGIN_SUCCESS",
+		"data": fiber.Map{
+			"token": response.Token,
+			"expires_at": response.ExpiresAt,
+			"expires_in_seconds": int(time.Until(response.ExpiresAt).Seconds()),
+			"refresh_token": response.RefreshToken,
+			"refresh_expires_at": response.RefreshExpiresAt,
+			"user": fiber.Map{
+				"id": response.User.ID,
+				"username": response.User.Username,
+				"email": response.User.Email,
+				"full_name": response.User.FullName,
+			},
+			"role": fiber.Map{
+				"id": response.Role.ID,
+				"name": response.Role.Name,
+			},
+			"permissions": response.Permissions,
+		},
+	})
+}
+
+// DisableRequest turns TOTP off
+// @Summary Disable Two-Factor
+// @Description Disable TOTP after confirming the password and a TOTP or recovery code. Not allowed for roles that require two-factor
+// @Tags Two-Factor Authentication
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body TwoFactorDisableRequest true "Password and code"
+// @Success 200 {object} map[string]interface{} "Two-factor disabled"
+// @Failure 403 {object} map[string]interface{} "Required for role"
+// @Router /auth/2fa/disable [post]
+func (s *TwoFactorService) DisableRequest(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	roleNames := requestRoles(c)
+
+	var req TwoFactorDisableRequest
+	if err := c.BodyParser(&req); err != nil || req.Password == "" || (req.Code == "" && req.RecoveryCode == "") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error": "Invalid request body",
+			"message": "password and either code or recovery_code are required",
+			"code": "MISSING_FIELDS",
+		})
+	}
+
+	if err := s.Disable(userID, roleNames, req.Password, req.Code, req.RecoveryCode); err != nil {
+		return twoFactorErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Two-factor authentication disabled",
+		"code": "TWO_FACTOR_DISABLED",
+	})
+}
+
+// RecoveryCodesRequest regenerates recovery codes
+// @Summary Regenerate Recovery Codes
+// @Description Replace all recovery codes. Requires a current TOTP code
+// @Tags Two-Factor Authentication
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body TwoFactorCodeRequest true "Current TOTP code"
+// @Success 200 {object} map[string]interface{} "New recovery codes"
+// @Router /auth/2fa/recovery-codes [post]
+func (s *TwoFactorService) RecoveryCodesRequest(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error": "Invalid request body",
+			"message": "code is required",
+			"code": "MISSING_CODE",
+		})
+	}
+
+	codes, err := s.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		return twoFactorErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Recovery codes regenerated. Previous codes no longer work",
+		"code": "RECOVERY_CODES_REGENERATED",
+		"data": fiber.Map{
+			"recovery_codes": codes,
+		},
+	})
+}
@@ -2,19 +2,23 @@ package service
 
 import (
 	"UASBE/app/model"
+	"UASBE/app/policy"
 	"UASBE/app/repository"
 	"errors"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type UserService struct {
 	userRepo     *repository.UserRepository
 	studentRepo  *repository.StudentRepository
 	lecturerRepo *repository.LecturerRepository
+	authService  *AuthService
+
+	adminScopeService *AdminScopeService
 }
 
 type CreateUserRequest struct {
@@ -55,11 +59,13 @@ type UpdateUserRequest struct {
 	Department   string `json:"department,omitempty"`
 }
 
-func NewUserService(userRepo *repository.UserRepository, studentRepo *repository.StudentRepository, lecturerRepo *repository.LecturerRepository) *UserService {
+func NewUserService(userRepo *repository.UserRepository, studentRepo *repository.StudentRepository, lecturerRepo *repository.LecturerRepository, authService *AuthService, adminScopeService *AdminScopeService) *UserService {
 	return &UserService{
-		userRepo:     userRepo,
-		studentRepo:  studentRepo,
-		lecturerRepo: lecturerRepo,
+		userRepo:          userRepo,
+		studentRepo:       studentRepo,
+		lecturerRepo:      lecturerRepo,
+		authService:       authService,
+		adminScopeService: adminScopeService,
 	}
 }
 
@@ -80,8 +86,9 @@ func (s *UserService) CreateUserRequest(c *fiber.Ctx) error {
 	userRole := c.Locals("role").(string)
 	adminUsername := c.Locals("username").(string)
 	
-	// Only admin can create users
-	if userRole != "admin" {
+	// Only admin can create users; department admins only within their scopes
+	scopes, scoped := requestAdminScopes(c)
+	if scoped && len(scopes) == 0 {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"success": false,
 			"error": "Access denied",
@@ -164,15 +171,17 @@ func (s *UserService) CreateUserRequest(c *fiber.Ctx) error {
 					validationErrors["advisor_id"] = "Advisor user not found. No lecturers available. Create a lecturer first or leave empty if no advisor."
 				}
 			} else {
-				// Check if user is a lecturer (accept both "lecturer" and "Dosen Wali")
+				// Check if user is a lecturer (aliases such as "Dosen Wali" included)
 				roleName := s.getRoleNameByID(user.RoleID)
-				if roleName != "lecturer" && roleName != "Dosen Wali" {
+				if policy.CanonicalRole(roleName) != policy.RoleLecturer {
 					validationErrors["advisor_id"] = fmt.Sprintf("Advisor must be a lecturer. Provided user has role: %s", roleName)
 				} else {
 					// Check if lecturer profile exists
-					_, err := s.lecturerRepo.GetByUserID(req.AdvisorID)
+					lecturer, err := s.lecturerRepo.GetByUserID(req.AdvisorID)
 					if err != nil {
 						validationErrors["advisor_id"] = "Lecturer profile not found for this user"
+					} else if scoped && !policy.InScope(scopes, lecturer.Department) {
+						validationErrors["advisor_id"] = fmt.Sprintf("Advisor must be a lecturer of a department you administer: %v", scopes)
 					}
 				}
 			}
@@ -188,6 +197,23 @@ func (s *UserService) CreateUserRequest(c *fiber.Ctx) error {
 		}
 	}
 
+	// Department admins create students and lecturers of their own scopes only;
+	// an empty program study or department is in no scope
+	if scoped {
+		switch req.Role {
+		case "student":
+			if !policy.InScope(scopes, req.ProgramStudy) {
+				return outOfScopeResponse(c)
+			}
+		case "lecturer":
+			if !policy.InScope(scopes, req.Department) {
+				return outOfScopeResponse(c)
+			}
+		default:
+			validationErrors["role"] = "Department admins can only create students and lecturers"
+		}
+	}
+
 	if len(validationErrors) > 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"success": false,
@@ -205,6 +231,9 @@ func (s *UserService) CreateUserRequest(c *fiber.Ctx) error {
 	}
 
 	user, err := s.CreateUser(&req)
+	if isPasswordRejection(err) {
+		return passwordRejectedResponse(c, err)
+	}
 	if err != nil {
 		var errorCode string
 		var message string
@@ -314,10 +343,9 @@ func (s *UserService) CreateUserRequest(c *fiber.Ctx) error {
 
 // FR-009 Step 1: Update user
 func (s *UserService) UpdateUserRequest(c *fiber.Ctx) error {
-	userRole := c.Locals("role").(string)
-	
-	// Only admin can update users
-	if userRole != "admin" {
+	// Only admin can update users; department admins only within their scopes
+	scopes, scoped := requestAdminScopes(c)
+	if scoped && len(scopes) == 0 {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": "Only admin can update users",
 		})
@@ -337,7 +365,32 @@ func (s *UserService) UpdateUserRequest(c *fiber.Ctx) error {
 		})
 	}
 
+	if scoped {
+		if !s.userInScope(userID, scopes) {
+			return outOfScopeResponse(c)
+		}
+		if req.Role != "" {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Department admins cannot change roles",
+			})
+		}
+		if req.AdvisorID != "" && !s.advisorInScope(req.AdvisorID, scopes) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error": "Validation failed",
+				"message": "Please correct the following errors",
+				"code": "VALIDATION_ERROR",
+				"details": fiber.Map{
+					"advisor_id": fmt.Sprintf("Advisor must be a lecturer of a department you administer: %v", scopes),
+				},
+			})
+		}
+	}
+
 	user, err := s.UpdateUser(userID, &req)
+	if isPasswordRejection(err) {
+		return passwordRejectedResponse(c, err)
+	}
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
@@ -352,10 +405,9 @@ func (s *UserService) UpdateUserRequest(c *fiber.Ctx) error {
 
 // FR-009 Step 1: Delete user
 func (s *UserService) DeleteUserRequest(c *fiber.Ctx) error {
-	userRole := c.Locals("role").(string)
-	
-	// Only admin can delete users
-	if userRole != "admin" {
+	// Only admin can delete users; department admins only within their scopes
+	scopes, scoped := requestAdminScopes(c)
+	if scoped && len(scopes) == 0 {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": "Only admin can delete users",
 		})
@@ -368,6 +420,10 @@ func (s *UserService) DeleteUserRequest(c *fiber.Ctx) error {
 		})
 	}
 
+	if scoped && !s.userInScope(userID, scopes) {
+		return outOfScopeResponse(c)
+	}
+
 	err := s.DeleteUser(userID)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -381,12 +437,60 @@ func (s *UserService) DeleteUserRequest(c *fiber.Ctx) error {
 	})
 }
 
+// UnlockUserRequest lifts a login lockout
+// @Summary Unlock User Account
+// @Description Clear failed login attempts and any temporary lockout of a user account (admin only)
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]interface{} "Account unlocked"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Router /users/{id}/unlock [post]
+func (s *UserService) UnlockUserRequest(c *fiber.Ctx) error {
+	userID := c.Params("id")
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"success": false,
+			"error": "User not found",
+			"message": "No user with ID " + userID,
+			"code": "USER_NOT_FOUND",
+		})
+	}
+
+	if scopes, scoped := requestAdminScopes(c); scoped && !s.adminScopeService.UserInScope(user, scopes) {
+		return outOfScopeResponse(c)
+	}
+
+	wasLocked, err := s.authService.UnlockAccount(user.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error": "Failed to unlock account",
+			"message": err.Error(),
+			"code": "UNLOCK_FAILED",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Account unlocked, failed login attempts have been reset",
+		"code": "ACCOUNT_UNLOCKED",
+		"data": fiber.Map{
+			"user_id": user.ID,
+			"username": user.Username,
+			"was_locked": wasLocked,
+		},
+	})
+}
+
 // Get all users
 func (s *UserService) GetAllUsersRequest(c *fiber.Ctx) error {
-	userRole := c.Locals("role").(string)
-	
-	// Only admin can view all users
-	if userRole != "admin" {
+	// Only admin can view all users; department admins see the users of their scopes
+	scopes, scoped := requestAdminScopes(c)
+	if scoped && len(scopes) == 0 {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"success": false,
 			"error": "Access denied",
@@ -405,6 +509,10 @@ func (s *UserService) GetAllUsersRequest(c *fiber.Ctx) error {
 		})
 	}
 
+	if scoped {
+		users = s.adminScopeService.FilterUsersInScope(users, scopes)
+	}
+
 	// Enhanced user data with profile information
 	var enhancedUsers []fiber.Map
 	for _, user := range users {
@@ -475,10 +583,9 @@ func (s *UserService) GetAllUsersRequest(c *fiber.Ctx) error {
 
 // Get user by ID
 func (s *UserService) GetUserByIDRequest(c *fiber.Ctx) error {
-	userRole := c.Locals("role").(string)
-	
-	// Only admin can view user details
-	if userRole != "admin" {
+	// Only admin can view user details; department admins within their scopes
+	scopes, scoped := requestAdminScopes(c)
+	if scoped && len(scopes) == 0 {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"success": false,
 			"error": "Access denied",
@@ -498,6 +605,10 @@ func (s *UserService) GetUserByIDRequest(c *fiber.Ctx) error {
 		})
 	}
 
+	if scoped && !s.adminScopeService.UserInScope(user, scopes) {
+		return outOfScopeResponse(c)
+	}
+
 	// Enhanced user data with profile information
 	userData := fiber.Map{
 		"id": user.ID,
@@ -575,8 +686,8 @@ func (s *UserService) CreateUser(req *CreateUserRequest) (*model.User, error) {
 		return nil, errors.New("email already exists")
 	}
 
-	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	// Check the password policy and hash the password
+	hashedPassword, err := s.authService.HashNewPassword(req.Password, req.Username, req.Email)
 	if err != nil {
 		return nil, err
 	}
@@ -591,7 +702,7 @@ func (s *UserService) CreateUser(req *CreateUserRequest) (*model.User, error) {
 	user := &model.User{
 		Username:  req.Username,
 		Email:     req.Email,
-		Password:  string(hashedPassword),
+		Password:  hashedPassword,
 		FullName:  req.FullName,
 		RoleID:    roleID,
 		IsActive:  req.IsActive,
@@ -610,6 +721,10 @@ func (s *UserService) CreateUser(req *CreateUserRequest) (*model.User, error) {
 		return nil, err
 	}
 
+	if err := s.authService.SendEmailVerification(user); err != nil {
+		log.Printf("Failed to send verification email to user %s: %v", user.ID, err)
+	}
+
 	// Remove password from response
 	user.Password = ""
 	return user, nil
@@ -621,20 +736,18 @@ func (s *UserService) UpdateUser(userID string, req *UpdateUserRequest) (*model.
 	if err != nil {
 		return nil, errors.New("user not found")
 	}
+	wasActive := user.IsActive
+	previousRoleID := user.RoleID
 
 	// Update fields if provided
 	if req.Username != "" {
 		user.Username = req.Username
 	}
-	if req.Email != "" {
+	emailChanged := false
+	if req.Email != "" && req.Email != user.Email {
 		user.Email = req.Email
-	}
-	if req.Password != "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-		if err != nil {
-			return nil, err
-		}
-		user.Password = string(hashedPassword)
+		user.EmailVerifiedAt = nil
+		emailChanged = true
 	}
 	if req.FullName != "" {
 		user.FullName = req.FullName
@@ -643,6 +756,13 @@ func (s *UserService) UpdateUser(userID string, req *UpdateUserRequest) (*model.
 		user.IsActive = *req.IsActive
 	}
 
+	// Policy and history checks; stores the new hash and keeps the old one
+	if req.Password != "" {
+		if err := s.authService.SetPassword(user, req.Password); err != nil {
+			return nil, err
+		}
+	}
+
 	// FR-009 Step 2: Assign role if changed
 	if req.Role != "" {
 		roleID, err := s.getRoleIDByName(req.Role)
@@ -657,6 +777,20 @@ func (s *UserService) UpdateUser(userID string, req *UpdateUserRequest) (*model.
 		return nil, err
 	}
 
+	// The new address has to be confirmed again
+	if emailChanged {
+		if err := s.authService.SendEmailVerification(user); err != nil {
+			log.Printf("Failed to send verification email to user %s: %v", user.ID, err)
+		}
+	}
+
+	// Outstanding tokens carry the old status/role, so they must stop working now
+	if (wasActive && !user.IsActive) || previousRoleID != user.RoleID {
+		if err := s.authService.RevokeAllUserTokens(user.ID); err != nil {
+			return nil, errors.New("user updated but failed to revoke existing tokens: " + err.Error())
+		}
+	}
+
 	// Update role profile if needed
 	if req.Role != "" {
 		err = s.updateRoleProfile(user, req)
@@ -670,7 +804,30 @@ func (s *UserService) UpdateUser(userID string, req *UpdateUserRequest) (*model.
 	return user, nil
 }
 
+// userInScope reports whether a department admin with the scopes may manage the user
+func (s *UserService) userInScope(userID string, scopes []string) bool {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return false
+	}
+	return s.adminScopeService.UserInScope(user, scopes)
+}
+
+// advisorInScope reports whether a department admin with the scopes may assign
+// the lecturer user as advisor: the lecturer's department must be in scope
+func (s *UserService) advisorInScope(advisorUserID string, scopes []string) bool {
+	lecturer, err := s.lecturerRepo.GetByUserID(advisorUserID)
+	if err != nil {
+		return false
+	}
+	return policy.InScope(scopes, lecturer.Department)
+}
+
 func (s *UserService) DeleteUser(userID string) error {
+	// Revoke first so a deleted user's tokens can never outlive the account
+	if err := s.authService.RevokeAllUserTokens(userID); err != nil {
+		return errors.New("failed to revoke user tokens: " + err.Error())
+	}
 	return s.userRepo.Delete(userID)
 }
 
@@ -696,9 +853,9 @@ func (s *UserService) createRoleProfile(user *model.User, req *CreateUserRequest
 				return errors.New("advisor user not found")
 			}
 			
-			// Check if user is a lecturer (accept both "lecturer" and "Dosen Wali")
+			// Check if user is a lecturer (aliases such as "Dosen Wali" included)
 			roleName := s.getRoleNameByID(advisorUser.RoleID)
-			if roleName != "lecturer" && roleName != "Dosen Wali" {
+			if policy.CanonicalRole(roleName) != policy.RoleLecturer {
 				return fmt.Errorf("advisor must be a lecturer. Current role: %s", roleName)
 			}
 			
@@ -762,10 +919,10 @@ func (s *UserService) getRoleIDByName(roleName string) (string, error) {
 // @Failure 403 {object} map[string]interface{} "Forbidden - Admin only"
 // @Router /users/advisors [get]
 func (s *UserService) GetAvailableAdvisorsRequest(c *fiber.Ctx) error {
-	userRole := c.Locals("role").(string)
-	
-	// Only admin can view available advisors
-	if userRole != "admin" {
+	// Only admin can view available advisors; department admins see the
+	// lecturers of their departments
+	scopes, scoped := requestAdminScopes(c)
+	if scoped && len(scopes) == 0 {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"success": false,
 			"error": "Access denied",
@@ -788,6 +945,10 @@ func (s *UserService) GetAvailableAdvisorsRequest(c *fiber.Ctx) error {
 	// Get user details for each lecturer
 	var advisors []fiber.Map
 	for _, lecturer := range lecturers {
+		if scoped && !policy.InScope(scopes, lecturer.Department) {
+			continue
+		}
+
 		user, err := s.userRepo.GetByID(lecturer.UserID)
 		if err != nil {
 			continue // Skip if user not found
@@ -852,10 +1013,8 @@ func (s *UserService) countActiveUsers(users []model.User) int {
 // @Failure 403 {object} map[string]interface{} "Forbidden - Admin only"
 // @Router /users/create-default-lecturer [post]
 func (s *UserService) CreateDefaultLecturerRequest(c *fiber.Ctx) error {
-	userRole := c.Locals("role").(string)
-	
 	// Only admin can create default lecturer
-	if userRole != "admin" {
+	if !requestHasRole(c, policy.RoleAdmin) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"success": false,
 			"error": "Access denied",
@@ -879,7 +1038,7 @@ func (s *UserService) CreateDefaultLecturerRequest(c *fiber.Ctx) error {
 	req := &CreateUserRequest{
 		Username:   "dosen1",
 		Email:      "dosen1@unair.ac.id",
-		Password:   "admin123",
+		Password:   "Lecturer#2024", // must satisfy the password policy
 		FullName:   "Dr. Dosen Satu",
 		Role:       "lecturer",
 		IsActive:   true,
@@ -932,10 +1091,8 @@ func (s *UserService) CreateDefaultLecturerRequest(c *fiber.Ctx) error {
 // @Failure 403 {object} map[string]interface{} "Forbidden - Admin only"
 // @Router /users/reset-database [post]
 func (s *UserService) ResetDatabaseRequest(c *fiber.Ctx) error {
-	userRole := c.Locals("role").(string)
-	
 	// Only admin can reset database
-	if userRole != "admin" {
+	if !requestHasRole(c, policy.RoleAdmin) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"success": false,
 			"error": "Access denied",
@@ -984,10 +1141,8 @@ func (s *UserService) ResetDatabaseRequest(c *fiber.Ctx) error {
 // @Failure 403 {object} map[string]interface{} "Forbidden - Admin only"
 // @Router /users/debug [get]
 func (s *UserService) DebugUsersRequest(c *fiber.Ctx) error {
-	userRole := c.Locals("role").(string)
-	
 	// Only admin can debug users
-	if userRole != "admin" {
+	if !requestHasRole(c, policy.RoleAdmin) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"success": false,
 			"error": "Access denied",
@@ -1063,10 +1218,8 @@ func (s *UserService) DebugUsersRequest(c *fiber.Ctx) error {
 // @Failure 403 {object} map[string]interface{} "Forbidden - Admin only"
 // @Router /users/debug-role/{user_id} [get]
 func (s *UserService) DebugUserRoleRequest(c *fiber.Ctx) error {
-	userRole := c.Locals("role").(string)
-	
 	// Only admin can debug user role
-	if userRole != "admin" {
+	if !requestHasRole(c, policy.RoleAdmin) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"success": false,
 			"error": "Access denied",
@@ -1136,9 +1289,9 @@ func (s *UserService) DebugUserRoleRequest(c *fiber.Ctx) error {
 			},
 		},
 		"validation": fiber.Map{
-			"is_lecturer_role": roleName == "lecturer" || roleName == "Dosen Wali",
+			"is_lecturer_role": policy.CanonicalRole(roleName) == policy.RoleLecturer,
 			"has_lecturer_profile": lecturerErr == nil,
-			"can_be_advisor": (roleName == "lecturer" || roleName == "Dosen Wali") && lecturerErr == nil,
+			"can_be_advisor": policy.CanonicalRole(roleName) == policy.RoleLecturer && lecturerErr == nil,
 		},
 	})
 }
@@ -1153,10 +1306,8 @@ func (s *UserService) DebugUserRoleRequest(c *fiber.Ctx) error {
 // @Failure 403 {object} map[string]interface{} "Forbidden - Admin only"
 // @Router /users/fix-constraints [post]
 func (s *UserService) FixDatabaseConstraintsRequest(c *fiber.Ctx) error {
-	userRole := c.Locals("role").(string)
-	
 	// Only admin can fix database constraints
-	if userRole != "admin" {
+	if !requestHasRole(c, policy.RoleAdmin) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"success": false,
 			"error": "Access denied",
@@ -1252,10 +1403,8 @@ func (s *UserService) FixDatabaseConstraintsRequest(c *fiber.Ctx) error {
 // @Failure 403 {object} map[string]interface{} "Forbidden - Admin only"
 // @Router /users/clean-invalid-data [post]
 func (s *UserService) CleanInvalidDataRequest(c *fiber.Ctx) error {
-	userRole := c.Locals("role").(string)
-	
 	// Only admin can clean invalid data
-	if userRole != "admin" {
+	if !requestHasRole(c, policy.RoleAdmin) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"success": false,
 			"error": "Access denied",
@@ -1375,10 +1524,8 @@ func (s *UserService) CleanInvalidDataRequest(c *fiber.Ctx) error {
 // @Failure 403 {object} map[string]interface{} "Forbidden - Admin only"
 // @Router /users/clean-achievement-references [post]
 func (s *UserService) CleanAchievementReferencesRequest(c *fiber.Ctx) error {
-	userRole := c.Locals("role").(string)
-	
 	// Only admin can clean achievement references
-	if userRole != "admin" {
+	if !requestHasRole(c, policy.RoleAdmin) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"success": false,
 			"error": "Access denied",
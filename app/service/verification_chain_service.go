@@ -0,0 +1,250 @@
+package service
+
+import (
+	"UASBE/app/model"
+	"UASBE/app/policy"
+	"UASBE/app/repository"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// VerificationChainService manages the verification chains: the stages that
+// approve, one after the other, achievements of a category and competition
+// level before they are verified
+type VerificationChainService struct {
+	chainRepo *repository.VerificationChainRepository
+	roleRepo  *repository.RoleRepository
+}
+
+func NewVerificationChainService(chainRepo *repository.VerificationChainRepository, roleRepo *repository.RoleRepository) *VerificationChainService {
+	return &VerificationChainService{
+		chainRepo: chainRepo,
+		roleRepo:  roleRepo,
+	}
+}
+
+type SaveVerificationChainRequest struct {
+	Category         string   `json:"category"`
+	CompetitionLevel string   `json:"competition_level,omitempty"` // empty for any level
+	Stages           []string `json:"stages"`                      // e.g. ["advisor", "student_affairs", "university_verifier"]
+}
+
+const maxVerificationStages = 10
+
+// NormalizeStages lowercases and trims the stages and drops empty ones. A chain
+// needs at least one stage and cannot approve at the same stage twice. The
+// student, service, admin and lecturer roles cannot be stages: verifiers are
+// the advisor or the holders of a role granted achievements:verify.
+func NormalizeStages(stages []string) ([]string, error) {
+	seen := make(map[string]bool)
+	normalized := []string{}
+	for _, stage := range stages {
+		stage = policy.CanonicalRole(stage)
+		if stage == "" {
+			continue
+		}
+		switch stage {
+		case policy.RoleStudent, policy.RoleService, policy.RoleAdmin, policy.RoleLecturer:
+			return nil, errors.New("stage '" + stage + "' is a built-in role and cannot verify; use advisor or a role with the achievements:verify permission")
+		}
+		if seen[stage] {
+			return nil, errors.New("stage '" + stage + "' appears more than once")
+		}
+		seen[stage] = true
+		normalized = append(normalized, stage)
+	}
+
+	if len(normalized) == 0 {
+		return nil, errors.New("a verification chain needs at least one stage")
+	}
+	if len(normalized) > maxVerificationStages {
+		return nil, errors.New("a verification chain can have at most 10 stages")
+	}
+	return normalized, nil
+}
+
+// SelectVerificationChain picks the stages for an achievement from the chains
+// of its category: the chain of its competition level, else the chain for any
+// level, else the advisor alone
+func SelectVerificationChain(chains []model.VerificationChain, competitionLevel string) []string {
+	var anyLevel []string
+	for _, chain := range chains {
+		switch {
+		case chain.CompetitionLevel == "":
+			anyLevel = chain.Stages
+		case strings.EqualFold(chain.CompetitionLevel, strings.TrimSpace(competitionLevel)):
+			return chain.Stages
+		}
+	}
+
+	if len(anyLevel) > 0 {
+		return anyLevel
+	}
+	return []string{policy.StageAdvisor}
+}
+
+// ChainFor returns the stages that verify an achievement
+func (s *VerificationChainService) ChainFor(achievement *model.Achievement) ([]string, error) {
+	chains, err := s.chainRepo.GetForAchievement(achievement.Category, achievement.Details.CompetitionLevel)
+	if err != nil {
+		return nil, err
+	}
+	return SelectVerificationChain(chains, achievement.Details.CompetitionLevel), nil
+}
+
+// SaveChain sets the stages of a category and competition level. Every stage
+// other than the advisor must be an existing role granted achievements:verify,
+// since the verify routes require it of whoever approves a stage.
+func (s *VerificationChainService) SaveChain(req *SaveVerificationChainRequest, adminID string) (*model.VerificationChain, error) {
+	category := strings.ToLower(strings.TrimSpace(req.Category))
+	if category == "" {
+		return nil, errors.New("category is required")
+	}
+	competitionLevel := strings.ToLower(strings.TrimSpace(req.CompetitionLevel))
+
+	stages, err := NormalizeStages(req.Stages)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, stage := range stages {
+		if stage == policy.StageAdvisor {
+			continue
+		}
+		role, err := s.roleRepo.GetByName(stage)
+		if err != nil {
+			return nil, errors.New("stage '" + stage + "' is not a role")
+		}
+		permissions, err := s.roleRepo.GetPermissionsByRoleID(role.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !canVerify(permissions) {
+			return nil, errors.New("stage '" + stage + "' cannot verify: the role lacks the achievements:verify permission")
+		}
+	}
+
+	return s.chainRepo.Save(category, competitionLevel, stages, adminID)
+}
+
+func canVerify(permissions []model.Permission) bool {
+	for _, permission := range permissions {
+		if permission.Key() == "achievements:verify" {
+			return true
+		}
+	}
+	return false
+}
+
+// GetVerificationChainsRequest lists the verification chains
+// @Summary Get Verification Chains
+// @Description Stages that verify achievements per category and competition level; achievements without a chain are verified by the advisor alone (admin only)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Verification chains"
+// @Router /admin/verification-chains [get]
+func (s *VerificationChainService) GetVerificationChainsRequest(c *fiber.Ctx) error {
+	chains, err := s.chainRepo.GetAll()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error": "Failed to get verification chains",
+			"message": err.Error(),
+			"code": "FETCH_FAILED",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data": chains,
+	})
+}
+
+// SaveVerificationChainRequest creates or replaces the chain of a category and competition level
+// @Summary Save Verification Chain
+// @Description Set the stages, in order, that must approve achievements of a category and competition level. A stage is "advisor" or a role granted the achievements:verify permission; the student, lecturer, admin and service roles are rejected (admin only)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body SaveVerificationChainRequest true "Verification chain"
+// @Success 200 {object} map[string]interface{} "Verification chain saved"
+// @Failure 400 {object} map[string]interface{} "Validation error"
+// @Router /admin/verification-chains [put]
+func (s *VerificationChainService) SaveVerificationChainRequest(c *fiber.Ctx) error {
+	var req SaveVerificationChainRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error": "Invalid request body",
+			"message": "Please provide valid JSON data",
+			"code": "INVALID_REQUEST_BODY",
+		})
+	}
+
+	adminID, _ := c.Locals("user_id").(string)
+
+	chain, err := s.SaveChain(&req, adminID)
+	if err != nil {
+		msg := err.Error()
+		if msg == "category is required" || strings.HasPrefix(msg, "stage ") || strings.HasPrefix(msg, "a verification chain") {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error": "Validation failed",
+				"message": msg,
+				"code": "VALIDATION_ERROR",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error": "Failed to save verification chain",
+			"message": msg,
+			"code": "UPDATE_FAILED",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Verification chain saved",
+		"code": "VERIFICATION_CHAIN_SAVED",
+		"data": chain,
+	})
+}
+
+// DeleteVerificationChainRequest removes a chain; its achievements go back to the advisor alone
+// @Summary Delete Verification Chain
+// @Description Remove a verification chain. Achievements already in verification keep the stages they were submitted with (admin only)
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Verification chain ID"
+// @Success 200 {object} map[string]interface{} "Verification chain deleted"
+// @Failure 404 {object} map[string]interface{} "Verification chain not found"
+// @Router /admin/verification-chains/{id} [delete]
+func (s *VerificationChainService) DeleteVerificationChainRequest(c *fiber.Ctx) error {
+	if err := s.chainRepo.Delete(c.Params("id")); err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"success": false,
+				"error": "Verification chain not found",
+				"code": "VERIFICATION_CHAIN_NOT_FOUND",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error": "Failed to delete verification chain",
+			"message": err.Error(),
+			"code": "DELETE_FAILED",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Verification chain deleted",
+		"code": "VERIFICATION_CHAIN_DELETED",
+	})
+}
@@ -0,0 +1,291 @@
+package service
+
+import (
+	"UASBE/app/model"
+	"UASBE/app/policy"
+	"UASBE/app/repository"
+	"fmt"
+	"log"
+	"time"
+)
+
+// VerificationSLAConfig sets how long a submitted achievement may wait for its
+// verification stage: past RemindAfter the verifiers are reminded, past
+// EscalateAfter the administrators of the student's program study are told.
+type VerificationSLAConfig struct {
+	Enabled       bool
+	CheckInterval time.Duration
+	RemindAfter   time.Duration
+	EscalateAfter time.Duration
+}
+
+// VerificationSLAConfigFromEnv builds the configuration from VERIFICATION_SLA_*
+// and VERIFICATION_*_AFTER variables
+func VerificationSLAConfigFromEnv() VerificationSLAConfig {
+	config := VerificationSLAConfig{
+		Enabled:       getEnvBool("VERIFICATION_SLA_ENABLED", true),
+		CheckInterval: getEnvDuration("VERIFICATION_SLA_CHECK_INTERVAL", time.Hour),
+		RemindAfter:   getEnvDuration("VERIFICATION_REMINDER_AFTER", 72*time.Hour),
+		EscalateAfter: getEnvDuration("VERIFICATION_ESCALATE_AFTER", 168*time.Hour),
+	}
+
+	if config.EscalateAfter <= config.RemindAfter {
+		log.Printf("Warning: VERIFICATION_ESCALATE_AFTER (%s) is not after VERIFICATION_REMINDER_AFTER (%s), using %s",
+			config.EscalateAfter, config.RemindAfter, 2*config.RemindAfter)
+		config.EscalateAfter = 2 * config.RemindAfter
+	}
+
+	return config
+}
+
+// WaitingSince is when a submitted reference started waiting for its current
+// verification stage: the approval of the previous stage, else the submission
+func WaitingSince(reference *model.AchievementReference) time.Time {
+	since := reference.SubmittedAt
+	if n := len(reference.Approvals); n > 0 && reference.Approvals[n-1].At.After(since) {
+		since = reference.Approvals[n-1].At
+	}
+	return since
+}
+
+// Due reports whether the verifiers of a reference should be reminded and
+// whether it should be escalated. Each happens once per stage: a reminder or
+// escalation sent before the reference started waiting for its stage belongs
+// to an earlier stage or submission.
+func (c VerificationSLAConfig) Due(reference *model.AchievementReference, now time.Time) (remind, escalate bool) {
+	if reference.Status != policy.StatusSubmitted {
+		return false, false
+	}
+
+	since := WaitingSince(reference)
+	if since.IsZero() {
+		return false, false
+	}
+
+	waiting := now.Sub(since)
+	remind = waiting >= c.RemindAfter && !reference.RemindedAt.After(since)
+	escalate = waiting >= c.EscalateAfter && !reference.EscalatedAt.After(since)
+	return remind, escalate
+}
+
+// SLASummary counts the submitted achievements by how long they have waited
+type SLASummary struct {
+	Pending            int     `json:"pending"`
+	Overdue            int     `json:"overdue"`   // waiting longer than the reminder threshold
+	Breached           int     `json:"breached"`  // waiting longer than the escalation threshold
+	Escalated          int     `json:"escalated"` // escalated to the administrators for their current stage
+	RemindAfterHours   float64 `json:"remind_after_hours"`
+	EscalateAfterHours float64 `json:"escalate_after_hours"`
+}
+
+// Summarize counts the SLA breaches among the references; references that are
+// not submitted are skipped
+func (c VerificationSLAConfig) Summarize(references []model.AchievementReference, now time.Time) SLASummary {
+	summary := SLASummary{
+		RemindAfterHours:   c.RemindAfter.Hours(),
+		EscalateAfterHours: c.EscalateAfter.Hours(),
+	}
+
+	for i := range references {
+		reference := &references[i]
+		if reference.Status != policy.StatusSubmitted {
+			continue
+		}
+
+		summary.Pending++
+		since := WaitingSince(reference)
+		if since.IsZero() {
+			continue
+		}
+
+		waiting := now.Sub(since)
+		if waiting >= c.RemindAfter {
+			summary.Overdue++
+		}
+		if waiting >= c.EscalateAfter {
+			summary.Breached++
+		}
+		if reference.EscalatedAt.After(since) {
+			summary.Escalated++
+		}
+	}
+
+	return summary
+}
+
+// VerificationSLAService reminds verifiers of achievements that wait too long
+// and escalates them to the administrators, see VerificationSLAConfig
+type VerificationSLAService struct {
+	config              VerificationSLAConfig
+	achievementRepo     *repository.AchievementRepository
+	studentRepo         *repository.StudentRepository
+	lecturerRepo        *repository.LecturerRepository
+	userRepo            *repository.UserRepository
+	adminScopeRepo      *repository.AdminScopeRepository
+	delegationRepo      *repository.VerificationDelegationRepository
+	notificationService *NotificationService
+}
+
+func NewVerificationSLAService(config VerificationSLAConfig, achievementRepo *repository.AchievementRepository, studentRepo *repository.StudentRepository, lecturerRepo *repository.LecturerRepository, userRepo *repository.UserRepository, adminScopeRepo *repository.AdminScopeRepository, delegationRepo *repository.VerificationDelegationRepository, notificationService *NotificationService) *VerificationSLAService {
+	return &VerificationSLAService{
+		config:              config,
+		achievementRepo:     achievementRepo,
+		studentRepo:         studentRepo,
+		lecturerRepo:        lecturerRepo,
+		userRepo:            userRepo,
+		adminScopeRepo:      adminScopeRepo,
+		delegationRepo:      delegationRepo,
+		notificationService: notificationService,
+	}
+}
+
+// Start checks the submitted achievements now and then every CheckInterval, in
+// the background. It does nothing when the SLA is disabled.
+func (s *VerificationSLAService) Start() {
+	if !s.config.Enabled {
+		log.Println("Verification SLA checks disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.config.CheckInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.RunOnce(time.Now()); err != nil {
+				log.Printf("Verification SLA check failed: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// RunOnce sends the reminders and escalations due at the given time
+func (s *VerificationSLAService) RunOnce(now time.Time) error {
+	references, err := s.achievementRepo.GetReferencesByStatus(policy.StatusSubmitted)
+	if err != nil {
+		return err
+	}
+
+	for i := range references {
+		reference := &references[i]
+		remind, escalate := s.config.Due(reference, now)
+		if !remind && !escalate {
+			continue
+		}
+
+		student, err := s.studentRepo.GetByUserID(reference.StudentID)
+		if err != nil {
+			log.Printf("Verification SLA: student %s of achievement %s not found: %v", reference.StudentID, reference.AchievementID, err)
+			continue
+		}
+
+		title := reference.AchievementID
+		if achievement, err := s.achievementRepo.GetByObjectID(reference.AchievementID); err == nil {
+			title = achievement.Title
+		}
+
+		stage := VerificationStage(reference)
+		waitingDays := int(now.Sub(WaitingSince(reference)).Hours() / 24)
+		data := map[string]interface{}{
+			"achievement_id":     reference.AchievementID,
+			"achievement_title":  title,
+			"student_id":         reference.StudentID,
+			"verification_stage": stage,
+			"waiting_since":      WaitingSince(reference),
+		}
+
+		// The reference is marked first: one verified or moved to the next stage
+		// since it was listed is neither marked nor notified about
+		if remind {
+			marked, err := s.achievementRepo.MarkReferenceReminded(reference.ID, reference.CurrentStage, now)
+			if err != nil {
+				log.Printf("Verification SLA: failed to mark achievement %s reminded: %v", reference.AchievementID, err)
+			} else if marked {
+				message := fmt.Sprintf("The achievement '%s' has been waiting for your verification for %d day(s).", title, waitingDays)
+				s.notify(s.stageVerifierIDs(student, stage), "verification_reminder", "Verification Reminder", message, data)
+			}
+		}
+
+		if escalate {
+			marked, err := s.achievementRepo.MarkReferenceEscalated(reference.ID, reference.CurrentStage, now)
+			if err != nil {
+				log.Printf("Verification SLA: failed to mark achievement %s escalated: %v", reference.AchievementID, err)
+			} else if marked {
+				message := fmt.Sprintf("The achievement '%s' of a %s student has been waiting for the %s verification stage for %d day(s).",
+					title, student.ProgramStudy, stage, waitingDays)
+				s.notify(s.escalationRecipientIDs(student), "verification_escalated", "Verification Overdue", message, data)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *VerificationSLAService) notify(userIDs []string, notificationType, title, message string, data map[string]interface{}) {
+	if len(userIDs) == 0 {
+		log.Printf("Verification SLA: nobody to send %s for achievement %v", notificationType, data["achievement_id"])
+		return
+	}
+	for _, userID := range userIDs {
+		if err := s.notificationService.CreateNotification(userID, notificationType, title, message, data); err != nil {
+			log.Printf("Verification SLA: failed to notify user %s: %v", userID, err)
+		}
+	}
+}
+
+// stageVerifierIDs returns the users who may approve the stage: the advisor and
+// their active delegates for the advisor stage, else the holders of the stage's
+// role whose scopes, if any, include the student's program study
+func (s *VerificationSLAService) stageVerifierIDs(student *model.Student, stage string) []string {
+	if stage == policy.StageAdvisor {
+		advisor, err := s.lecturerRepo.GetByID(student.AdvisorID)
+		if err != nil {
+			log.Printf("Verification SLA: advisor %s not found: %v", student.AdvisorID, err)
+			return nil
+		}
+		userIDs := []string{advisor.UserID}
+		delegateIDs, err := s.delegationRepo.GetActiveDelegateUserIDs(advisor.ID)
+		if err != nil {
+			log.Printf("Verification SLA: failed to load delegates of advisor %s: %v", advisor.ID, err)
+		}
+		return append(userIDs, delegateIDs...)
+	}
+
+	return s.roleHoldersInScope(stage, student.ProgramStudy, false)
+}
+
+// escalationRecipientIDs returns the department admins of the student's program
+// study, or the system administrators when there are none
+func (s *VerificationSLAService) escalationRecipientIDs(student *model.Student) []string {
+	if userIDs := s.roleHoldersInScope(policy.RoleDepartmentAdmin, student.ProgramStudy, true); len(userIDs) > 0 {
+		return userIDs
+	}
+	return s.roleHoldersInScope(policy.RoleAdmin, "", false)
+}
+
+// roleHoldersInScope returns the active holders of a role whose scopes include
+// the program study; holders without scopes match unless scopeRequired
+func (s *VerificationSLAService) roleHoldersInScope(role, programStudy string, scopeRequired bool) []string {
+	userIDs, err := s.userRepo.GetActiveUserIDsByRole(role)
+	if err != nil {
+		log.Printf("Verification SLA: failed to load holders of role %s: %v", role, err)
+		return nil
+	}
+	if programStudy == "" && !scopeRequired {
+		return userIDs
+	}
+
+	var inScope []string
+	for _, userID := range userIDs {
+		scopes, err := s.adminScopeRepo.GetScopes(userID)
+		if err != nil {
+			log.Printf("Verification SLA: failed to load scopes of user %s: %v", userID, err)
+			continue
+		}
+		if (len(scopes) == 0 && !scopeRequired) || policy.InScope(scopes, programStudy) {
+			inScope = append(inScope, userID)
+		}
+	}
+	return inScope
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"UASBE/app/model"
+	"UASBE/app/repository"
+	"UASBE/app/service"
+	"UASBE/mail"
+	"database/sql/driver"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// authStore adalah isi tabel users, roles, sessions dan token di fakeDB. Handler
+// yang didaftarkan registerAuthHandlers meniru SQL repository terhadap isi ini.
+type authStore struct {
+	mu sync.Mutex
+
+	users           map[string]*model.User
+	roles           map[string]*model.Role
+	permissions     map[string]*model.Permission
+	rolePermissions map[string][]string // role_id -> permission_id
+	userRoles       map[string][]string // user_id -> role_id tambahan
+
+	sessions      map[string]*fakeSession
+	refreshTokens map[string]*model.RefreshToken // id -> token
+	revokedJTIs   map[string]bool
+	revokedBefore map[string]int64 // user_id -> revoked_before_epoch
+}
+
+type fakeSession struct {
+	UserID    string
+	ExpiresAt time.Time
+	Revoked   bool
+}
+
+func newAuthStore() *authStore {
+	return &authStore{
+		users:           make(map[string]*model.User),
+		roles:           make(map[string]*model.Role),
+		permissions:     make(map[string]*model.Permission),
+		rolePermissions: make(map[string][]string),
+		userRoles:       make(map[string][]string),
+		sessions:        make(map[string]*fakeSession),
+		refreshTokens:   make(map[string]*model.RefreshToken),
+		revokedJTIs:     make(map[string]bool),
+		revokedBefore:   make(map[string]int64),
+	}
+}
+
+// addPermission membuat permission "resource:action"
+func (s *authStore) addPermission(resource, action string) *model.Permission {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	permission := &model.Permission{ID: uuid.New().String(), Name: resource + ":" + action, Resource: resource, Action: action}
+	s.permissions[permission.ID] = permission
+	return permission
+}
+
+// addRole membuat role dengan permission yang diberikan
+func (s *authStore) addRole(name string, system bool, permissions ...*model.Permission) *model.Role {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	role := &model.Role{ID: uuid.New().String(), Name: name, IsSystem: system, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	s.roles[role.ID] = role
+	for _, permission := range permissions {
+		s.rolePermissions[role.ID] = append(s.rolePermissions[role.ID], permission.ID)
+	}
+	return role
+}
+
+// addUser membuat user aktif yang sudah disetujui dengan role utama
+func (s *authStore) addUser(username string, role *model.Role) *model.User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	verifiedAt := time.Now()
+	user := &model.User{
+		ID:              uuid.New().String(),
+		Username:        username,
+		Email:           username + "@example.ac.id",
+		FullName:        username,
+		RoleID:          role.ID,
+		IsActive:        true,
+		ApprovalStatus:  model.ApprovalStatusApproved,
+		EmailVerifiedAt: &verifiedAt,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+	s.users[user.ID] = user
+	return user
+}
+
+// addSession membuat sesi aktif milik user
+func (s *authStore) addSession(userID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := uuid.New().String()
+	s.sessions[id] = &fakeSession{UserID: userID, ExpiresAt: time.Now().Add(time.Hour)}
+	return id
+}
+
+var (
+	userColumns       = []string{"id", "username", "email", "password_hash", "full_name", "role_id", "is_active", "approval_status", "email_verified_at", "created_at", "updated_at"}
+	roleColumns       = []string{"id", "name", "description", "is_system", "created_at", "updated_at"}
+	permissionColumns = []string{"id", "name", "resource", "action", "description"}
+)
+
+func userRow(user *model.User) []driver.Value {
+	var verifiedAt driver.Value
+	if user.EmailVerifiedAt != nil {
+		verifiedAt = *user.EmailVerifiedAt
+	}
+	return []driver.Value{user.ID, user.Username, user.Email, user.Password, user.FullName, user.RoleID,
+		user.IsActive, user.ApprovalStatus, verifiedAt, user.CreatedAt, user.UpdatedAt}
+}
+
+func roleRow(role *model.Role) []driver.Value {
+	return []driver.Value{role.ID, role.Name, role.Description, role.IsSystem, role.CreatedAt, role.UpdatedAt}
+}
+
+func permissionRow(permission *model.Permission) []driver.Value {
+	return []driver.Value{permission.ID, permission.Name, permission.Resource, permission.Action, permission.Description}
+}
+
+func stringArg(args []driver.Value, i int) string {
+	value, _ := args[i].(string)
+	return value
+}
+
+// registerAuthHandlers meniru query repository user, role, sesi dan token
+func (s *authStore) registerAuthHandlers(t *testing.T, db *fakeDB) {
+	// Users and roles
+	db.On("FROM users WHERE id = $1", func(args []driver.Value) fakeResult {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if user, ok := s.users[stringArg(args, 0)]; ok {
+			return fakeRows(userColumns, userRow(user))
+		}
+		return fakeRows(userColumns)
+	})
+	db.On("FROM roles WHERE id = $1", func(args []driver.Value) fakeResult {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if role, ok := s.roles[stringArg(args, 0)]; ok {
+			return fakeRows(roleColumns, roleRow(role))
+		}
+		return fakeRows(roleColumns)
+	})
+	db.On("FROM roles WHERE name = $1", func(args []driver.Value) fakeResult {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, role := range s.roles {
+			if role.Name == stringArg(args, 0) {
+				return fakeRows(roleColumns, roleRow(role))
+			}
+		}
+		return fakeRows(roleColumns)
+	})
+	db.On("JOIN role_permissions rp ON p.id = rp.permission_id WHERE rp.role_id = $1", func(args []driver.Value) fakeResult {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		result := fakeRows(permissionColumns)
+		for _, permissionID := range s.rolePermissions[stringArg(args, 0)] {
+			result.Rows = append(result.Rows, permissionRow(s.permissions[permissionID]))
+		}
+		return result
+	})
+	db.On("SELECT role_id FROM user_roles WHERE user_id = $1", func(args []driver.Value) fakeResult {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		result := fakeRows([]string{"role_id"})
+		for _, roleID := range s.userRoles[stringArg(args, 0)] {
+			result.Rows = append(result.Rows, []driver.Value{roleID})
+		}
+		return result
+	})
+	db.On("DELETE FROM login_throttles WHERE scope = $1 AND key = $2", func(args []driver.Value) fakeResult {
+		return fakeAffected(0)
+	})
+
+	// Sessions
+	db.On("INSERT INTO sessions", func(args []driver.Value) fakeResult {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		expiresAt, _ := args[7].(time.Time)
+		s.sessions[stringArg(args, 0)] = &fakeSession{UserID: stringArg(args, 1), ExpiresAt: expiresAt}
+		return fakeAffected(1)
+	})
+	db.On("WITH existing AS ( SELECT revoked_at IS NULL AND expires_at > NOW() AS active FROM sessions WHERE id = $1 AND user_id = $2", func(args []driver.Value) fakeResult {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		session, ok := s.sessions[stringArg(args, 0)]
+		if !ok || session.UserID != stringArg(args, 1) {
+			return fakeRows([]string{"active"})
+		}
+		return fakeRows([]string{"active"}, []driver.Value{!session.Revoked && session.ExpiresAt.After(time.Now())})
+	})
+	db.On("UPDATE sessions SET last_seen_at = NOW(), last_ip_address = $2, expires_at = $3 WHERE id = $1 AND revoked_at IS NULL", func(args []driver.Value) fakeResult {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if session, ok := s.sessions[stringArg(args, 0)]; ok && !session.Revoked {
+			session.ExpiresAt, _ = args[2].(time.Time)
+			return fakeAffected(1)
+		}
+		return fakeAffected(0)
+	})
+	db.On("UPDATE sessions SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL", func(args []driver.Value) fakeResult {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if session, ok := s.sessions[stringArg(args, 0)]; ok && !session.Revoked {
+			session.Revoked = true
+			return fakeAffected(1)
+		}
+		return fakeAffected(0)
+	})
+	db.On("UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL", func(args []driver.Value) fakeResult {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		var affected int64
+		for _, session := range s.sessions {
+			if session.UserID == stringArg(args, 0) && !session.Revoked {
+				session.Revoked = true
+				affected++
+			}
+		}
+		return fakeAffected(affected)
+	})
+	db.On("DELETE FROM sessions WHERE COALESCE(revoked_at, expires_at) < NOW() - INTERVAL '30 days'", func(args []driver.Value) fakeResult {
+		return fakeAffected(0)
+	})
+
+	// Refresh tokens
+	db.On("INSERT INTO refresh_tokens", func(args []driver.Value) fakeResult {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		expiresAt, _ := args[4].(time.Time)
+		createdAt, _ := args[5].(time.Time)
+		s.refreshTokens[stringArg(args, 0)] = &model.RefreshToken{
+			ID: stringArg(args, 0), UserID: stringArg(args, 1), FamilyID: stringArg(args, 2),
+			TokenHash: stringArg(args, 3), ExpiresAt: expiresAt, CreatedAt: createdAt,
+		}
+		return fakeAffected(1)
+	})
+	db.On("FROM refresh_tokens WHERE token_hash = $1", func(args []driver.Value) fakeResult {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		columns := []string{"id", "user_id", "family_id", "token_hash", "expires_at", "revoked_at", "replaced_by", "created_at", "expired"}
+		for _, token := range s.refreshTokens {
+			if token.TokenHash != stringArg(args, 0) {
+				continue
+			}
+			var revokedAt, replacedBy driver.Value
+			if token.RevokedAt != nil {
+				revokedAt = *token.RevokedAt
+			}
+			if token.ReplacedBy != "" {
+				replacedBy = token.ReplacedBy
+			}
+			return fakeRows(columns, []driver.Value{token.ID, token.UserID, token.FamilyID, token.TokenHash,
+				token.ExpiresAt, revokedAt, replacedBy, token.CreatedAt, !token.ExpiresAt.After(time.Now())})
+		}
+		return fakeRows(columns)
+	})
+	db.On("UPDATE refresh_tokens SET revoked_at = NOW(), replaced_by = $2 WHERE id = $1 AND revoked_at IS NULL", func(args []driver.Value) fakeResult {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		token, ok := s.refreshTokens[stringArg(args, 0)]
+		if !ok || token.RevokedAt != nil {
+			return fakeAffected(0)
+		}
+		now := time.Now()
+		token.RevokedAt = &now
+		token.ReplacedBy = stringArg(args, 1)
+		return fakeAffected(1)
+	})
+	revokeRefreshTokens := func(match func(token *model.RefreshToken) bool) fakeResult {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		var affected int64
+		now := time.Now()
+		for _, token := range s.refreshTokens {
+			if token.RevokedAt == nil && match(token) {
+				token.RevokedAt = &now
+				affected++
+			}
+		}
+		return fakeAffected(affected)
+	}
+	db.On("UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL", func(args []driver.Value) fakeResult {
+		return revokeRefreshTokens(func(token *model.RefreshToken) bool { return token.FamilyID == stringArg(args, 0) })
+	})
+	db.On("UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL", func(args []driver.Value) fakeResult {
+		return revokeRefreshTokens(func(token *model.RefreshToken) bool { return token.UserID == stringArg(args, 0) })
+	})
+
+	// Access token revocation
+	db.On("INSERT INTO revoked_tokens (jti, user_id, expires_at, revoked_at)", func(args []driver.Value) fakeResult {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.revokedJTIs[stringArg(args, 0)] = true
+		return fakeAffected(1)
+	})
+	db.On("DELETE FROM revoked_tokens WHERE expires_at < NOW()", func(args []driver.Value) fakeResult {
+		return fakeAffected(0)
+	})
+	db.On("INSERT INTO user_token_revocations (user_id, revoked_before, revoked_before_epoch) VALUES ($1, NOW(), FLOOR(EXTRACT(EPOCH FROM NOW()))::BIGINT)", func(args []driver.Value) fakeResult {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.revokedBefore[stringArg(args, 0)] = time.Now().Unix()
+		return fakeAffected(1)
+	})
+	db.On("SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1) OR EXISTS (SELECT 1 FROM user_token_revocations WHERE user_id = $2 AND revoked_before_epoch > $3)", func(args []driver.Value) fakeResult {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		issuedAt, ok := args[2].(int64)
+		if !ok {
+			t.Errorf("iat must be compared in unix seconds, got %T", args[2])
+		}
+		cutoff, hasCutoff := s.revokedBefore[stringArg(args, 1)]
+		revoked := s.revokedJTIs[stringArg(args, 0)] || (hasCutoff && cutoff > issuedAt)
+		return fakeRows([]string{"revoked"}, []driver.Value{revoked})
+	})
+}
+
+// authFixture adalah AuthService di atas fakeDB dan authStore
+type authFixture struct {
+	db       *fakeDB
+	store    *authStore
+	keys     *service.KeySet
+	resolver *service.PermissionResolver
+	outbox   *mail.OutboxMailer
+	auth     *service.AuthService
+}
+
+func newAuthFixture(t *testing.T) *authFixture {
+	t.Helper()
+
+	// A cheap hash keeps NewAuthService (dummy password hash) fast
+	t.Setenv("PASSWORD_HASH_ALGORITHM", "bcrypt")
+	t.Setenv("BCRYPT_COST", "4")
+	t.Setenv("PERMISSION_CACHE_TTL", "1h")
+
+	db := useFakeDB(t)
+	store := newAuthStore()
+	store.registerAuthHandlers(t, db)
+
+	keys, err := service.NewEphemeralKeySet()
+	if err != nil {
+		t.Fatalf("NewEphemeralKeySet() error = %v", err)
+	}
+
+	roleRepo := repository.NewRoleRepository()
+	resolver := service.NewPermissionResolver(roleRepo)
+	outbox := mail.NewOutboxMailer("", "noreply@example.ac.id")
+	auth := service.NewAuthService(repository.NewUserRepository(), repository.NewStudentRepository(), repository.NewLecturerRepository(),
+		repository.NewTokenRepository(), repository.NewTwoFactorRepository(), repository.NewLoginAttemptRepository(),
+		repository.NewSessionRepository(), repository.NewServiceAccountRepository(), repository.NewUserRoleRepository(),
+		nil, outbox, keys, resolver)
+
+	return &authFixture{db: db, store: store, keys: keys, resolver: resolver, outbox: outbox, auth: auth}
+}
+
+// accessToken menandatangani access token dengan iat tertentu pada sesi yang ada
+func (f *authFixture) accessToken(t *testing.T, user *model.User, sessionID string, issuedAt time.Time, extra jwt.MapClaims) string {
+	t.Helper()
+
+	claims := jwt.MapClaims{
+		"jti":     uuid.New().String(),
+		"typ":     "access",
+		"ver":     3,
+		"user_id": user.ID,
+		"role_id": user.RoleID,
+		"sid":     sessionID,
+		"iat":     issuedAt.Unix(),
+		"exp":     issuedAt.Add(15 * time.Minute).Unix(),
+	}
+	for key, value := range extra {
+		claims[key] = value
+	}
+
+	token, err := f.keys.Sign(claims)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	return token
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"UASBE/app/model"
+	"UASBE/app/service"
+	"testing"
+	"time"
+)
+
+var testClient = service.ClientInfo{IP: "127.0.0.1", UserAgent: "go-test"}
+
+// loginAs membuat user mahasiswa dan login sebagai user tersebut
+func loginAs(t *testing.T, f *authFixture, username string) (*model.User, *service.LoginResponse) {
+	t.Helper()
+
+	role := f.store.addRole("Mahasiswa", true)
+	user := f.store.addUser(username, role)
+	login, err := f.auth.CompleteLogin(user.ID, testClient)
+	if err != nil {
+		t.Fatalf("CompleteLogin() error = %v", err)
+	}
+	return user, login
+}
+
+func expectTokenError(t *testing.T, f *authFixture, token, want string) {
+	t.Helper()
+	if _, err := f.auth.ValidateToken(token); err == nil || err.Error() != want {
+		t.Errorf("ValidateToken() error = %v, want %q", err, want)
+	}
+}
+
+// TestRefreshTokenRotation menguji rotasi refresh token dan deteksi pemakaian ulang
+func TestRefreshTokenRotation(t *testing.T) {
+	f := newAuthFixture(t)
+	_, login := loginAs(t, f, "budi")
+
+	rotated, err := f.auth.Refresh(login.RefreshToken, testClient)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if rotated.RefreshToken == login.RefreshToken || rotated.SessionID != login.SessionID {
+		t.Fatalf("Refresh() must rotate the refresh token within the session")
+	}
+	if _, err := f.auth.ValidateToken(rotated.Token); err != nil {
+		t.Fatalf("ValidateToken() of the rotated access token error = %v", err)
+	}
+
+	// The rotated token presented again is theft: the whole family is revoked
+	if _, err := f.auth.Refresh(login.RefreshToken, testClient); err == nil || err.Error() != "refresh token reuse detected" {
+		t.Errorf("reused refresh token: error = %v, want reuse detected", err)
+	}
+	if _, err := f.auth.Refresh(rotated.RefreshToken, testClient); err == nil {
+		t.Errorf("the successor of a reused refresh token must be revoked")
+	}
+	expectTokenError(t, f, rotated.Token, "session has been revoked")
+}
+
+// TestLogoutRevokesAccessToken menguji pencabutan access token berdasarkan jti saat logout
+func TestLogoutRevokesAccessToken(t *testing.T) {
+	f := newAuthFixture(t)
+	user, login := loginAs(t, f, "budi")
+
+	claims, err := f.auth.ValidateToken(login.Token)
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	jti, _ := (*claims)["jti"].(string)
+
+	if err := f.auth.Logout(user.ID, login.SessionID, jti, login.ExpiresAt, login.RefreshToken, false); err != nil {
+		t.Fatalf("Logout() error = %v", err)
+	}
+
+	expectTokenError(t, f, login.Token, "token has been revoked")
+	if _, err := f.auth.Refresh(login.RefreshToken, testClient); err == nil {
+		t.Errorf("Refresh() after logout must fail")
+	}
+}
+
+// TestLogoutAllDevices menguji logout dari semua perangkat
+func TestLogoutAllDevices(t *testing.T) {
+	f := newAuthFixture(t)
+	user, laptop := loginAs(t, f, "budi")
+	phone, err := f.auth.CompleteLogin(user.ID, testClient)
+	if err != nil {
+		t.Fatalf("CompleteLogin() error = %v", err)
+	}
+
+	if err := f.auth.Logout(user.ID, laptop.SessionID, "", laptop.ExpiresAt, "", true); err != nil {
+		t.Fatalf("Logout() error = %v", err)
+	}
+
+	for _, login := range []*service.LoginResponse{laptop, phone} {
+		if _, err := f.auth.ValidateToken(login.Token); err == nil {
+			t.Errorf("access token of session %s still valid", login.SessionID)
+		}
+		if _, err := f.auth.Refresh(login.RefreshToken, testClient); err == nil {
+			t.Errorf("refresh token of session %s still valid", login.SessionID)
+		}
+	}
+}
+
+// TestUserWideRevocationCutoff menguji batas waktu pencabutan semua token user,
+// yang dibandingkan dalam detik unix terhadap klaim iat
+func TestUserWideRevocationCutoff(t *testing.T) {
+	f := newAuthFixture(t)
+	role := f.store.addRole("Mahasiswa", true)
+	user := f.store.addUser("budi", role)
+	other := f.store.addUser("siti", role)
+
+	oldSession := f.store.addSession(user.ID)
+	beforeCutoff := f.accessToken(t, user, oldSession, time.Now().Add(-time.Minute), nil)
+	otherToken := f.accessToken(t, other, f.store.addSession(other.ID), time.Now().Add(-time.Minute), nil)
+
+	if err := f.auth.RevokeAllUserTokens(user.ID); err != nil {
+		t.Fatalf("RevokeAllUserTokens() error = %v", err)
+	}
+	cutoff := time.Unix(f.store.revokedBefore[user.ID], 0)
+
+	// Tokens issued before the cutoff are rejected even on a session that is still active
+	newSession := f.store.addSession(user.ID)
+	expectTokenError(t, f, f.accessToken(t, user, newSession, cutoff.Add(-time.Second), nil), "token has been revoked")
+	expectTokenError(t, f, beforeCutoff, "token has been revoked")
+
+	// A login in the same second as the cutoff is not rejected
+	if _, err := f.auth.ValidateToken(f.accessToken(t, user, newSession, cutoff, nil)); err != nil {
+		t.Errorf("token issued in the cutoff second: error = %v", err)
+	}
+	if _, err := f.auth.ValidateToken(f.accessToken(t, user, newSession, cutoff.Add(time.Second), nil)); err != nil {
+		t.Errorf("token issued after the cutoff: error = %v", err)
+	}
+
+	// Other users are not affected
+	if _, err := f.auth.ValidateToken(otherToken); err != nil {
+		t.Errorf("token of another user: error = %v", err)
+	}
+}
@@ -387,7 +387,8 @@ func SetupSchema() error {
 		INSERT INTO roles (name, description) VALUES 
 		('admin', 'System Administrator'),
 		('student', 'Student User'),
-		('lecturer', 'Lecturer User')
+		('lecturer', 'Lecturer User'),
+		('service', 'Service account for system integrations')
 		ON CONFLICT (name) DO NOTHING;
 	`)
 	if err != nil {
@@ -412,6 +413,40 @@ func SetupSchema() error {
 		return fmt.Errorf("failed to create users table: %v", err)
 	}
 
+	// Self-registration approval. Existing accounts were created by admins and
+	// stay approved; accounts from POST /api/auth/register start pending.
+	_, err = PostgresDB.Exec(`
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS approval_status VARCHAR(20) NOT NULL DEFAULT 'approved'
+			CHECK (approval_status IN ('pending', 'approved', 'rejected'));
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS self_registered BOOLEAN NOT NULL DEFAULT false;
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL;
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS reviewed_at TIMESTAMP;
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS rejection_reason TEXT;
+		CREATE INDEX IF NOT EXISTS idx_users_pending_registrations ON users(created_at)
+			WHERE self_registered = true AND approval_status = 'pending';
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to add registration approval columns: %v", err)
+	}
+
+	// Email verification. Accounts that exist when the column is introduced
+	// predate verification and are treated as verified.
+	_, err = PostgresDB.Exec(`
+		DO $$
+		BEGIN
+			IF NOT EXISTS (
+				SELECT FROM information_schema.columns
+				WHERE table_schema = 'public' AND table_name = 'users' AND column_name = 'email_verified_at'
+			) THEN
+				ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
+				UPDATE users SET email_verified_at = NOW();
+			END IF;
+		END $$;
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to add email_verified_at column: %v", err)
+	}
+
 	// Create lecturers table
 	_, err = PostgresDB.Exec(`
 		CREATE TABLE IF NOT EXISTS lecturers (
@@ -481,82 +516,528 @@ func SetupSchema() error {
 		return fmt.Errorf("failed to create students table: %v", err)
 	}
 
-	// Check if permissions table exists and has correct structure
-	var permissionsExists bool
-	err = PostgresDB.QueryRow(`
-		SELECT EXISTS (
-			SELECT FROM information_schema.tables 
-			WHERE table_schema = 'public' 
-			AND table_name = 'permissions'
+	// RBAC model: permissions are resource:action pairs, granted to roles through
+	// role_permissions. System roles are referenced by name in code and cannot be
+	// renamed or deleted.
+	_, err = PostgresDB.Exec(`
+		ALTER TABLE roles ADD COLUMN IF NOT EXISTS is_system BOOLEAN NOT NULL DEFAULT false;
+		UPDATE roles SET is_system = true WHERE name IN ('admin', 'student', 'lecturer', 'service', 'department_admin');
+
+		CREATE TABLE IF NOT EXISTS permissions (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			name VARCHAR(100) NOT NULL,
+			resource VARCHAR(100) NOT NULL,
+			action VARCHAR(100) NOT NULL,
+			description TEXT,
+			created_at TIMESTAMP DEFAULT NOW()
 		);
-	`).Scan(&permissionsExists)
+		ALTER TABLE permissions ADD COLUMN IF NOT EXISTS name VARCHAR(100);
+		ALTER TABLE permissions ADD COLUMN IF NOT EXISTS description TEXT;
+
+		CREATE TABLE IF NOT EXISTS role_permissions (
+			role_id UUID NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+			permission_id UUID NOT NULL REFERENCES permissions(id) ON DELETE CASCADE,
+			created_at TIMESTAMP DEFAULT NOW(),
+			PRIMARY KEY (role_id, permission_id)
+		);
+	`)
 	if err != nil {
-		return fmt.Errorf("failed to check permissions table: %v", err)
+		return fmt.Errorf("failed to create RBAC tables: %v", err)
 	}
 
-	if !permissionsExists {
-		// Create permissions table with correct structure
-		_, err = PostgresDB.Exec(`
-			CREATE TABLE permissions (
-				id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-				role_id UUID NOT NULL REFERENCES roles(id),
-				resource VARCHAR(100) NOT NULL,
-				action VARCHAR(100) NOT NULL,
-				created_at TIMESTAMP DEFAULT NOW(),
-				UNIQUE(role_id, resource, action)
-			);
-		`)
-		if err != nil {
-			return fmt.Errorf("failed to create permissions table: %v", err)
-		}
+	if err := MigrateLegacyPermissions(PostgresDB); err != nil {
+		return err
+	}
+
+	_, err = PostgresDB.Exec(`
+		UPDATE permissions SET name = resource || ':' || action WHERE name IS NULL;
+		ALTER TABLE permissions ALTER COLUMN name SET NOT NULL;
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_permissions_resource_action ON permissions(resource, action);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to finalize permissions table: %v", err)
+	}
 
-		// Insert default permissions
+	// Insert default permissions and grant them to the system roles (only on a
+	// fresh database, so grants removed by an admin are not restored on restart)
+	var grantCount int
+	err = PostgresDB.QueryRow("SELECT COUNT(*) FROM role_permissions").Scan(&grantCount)
+	if err != nil {
+		return fmt.Errorf("failed to count role permissions: %v", err)
+	}
+
+	if grantCount == 0 {
 		_, err = PostgresDB.Exec(`
-			INSERT INTO permissions (role_id, resource, action) 
-			SELECT r.id, p.resource, p.action FROM roles r
-			CROSS JOIN (VALUES 
-				('achievements', 'create'),
-				('achievements', 'read'),
-				('achievements', 'update'),
-				('achievements', 'delete'),
-				('achievements', 'view_advisee'),
-				('achievements', 'verify')
-			) AS p(resource, action)
-			WHERE r.name IN ('student', 'lecturer', 'admin');
+			INSERT INTO permissions (name, resource, action, description) VALUES
+			('achievements:create', 'achievements', 'create', 'Create and submit own achievements'),
+			('achievements:read', 'achievements', 'read', 'View achievements'),
+			('achievements:update', 'achievements', 'update', 'Update own achievements'),
+			('achievements:delete', 'achievements', 'delete', 'Delete own draft achievements'),
+			('achievements:view_advisee', 'achievements', 'view_advisee', 'View achievements of advised students'),
+			('achievements:verify', 'achievements', 'verify', 'Verify or reject submitted achievements'),
+			('users:manage', 'users', 'manage', 'Manage user accounts')
+			ON CONFLICT (resource, action) DO NOTHING;
+
+			INSERT INTO role_permissions (role_id, permission_id)
+			SELECT r.id, p.id FROM roles r
+			JOIN permissions p ON p.name = ANY(CASE r.name
+				WHEN 'student' THEN ARRAY['achievements:create', 'achievements:read', 'achievements:update', 'achievements:delete']
+				WHEN 'lecturer' THEN ARRAY['achievements:read', 'achievements:view_advisee', 'achievements:verify']
+				WHEN 'admin' THEN ARRAY['achievements:create', 'achievements:read', 'achievements:update', 'achievements:delete',
+					'achievements:view_advisee', 'achievements:verify', 'users:manage']
+			END)
+			WHERE r.name IN ('student', 'lecturer', 'admin')
+			ON CONFLICT DO NOTHING;
 		`)
 		if err != nil {
 			return fmt.Errorf("failed to insert default permissions: %v", err)
 		}
-	} else {
-		// Check if permissions table has role_id column
-		var hasRoleID bool
-		err = PostgresDB.QueryRow(`
-			SELECT EXISTS (
-				SELECT FROM information_schema.columns 
-				WHERE table_name = 'permissions' 
-				AND column_name = 'role_id'
-			);
-		`).Scan(&hasRoleID)
-		if err != nil {
-			return fmt.Errorf("failed to check role_id column: %v", err)
-		}
+	}
+
+	// Permission to pull verified achievements (integrations). Granted to admin and
+	// service only when it is first created, so a revoked grant stays revoked.
+	var exportPermissionID string
+	err = PostgresDB.QueryRow(`
+		INSERT INTO permissions (name, resource, action, description)
+		VALUES ('achievements:export', 'achievements', 'export', 'Export verified achievements to other systems')
+		ON CONFLICT (resource, action) DO NOTHING
+		RETURNING id
+	`).Scan(&exportPermissionID)
+	if err == nil {
+		_, err = PostgresDB.Exec(`
+			INSERT INTO role_permissions (role_id, permission_id)
+			SELECT id, $1 FROM roles WHERE name IN ('admin', 'service')
+			ON CONFLICT DO NOTHING
+		`, exportPermissionID)
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to insert export permission: %v", err)
+	}
+
+	// Administrators scoped to departments / program studies (see admin_scopes).
+	// Like above, the role gets its permissions only when it is first created.
+	var departmentAdminRoleID string
+	err = PostgresDB.QueryRow(`
+		INSERT INTO roles (name, description, is_system)
+		VALUES ('department_admin', 'Administrator of one or more departments or program studies', true)
+		ON CONFLICT (name) DO NOTHING
+		RETURNING id
+	`).Scan(&departmentAdminRoleID)
+	if err == nil {
+		_, err = PostgresDB.Exec(`
+			INSERT INTO role_permissions (role_id, permission_id)
+			SELECT $1, id FROM permissions WHERE name IN ('achievements:read', 'users:manage')
+			ON CONFLICT DO NOTHING
+		`, departmentAdminRoleID)
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to insert department admin role: %v", err)
+	}
 
-		if !hasRoleID {
-			// Add role_id column if it doesn't exist
+	// Later stages of verification chains (see verification_chains): faculty
+	// student affairs and the university. Like above, the roles get their
+	// permissions only when they are first created.
+	verifierRoles := [][2]string{
+		{"student_affairs", "Faculty student affairs, verifies achievements after the advisor"},
+		{"university_verifier", "University staff, verifies achievements at the last stage"},
+	}
+	for _, verifierRole := range verifierRoles {
+		var verifierRoleID string
+		err = PostgresDB.QueryRow(`
+			INSERT INTO roles (name, description, is_system)
+			VALUES ($1, $2, false)
+			ON CONFLICT (name) DO NOTHING
+			RETURNING id
+		`, verifierRole[0], verifierRole[1]).Scan(&verifierRoleID)
+		if err == nil {
 			_, err = PostgresDB.Exec(`
-				ALTER TABLE permissions 
-				ADD COLUMN role_id UUID REFERENCES roles(id);
-			`)
-			if err != nil {
-				return fmt.Errorf("failed to add role_id column: %v", err)
-			}
+				INSERT INTO role_permissions (role_id, permission_id)
+				SELECT $1, id FROM permissions WHERE name IN ('achievements:read', 'achievements:verify')
+				ON CONFLICT DO NOTHING
+			`, verifierRoleID)
 		}
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to insert %s role: %v", verifierRole[0], err)
+		}
+	}
+
+	// Create refresh tokens table (server-side refresh token rotation)
+	_, err = PostgresDB.Exec(`
+		CREATE TABLE IF NOT EXISTS refresh_tokens (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			family_id UUID NOT NULL,
+			token_hash VARCHAR(64) UNIQUE NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			revoked_at TIMESTAMP,
+			replaced_by UUID,
+			created_at TIMESTAMP DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+		CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh_tokens table: %v", err)
+	}
+
+	// Create sessions table. A session is one login; its ID is the family_id of
+	// the refresh tokens it rotates through and the "sid" claim of its access tokens.
+	_, err = PostgresDB.Exec(`
+		CREATE TABLE IF NOT EXISTS sessions (
+			id UUID PRIMARY KEY,
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			user_agent TEXT,
+			ip_address VARCHAR(64),
+			last_ip_address VARCHAR(64),
+			created_at TIMESTAMP DEFAULT NOW(),
+			last_seen_at TIMESTAMP DEFAULT NOW(),
+			expires_at TIMESTAMP NOT NULL,
+			revoked_at TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create sessions table: %v", err)
+	}
+
+	// Create access token revocation tables (logout and user-wide revocation)
+	_, err = PostgresDB.Exec(`
+		CREATE TABLE IF NOT EXISTS revoked_tokens (
+			jti UUID PRIMARY KEY,
+			user_id UUID NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			revoked_at TIMESTAMP DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens(expires_at);
+
+		CREATE TABLE IF NOT EXISTS user_token_revocations (
+			user_id UUID PRIMARY KEY,
+			revoked_before TIMESTAMP NOT NULL
+		);
+
+		-- The cutoff in unix seconds, compared with the iat claim of access tokens.
+		-- Cutoffs stored before are rounded up, so they keep revoking their second.
+		ALTER TABLE user_token_revocations ADD COLUMN IF NOT EXISTS revoked_before_epoch BIGINT;
+		UPDATE user_token_revocations SET revoked_before_epoch = CEIL(EXTRACT(EPOCH FROM revoked_before::timestamptz))
+		WHERE revoked_before_epoch IS NULL;
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create token revocation tables: %v", err)
+	}
+
+	// Create password reset tokens table (forgot/reset password flow)
+	_, err = PostgresDB.Exec(`
+		CREATE TABLE IF NOT EXISTS password_reset_tokens (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			token_hash VARCHAR(64) UNIQUE NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			used_at TIMESTAMP,
+			requested_ip VARCHAR(64),
+			created_at TIMESTAMP DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens(user_id);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create password_reset_tokens table: %v", err)
+	}
+
+	// Create password history table (previous hashes, to block password reuse)
+	_, err = PostgresDB.Exec(`
+		CREATE TABLE IF NOT EXISTS password_history (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			password_hash VARCHAR(255) NOT NULL,
+			created_at TIMESTAMP DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_password_history_user_id ON password_history(user_id, created_at);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create password_history table: %v", err)
+	}
+
+	// Create email verification tokens table. The token is bound to the address it
+	// was sent to, so changing the email invalidates it.
+	_, err = PostgresDB.Exec(`
+		CREATE TABLE IF NOT EXISTS email_verification_tokens (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			email VARCHAR(255) NOT NULL,
+			token_hash VARCHAR(64) UNIQUE NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			used_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_email_verification_tokens_user_id ON email_verification_tokens(user_id);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create email_verification_tokens table: %v", err)
+	}
+
+	// Create single sign-on tables: pending OIDC authorization requests and the
+	// external identities (issuer + subject) linked to local users
+	_, err = PostgresDB.Exec(`
+		CREATE TABLE IF NOT EXISTS oidc_states (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			state_hash VARCHAR(64) UNIQUE NOT NULL,
+			nonce VARCHAR(128) NOT NULL,
+			code_verifier VARCHAR(128) NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			created_at TIMESTAMP DEFAULT NOW()
+		);
+
+		CREATE TABLE IF NOT EXISTS user_identities (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			issuer VARCHAR(255) NOT NULL,
+			subject VARCHAR(255) NOT NULL,
+			email VARCHAR(255),
+			created_at TIMESTAMP DEFAULT NOW(),
+			last_login_at TIMESTAMP,
+			UNIQUE (issuer, subject),
+			UNIQUE (user_id, issuer)
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create single sign-on tables: %v", err)
+	}
+
+	// Create TOTP two-factor tables
+	_, err = PostgresDB.Exec(`
+		CREATE TABLE IF NOT EXISTS user_totp (
+			user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+			secret_encrypted TEXT NOT NULL,
+			enabled BOOLEAN NOT NULL DEFAULT false,
+			confirmed_at TIMESTAMP,
+			last_used_step BIGINT NOT NULL DEFAULT 0,
+			failed_attempts INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT NOW(),
+			updated_at TIMESTAMP DEFAULT NOW()
+		);
+
+		CREATE TABLE IF NOT EXISTS totp_recovery_codes (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			code_hash VARCHAR(64) NOT NULL,
+			used_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT NOW(),
+			UNIQUE(user_id, code_hash)
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create two-factor tables: %v", err)
+	}
+
+	// Create login attempt log and brute-force throttle tables
+	_, err = PostgresDB.Exec(`
+		CREATE TABLE IF NOT EXISTS login_attempts (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+			credential VARCHAR(255) NOT NULL,
+			ip_address VARCHAR(64),
+			user_agent TEXT,
+			success BOOLEAN NOT NULL,
+			failure_reason VARCHAR(50),
+			created_at TIMESTAMP DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_login_attempts_user_id ON login_attempts(user_id, created_at);
+		CREATE INDEX IF NOT EXISTS idx_login_attempts_ip_address ON login_attempts(ip_address, created_at);
+		ALTER TABLE login_attempts ADD COLUMN IF NOT EXISTS ip_range VARCHAR(64);
+		ALTER TABLE login_attempts ADD COLUMN IF NOT EXISTS device VARCHAR(100);
+
+		CREATE TABLE IF NOT EXISTS login_throttles (
+			scope VARCHAR(16) NOT NULL,
+			key VARCHAR(255) NOT NULL,
+			failed_count INTEGER NOT NULL DEFAULT 0,
+			last_failed_at TIMESTAMP,
+			locked_until TIMESTAMP,
+			lockout_count INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (scope, key)
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create login attempt tables: %v", err)
+	}
+
+	// Create additional roles of users. users.role_id stays the primary role;
+	// a user's permissions are the union of the primary and these roles.
+	_, err = PostgresDB.Exec(`
+		CREATE TABLE IF NOT EXISTS user_roles (
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			role_id UUID NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+			granted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+			granted_at TIMESTAMP DEFAULT NOW(),
+			PRIMARY KEY (user_id, role_id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_user_roles_role_id ON user_roles(role_id);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create user roles table: %v", err)
+	}
+
+	// Create the scopes of department administrators: the program studies
+	// (students) and departments (lecturers) whose users and achievements they
+	// manage. Matched case-insensitively.
+	_, err = PostgresDB.Exec(`
+		CREATE TABLE IF NOT EXISTS admin_scopes (
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			scope VARCHAR(255) NOT NULL,
+			granted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+			granted_at TIMESTAMP DEFAULT NOW(),
+			PRIMARY KEY (user_id, scope)
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create admin scopes table: %v", err)
+	}
+
+	// Create service accounts and their API keys. A service account is a user
+	// that cannot log in; it authenticates with API keys whose scopes narrow
+	// the permissions of its role.
+	_, err = PostgresDB.Exec(`
+		CREATE TABLE IF NOT EXISTS service_accounts (
+			user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+			description TEXT,
+			created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+			created_at TIMESTAMP DEFAULT NOW()
+		);
+
+		CREATE TABLE IF NOT EXISTS api_keys (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID NOT NULL REFERENCES service_accounts(user_id) ON DELETE CASCADE,
+			name VARCHAR(100) NOT NULL,
+			key_prefix VARCHAR(16) NOT NULL,
+			key_hash VARCHAR(64) UNIQUE NOT NULL,
+			scopes TEXT[] NOT NULL DEFAULT '{}',
+			created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+			created_at TIMESTAMP DEFAULT NOW(),
+			expires_at TIMESTAMP,
+			last_used_at TIMESTAMP,
+			last_used_ip VARCHAR(64),
+			revoked_at TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create service account tables: %v", err)
+	}
+
+	// Create audit log. Rows reference users by ID without a foreign key so the
+	// trail survives when an account is deleted.
+	_, err = PostgresDB.Exec(`
+		CREATE TABLE IF NOT EXISTS audit_logs (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			actor_id UUID,
+			subject_user_id UUID,
+			action VARCHAR(50) NOT NULL,
+			method VARCHAR(10),
+			path TEXT,
+			status_code INTEGER,
+			ip_address VARCHAR(64),
+			user_agent TEXT,
+			details TEXT,
+			created_at TIMESTAMP DEFAULT NOW()
+		);
+		CREATE INDEX IF NOT EXISTS idx_audit_logs_actor_id ON audit_logs(actor_id, created_at);
+		CREATE INDEX IF NOT EXISTS idx_audit_logs_subject_user_id ON audit_logs(subject_user_id, created_at);
+		CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs(created_at);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create audit_logs table: %v", err)
+	}
+
+	// Create verification chains: the stages that must each approve an
+	// achievement of a category (and competition level, empty for any level)
+	// before it is verified. A stage is "advisor" or the name of the role whose
+	// holders approve it. Achievements without a chain are verified by the
+	// advisor alone.
+	_, err = PostgresDB.Exec(`
+		CREATE TABLE IF NOT EXISTS verification_chains (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			category VARCHAR(50) NOT NULL,
+			competition_level VARCHAR(50) NOT NULL DEFAULT '',
+			stages TEXT[] NOT NULL,
+			updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+			created_at TIMESTAMP DEFAULT NOW(),
+			updated_at TIMESTAMP DEFAULT NOW(),
+			UNIQUE (category, competition_level)
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create verification_chains table: %v", err)
+	}
+
+	// Create verification delegations: a lecturer (the delegator) lets another
+	// lecturer verify the achievements of their advisees for a period, e.g.
+	// while on leave. A delegation is in effect from starts_at until ends_at
+	// unless revoked earlier.
+	_, err = PostgresDB.Exec(`
+		CREATE TABLE IF NOT EXISTS verification_delegations (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			delegator_id UUID NOT NULL REFERENCES lecturers(id) ON DELETE CASCADE,
+			delegate_id UUID NOT NULL REFERENCES lecturers(id) ON DELETE CASCADE,
+			starts_at TIMESTAMP NOT NULL,
+			ends_at TIMESTAMP NOT NULL,
+			reason TEXT,
+			created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+			created_at TIMESTAMP DEFAULT NOW(),
+			revoked_at TIMESTAMP,
+			CHECK (ends_at > starts_at),
+			CHECK (delegate_id <> delegator_id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_verification_delegations_delegate_id ON verification_delegations(delegate_id, ends_at);
+		CREATE INDEX IF NOT EXISTS idx_verification_delegations_delegator_id ON verification_delegations(delegator_id, ends_at);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create verification_delegations table: %v", err)
 	}
 
 	log.Println("Database schema setup completed successfully")
 	return nil
 }
 
+// MigrateLegacyPermissions migrates the legacy layout where every permission row
+// carried a role_id: it keeps one row per resource:action and turns the role_id
+// into a grant in role_permissions. It does nothing on the current layout.
+func MigrateLegacyPermissions(db *sql.DB) error {
+	var hasRoleID bool
+	err := db.QueryRow(`
+		SELECT EXISTS (
+			SELECT FROM information_schema.columns 
+			WHERE table_name = 'permissions' 
+			AND column_name = 'role_id'
+		);
+	`).Scan(&hasRoleID)
+	if err != nil {
+		return fmt.Errorf("failed to check role_id column: %v", err)
+	}
+
+	if hasRoleID {
+		log.Println("Migrating legacy permissions.role_id to role_permissions")
+		_, err = db.Exec(`
+			CREATE TEMP TABLE canonical_permissions ON COMMIT DROP AS
+			SELECT DISTINCT ON (resource, action) id, resource, action
+			FROM permissions ORDER BY resource, action, created_at, id;
+
+			INSERT INTO role_permissions (role_id, permission_id)
+			SELECT p.role_id, c.id
+			FROM permissions p
+			JOIN canonical_permissions c ON c.resource = p.resource AND c.action = p.action
+			WHERE p.role_id IS NOT NULL
+			ON CONFLICT DO NOTHING;
+
+			DELETE FROM permissions WHERE id NOT IN (SELECT id FROM canonical_permissions);
+
+			ALTER TABLE permissions DROP COLUMN role_id CASCADE;
+		`)
+		if err != nil {
+			return fmt.Errorf("failed to migrate legacy permissions: %v", err)
+		}
+	}
+
+	return nil
+}
+
 // CreateDefaultUsers creates default admin user if not exists
 func CreateDefaultUsers() error {
 	// Check if admin user exists
@@ -580,8 +1061,8 @@ func CreateDefaultUsers() error {
 
 	// Create admin user
 	_, err = PostgresDB.Exec(`
-		INSERT INTO users (username, email, password, full_name, role_id, is_active)
-		VALUES ('admin', 'admin@unair.ac.id', '$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi', 'System Administrator', $1, true)
+		INSERT INTO users (username, email, password, full_name, role_id, is_active, email_verified_at)
+		VALUES ('admin', 'admin@unair.ac.id', '$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi', 'System Administrator', $1, true, NOW())
 	`, adminRoleID)
 	if err != nil {
 		return fmt.Errorf("failed to create admin user: %v", err)
@@ -595,7 +1076,7 @@ func ResetDatabase() error {
 	log.Println("WARNING: Resetting database - all data will be lost!")
 	
 	// Drop tables in reverse order due to foreign key constraints
-	tables := []string{"permissions", "students", "lecturers", "users", "roles"}
+	tables := []string{"verification_delegations", "verification_chains", "audit_logs", "api_keys", "service_accounts", "user_identities", "oidc_states", "login_throttles", "login_attempts", "totp_recovery_codes", "user_totp", "email_verification_tokens", "password_history", "password_reset_tokens", "user_token_revocations", "revoked_tokens", "refresh_tokens", "sessions", "admin_scopes", "user_roles", "role_permissions", "permissions", "students", "lecturers", "users", "roles"}
 	
 	for _, table := range tables {
 		_, err := PostgresDB.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s CASCADE", table))
@@ -612,7 +1093,7 @@ func ResetDatabase() error {
 
 // CheckDatabaseHealth checks if all required tables exist with correct structure
 func CheckDatabaseHealth() error {
-	requiredTables := []string{"roles", "users", "lecturers", "students", "permissions"}
+	requiredTables := []string{"roles", "users", "lecturers", "students", "permissions", "role_permissions", "refresh_tokens", "sessions", "revoked_tokens", "user_token_revocations", "password_reset_tokens", "password_history", "email_verification_tokens", "oidc_states", "user_identities", "user_totp", "totp_recovery_codes", "login_attempts", "login_throttles", "user_roles", "admin_scopes", "service_accounts", "api_keys", "audit_logs", "verification_chains", "verification_delegations"}
 	
 	for _, table := range requiredTables {
 		var exists bool
@@ -632,6 +1113,44 @@ func CheckDatabaseHealth() error {
 			return fmt.Errorf("required table %s does not exist", table)
 		}
 	}
+
+	// Columns added to existing tables after the initial schema
+	requiredColumns := [][2]string{{"roles", "is_system"}, {"permissions", "name"}, {"users", "approval_status"}, {"users", "email_verified_at"}, {"login_attempts", "device"}}
+
+	for _, column := range requiredColumns {
+		var exists bool
+		err := PostgresDB.QueryRow(`
+			SELECT EXISTS (
+				SELECT FROM information_schema.columns 
+				WHERE table_name = $1 
+				AND column_name = $2
+			);
+		`, column[0], column[1]).Scan(&exists)
+
+		if err != nil {
+			return fmt.Errorf("failed to check column %s.%s: %v", column[0], column[1], err)
+		}
+
+		if !exists {
+			return fmt.Errorf("required column %s.%s does not exist", column[0], column[1])
+		}
+	}
+
+	// The legacy permissions.role_id layout has to be migrated to role_permissions
+	var hasLegacyRoleID bool
+	err := PostgresDB.QueryRow(`
+		SELECT EXISTS (
+			SELECT FROM information_schema.columns 
+			WHERE table_name = 'permissions' 
+			AND column_name = 'role_id'
+		);
+	`).Scan(&hasLegacyRoleID)
+	if err != nil {
+		return fmt.Errorf("failed to check permissions layout: %v", err)
+	}
+	if hasLegacyRoleID {
+		return fmt.Errorf("permissions table uses the legacy role_id layout")
+	}
 	
 	log.Println("Database health check passed")
 	return nil
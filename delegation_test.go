@@ -0,0 +1,60 @@
+package main
+
+import (
+	"UASBE/app/model"
+	"UASBE/app/service"
+	"testing"
+	"time"
+)
+
+// TestValidateDelegationPeriod menguji validasi periode delegasi verifikasi
+func TestValidateDelegationPeriod(t *testing.T) {
+	now := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		startsAt time.Time
+		endsAt   time.Time
+		wantErr  bool
+	}{
+		{"starts now by default", time.Time{}, now.Add(7 * 24 * time.Hour), false},
+		{"scheduled", now.Add(24 * time.Hour), now.Add(48 * time.Hour), false},
+		{"missing end", now, time.Time{}, true},
+		{"end before start", now.Add(48 * time.Hour), now.Add(24 * time.Hour), true},
+		{"already over", now.Add(-48 * time.Hour), now.Add(-24 * time.Hour), true},
+		{"longer than a year", now, now.Add(400 * 24 * time.Hour), true},
+	}
+
+	for _, tt := range tests {
+		startsAt, err := service.ValidateDelegationPeriod(tt.startsAt, tt.endsAt, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if tt.startsAt.IsZero() && !startsAt.Equal(now) {
+			t.Errorf("%s: starts_at = %v, want now", tt.name, startsAt)
+		}
+	}
+}
+
+// TestDelegationStatus menguji status delegasi yang berakhir otomatis
+func TestDelegationStatus(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	delegation := model.VerificationDelegation{StartsAt: start, EndsAt: start.Add(14 * 24 * time.Hour)}
+
+	tests := map[string]time.Time{
+		"scheduled": start.Add(-time.Hour),
+		"active":    start.Add(time.Hour),
+		"expired":   start.Add(15 * 24 * time.Hour),
+	}
+	for want, at := range tests {
+		if got := delegation.StatusAt(at); got != want {
+			t.Errorf("StatusAt(%v) = %q, want %q", at, got, want)
+		}
+	}
+
+	revokedAt := start.Add(2 * time.Hour)
+	delegation.RevokedAt = &revokedAt
+	if got := delegation.StatusAt(start.Add(3 * time.Hour)); got != "revoked" {
+		t.Errorf("revoked delegation status = %q", got)
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"UASBE/app/model"
+	"UASBE/mail"
+	"UASBE/middleware"
+	"database/sql/driver"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// emailVerificationStore adalah tabel email_verification_tokens tiruan
+type emailVerificationStore struct {
+	store  *authStore
+	tokens []*model.EmailVerificationToken
+}
+
+// registerEmailVerificationHandlers meniru query token verifikasi email dan
+// penandaan email terverifikasi pada tabel users
+func (s *authStore) registerEmailVerificationHandlers(db *fakeDB) *emailVerificationStore {
+	v := &emailVerificationStore{store: s}
+
+	db.On("UPDATE email_verification_tokens SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL", func(args []driver.Value) fakeResult {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		var affected int64
+		now := time.Now()
+		for _, token := range v.tokens {
+			if token.UserID == stringArg(args, 0) && token.UsedAt == nil {
+				token.UsedAt = &now
+				affected++
+			}
+		}
+		return fakeAffected(affected)
+	})
+	db.On("INSERT INTO email_verification_tokens", func(args []driver.Value) fakeResult {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		expiresAt, _ := args[4].(time.Time)
+		v.tokens = append(v.tokens, &model.EmailVerificationToken{
+			ID: stringArg(args, 0), UserID: stringArg(args, 1), Email: stringArg(args, 2),
+			TokenHash: stringArg(args, 3), ExpiresAt: expiresAt,
+		})
+		return fakeAffected(1)
+	})
+	db.On("UPDATE email_verification_tokens SET used_at = NOW() WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW() RETURNING user_id, email", func(args []driver.Value) fakeResult {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		columns := []string{"user_id", "email"}
+		now := time.Now()
+		for _, token := range v.tokens {
+			if token.TokenHash == stringArg(args, 0) && token.UsedAt == nil && token.ExpiresAt.After(now) {
+				token.UsedAt = &now
+				return fakeRows(columns, []driver.Value{token.UserID, token.Email})
+			}
+		}
+		return fakeRows(columns)
+	})
+	db.On("DELETE FROM email_verification_tokens WHERE expires_at < NOW() - INTERVAL '1 day'", func(args []driver.Value) fakeResult {
+		return fakeAffected(0)
+	})
+	db.On("UPDATE users SET email_verified_at = NOW(), updated_at = NOW() WHERE id = $1 AND email = $2", func(args []driver.Value) fakeResult {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		user, ok := s.users[stringArg(args, 0)]
+		if !ok || user.Email != stringArg(args, 1) {
+			return fakeAffected(0)
+		}
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		return fakeAffected(1)
+	})
+
+	return v
+}
+
+// expire membuat semua token verifikasi user kedaluwarsa
+func (v *emailVerificationStore) expire(userID string) {
+	v.store.mu.Lock()
+	defer v.store.mu.Unlock()
+	for _, token := range v.tokens {
+		if token.UserID == userID {
+			token.ExpiresAt = time.Now().Add(-time.Minute)
+		}
+	}
+}
+
+var verificationLinkToken = regexp.MustCompile(`[?&]token=([^\s&]+)`)
+
+// sentVerificationToken menunggu email verifikasi ke alamat tujuan (dikirim di
+// background) dan mengembalikan token dari tautannya
+func sentVerificationToken(t *testing.T, outbox *mail.OutboxMailer, to string) string {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, msg := range outbox.Messages() {
+			if len(msg.To) == 0 || msg.To[0] != to || msg.Subject != "Verify your email address" {
+				continue
+			}
+			match := verificationLinkToken.FindStringSubmatch(msg.Body)
+			if match == nil {
+				t.Fatalf("verification email without a token link: %q", msg.Body)
+			}
+			token, err := url.QueryUnescape(match[1])
+			if err != nil {
+				t.Fatalf("QueryUnescape() error = %v", err)
+			}
+			return token
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	t.Fatalf("no verification email sent to %s", to)
+	return ""
+}
+
+func newUnverifiedUser(f *authFixture) *model.User {
+	user := f.store.addUser("budi", f.store.addRole("student", true))
+	user.EmailVerifiedAt = nil
+	return user
+}
+
+// TestVerifyEmail menguji verifikasi email dengan token yang dikirim
+func TestVerifyEmail(t *testing.T) {
+	f := newAuthFixture(t)
+	tokens := f.store.registerEmailVerificationHandlers(f.db)
+	user := newUnverifiedUser(f)
+
+	if err := f.auth.SendEmailVerification(user); err != nil {
+		t.Fatalf("SendEmailVerification() error = %v", err)
+	}
+	token := sentVerificationToken(t, f.outbox, user.Email)
+
+	verified, err := f.auth.VerifyEmail(token)
+	if err != nil {
+		t.Fatalf("VerifyEmail() error = %v", err)
+	}
+	if verified.EmailVerifiedAt == nil {
+		t.Errorf("email not marked verified")
+	}
+
+	// A used token cannot verify again
+	if _, err := f.auth.VerifyEmail(token); err == nil {
+		t.Errorf("VerifyEmail() of a used token must fail")
+	}
+	if len(tokens.tokens) != 1 || tokens.tokens[0].TokenHash == token {
+		t.Errorf("only the hash of the token must be stored")
+	}
+}
+
+// TestVerifyEmailRejectsExpiredToken menguji penolakan token yang kedaluwarsa
+func TestVerifyEmailRejectsExpiredToken(t *testing.T) {
+	f := newAuthFixture(t)
+	tokens := f.store.registerEmailVerificationHandlers(f.db)
+	user := newUnverifiedUser(f)
+
+	if err := f.auth.SendEmailVerification(user); err != nil {
+		t.Fatalf("SendEmailVerification() error = %v", err)
+	}
+	token := sentVerificationToken(t, f.outbox, user.Email)
+	tokens.expire(user.ID)
+
+	if _, err := f.auth.VerifyEmail(token); err == nil || err.Error() != "invalid or expired verification token" {
+		t.Errorf("VerifyEmail() error = %v, want invalid or expired verification token", err)
+	}
+	if user.EmailVerifiedAt != nil {
+		t.Errorf("expired token verified the email")
+	}
+}
+
+// TestVerifyEmailRejectsTokenOfPreviousAddress menguji bahwa token yang dikirim ke
+// alamat lama ditolak setelah email user diganti
+func TestVerifyEmailRejectsTokenOfPreviousAddress(t *testing.T) {
+	f := newAuthFixture(t)
+	f.store.registerEmailVerificationHandlers(f.db)
+	user := newUnverifiedUser(f)
+	oldEmail := user.Email
+
+	if err := f.auth.SendEmailVerification(user); err != nil {
+		t.Fatalf("SendEmailVerification() error = %v", err)
+	}
+	token := sentVerificationToken(t, f.outbox, oldEmail)
+
+	f.store.mu.Lock()
+	user.Email = "budi.baru@example.ac.id"
+	f.store.mu.Unlock()
+
+	if _, err := f.auth.VerifyEmail(token); err == nil || err.Error() != "invalid or expired verification token" {
+		t.Errorf("VerifyEmail() error = %v, want invalid or expired verification token", err)
+	}
+	if user.EmailVerifiedAt != nil {
+		t.Errorf("token of the previous address verified the new one")
+	}
+
+	// The new address needs its own link; the earlier one stays unusable
+	if err := f.auth.SendEmailVerification(user); err != nil {
+		t.Fatalf("SendEmailVerification() error = %v", err)
+	}
+	if _, err := f.auth.VerifyEmail(sentVerificationToken(t, f.outbox, user.Email)); err != nil {
+		t.Errorf("VerifyEmail() of the new address error = %v", err)
+	}
+	if _, err := f.auth.VerifyEmail(token); err == nil {
+		t.Errorf("token of the previous address accepted after the new one was verified")
+	}
+}
+
+// TestRequireVerifiedEmail menguji bahwa akun yang belum terverifikasi tidak bisa
+// mengajukan prestasi selama REQUIRE_VERIFIED_EMAIL_FOR_SUBMISSION aktif
+func TestRequireVerifiedEmail(t *testing.T) {
+	for _, required := range []bool{true, false} {
+		t.Setenv("REQUIRE_VERIFIED_EMAIL_FOR_SUBMISSION", strconv.FormatBool(required))
+		f := newAuthFixture(t)
+		role := f.store.addRole("student", true)
+
+		app := fiber.New()
+		app.Post("/achievements/:achievement_id/submit",
+			middleware.AuthMiddleware(f.auth),
+			middleware.RequireVerifiedEmail(f.auth),
+			func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+		submit := func(user *model.User) int {
+			t.Helper()
+			token := f.accessToken(t, user, f.store.addSession(user.ID), time.Now(), nil)
+			req := httptest.NewRequest("POST", "/achievements/abc/submit", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("app.Test() error = %v", err)
+			}
+			return resp.StatusCode
+		}
+
+		verified := f.store.addUser("siti", role)
+		unverified := f.store.addUser("budi", role)
+		unverified.EmailVerifiedAt = nil
+
+		if status := submit(verified); status != fiber.StatusOK {
+			t.Errorf("required=%v: verified user got %d, want 200", required, status)
+		}
+		want := fiber.StatusOK
+		if required {
+			want = fiber.StatusForbidden
+		}
+		if status := submit(unverified); status != want {
+			t.Errorf("required=%v: unverified user got %d, want %d", required, status, want)
+		}
+	}
+}
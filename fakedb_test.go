@@ -0,0 +1,207 @@
+package main
+
+import (
+	"UASBE/database"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeDB adalah driver database/sql tiruan untuk menguji repository tanpa server
+// PostgreSQL. Setiap statement dijawab oleh handler pertama yang pattern-nya
+// terkandung di dalamnya (spasi diringkas di kedua sisi). Statement yang tidak
+// dikenal handler mana pun menggagalkan test, jadi handler juga mengunci SQL
+// yang dikirim kode.
+type fakeDB struct {
+	t *testing.T
+
+	mu         sync.Mutex
+	handlers   []fakeHandler
+	statements []fakeStatement
+}
+
+// fakeStatement adalah statement yang sudah dijalankan beserta argumennya
+type fakeStatement struct {
+	Query string
+	Args  []driver.Value
+}
+
+// fakeResult menjawab statement: baris untuk query, jumlah baris terpengaruh untuk exec
+type fakeResult struct {
+	Columns  []string
+	Rows     [][]driver.Value
+	Affected int64
+	Err      error
+}
+
+type fakeHandler struct {
+	pattern string
+	answer  func(args []driver.Value) fakeResult
+}
+
+// useFakeDB memasang fakeDB sebagai database.PostgresDB untuk repository yang
+// dibuat sesudahnya; database sebelumnya dikembalikan di akhir test
+func useFakeDB(t *testing.T) *fakeDB {
+	t.Helper()
+	db := &fakeDB{t: t}
+
+	previous := database.PostgresDB
+	database.PostgresDB = sql.OpenDB(fakeConnector{db: db})
+	t.Cleanup(func() {
+		database.PostgresDB.Close()
+		database.PostgresDB = previous
+	})
+
+	return db
+}
+
+// On menjawab statement yang mengandung pattern
+func (db *fakeDB) On(pattern string, answer func(args []driver.Value) fakeResult) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.handlers = append(db.handlers, fakeHandler{pattern: collapseSpaces(pattern), answer: answer})
+}
+
+// Statements mengembalikan statement yang sudah dijalankan dan mengandung pattern
+func (db *fakeDB) Statements(pattern string) []fakeStatement {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	pattern = collapseSpaces(pattern)
+	var matched []fakeStatement
+	for _, statement := range db.statements {
+		if strings.Contains(statement.Query, pattern) {
+			matched = append(matched, statement)
+		}
+	}
+	return matched
+}
+
+func (db *fakeDB) run(query string, named []driver.NamedValue) fakeResult {
+	query = collapseSpaces(query)
+	// Like a real driver, keep copies: fiber passes strings backed by buffers that
+	// are reused after the request
+	args := make([]driver.Value, len(named))
+	for i, value := range named {
+		if text, ok := value.Value.(string); ok {
+			args[i] = strings.Clone(text)
+		} else {
+			args[i] = value.Value
+		}
+	}
+
+	db.mu.Lock()
+	db.statements = append(db.statements, fakeStatement{Query: query, Args: args})
+	var answer func(args []driver.Value) fakeResult
+	for _, handler := range db.handlers {
+		if strings.Contains(query, handler.pattern) {
+			answer = handler.answer
+			break
+		}
+	}
+	db.mu.Unlock()
+
+	if answer == nil {
+		db.t.Errorf("fakeDB: unexpected statement %q", query)
+		return fakeResult{Err: errors.New("fakeDB: unexpected statement")}
+	}
+	return answer(args)
+}
+
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// fakeRows membangun jawaban query dengan kolom dan baris
+func fakeRows(columns []string, rows ...[]driver.Value) fakeResult {
+	return fakeResult{Columns: columns, Rows: rows}
+}
+
+// fakeAffected membangun jawaban exec
+func fakeAffected(n int64) fakeResult {
+	return fakeResult{Affected: n}
+}
+
+type fakeConnector struct {
+	db *fakeDB
+}
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{db: c.db}, nil
+}
+
+func (c fakeConnector) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("fakeDB: open through fakeConnector")
+}
+
+// fakeConn menjalankan statement langsung (QueryerContext/ExecerContext), tanpa prepare
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("fakeDB: prepared statements are not supported")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	result := c.db.run(query, args)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	return &fakeRowsIterator{columns: result.Columns, rows: result.Rows}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	result := c.db.run(query, args)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	return driver.RowsAffected(result.Affected), nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRowsIterator struct {
+	columns []string
+	rows    [][]driver.Value
+	next    int
+}
+
+func (r *fakeRowsIterator) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRowsIterator) Close() error {
+	return nil
+}
+
+func (r *fakeRowsIterator) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.next])
+	r.next++
+	return nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"UASBE/app/model"
+	"UASBE/app/repository"
+	"UASBE/app/service"
+	"UASBE/middleware"
+	"database/sql/driver"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// impersonationFixture adalah ImpersonationService di atas authFixture, dengan
+// admin yang sedang login dan mahasiswa sebagai target
+type impersonationFixture struct {
+	*authFixture
+	impersonation *service.ImpersonationService
+	admin         *model.User
+	adminSession  string
+	student       *model.User
+	auditLogs     []model.AuditLog
+}
+
+func newImpersonationFixture(t *testing.T) *impersonationFixture {
+	t.Helper()
+
+	f := &impersonationFixture{authFixture: newAuthFixture(t)}
+	f.admin = f.store.addUser("admin", f.store.addRole("admin", true))
+	f.adminSession = f.store.addSession(f.admin.ID)
+	f.student = f.store.addUser("budi", f.store.addRole("student", true))
+
+	f.db.On("INSERT INTO audit_logs (id, actor_id, subject_user_id, action, method, path, status_code, ip_address, user_agent, details, created_at)", func(args []driver.Value) fakeResult {
+		f.store.mu.Lock()
+		defer f.store.mu.Unlock()
+		statusCode, _ := args[6].(int64)
+		f.auditLogs = append(f.auditLogs, model.AuditLog{
+			ID: stringArg(args, 0), ActorID: stringArg(args, 1), SubjectUserID: stringArg(args, 2), Action: stringArg(args, 3),
+			Method: stringArg(args, 4), Path: stringArg(args, 5), StatusCode: int(statusCode), Details: stringArg(args, 9),
+		})
+		return fakeAffected(1)
+	})
+
+	f.impersonation = service.NewImpersonationService(f.auth, repository.NewUserRepository(), repository.NewAuditLogRepository())
+	return f
+}
+
+// TestImpersonateRejectsAdministrators menguji bahwa admin tidak bisa di-impersonate
+func TestImpersonateRejectsAdministrators(t *testing.T) {
+	f := newImpersonationFixture(t)
+	adminRole := f.store.roles[f.admin.RoleID]
+
+	otherAdmin := f.store.addUser("admin2", adminRole)
+	if _, err := f.impersonation.Impersonate(f.admin.ID, f.adminSession, otherAdmin.ID, "Investigating a report", testClient); err == nil || err.Error() != "administrators cannot be impersonated" {
+		t.Errorf("Impersonate() of an admin error = %v, want administrators cannot be impersonated", err)
+	}
+
+	// Admin as an additional role counts as well
+	lecturer := f.store.addUser("dosen", f.store.addRole("lecturer", true))
+	f.store.userRoles[lecturer.ID] = []string{adminRole.ID}
+	if _, err := f.impersonation.Impersonate(f.admin.ID, f.adminSession, lecturer.ID, "Investigating a report", testClient); err == nil || err.Error() != "administrators cannot be impersonated" {
+		t.Errorf("Impersonate() of an additional admin error = %v, want administrators cannot be impersonated", err)
+	}
+
+	if len(f.auditLogs) != 0 {
+		t.Errorf("refused impersonation wrote %d audit logs", len(f.auditLogs))
+	}
+}
+
+// TestImpersonationTokenIsReadOnly menguji bahwa token impersonation hanya bisa
+// membaca dan setiap request tercatat di audit_logs atas nama admin dan user
+func TestImpersonationTokenIsReadOnly(t *testing.T) {
+	f := newImpersonationFixture(t)
+
+	impersonated, err := f.impersonation.Impersonate(f.admin.ID, f.adminSession, f.student.ID, "Investigating a report", testClient)
+	if err != nil {
+		t.Fatalf("Impersonate() error = %v", err)
+	}
+	if len(f.auditLogs) != 1 || f.auditLogs[0].Action != model.AuditActionImpersonationStart ||
+		f.auditLogs[0].ActorID != f.admin.ID || f.auditLogs[0].SubjectUserID != f.student.ID || f.auditLogs[0].Details != "Investigating a report" {
+		t.Fatalf("start audit log = %+v", f.auditLogs)
+	}
+
+	app := fiber.New()
+	app.Use(middleware.ImpersonationAuditMiddleware(f.impersonation))
+	reached := 0
+	handler := func(c *fiber.Ctx) error {
+		reached++
+		return c.SendStatus(fiber.StatusOK)
+	}
+	api := app.Group("/api/achievements", middleware.AuthMiddleware(f.auth))
+	api.Get("/", handler)
+	api.Post("/", handler)
+	api.Put("/:id", handler)
+	api.Delete("/:id", handler)
+
+	request := func(method, path, token string) int {
+		t.Helper()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test() error = %v", err)
+		}
+		return resp.StatusCode
+	}
+
+	requests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{fiber.MethodGet, "/api/achievements/", fiber.StatusOK},
+		{fiber.MethodPost, "/api/achievements/", fiber.StatusForbidden},
+		{fiber.MethodPut, "/api/achievements/abc", fiber.StatusForbidden},
+		{fiber.MethodDelete, "/api/achievements/abc", fiber.StatusForbidden},
+	}
+	for _, r := range requests {
+		if status := request(r.method, r.path, impersonated.Token); status != r.want {
+			t.Errorf("%s %s: got %d, want %d", r.method, r.path, status, r.want)
+		}
+	}
+	if reached != 1 {
+		t.Errorf("handler reached %d times, want only for GET", reached)
+	}
+
+	// Every request, refused ones included, names the admin and the user
+	logs := f.auditLogs[1:]
+	if len(logs) != len(requests) {
+		t.Fatalf("got %d request audit logs, want %d", len(logs), len(requests))
+	}
+	for i, r := range requests {
+		entry := logs[i]
+		if entry.Action != model.AuditActionImpersonationRequest || entry.ActorID != f.admin.ID || entry.SubjectUserID != f.student.ID {
+			t.Errorf("%s %s: audit log %+v must name impersonator %s and user %s", r.method, r.path, entry, f.admin.ID, f.student.ID)
+		}
+		if entry.Method != r.method || entry.Path != r.path || entry.StatusCode != r.want {
+			t.Errorf("%s %s: audit log recorded %s %s %d", r.method, r.path, entry.Method, entry.Path, entry.StatusCode)
+		}
+	}
+
+	// Requests with the user's own token are not audited
+	own := f.accessToken(t, f.student, f.store.addSession(f.student.ID), time.Now(), nil)
+	if status := request(fiber.MethodPost, "/api/achievements/", own); status != fiber.StatusOK {
+		t.Errorf("own token POST: got %d, want 200", status)
+	}
+	if len(f.auditLogs) != 1+len(requests) {
+		t.Errorf("request with the user's own token was audited")
+	}
+}
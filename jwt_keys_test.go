@@ -0,0 +1,138 @@
+package main
+
+import (
+	"UASBE/app/service"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func writeEd25519Key(t *testing.T, dir, kid string) ed25519.PrivateKey {
+	t.Helper()
+	_, private, _ := ed25519.GenerateKey(rand.Reader)
+	der, _ := x509.MarshalPKCS8PrivateKey(private)
+	writePEM(t, filepath.Join(dir, kid+".pem"), "PRIVATE KEY", der)
+	return private
+}
+
+func testClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"user_id": "user-1",
+		"exp":     time.Now().Add(time.Minute).Unix(),
+		"iat":     time.Now().Unix(),
+	}
+}
+
+// TestKeySetSignAndParse menguji tanda tangan RS256 dan EdDSA beserta header kid
+func TestKeySetSignAndParse(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	writePEM(t, filepath.Join(dir, "rsa-1.pem"), "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+	writeEd25519Key(t, dir, "ed-1")
+
+	for _, kid := range []string{"rsa-1", "ed-1"} {
+		t.Run(kid, func(t *testing.T) {
+			keys, err := service.LoadKeySet(dir, kid)
+			if err != nil {
+				t.Fatalf("LoadKeySet() error = %v", err)
+			}
+
+			signed, err := keys.Sign(testClaims())
+			if err != nil {
+				t.Fatalf("Sign() error = %v", err)
+			}
+
+			parsed, _, _ := jwt.NewParser().ParseUnverified(signed, jwt.MapClaims{})
+			if parsed.Header["kid"] != kid {
+				t.Errorf("kid header = %v, want %v", parsed.Header["kid"], kid)
+			}
+
+			claims, err := keys.Parse(signed)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if claims["user_id"] != "user-1" {
+				t.Errorf("user_id = %v, want user-1", claims["user_id"])
+			}
+		})
+	}
+}
+
+// TestKeySetRotation menguji rotasi key: token lama tetap valid selama key lama masih ada
+func TestKeySetRotation(t *testing.T) {
+	dir := t.TempDir()
+	oldKey := writeEd25519Key(t, dir, "2025-01")
+
+	before, err := service.LoadKeySet(dir, "")
+	if err != nil {
+		t.Fatalf("LoadKeySet() error = %v", err)
+	}
+	oldToken, _ := before.Sign(testClaims())
+
+	// Rotate: new key, the old key is retired to its public half
+	writeEd25519Key(t, dir, "2025-06")
+	os.Remove(filepath.Join(dir, "2025-01.pem"))
+	publicDER, _ := x509.MarshalPKIXPublicKey(oldKey.Public())
+	writePEM(t, filepath.Join(dir, "2025-01.pub.pem"), "PUBLIC KEY", publicDER)
+
+	after, err := service.LoadKeySet(dir, "")
+	if err != nil {
+		t.Fatalf("LoadKeySet() after rotation error = %v", err)
+	}
+
+	if after.ActiveKID() != "2025-06" {
+		t.Errorf("ActiveKID() = %v, want newest key 2025-06", after.ActiveKID())
+	}
+	if _, err := after.Parse(oldToken); err != nil {
+		t.Errorf("token signed with the retired key rejected: %v", err)
+	}
+	if _, err := service.LoadKeySet(dir, "2025-01"); err == nil {
+		t.Errorf("a public-only key must not be usable as the active key")
+	}
+
+	// A key set without the old key rejects its tokens
+	other, _ := service.NewEphemeralKeySet()
+	if _, err := other.Parse(oldToken); err == nil {
+		t.Errorf("token with unknown kid accepted")
+	}
+
+	jwks := after.JWKS()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("JWKS has %d keys, want 2", len(jwks.Keys))
+	}
+	for _, key := range jwks.Keys {
+		if key.Kty != "OKP" || key.Crv != "Ed25519" || key.Alg != "EdDSA" || key.X == "" {
+			t.Errorf("unexpected JWK %+v", key)
+		}
+	}
+}
+
+// TestKeySetRejectsHMAC menguji bahwa token HS256 (mis. dengan public key sebagai secret) ditolak
+func TestKeySetRejectsHMAC(t *testing.T) {
+	keys, _ := service.NewEphemeralKeySet()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims())
+	token.Header["kid"] = keys.ActiveKID()
+	signed, _ := token.SignedString([]byte("shared-secret"))
+
+	if _, err := keys.Parse(signed); err == nil {
+		t.Errorf("HS256 token accepted")
+	}
+}
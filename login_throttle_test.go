@@ -0,0 +1,86 @@
+package main
+
+import (
+	"UASBE/app/service"
+	"testing"
+	"time"
+)
+
+func testThrottlePolicy() service.ThrottlePolicy {
+	return service.ThrottlePolicy{
+		FreeAttempts:    2,
+		Threshold:       5,
+		LockoutDuration: 15 * time.Minute,
+		BackoffBase:     time.Second,
+		BackoffMax:      30 * time.Second,
+		Window:          15 * time.Minute,
+	}
+}
+
+// TestThrottleBackoff menguji exponential backoff setelah percobaan gratis
+func TestThrottleBackoff(t *testing.T) {
+	policy := testThrottlePolicy()
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{5, 4 * time.Second},
+		{8, 30 * time.Second}, // 32s capped at BackoffMax
+		{100, 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := policy.Backoff(tt.failures); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+// TestThrottleRetryAfter menguji kapan percobaan login berikutnya diizinkan
+func TestThrottleRetryAfter(t *testing.T) {
+	policy := testThrottlePolicy()
+
+	tests := []struct {
+		name  string
+		state service.ThrottleState
+		want  time.Duration
+	}{
+		{"No failures", service.ThrottleState{}, 0},
+		{"Within free attempts", service.ThrottleState{Failures: 2, SinceLastFailure: 0}, 0},
+		{"Backing off", service.ThrottleState{Failures: 4, SinceLastFailure: 500 * time.Millisecond}, 1500 * time.Millisecond},
+		{"Backoff elapsed", service.ThrottleState{Failures: 4, SinceLastFailure: 3 * time.Second}, 0},
+		{"Failures outside window are forgotten", service.ThrottleState{Failures: 100, SinceLastFailure: 20 * time.Minute}, 0},
+		{"Locked out", service.ThrottleState{LockRemaining: 10 * time.Minute}, 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.RetryAfter(tt.state); got != tt.want {
+				t.Errorf("RetryAfter(%+v) = %v, want %v", tt.state, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestThrottleShouldLock menguji threshold lockout
+func TestThrottleShouldLock(t *testing.T) {
+	policy := testThrottlePolicy()
+
+	if policy.ShouldLock(4) {
+		t.Errorf("ShouldLock(4) = true, want false below threshold")
+	}
+	if !policy.ShouldLock(5) {
+		t.Errorf("ShouldLock(5) = false, want true at threshold")
+	}
+
+	policy.Threshold = 0
+	if policy.ShouldLock(1000) {
+		t.Errorf("ShouldLock with Threshold 0 should never lock")
+	}
+}
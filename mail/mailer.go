@@ -0,0 +1,89 @@
+package mail
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      []string  `json:"to"`
+	Subject string    `json:"subject"`
+	Body    string    `json:"body"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+// Mailer sends email. Services depend on this interface so the SMTP transport can be
+// swapped for the outbox in development and tests.
+type Mailer interface {
+	Send(msg *Message) error
+}
+
+// NewMailerFromEnv builds the mailer selected by MAIL_DRIVER:
+//   - "smtp": deliver through SMTP_HOST/SMTP_PORT (e.g. a local mail catcher on :1025)
+//   - "outbox" (default): keep messages in memory and, when MAIL_OUTBOX_DIR is set,
+//     also write each one to that directory as an .eml file
+func NewMailerFromEnv() Mailer {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@uasbe.local"
+	}
+
+	switch strings.ToLower(os.Getenv("MAIL_DRIVER")) {
+	case "smtp":
+		host := os.Getenv("SMTP_HOST")
+		if host == "" {
+			host = "localhost"
+		}
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "1025"
+		}
+		log.Printf("Mailer: SMTP %s:%s", host, port)
+		return NewSMTPMailer(host, port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from)
+	case "", "outbox":
+		dir := os.Getenv("MAIL_OUTBOX_DIR")
+		if dir != "" {
+			log.Printf("Mailer: outbox (writing messages to %s)", dir)
+		} else {
+			log.Println("Mailer: in-memory outbox, emails are not delivered")
+		}
+		return NewOutboxMailer(dir, from)
+	default:
+		log.Printf("Warning: unknown MAIL_DRIVER=%q, using in-memory outbox", os.Getenv("MAIL_DRIVER"))
+		return NewOutboxMailer("", from)
+	}
+}
+
+func validate(msg *Message) error {
+	if msg == nil || len(msg.To) == 0 {
+		return fmt.Errorf("mail: message has no recipients")
+	}
+	for _, to := range msg.To {
+		// Reject header injection through recipient addresses
+		if strings.ContainsAny(to, "\r\n") || !strings.Contains(to, "@") {
+			return fmt.Errorf("mail: invalid recipient %q", to)
+		}
+	}
+	if strings.ContainsAny(msg.Subject, "\r\n") {
+		return fmt.Errorf("mail: invalid subject")
+	}
+	return nil
+}
+
+// format renders the message as an RFC 5322 document
+func format(from string, msg *Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + strings.Join(msg.To, ", ") + "\r\n")
+	b.WriteString("Subject: " + msg.Subject + "\r\n")
+	b.WriteString("Date: " + msg.SentAt.Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return []byte(b.String())
+}
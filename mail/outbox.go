@@ -0,0 +1,71 @@
+package mail
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OutboxMailer keeps every sent message in memory instead of delivering it, and
+// optionally writes it to a directory. Tests read the messages back with Messages.
+type OutboxMailer struct {
+	mu       sync.Mutex
+	dir      string
+	from     string
+	messages []Message
+}
+
+func NewOutboxMailer(dir, from string) *OutboxMailer {
+	return &OutboxMailer{dir: dir, from: from}
+}
+
+func (m *OutboxMailer) Send(msg *Message) error {
+	if err := validate(msg); err != nil {
+		return err
+	}
+	if msg.SentAt.IsZero() {
+		msg.SentAt = time.Now()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.dir != "" {
+		if err := os.MkdirAll(m.dir, 0o755); err != nil {
+			return err
+		}
+		name := fmt.Sprintf("%s-%s.eml", msg.SentAt.Format("20060102T150405"), uuid.New().String())
+		if err := os.WriteFile(filepath.Join(m.dir, name), format(m.from, msg), 0o644); err != nil {
+			return err
+		}
+	}
+
+	m.messages = append(m.messages, *msg)
+	return nil
+}
+
+// Messages returns a copy of every message sent so far
+func (m *OutboxMailer) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	messages := make([]Message, len(m.messages))
+	copy(messages, m.messages)
+	return messages
+}
+
+// Last returns the most recently sent message, or nil when the outbox is empty
+func (m *OutboxMailer) Last() *Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.messages) == 0 {
+		return nil
+	}
+	msg := m.messages[len(m.messages)-1]
+	return &msg
+}
@@ -0,0 +1,44 @@
+package mail
+
+import (
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// SMTPMailer delivers messages through an SMTP server. Authentication is only used
+// when a username is configured, so it also works with local catchers such as
+// MailHog or Mailpit that accept anonymous mail.
+type SMTPMailer struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+}
+
+func NewSMTPMailer(host, port, username, password, from string) *SMTPMailer {
+	return &SMTPMailer{
+		addr:     net.JoinHostPort(host, port),
+		host:     host,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+func (m *SMTPMailer) Send(msg *Message) error {
+	if err := validate(msg); err != nil {
+		return err
+	}
+	if msg.SentAt.IsZero() {
+		msg.SentAt = time.Now()
+	}
+
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+
+	return smtp.SendMail(m.addr, auth, m.from, msg.To, format(m.from, msg))
+}
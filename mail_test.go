@@ -0,0 +1,72 @@
+package main
+
+import (
+	"UASBE/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestOutboxMailer menguji outbox mailer yang dipakai untuk development dan testing
+func TestOutboxMailer(t *testing.T) {
+	dir := t.TempDir()
+	outbox := mail.NewOutboxMailer(dir, "no-reply@uasbe.local")
+
+	if outbox.Last() != nil {
+		t.Fatalf("Last() on empty outbox should be nil")
+	}
+
+	err := outbox.Send(&mail.Message{
+		To:      []string{"student@example.com"},
+		Subject: "Reset your password",
+		Body:    "Open http://localhost:3000/reset-password?token=abc\n",
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	last := outbox.Last()
+	if last == nil || last.Subject != "Reset your password" || last.SentAt.IsZero() {
+		t.Fatalf("Last() = %+v, want the sent message with SentAt set", last)
+	}
+	if len(outbox.Messages()) != 1 {
+		t.Errorf("Messages() length = %d, want 1", len(outbox.Messages()))
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("outbox dir has %d .eml files, want 1", len(files))
+	}
+	content, _ := os.ReadFile(files[0])
+	for _, want := range []string{"From: no-reply@uasbe.local", "To: student@example.com", "Subject: Reset your password", "token=abc"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf(".eml file missing %q", want)
+		}
+	}
+}
+
+// TestMailerRejectsInvalidMessages menguji validasi penerima dan header injection
+func TestMailerRejectsInvalidMessages(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  *mail.Message
+	}{
+		{"No recipients", &mail.Message{Subject: "Hi"}},
+		{"Recipient without @", &mail.Message{To: []string{"student"}, Subject: "Hi"}},
+		{"Header injection in recipient", &mail.Message{To: []string{"a@example.com\r\nBcc: x@example.com"}, Subject: "Hi"}},
+		{"Header injection in subject", &mail.Message{To: []string{"a@example.com"}, Subject: "Hi\r\nBcc: x@example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outbox := mail.NewOutboxMailer("", "no-reply@uasbe.local")
+			if err := outbox.Send(tt.msg); err == nil {
+				t.Errorf("Send() error = nil, want error")
+			}
+			if len(outbox.Messages()) != 0 {
+				t.Errorf("invalid message was stored in the outbox")
+			}
+		})
+	}
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"UASBE/app/policy"
 	"UASBE/app/repository"
 	"UASBE/app/service"
 	"UASBE/database"
+	"UASBE/mail"
+	"UASBE/middleware"
 	"UASBE/route"
 	"log"
 	"os"
@@ -38,6 +41,11 @@ import (
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
 
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @description Service account API key, issued by an admin under /api/admin/service-accounts.
+
 func main() {
 	// Load .env file
 	if err := godotenv.Load(); err != nil {
@@ -49,12 +57,8 @@ func main() {
 
 
 
-	// Get JWT secret from environment
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "your-secret-key-change-in-production"
-		log.Println("Warning: Using default JWT secret. Please set JWT_SECRET in production.")
-	}
+	// Load JWT signing/verification keys (JWT_KEYS_DIR, JWT_ACTIVE_KID)
+	jwtKeys := service.LoadKeySetFromEnv()
 
 	// Initialize repositories
 	userRepo := repository.NewUserRepository()
@@ -62,12 +66,49 @@ func main() {
 	lecturerRepo := repository.NewLecturerRepository()
 	achievementRepo := repository.NewAchievementRepository()
 	notificationRepo := repository.NewNotificationRepository()
+	tokenRepo := repository.NewTokenRepository()
+	twoFactorRepo := repository.NewTwoFactorRepository()
+	loginAttemptRepo := repository.NewLoginAttemptRepository()
+	sessionRepo := repository.NewSessionRepository()
+	roleRepo := repository.NewRoleRepository()
+	permissionRepo := repository.NewPermissionRepository()
+	identityRepo := repository.NewIdentityRepository()
+	auditLogRepo := repository.NewAuditLogRepository()
+	serviceAccountRepo := repository.NewServiceAccountRepository()
+	userRoleRepo := repository.NewUserRoleRepository()
+	adminScopeRepo := repository.NewAdminScopeRepository()
+	verificationChainRepo := repository.NewVerificationChainRepository()
+	verificationDelegationRepo := repository.NewVerificationDelegationRepository()
+
+	// Single sign-on with the university identity provider (OIDC_ISSUER), optional
+	var oidcProvider *service.OIDCProvider
+	if oidcConfig := service.OIDCConfigFromEnv(); oidcConfig != nil {
+		oidcProvider = service.NewOIDCProvider(*oidcConfig)
+	}
+
+	// Outgoing email (SMTP or local outbox, see MAIL_DRIVER)
+	mailer := mail.NewMailerFromEnv()
 
 	// Initialize services
-	authService := service.NewAuthService(userRepo, studentRepo, lecturerRepo, jwtSecret)
 	notificationService := service.NewNotificationService(notificationRepo)
-	achievementService := service.NewAchievementService(achievementRepo, studentRepo, lecturerRepo, notificationService)
-	userService := service.NewUserService(userRepo, studentRepo, lecturerRepo)
+	permissionResolver := service.NewPermissionResolver(roleRepo)
+	roleService := service.NewRoleService(roleRepo, permissionRepo, userRepo, userRoleRepo, permissionResolver)
+	authService := service.NewAuthService(userRepo, studentRepo, lecturerRepo, tokenRepo, twoFactorRepo, loginAttemptRepo, sessionRepo, serviceAccountRepo, userRoleRepo, notificationService, mailer, jwtKeys, permissionResolver)
+	registrationService := service.NewRegistrationService(authService, userRepo, studentRepo)
+	sessionService := service.NewSessionService(authService, sessionRepo, userRepo)
+	accountService := service.NewAccountService(authService, userRepo, studentRepo, lecturerRepo)
+	impersonationService := service.NewImpersonationService(authService, userRepo, auditLogRepo)
+	serviceAccountService := service.NewServiceAccountService(authService, userRepo, serviceAccountRepo)
+	ssoService := service.NewSSOService(authService, oidcProvider, identityRepo, userRepo, studentRepo)
+	twoFactorService := service.NewTwoFactorService(authService, twoFactorRepo, userRepo)
+	adminScopeService := service.NewAdminScopeService(authService, userRepo, studentRepo, lecturerRepo, adminScopeRepo)
+	verificationChainService := service.NewVerificationChainService(verificationChainRepo, roleRepo)
+	delegationService := service.NewDelegationService(verificationDelegationRepo, lecturerRepo, notificationService)
+	// Reminders and escalation of achievements waiting too long for verification
+	slaConfig := service.VerificationSLAConfigFromEnv()
+	verificationSLAService := service.NewVerificationSLAService(slaConfig, achievementRepo, studentRepo, lecturerRepo, userRepo, adminScopeRepo, verificationDelegationRepo, notificationService)
+	achievementService := service.NewAchievementService(achievementRepo, studentRepo, lecturerRepo, notificationService, adminScopeService, policy.Default(), policy.DefaultWorkflow(), verificationChainService, delegationService, slaConfig)
+	userService := service.NewUserService(userRepo, studentRepo, lecturerRepo, authService, adminScopeService)
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
@@ -79,20 +120,24 @@ func main() {
 	app.Use(recover.New())
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: "*",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
+		AllowHeaders: "Origin, Content-Type, Accept, Authorization, X-API-Key",
 		AllowMethods: "GET, POST, PUT, DELETE",
 	}))
 	app.Use(logger.New())
 
+	// Audit every request made with an impersonation token
+	app.Use(middleware.ImpersonationAuditMiddleware(impersonationService))
+
 	// Static file serving for uploads
 	app.Static("/uploads", "./uploads")
 
 	// Routes
-	route.SetupAuthRoutes(app, authService)
-	route.SetupAchievementRoutes(app, achievementService, authService)
+	route.SetupAuthRoutes(app, authService, twoFactorService, ssoService, sessionService, accountService)
+	route.SetupAchievementRoutes(app, achievementService, authService, adminScopeService)
 	route.SetupNotificationRoutes(app, notificationService, authService)
-	route.SetupUserRoutes(app, userService, authService)
-	route.SetupAdminRoutes(app, authService)
+	route.SetupDelegationRoutes(app, delegationService, authService)
+	route.SetupUserRoutes(app, userService, authService, sessionService, adminScopeService)
+	route.SetupAdminRoutes(app, authService, roleService, registrationService, impersonationService, serviceAccountService, adminScopeService, verificationChainService)
 	route.SetupTestRoutes(app, authService)
 
 	// Swagger documentation
@@ -114,6 +159,9 @@ func main() {
 		})
 	})
 
+	// Background jobs
+	verificationSLAService.Start()
+
 	// Graceful shutdown
 	defer database.Disconnect()
 
@@ -1,7 +1,9 @@
 package middleware
 
 import (
+	"UASBE/app/policy"
 	"UASBE/app/service"
+	"errors"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
@@ -13,6 +15,10 @@ func AuthMiddleware(authService *service.AuthService) fiber.Handler {
 		// FR-002 Step 1: Ekstrak JWT dari header
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
+			// Service accounts authenticate with an API key instead of a JWT
+			if apiKey := c.Get("X-API-Key"); apiKey != "" {
+				return apiKeyAuth(c, authService, apiKey)
+			}
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "Authorization header required",
 			})
@@ -42,45 +48,205 @@ func AuthMiddleware(authService *service.AuthService) fiber.Handler {
 			})
 		}
 
+		// The token only carries user_id and role_id; everything else is live
+		user, err := authService.GetActiveUser(userID)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid token",
+			})
+		}
+
+		roleID, _ := (*claims)["role_id"].(string)
+		if roleID != user.RoleID {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Role has changed, please log in again",
+			})
+		}
+
+		// The primary role plus any additional roles, with the union of their permissions
+		roles, err := authService.ResolveUserRoles(user)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to resolve role permissions",
+			})
+		}
+
 		// Store user information in context
 		c.Locals("user_id", userID)
-		c.Locals("username", (*claims)["username"])
-		c.Locals("role", (*claims)["role"])
-		c.Locals("role_id", (*claims)["role_id"])
-		
-		// Store permissions if available
-		if permissions, ok := (*claims)["permissions"].([]interface{}); ok {
-			var permissionStrings []string
-			for _, perm := range permissions {
-				if permStr, ok := perm.(string); ok {
-					permissionStrings = append(permissionStrings, permStr)
-				}
+		c.Locals("jti", (*claims)["jti"])
+		c.Locals("session_id", (*claims)["sid"])
+
+		// Impersonation tokens ("view as user") may only read
+		if impersonatorID, _ := (*claims)["imp"].(string); impersonatorID != "" {
+			c.Locals("impersonator_id", impersonatorID)
+			if !isReadOnlyMethod(c.Method()) {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error": "Impersonation tokens are read-only",
+					"code": "IMPERSONATION_READ_ONLY",
+				})
 			}
-			c.Locals("permissions", permissionStrings)
+		}
+		if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+			c.Locals("token_expires_at", exp.Time)
+		}
+		c.Locals("username", user.Username)
+		c.Locals("email_verified", user.EmailVerifiedAt != nil)
+		c.Locals("role", roles.Primary.Role.Name)
+		c.Locals("role_id", roleID)
+		c.Locals("roles", roles.Names())
+		c.Locals("role_ids", roles.IDs())
+		c.Locals("permissions", roles.PermissionStrings())
+
+		return c.Next()
+	}
+}
+
+// apiKeyAuth authenticates a service account by its X-API-Key. The request may only
+// use the key's scopes, which RBACMiddleware checks instead of the role.
+func apiKeyAuth(c *fiber.Ctx, authService *service.AuthService, apiKey string) error {
+	principal, err := authService.AuthenticateAPIKey(apiKey, c.IP())
+	if err != nil {
+		if err.Error() == "invalid api key" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid API key",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to resolve role permissions",
+		})
+	}
+
+	c.Locals("user_id", principal.User.ID)
+	c.Locals("api_key_id", principal.Key.ID)
+	c.Locals("username", principal.User.Username)
+	c.Locals("email_verified", principal.User.EmailVerifiedAt != nil)
+	c.Locals("role", principal.Role.Role.Name)
+	c.Locals("role_id", principal.User.RoleID)
+	c.Locals("roles", []string{principal.Role.Role.Name})
+	c.Locals("role_ids", []string{principal.User.RoleID})
+	c.Locals("permissions", principal.Scopes)
+	c.Locals("api_key_scopes", principal.Scopes)
+
+	return c.Next()
+}
+
+// TwoFactorSetupMiddleware - accepts a normal access token or the setup token a user
+// gets at login when their role requires two-factor but they have not enrolled yet
+func TwoFactorSetupMiddleware(authService *service.AuthService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		authHeader := c.Get("Authorization")
+		if !strings.HasPrefix(authHeader, "Bearer ") {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Authorization header required",
+			})
+		}
+
+		token := strings.TrimPrefix(authHeader, "Bearer ")
+
+		tokenType := "access"
+		claims, err := authService.ValidateToken(token)
+		if err != nil {
+			tokenType = "2fa_setup"
+			claims, err = authService.ValidateTwoFactorSetupToken(token)
+		}
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid token",
+			})
+		}
+
+		// Enrolment changes the account, which an impersonating admin may not do
+		if impersonatorID, _ := (*claims)["imp"].(string); impersonatorID != "" {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Impersonation tokens are read-only",
+				"code": "IMPERSONATION_READ_ONLY",
+			})
+		}
+
+		c.Locals("user_id", (*claims)["user_id"])
+		c.Locals("jti", (*claims)["jti"])
+		c.Locals("token_type", tokenType)
+		if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+			c.Locals("token_expires_at", exp.Time)
 		}
 
 		return c.Next()
 	}
 }
 
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// isReadOnlyMethod reports whether an HTTP method only reads
+func isReadOnlyMethod(method string) bool {
+	return method == fiber.MethodGet || method == fiber.MethodHead || method == fiber.MethodOptions
+}
+
+// ImpersonationAuditMiddleware - records every request made with an impersonation
+// token, including refused ones. Registered app-wide so it sees the final status;
+// AuthMiddleware further down the chain marks the request as impersonated.
+func ImpersonationAuditMiddleware(impersonationService *service.ImpersonationService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+
+		impersonatorID, _ := c.Locals("impersonator_id").(string)
+		if impersonatorID == "" {
+			return err
+		}
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+		}
+
+		userID, _ := c.Locals("user_id").(string)
+		impersonationService.RecordRequest(impersonatorID, userID, c.Method(), c.OriginalURL(), status,
+			service.ClientInfo{IP: c.IP(), UserAgent: c.Get("User-Agent")})
+
+		return err
+	}
+}
+
 // RBACMiddleware - Role-Based Access Control middleware
 func RBACMiddleware(authService *service.AuthService, requiredPermission string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		// FR-002 Step 3: Load user permissions dari token (sudah di-cache di JWT)
-		permissions, ok := c.Locals("permissions").([]string)
-		if !ok {
+		// FR-002 Step 3: Load user permissions dari database (di-cache per role_id)
+		roleIDs, _ := c.Locals("role_ids").([]string)
+		if len(roleIDs) == 0 {
+			if roleID, _ := c.Locals("role_id").(string); roleID != "" {
+				roleIDs = []string{roleID}
+			}
+		}
+		if len(roleIDs) == 0 {
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 				"error": "No permissions found",
 			})
 		}
 
-		// FR-002 Step 4: Check apakah user memiliki permission yang diperlukan
-		hasPermission := false
-		for _, permission := range permissions {
-			if permission == requiredPermission {
-				hasPermission = true
-				break
-			}
+		resolved, err := authService.ResolveRoles(roleIDs)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to resolve role permissions",
+			})
+		}
+
+		// FR-002 Step 4: Check apakah user memiliki permission yang diperlukan (dari role mana pun)
+		hasPermission := resolved.Has(requiredPermission)
+
+		// An API key is further limited to the scopes it was issued with
+		if scopes, ok := c.Locals("api_key_scopes").([]string); ok {
+			hasPermission = hasPermission && containsString(scopes, requiredPermission)
 		}
 
 		// FR-002 Step 5: Allow/deny request
@@ -95,7 +261,29 @@ func RBACMiddleware(authService *service.AuthService, requiredPermission string)
 	}
 }
 
-// RoleMiddleware - Simple role-based middleware
+// RequireVerifiedEmail - Blocks users whose email address is not verified yet,
+// when REQUIRE_VERIFIED_EMAIL_FOR_SUBMISSION is enabled. Use after AuthMiddleware.
+func RequireVerifiedEmail(authService *service.AuthService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !authService.RequireVerifiedEmailForSubmission() {
+			return c.Next()
+		}
+
+		if verified, _ := c.Locals("email_verified").(bool); !verified {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"success": false,
+				"error": "Email not verified",
+				"message": "Please verify your email address first. Request a new link with POST /api/auth/verify-email/resend",
+				"code": "EMAIL_NOT_VERIFIED",
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// RoleMiddleware - Simple role-based middleware; passes when the user holds any
+// of the allowed roles, as primary or additional role
 func RoleMiddleware(allowedRoles ...string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		userRole, ok := c.Locals("role").(string)
@@ -105,9 +293,14 @@ func RoleMiddleware(allowedRoles ...string) fiber.Handler {
 			})
 		}
 
-		// Check if user role is in allowed roles
+		heldRoles, _ := c.Locals("roles").([]string)
+		if len(heldRoles) == 0 {
+			heldRoles = []string{userRole}
+		}
+
+		// Check if any held role is in allowed roles
 		for _, role := range allowedRoles {
-			if userRole == role {
+			if containsString(heldRoles, role) {
 				return c.Next()
 			}
 		}
@@ -115,6 +308,7 @@ func RoleMiddleware(allowedRoles ...string) fiber.Handler {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": "Insufficient role permissions",
 			"user_role": userRole,
+			"user_roles": heldRoles,
 			"allowed_roles": allowedRoles,
 		})
 	}
@@ -131,6 +325,54 @@ func AdminOnlyMiddleware() fiber.Handler {
 	return RoleMiddleware("admin")
 }
 
+// ScopedAdminMiddleware - For endpoints open to department admins as well as
+// admins. Department admins pass only with at least one scope; their scopes are
+// stored in locals "admin_scopes" and the handler filters by them.
+func ScopedAdminMiddleware(adminScopeService *service.AdminScopeService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		heldRoles, _ := c.Locals("roles").([]string)
+		if len(heldRoles) == 0 {
+			if role, ok := c.Locals("role").(string); ok {
+				heldRoles = []string{role}
+			}
+		}
+
+		subject := policy.Subject{Roles: heldRoles}
+		if subject.HasRole(policy.RoleAdmin) {
+			return c.Next()
+		}
+
+		if !subject.HasRole(policy.RoleDepartmentAdmin) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Insufficient role permissions",
+				"user_roles": heldRoles,
+				"allowed_roles": []string{policy.RoleAdmin, policy.RoleDepartmentAdmin},
+			})
+		}
+
+		userID, _ := c.Locals("user_id").(string)
+		scopes, err := adminScopeService.GetScopes(userID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"success": false,
+				"error": "Failed to load admin scopes",
+				"message": err.Error(),
+			})
+		}
+		if len(scopes) == 0 {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"success": false,
+				"error": "Access denied",
+				"message": "No program study or department has been assigned to you yet",
+				"code": "NO_ADMIN_SCOPE",
+			})
+		}
+
+		c.Locals("admin_scopes", scopes)
+		return c.Next()
+	}
+}
+
 // LecturerOrAdminMiddleware - For lecturer and admin access
 func LecturerOrAdminMiddleware() fiber.Handler {
 	return RoleMiddleware("admin", "lecturer")
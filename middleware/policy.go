@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"UASBE/app/policy"
+	"UASBE/app/service"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AchievementPolicyMiddleware - Resource-level authorization for routes that name an
+// achievement in the given route parameter. Checks the policy (owner, advisor of the
+// owner, admin of the owner's program study, ...) for the action. Use after
+// AuthMiddleware and the permission check.
+func AchievementPolicyMiddleware(achievementService *service.AchievementService, action policy.Action, param string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, _ := c.Locals("user_id").(string)
+		roles, _ := c.Locals("roles").([]string)
+
+		err := achievementService.AuthorizeAchievement(achievementService.PolicySubject(userID, roles...), action, c.Params(param))
+		if err == nil {
+			return c.Next()
+		}
+
+		switch {
+		case errors.Is(err, policy.ErrForbidden):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"success": false,
+				"error": "Access denied",
+				"message": "You are not allowed to " + string(action) + " this achievement",
+				"code": "FORBIDDEN",
+			})
+		case err.Error() == "invalid achievement ID":
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error": "Invalid achievement ID",
+				"code": "INVALID_ACHIEVEMENT_ID",
+			})
+		case err.Error() == "achievement not found":
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"success": false,
+				"error": "Achievement not found",
+				"code": "ACHIEVEMENT_NOT_FOUND",
+			})
+		}
+
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error": "Authorization failed",
+			"message": err.Error(),
+		})
+	}
+}
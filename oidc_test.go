@@ -0,0 +1,275 @@
+package main
+
+import (
+	"UASBE/app/service"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockIdP adalah identity provider OpenID Connect lokal untuk pengujian SSO
+type mockIdP struct {
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+	clientID string
+	secret   string
+
+	mu     sync.Mutex
+	codes  map[string]mockAuthorization
+	claims jwt.MapClaims // claims tambahan / pengganti untuk id_token berikutnya
+}
+
+type mockAuthorization struct {
+	challenge string
+	nonce     string
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	idp := &mockIdP{key: key, clientID: "uasbe", secret: "s3cret", codes: map[string]mockAuthorization{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(service.JWKS{Keys: []service.JWK{{
+			Kty: "RSA", Kid: "idp-1", Use: "sig", Alg: "RS256",
+			N: base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+			E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", idp.token)
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+
+	return idp
+}
+
+// authorize mensimulasikan login user di IdP: menerima parameter dari AuthCodeURL
+// dan mengembalikan code yang terikat pada code_challenge dan nonce
+func (idp *mockIdP) authorize(t *testing.T, authURL string) (code, state string) {
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("invalid authorization URL: %v", err)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("client_id") != idp.clientID {
+		t.Fatalf("unexpected authorization request: %s", authURL)
+	}
+
+	code = "code-" + query.Get("state")[:8]
+	idp.mu.Lock()
+	idp.codes[code] = mockAuthorization{challenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+	idp.mu.Unlock()
+	return code, query.Get("state")
+}
+
+func (idp *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	user, pass, ok := r.BasicAuth()
+	if !ok || user != idp.clientID || pass != idp.secret {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	r.ParseForm()
+	idp.mu.Lock()
+	authorization, found := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+
+	if !found || service.PKCEChallenge(r.PostForm.Get("code_verifier")) != authorization.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "at",
+		"token_type":   "Bearer",
+		"id_token":     idp.idToken(authorization.nonce),
+	})
+}
+
+func (idp *mockIdP) idToken(nonce string) string {
+	claims := jwt.MapClaims{
+		"iss":            idp.server.URL,
+		"sub":            "campus-12345",
+		"aud":            idp.clientID,
+		"exp":            time.Now().Add(5 * time.Minute).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          nonce,
+		"email":          "budi@unair.ac.id",
+		"email_verified": true,
+		"name":           "Budi Santoso",
+		"affiliation":    []string{"member", "student"},
+		"student_id":     "081911133001",
+	}
+	idp.mu.Lock()
+	for name, value := range idp.claims {
+		claims[name] = value
+	}
+	idp.mu.Unlock()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "idp-1"
+	signed, _ := token.SignedString(idp.key)
+	return signed
+}
+
+func (idp *mockIdP) provider() *service.OIDCProvider {
+	return service.NewOIDCProvider(service.OIDCConfig{
+		Issuer:       idp.server.URL,
+		ClientID:     idp.clientID,
+		ClientSecret: idp.secret,
+		RedirectURL:  "http://localhost:3000/api/auth/sso/callback",
+		Scopes:       []string{"openid", "email", "profile"},
+	})
+}
+
+// startLogin menjalankan langkah authorize sampai IdP mengembalikan code
+func startLogin(t *testing.T, idp *mockIdP, provider *service.OIDCProvider) (code, verifier, nonce string) {
+	verifier, _ = service.GeneratePKCEVerifier()
+	nonce = "nonce-123"
+	authURL, err := provider.AuthCodeURL("state-abcdefgh", nonce, service.PKCEChallenge(verifier))
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	code, _ = idp.authorize(t, authURL)
+	return code, verifier, nonce
+}
+
+// TestOIDCAuthorizationCodeFlow menguji alur authorization code + PKCE terhadap mock IdP
+func TestOIDCAuthorizationCodeFlow(t *testing.T) {
+	idp := newMockIdP(t)
+	provider := idp.provider()
+
+	code, verifier, nonce := startLogin(t, idp, provider)
+
+	tokens, err := provider.Exchange(code, verifier)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+
+	identity, err := provider.VerifyIDToken(tokens.IDToken, nonce)
+	if err != nil {
+		t.Fatalf("VerifyIDToken: %v", err)
+	}
+
+	if identity.Subject != "campus-12345" || identity.Email != "budi@unair.ac.id" || !identity.EmailVerified {
+		t.Errorf("unexpected identity: %+v", identity)
+	}
+	if identity.Issuer != idp.server.URL {
+		t.Errorf("Issuer = %q, want %q", identity.Issuer, idp.server.URL)
+	}
+	if got := identity.ClaimStrings("affiliation"); strings.Join(got, ",") != "member,student" {
+		t.Errorf("ClaimStrings(affiliation) = %v", got)
+	}
+	if got := identity.ClaimString("student_id"); got != "081911133001" {
+		t.Errorf("ClaimString(student_id) = %q", got)
+	}
+
+	// A code can be redeemed only once
+	if _, err := provider.Exchange(code, verifier); err == nil {
+		t.Error("Exchange accepted a code twice")
+	}
+}
+
+// TestOIDCPKCEVerifierMismatch menguji bahwa code tanpa verifier yang benar ditolak
+func TestOIDCPKCEVerifierMismatch(t *testing.T) {
+	idp := newMockIdP(t)
+	provider := idp.provider()
+
+	code, _, _ := startLogin(t, idp, provider)
+	otherVerifier, _ := service.GeneratePKCEVerifier()
+
+	if _, err := provider.Exchange(code, otherVerifier); err == nil {
+		t.Error("Exchange succeeded with the wrong code verifier")
+	}
+}
+
+// TestOIDCIDTokenValidation menguji penolakan id_token yang tidak valid
+func TestOIDCIDTokenValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		nonce  string
+	}{
+		{"Wrong nonce", nil, "other-nonce"},
+		{"Wrong audience", jwt.MapClaims{"aud": "another-client"}, ""},
+		{"Wrong issuer", jwt.MapClaims{"iss": "https://evil.example"}, ""},
+		{"Expired", jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}, ""},
+		{"Missing subject", jwt.MapClaims{"sub": ""}, ""},
+		{"Foreign azp", jwt.MapClaims{"aud": []string{"uasbe", "other"}, "azp": "other"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newMockIdP(t)
+			idp.claims = tt.claims
+			provider := idp.provider()
+
+			code, verifier, nonce := startLogin(t, idp, provider)
+			tokens, err := provider.Exchange(code, verifier)
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+
+			if tt.nonce != "" {
+				nonce = tt.nonce
+			}
+			if _, err := provider.VerifyIDToken(tokens.IDToken, nonce); err == nil {
+				t.Error("VerifyIDToken accepted an invalid token")
+			}
+		})
+	}
+}
+
+// TestOIDCRejectsForeignSignature menguji id_token yang ditandatangani kunci lain
+func TestOIDCRejectsForeignSignature(t *testing.T) {
+	idp := newMockIdP(t)
+	provider := idp.provider()
+
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss": idp.server.URL, "sub": "campus-12345", "aud": idp.clientID,
+		"exp": time.Now().Add(time.Minute).Unix(), "iat": time.Now().Unix(), "nonce": "n",
+	})
+	token.Header["kid"] = "idp-1"
+	forged, _ := token.SignedString(otherKey)
+
+	if _, err := provider.VerifyIDToken(forged, "n"); err == nil {
+		t.Error("VerifyIDToken accepted a token signed with a foreign key")
+	}
+}
+
+// TestPKCEChallenge menguji contoh S256 dari RFC 7636 Appendix B
+func TestPKCEChallenge(t *testing.T) {
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	if got := service.PKCEChallenge(verifier); got != want {
+		t.Errorf("PKCEChallenge = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"UASBE/app/service"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func testPasswordPolicy() *service.PasswordPolicy {
+	return &service.PasswordPolicy{
+		MinLength:    8,
+		MaxLength:    64,
+		RequireUpper: true,
+		RequireLower: true,
+		RequireDigit: true,
+		Blocklist:    map[string]bool{"password1": true},
+	}
+}
+
+// TestPasswordPolicyValidate menguji aturan kekuatan password
+func TestPasswordPolicyValidate(t *testing.T) {
+	policy := testPasswordPolicy()
+
+	tests := []struct {
+		name      string
+		password  string
+		personal  []string
+		violation string // empty means the password is accepted
+	}{
+		{"strong password", "Prestasi2024", nil, ""},
+		{"too short", "Ab1", nil, "at least 8"},
+		{"too long", "Aa1" + strings.Repeat("x", 70), nil, "at most 64"},
+		{"no uppercase", "prestasi2024", nil, "uppercase"},
+		{"no lowercase", "PRESTASI2024", nil, "lowercase"},
+		{"no digit", "PrestasiKu", nil, "digit"},
+		{"blocklisted, case insensitive", "Password1", nil, "too common"},
+		{"contains username", "Budi2024Santoso", []string{"budisantoso", "budi"}, "username or email"},
+		{"contains email local part", "Xbudi.s99", []string{"someone", "budi.s@unair.ac.id"}, "username or email"},
+		{"short personal info ignored", "Prestasi2024", []string{"pr"}, ""},
+	}
+
+	for _, tt := range tests {
+		err := policy.Validate(tt.password, tt.personal...)
+		if tt.violation == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+
+		var policyErr *service.PasswordPolicyError
+		if !errors.As(err, &policyErr) {
+			t.Errorf("%s: expected PasswordPolicyError, got %v", tt.name, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.violation) {
+			t.Errorf("%s: error %q does not mention %q", tt.name, err.Error(), tt.violation)
+		}
+	}
+
+	// All violations are reported together
+	err := policy.Validate("abc")
+	var policyErr *service.PasswordPolicyError
+	if !errors.As(err, &policyErr) || len(policyErr.Violations) != 3 {
+		t.Errorf("expected 3 violations for %q, got %v", "abc", err)
+	}
+}
+
+// TestPasswordPolicyBlocklistFile menguji pemuatan daftar password umum dari file
+func TestPasswordPolicyBlocklistFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	content := "# common passwords\nSummer2024\n\n  Unair12345  \n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	policy := testPasswordPolicy()
+	if err := policy.LoadBlocklist(path); err != nil {
+		t.Fatalf("LoadBlocklist: %v", err)
+	}
+
+	for _, password := range []string{"Summer2024", "UNAIR12345"} {
+		if err := policy.Validate(password); err == nil || !strings.Contains(err.Error(), "too common") {
+			t.Errorf("%q should be blocked, got %v", password, err)
+		}
+	}
+	if policy.Blocklist["# common passwords"] {
+		t.Error("comment lines must be skipped")
+	}
+}
+
+func testPasswordHasher(algorithm string) *service.PasswordHasher {
+	return &service.PasswordHasher{
+		Algorithm:  algorithm,
+		BcryptCost: bcrypt.MinCost,
+		Argon2: service.Argon2Params{
+			Memory:      1024,
+			Iterations:  1,
+			Parallelism: 1,
+			SaltLength:  16,
+			KeyLength:   32,
+		},
+	}
+}
+
+// TestPasswordHasherArgon2id menguji hash dan verifikasi argon2id
+func TestPasswordHasherArgon2id(t *testing.T) {
+	hasher := testPasswordHasher(service.PasswordAlgorithmArgon2id)
+
+	hash, err := hasher.Hash("Prestasi2024")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("unexpected hash format %q", hash)
+	}
+
+	other, _ := hasher.Hash("Prestasi2024")
+	if other == hash {
+		t.Error("hashes of the same password must use different salts")
+	}
+
+	if match, rehash := hasher.Verify(hash, "Prestasi2024"); !match || rehash {
+		t.Errorf("Verify(correct) = %v, %v; want true, false", match, rehash)
+	}
+	if match, _ := hasher.Verify(hash, "prestasi2024"); match {
+		t.Error("Verify must reject a wrong password")
+	}
+	if match, _ := hasher.Verify("$argon2id$v=19$broken", "Prestasi2024"); match {
+		t.Error("Verify must reject a malformed hash")
+	}
+
+	// Stronger parameters make existing hashes outdated
+	stronger := testPasswordHasher(service.PasswordAlgorithmArgon2id)
+	stronger.Argon2.Iterations = 2
+	if match, rehash := stronger.Verify(hash, "Prestasi2024"); !match || !rehash {
+		t.Errorf("Verify with new parameters = %v, %v; want true, true", match, rehash)
+	}
+}
+
+// TestPasswordHasherUpgradesBcrypt menguji bahwa hash bcrypt lama tetap valid dan ditandai untuk di-hash ulang
+func TestPasswordHasherUpgradesBcrypt(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("Prestasi2024"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	argon := testPasswordHasher(service.PasswordAlgorithmArgon2id)
+	if match, rehash := argon.Verify(string(legacy), "Prestasi2024"); !match || !rehash {
+		t.Errorf("argon2id hasher on bcrypt hash = %v, %v; want true, true", match, rehash)
+	}
+	if match, _ := argon.Verify(string(legacy), "wrong"); match {
+		t.Error("Verify must reject a wrong password for bcrypt hashes")
+	}
+
+	bcryptHasher := testPasswordHasher(service.PasswordAlgorithmBcrypt)
+	if match, rehash := bcryptHasher.Verify(string(legacy), "Prestasi2024"); !match || rehash {
+		t.Errorf("bcrypt hasher with same cost = %v, %v; want true, false", match, rehash)
+	}
+
+	bcryptHasher.BcryptCost = bcrypt.MinCost + 1
+	if _, rehash := bcryptHasher.Verify(string(legacy), "Prestasi2024"); !rehash {
+		t.Error("a higher bcrypt cost must mark the hash for rehashing")
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"UASBE/app/model"
+	"UASBE/app/service"
+	"errors"
+	"testing"
+	"time"
+)
+
+// MockRoleLoader adalah RoleRepository tiruan yang menghitung jumlah query
+type MockRoleLoader struct {
+	roles       map[string]*model.Role
+	permissions map[string][]model.Permission
+	loads       int
+}
+
+func (m *MockRoleLoader) GetByID(id string) (*model.Role, error) {
+	m.loads++
+	role, ok := m.roles[id]
+	if !ok {
+		return nil, errors.New("role not found")
+	}
+	return role, nil
+}
+
+func (m *MockRoleLoader) GetPermissionsByRoleID(roleID string) ([]model.Permission, error) {
+	return m.permissions[roleID], nil
+}
+
+func newMockRoleLoader() *MockRoleLoader {
+	return &MockRoleLoader{
+		roles: map[string]*model.Role{
+			"role-lecturer": {ID: "role-lecturer", Name: "lecturer"},
+		},
+		permissions: map[string][]model.Permission{
+			"role-lecturer": {{Resource: "achievements", Action: "verify"}},
+		},
+	}
+}
+
+// TestPermissionResolverCache menguji cache per role_id dan invalidasi
+func TestPermissionResolverCache(t *testing.T) {
+	t.Setenv("PERMISSION_CACHE_TTL", "1h")
+	loader := newMockRoleLoader()
+	resolver := service.NewPermissionResolver(loader)
+
+	resolved, err := resolver.Resolve("role-lecturer")
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if resolved.Role.Name != "lecturer" || !resolved.Has("achievements:verify") || resolved.Has("achievements:delete") {
+		t.Fatalf("Resolve() = %+v, want lecturer with achievements:verify only", resolved)
+	}
+
+	resolver.Resolve("role-lecturer")
+	if loader.loads != 1 {
+		t.Errorf("loads = %d, want 1 (second Resolve served from cache)", loader.loads)
+	}
+
+	// Admin grants a new permission: visible right after invalidation
+	loader.permissions["role-lecturer"] = append(loader.permissions["role-lecturer"], model.Permission{Resource: "achievements", Action: "delete"})
+	resolver.Invalidate("role-lecturer")
+
+	resolved, _ = resolver.Resolve("role-lecturer")
+	if !resolved.Has("achievements:delete") {
+		t.Errorf("permission granted after Invalidate not visible")
+	}
+	if loader.loads != 2 {
+		t.Errorf("loads = %d, want 2", loader.loads)
+	}
+
+	resolver.InvalidateAll()
+	resolver.Resolve("role-lecturer")
+	if loader.loads != 3 {
+		t.Errorf("loads = %d, want 3 after InvalidateAll", loader.loads)
+	}
+
+	if _, err := resolver.Resolve("role-unknown"); err == nil {
+		t.Errorf("Resolve() of unknown role should fail")
+	}
+}
+
+// TestPermissionResolverTTL menguji bahwa cache kedaluwarsa setelah TTL
+func TestPermissionResolverTTL(t *testing.T) {
+	t.Setenv("PERMISSION_CACHE_TTL", "10ms")
+	loader := newMockRoleLoader()
+	resolver := service.NewPermissionResolver(loader)
+
+	resolver.Resolve("role-lecturer")
+	time.Sleep(20 * time.Millisecond)
+	resolver.Resolve("role-lecturer")
+
+	if loader.loads != 2 {
+		t.Errorf("loads = %d, want 2 (entry reloaded after TTL)", loader.loads)
+	}
+}
+
+// TestPermissionResolverResolveAll menguji gabungan permission dari beberapa role
+func TestPermissionResolverResolveAll(t *testing.T) {
+	loader := newMockRoleLoader()
+	loader.roles["role-admin"] = &model.Role{ID: "role-admin", Name: "admin"}
+	loader.permissions["role-admin"] = []model.Permission{
+		{Resource: "users", Action: "manage"},
+		{Resource: "achievements", Action: "verify"},
+	}
+	resolver := service.NewPermissionResolver(loader)
+
+	resolved, err := resolver.ResolveAll([]string{"role-lecturer", "role-admin", "role-lecturer"})
+	if err != nil {
+		t.Fatalf("ResolveAll: %v", err)
+	}
+
+	if resolved.Primary.Role.ID != "role-lecturer" {
+		t.Errorf("primary = %s, want role-lecturer", resolved.Primary.Role.ID)
+	}
+	if len(resolved.Roles) != 2 {
+		t.Errorf("roles = %v, want duplicates skipped", resolved.Names())
+	}
+	if !resolved.Has("users:manage") || !resolved.Has("achievements:verify") {
+		t.Error("permissions of every role must be granted")
+	}
+	if !resolved.HasRole("admin") || !resolved.HasRole("Dosen") {
+		t.Error("HasRole must match any held role, including aliases")
+	}
+	if got := resolved.PermissionStrings(); len(got) != 2 {
+		t.Errorf("permissions = %v, want the union without duplicates", got)
+	}
+
+	if _, err := resolver.ResolveAll(nil); err == nil {
+		t.Error("a user without roles must not resolve")
+	}
+	if _, err := resolver.ResolveAll([]string{"role-lecturer", "role-missing"}); err == nil {
+		t.Error("an unknown role must fail")
+	}
+}
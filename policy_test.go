@@ -0,0 +1,139 @@
+package main
+
+import (
+	"UASBE/app/policy"
+	"errors"
+	"testing"
+)
+
+// TestCanonicalRole menguji pemetaan alias role ke nama kanonik
+func TestCanonicalRole(t *testing.T) {
+	tests := map[string]string{
+		"student":    policy.RoleStudent,
+		"Mahasiswa":  policy.RoleStudent,
+		"Dosen":      policy.RoleLecturer,
+		"Dosen Wali": policy.RoleLecturer,
+		" lecturer ": policy.RoleLecturer,
+		"admin":      policy.RoleAdmin,
+		"custom":     "custom",
+	}
+
+	for name, want := range tests {
+		if got := policy.CanonicalRole(name); got != want {
+			t.Errorf("CanonicalRole(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+// TestAchievementPolicy menguji aturan owner, advisor, admin program studi dan admin
+func TestAchievementPolicy(t *testing.T) {
+	p := policy.Default()
+
+	achievement := policy.Resource{
+		Type:              policy.ResourceAchievement,
+		ID:                "achievement-1",
+		OwnerID:           "student-1",
+		OwnerAdvisorID:    "lecturer-1",
+		OwnerProgramStudy: "Teknik Informatika",
+	}
+
+	owner := policy.Subject{UserID: "student-1", Roles: []string{"Mahasiswa"}}
+	otherStudent := policy.Subject{UserID: "student-2", Roles: []string{"student"}}
+	advisor := policy.Subject{UserID: "lecturer-user-1", Roles: []string{"Dosen Wali"}, LecturerID: "lecturer-1"}
+	otherLecturer := policy.Subject{UserID: "lecturer-user-2", Roles: []string{"lecturer"}, LecturerID: "lecturer-2"}
+	departmentAdmin := policy.Subject{UserID: "staff-1", Roles: []string{"department_admin"}, Scopes: []string{"teknik informatika"}}
+	otherDepartmentAdmin := policy.Subject{UserID: "staff-2", Roles: []string{"department_admin"}, Scopes: []string{"Sistem Informasi"}}
+	scopedStaff := policy.Subject{UserID: "staff-6", Roles: []string{"lecturer"}, Scopes: []string{"Teknik Informatika"}}
+	admin := policy.Subject{UserID: "admin-1", Roles: []string{"admin"}}
+
+	tests := []struct {
+		name    string
+		subject policy.Subject
+		action  policy.Action
+		allowed bool
+	}{
+		{"owner views", owner, policy.ActionView, true},
+		{"owner updates", owner, policy.ActionUpdate, true},
+		{"owner deletes", owner, policy.ActionDelete, true},
+		{"owner submits", owner, policy.ActionSubmit, true},
+		{"owner cannot verify", owner, policy.ActionVerify, false},
+		{"other student cannot view", otherStudent, policy.ActionView, false},
+		{"other student cannot update", otherStudent, policy.ActionUpdate, false},
+		{"advisor views", advisor, policy.ActionView, true},
+		{"advisor verifies", advisor, policy.ActionVerify, true},
+		{"advisor cannot update", advisor, policy.ActionUpdate, false},
+		{"other lecturer cannot verify", otherLecturer, policy.ActionVerify, false},
+		{"other lecturer cannot view", otherLecturer, policy.ActionView, false},
+		{"department admin views", departmentAdmin, policy.ActionView, true},
+		{"department admin cannot verify", departmentAdmin, policy.ActionVerify, false},
+		{"admin of other program cannot view", otherDepartmentAdmin, policy.ActionView, false},
+		{"scopes without the department_admin role cannot view", scopedStaff, policy.ActionView, false},
+		{"admin views", admin, policy.ActionView, true},
+		{"admin cannot delete", admin, policy.ActionDelete, false},
+		{"unknown action is denied", owner, policy.Action("archive"), false},
+	}
+
+	for _, tt := range tests {
+		if got := p.Can(tt.subject, tt.action, achievement); got != tt.allowed {
+			t.Errorf("%s: Can = %v, want %v", tt.name, got, tt.allowed)
+		}
+	}
+
+	if err := p.Authorize(otherStudent, policy.ActionView, achievement); !errors.Is(err, policy.ErrForbidden) {
+		t.Errorf("Authorize should return ErrForbidden, got %v", err)
+	}
+
+	// Later stages of a verification chain are verified by holders of the stage's role
+	atStudentAffairs := achievement
+	atStudentAffairs.VerificationStage = "student_affairs"
+	studentAffairs := policy.Subject{UserID: "staff-3", Roles: []string{"lecturer", "student_affairs"}, Scopes: []string{"Teknik Informatika"}}
+	otherFaculty := policy.Subject{UserID: "staff-4", Roles: []string{"student_affairs"}, Scopes: []string{"Kedokteran"}}
+	universityWide := policy.Subject{UserID: "staff-5", Roles: []string{"student_affairs"}}
+
+	if !p.Can(studentAffairs, policy.ActionVerify, atStudentAffairs) {
+		t.Error("student affairs of the owner's program study should verify its stage")
+	}
+	if !p.Can(studentAffairs, policy.ActionView, atStudentAffairs) || !p.Can(universityWide, policy.ActionView, atStudentAffairs) {
+		t.Error("the verifier of the current stage should view what they verify")
+	}
+	if p.Can(otherFaculty, policy.ActionView, atStudentAffairs) {
+		t.Error("student affairs of another program study must not view")
+	}
+	if p.Can(universityWide, policy.ActionView, achievement) {
+		t.Error("a stage verifier must not view an achievement waiting for another stage")
+	}
+	if p.Can(otherFaculty, policy.ActionVerify, atStudentAffairs) {
+		t.Error("student affairs of another program study must not verify")
+	}
+	if !p.Can(universityWide, policy.ActionVerify, atStudentAffairs) {
+		t.Error("a verifier without scopes should verify any program study")
+	}
+	if p.Can(advisor, policy.ActionVerify, atStudentAffairs) {
+		t.Error("the advisor must not verify a later stage")
+	}
+	if p.Can(studentAffairs, policy.ActionVerify, achievement) {
+		t.Error("a stage verifier must not verify the advisor stage")
+	}
+
+	// A delegate verifies the advisor stage for the advisor, and only for that advisor
+	delegate := policy.Subject{UserID: "lecturer-user-4", Roles: []string{"lecturer"}, LecturerID: "lecturer-4", DelegatorIDs: []string{"lecturer-1"}}
+	otherDelegate := policy.Subject{UserID: "lecturer-user-5", Roles: []string{"lecturer"}, LecturerID: "lecturer-5", DelegatorIDs: []string{"lecturer-9"}}
+	if !p.Can(delegate, policy.ActionVerify, achievement) {
+		t.Error("the delegate of the advisor should verify")
+	}
+	if !p.Can(delegate, policy.ActionView, achievement) {
+		t.Error("the delegate of the advisor should view")
+	}
+	if p.Can(otherDelegate, policy.ActionVerify, achievement) {
+		t.Error("the delegate of another advisor must not verify")
+	}
+	if p.Can(delegate, policy.ActionVerify, atStudentAffairs) {
+		t.Error("a delegate must not verify a later stage")
+	}
+
+	// A lecturer without a lecturer profile is nobody's advisor
+	unassigned := policy.Resource{Type: policy.ResourceAchievement, OwnerID: "student-3"}
+	if p.Can(policy.Subject{UserID: "lecturer-user-3", Roles: []string{"lecturer"}}, policy.ActionVerify, unassigned) {
+		t.Error("an empty lecturer ID must not match an empty advisor")
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"UASBE/app/service"
+	"testing"
+)
+
+// TestEmailDomainAllowed menguji allowlist domain email untuk registrasi mandiri
+func TestEmailDomainAllowed(t *testing.T) {
+	unair := []string{"unair.ac.id"}
+
+	tests := []struct {
+		name    string
+		email   string
+		domains []string
+		want    bool
+	}{
+		{"No allowlist", "someone@gmail.com", nil, true},
+		{"Exact domain", "budi@unair.ac.id", unair, true},
+		{"Subdomain", "budi@student.unair.ac.id", unair, true},
+		{"Case insensitive", "Budi@UNAIR.AC.ID", unair, true},
+		{"Leading @ in allowlist", "budi@unair.ac.id", []string{"@unair.ac.id"}, true},
+		{"Other domain", "budi@gmail.com", unair, false},
+		{"Suffix without dot", "budi@notunair.ac.id", unair, false},
+		{"Domain in local part", "unair.ac.id@gmail.com", unair, false},
+		{"Second allowed domain", "budi@its.ac.id", []string{"unair.ac.id", "its.ac.id"}, true},
+		{"Missing @", "budi.unair.ac.id", nil, false},
+		{"Empty domain", "budi@", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := service.EmailDomainAllowed(tt.email, tt.domains); got != tt.want {
+				t.Errorf("EmailDomainAllowed(%q, %v) = %v, want %v", tt.email, tt.domains, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"UASBE/app/repository"
+	"UASBE/app/service"
+	"UASBE/database"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// registerRoleHandlers meniru query tambahan RoleService: jumlah user per role,
+// perubahan role dan permission, serta grant dan revoke permission
+func (s *authStore) registerRoleHandlers(db *fakeDB) {
+	db.On("SELECT COUNT(*) FROM users u WHERE u.role_id = $1", func(args []driver.Value) fakeResult {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		var count int64
+		for _, user := range s.users {
+			if user.RoleID == stringArg(args, 0) {
+				count++
+			}
+		}
+		return fakeRows([]string{"count"}, []driver.Value{count})
+	})
+	db.On("UPDATE roles SET name = $2, description = $3, updated_at = $4 WHERE id = $1", func(args []driver.Value) fakeResult {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if role, ok := s.roles[stringArg(args, 0)]; ok {
+			role.Name, role.Description = stringArg(args, 1), stringArg(args, 2)
+			return fakeAffected(1)
+		}
+		return fakeAffected(0)
+	})
+	db.On("DELETE FROM roles WHERE id = $1 AND is_system = false", func(args []driver.Value) fakeResult {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if role, ok := s.roles[stringArg(args, 0)]; ok && !role.IsSystem {
+			delete(s.roles, role.ID)
+			return fakeAffected(1)
+		}
+		return fakeAffected(0)
+	})
+	db.On("FROM permissions p WHERE p.resource = $1 AND p.action = $2", func(args []driver.Value) fakeResult {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, permission := range s.permissions {
+			if permission.Resource == stringArg(args, 0) && permission.Action == stringArg(args, 1) {
+				return fakeRows(permissionColumns, permissionRow(permission))
+			}
+		}
+		return fakeRows(permissionColumns)
+	})
+	db.On("INSERT INTO permissions (id, name, resource, action, description, created_at)", func(args []driver.Value) fakeResult {
+		return fakeAffected(1)
+	})
+	db.On("INSERT INTO role_permissions (role_id, permission_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", func(args []driver.Value) fakeResult {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		roleID, permissionID := stringArg(args, 0), stringArg(args, 1)
+		for _, granted := range s.rolePermissions[roleID] {
+			if granted == permissionID {
+				return fakeAffected(0)
+			}
+		}
+		s.rolePermissions[roleID] = append(s.rolePermissions[roleID], permissionID)
+		return fakeAffected(1)
+	})
+	db.On("DELETE FROM role_permissions WHERE role_id = $1 AND permission_id = $2", func(args []driver.Value) fakeResult {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		roleID := stringArg(args, 0)
+		granted := s.rolePermissions[roleID]
+		for i, permissionID := range granted {
+			if permissionID == stringArg(args, 1) {
+				s.rolePermissions[roleID] = append(granted[:i:i], granted[i+1:]...)
+				return fakeAffected(1)
+			}
+		}
+		return fakeAffected(0)
+	})
+}
+
+func newRoleServiceFixture(t *testing.T) (*authFixture, *service.RoleService) {
+	t.Helper()
+
+	f := newAuthFixture(t)
+	f.store.registerRoleHandlers(f.db)
+	roleService := service.NewRoleService(repository.NewRoleRepository(), repository.NewPermissionRepository(),
+		repository.NewUserRepository(), repository.NewUserRoleRepository(), f.resolver)
+	return f, roleService
+}
+
+// TestSystemRoleProtection menguji bahwa role sistem tidak bisa diganti nama atau dihapus
+func TestSystemRoleProtection(t *testing.T) {
+	f, roles := newRoleServiceFixture(t)
+	lecturer := f.store.addRole("lecturer", true)
+
+	if _, err := roles.UpdateRole(lecturer.ID, &service.RoleRequest{Name: "dosen"}); err == nil || err.Error() != "system role cannot be renamed" {
+		t.Errorf("UpdateRole() error = %v, want system role cannot be renamed", err)
+	}
+	if err := roles.DeleteRole(lecturer.ID); err == nil || err.Error() != "system role cannot be deleted" {
+		t.Errorf("DeleteRole() error = %v, want system role cannot be deleted", err)
+	}
+	if len(f.db.Statements("UPDATE roles")) != 0 || len(f.db.Statements("DELETE FROM roles")) != 0 {
+		t.Errorf("a system role must not be written")
+	}
+	if lecturer.Name != "lecturer" {
+		t.Errorf("system role renamed to %q", lecturer.Name)
+	}
+
+	// The description of a system role may still change
+	if _, err := roles.UpdateRole(lecturer.ID, &service.RoleRequest{Name: "lecturer", Description: "Dosen"}); err != nil {
+		t.Errorf("UpdateRole() of the description error = %v", err)
+	}
+
+	// Custom roles can be renamed, and deleted once no user has them
+	custom := f.store.addRole("student_affairs", false)
+	if _, err := roles.UpdateRole(custom.ID, &service.RoleRequest{Name: "kemahasiswaan"}); err != nil {
+		t.Errorf("UpdateRole() of a custom role error = %v", err)
+	}
+	holder := f.store.addUser("siti", custom)
+	if err := roles.DeleteRole(custom.ID); err == nil || err.Error() != "role is assigned to users" {
+		t.Errorf("DeleteRole() of an assigned role error = %v, want role is assigned to users", err)
+	}
+	f.store.mu.Lock()
+	delete(f.store.users, holder.ID)
+	f.store.mu.Unlock()
+	if err := roles.DeleteRole(custom.ID); err != nil {
+		t.Errorf("DeleteRole() of a custom role error = %v", err)
+	}
+}
+
+// TestCreatePermissionRejectsDuplicate menguji penolakan resource:action yang sudah ada
+func TestCreatePermissionRejectsDuplicate(t *testing.T) {
+	f, roles := newRoleServiceFixture(t)
+	f.store.addPermission("achievements", "verify")
+
+	_, err := roles.CreatePermission(&service.PermissionRequest{Resource: " Achievements ", Action: "VERIFY"})
+	if err == nil || err.Error() != "permission already exists" {
+		t.Errorf("CreatePermission() error = %v, want permission already exists", err)
+	}
+	if len(f.db.Statements("INSERT INTO permissions")) != 0 {
+		t.Errorf("a duplicate permission must not be inserted")
+	}
+
+	permission, err := roles.CreatePermission(&service.PermissionRequest{Resource: "reports", Action: "export"})
+	if err != nil {
+		t.Fatalf("CreatePermission() error = %v", err)
+	}
+	if permission.Name != "reports:export" {
+		t.Errorf("permission name = %q, want reports:export", permission.Name)
+	}
+}
+
+// TestGrantRevokePermissionInvalidatesCache menguji bahwa grant dan revoke
+// permission langsung terlihat oleh PermissionResolver
+func TestGrantRevokePermissionInvalidatesCache(t *testing.T) {
+	f, roles := newRoleServiceFixture(t)
+	verify := f.store.addPermission("achievements", "verify")
+	role := f.store.addRole("student_affairs", false)
+
+	// Warm the cache; its TTL outlives the test
+	resolved, err := f.resolver.Resolve(role.ID)
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if resolved.Has("achievements:verify") {
+		t.Fatalf("role must start without achievements:verify")
+	}
+
+	if _, err := roles.GrantPermission(role.ID, "achievements:verify"); err != nil {
+		t.Fatalf("GrantPermission() error = %v", err)
+	}
+	if resolved, _ := f.resolver.Resolve(role.ID); !resolved.Has("achievements:verify") {
+		t.Errorf("granted permission not resolved")
+	}
+
+	if _, err := roles.RevokePermission(role.ID, verify.ID); err != nil {
+		t.Fatalf("RevokePermission() error = %v", err)
+	}
+	if resolved, _ := f.resolver.Resolve(role.ID); resolved.Has("achievements:verify") {
+		t.Errorf("revoked permission still resolved")
+	}
+
+	var unknown *service.UnknownPermissionsError
+	if _, err := roles.GrantPermission(role.ID, "achievements:approve"); !errors.As(err, &unknown) {
+		t.Errorf("GrantPermission() of an unknown permission error = %v", err)
+	}
+}
+
+// TestMigrateLegacyPermissions menguji migrasi permissions.role_id ke role_permissions
+func TestMigrateLegacyPermissions(t *testing.T) {
+	for _, legacy := range []bool{false, true} {
+		db := useFakeDB(t)
+		db.On("FROM information_schema.columns WHERE table_name = 'permissions' AND column_name = 'role_id'", func(args []driver.Value) fakeResult {
+			return fakeRows([]string{"exists"}, []driver.Value{legacy})
+		})
+		db.On("CREATE TEMP TABLE canonical_permissions", func(args []driver.Value) fakeResult {
+			return fakeAffected(0)
+		})
+
+		if err := database.MigrateLegacyPermissions(database.PostgresDB); err != nil {
+			t.Fatalf("MigrateLegacyPermissions() legacy=%v error = %v", legacy, err)
+		}
+
+		migrations := db.Statements("CREATE TEMP TABLE canonical_permissions")
+		if !legacy {
+			if len(migrations) != 0 {
+				t.Errorf("current layout must not be migrated")
+			}
+			continue
+		}
+		if len(migrations) != 1 {
+			t.Fatalf("legacy layout: got %d migrations, want 1", len(migrations))
+		}
+
+		// One row per resource:action is kept, every role_id becomes a grant of
+		// it, then the duplicates and the column go
+		query := migrations[0].Query
+		steps := []string{
+			"SELECT DISTINCT ON (resource, action) id, resource, action FROM permissions",
+			"INSERT INTO role_permissions (role_id, permission_id) SELECT p.role_id, c.id FROM permissions p JOIN canonical_permissions c ON c.resource = p.resource AND c.action = p.action WHERE p.role_id IS NOT NULL ON CONFLICT DO NOTHING",
+			"DELETE FROM permissions WHERE id NOT IN (SELECT id FROM canonical_permissions)",
+			"ALTER TABLE permissions DROP COLUMN role_id",
+		}
+		last := -1
+		for _, step := range steps {
+			index := strings.Index(query, step)
+			if index <= last {
+				t.Errorf("migration step missing or out of order: %q", step)
+			}
+			last = index
+		}
+	}
+}
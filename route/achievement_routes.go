@@ -1,13 +1,14 @@
 package route
 
 import (
+	"UASBE/app/policy"
 	"UASBE/app/service"
 	"UASBE/middleware"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-func SetupAchievementRoutes(app *fiber.App, achievementService *service.AchievementService, authService *service.AuthService) {
+func SetupAchievementRoutes(app *fiber.App, achievementService *service.AchievementService, authService *service.AuthService, adminScopeService *service.AdminScopeService) {
 	api := app.Group("/api/achievements")
 	
 	// Apply auth middleware to all achievement routes
@@ -39,27 +40,43 @@ func SetupAchievementRoutes(app *fiber.App, achievementService *service.Achievem
 		middleware.PermissionMiddleware(authService, "achievements", "view_advisee"),
 		achievementService.GetAdviseeAchievementsRequest)
 
+	// Verified achievements export for campus systems (service account API keys) - Must be BEFORE /:id route
+	api.Get("/verified", 
+		middleware.PermissionMiddleware(authService, "achievements", "export"),
+		achievementService.GetVerifiedAchievementsRequest)
+
 	// FR-011: Achievement Statistics - Must be BEFORE /:id route
 	api.Get("/statistics", 
 		achievementService.GetAchievementStatisticsRequest)
 
 	api.Get("/:id", 
 		middleware.PermissionMiddleware(authService, "achievements", "read"),
+		middleware.AchievementPolicyMiddleware(achievementService, policy.ActionView, "id"),
 		achievementService.GetAchievementByIDRequest)
 
+	// Status history - whoever may view the achievement sees how its status changed
+	api.Get("/:id/history", 
+		middleware.PermissionMiddleware(authService, "achievements", "read"),
+		middleware.AchievementPolicyMiddleware(achievementService, policy.ActionView, "id"),
+		achievementService.GetAchievementHistoryRequest)
+
 	// Update operations - students can update their own achievements
 	api.Put("/:id", 
 		middleware.PermissionMiddleware(authService, "achievements", "update"),
+		middleware.AchievementPolicyMiddleware(achievementService, policy.ActionUpdate, "id"),
 		achievementService.UpdateAchievementRequest)
 
 	// Delete operations - students can delete their own achievements
 	api.Delete("/:id", 
 		middleware.PermissionMiddleware(authService, "achievements", "delete"),
+		middleware.AchievementPolicyMiddleware(achievementService, policy.ActionDelete, "id"),
 		achievementService.DeleteAchievementRequest)
 
 	// Submit achievement for verification - students can submit their achievements
 	api.Post("/:achievement_id/submit", 
 		middleware.PermissionMiddleware(authService, "achievements", "update"),
+		middleware.AchievementPolicyMiddleware(achievementService, policy.ActionSubmit, "achievement_id"),
+		middleware.RequireVerifiedEmail(authService),
 		achievementService.SubmitAchievementRequest)
 
 	// File upload routes - students can upload attachments
@@ -89,9 +106,10 @@ func SetupAchievementRoutes(app *fiber.App, achievementService *service.Achievem
 	// FR-010: Admin routes - View All Achievements
 	admin := api.Group("/admin")
 	
-	// FR-010: Get all achievements with filters and pagination - admin only
+	// FR-010: Get all achievements with filters and pagination - admin only,
+	// department admins see their program studies
 	admin.Get("/all", 
-		middleware.AdminOnlyMiddleware(),
+		middleware.ScopedAdminMiddleware(adminScopeService),
 		achievementService.GetAllAchievementsRequest)
 
 	// FR-011: Achievement Statistics moved above to avoid route conflict
@@ -7,7 +7,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func SetupAdminRoutes(app *fiber.App, authService *service.AuthService) {
+func SetupAdminRoutes(app *fiber.App, authService *service.AuthService, roleService *service.RoleService, registrationService *service.RegistrationService, impersonationService *service.ImpersonationService, serviceAccountService *service.ServiceAccountService, adminScopeService *service.AdminScopeService, verificationChainService *service.VerificationChainService) {
 	admin := app.Group("/api/admin")
 	
 	// Apply auth middleware and admin-only middleware
@@ -33,6 +33,56 @@ func SetupAdminRoutes(app *fiber.App, authService *service.AuthService) {
 		})
 	})
 
+	// Reload role permissions from the database on the next request
+	admin.Post("/permission-cache/invalidate", authService.InvalidatePermissionCacheRequest)
+
+	// Review student self-registrations
+	admin.Get("/registrations", registrationService.GetRegistrationsRequest)
+	admin.Post("/registrations/:id/approve", registrationService.ApproveRegistrationRequest)
+	admin.Post("/registrations/:id/reject", registrationService.RejectRegistrationRequest)
+
+	// View the system as a user (read-only token) and the audit trail of it
+	admin.Post("/users/:id/impersonate", impersonationService.ImpersonateUserRequest)
+	admin.Get("/audit-logs", impersonationService.GetAuditLogsRequest)
+
+	// Service accounts for campus systems and their API keys
+	admin.Get("/service-accounts", serviceAccountService.GetServiceAccountsRequest)
+	admin.Post("/service-accounts", serviceAccountService.CreateServiceAccountRequest)
+	admin.Delete("/service-accounts/:id", serviceAccountService.DeactivateServiceAccountRequest)
+	admin.Get("/service-accounts/:id/api-keys", serviceAccountService.GetAPIKeysRequest)
+	admin.Post("/service-accounts/:id/api-keys", serviceAccountService.CreateAPIKeyRequest)
+	admin.Delete("/service-accounts/:id/api-keys/:key_id", serviceAccountService.RevokeAPIKeyRequest)
+
+	// Roles and role-permission assignments
+	admin.Get("/roles", roleService.GetRolesRequest)
+	admin.Post("/roles", roleService.CreateRoleRequest)
+	admin.Get("/roles/:id", roleService.GetRoleRequest)
+	admin.Put("/roles/:id", roleService.UpdateRoleRequest)
+	admin.Delete("/roles/:id", roleService.DeleteRoleRequest)
+	admin.Put("/roles/:id/permissions", roleService.SetRolePermissionsRequest)
+	admin.Post("/roles/:id/permissions", roleService.GrantRolePermissionRequest)
+	admin.Delete("/roles/:id/permissions/:permission_id", roleService.RevokeRolePermissionRequest)
+
+	// Additional roles of users (on top of their primary role)
+	admin.Get("/users/:id/roles", roleService.GetUserRolesRequest)
+	admin.Post("/users/:id/roles", roleService.GrantUserRoleRequest)
+	admin.Delete("/users/:id/roles/:role_id", roleService.RevokeUserRoleRequest)
+
+	// Program studies and departments of department admins
+	admin.Get("/users/:id/scopes", adminScopeService.GetAdminScopesRequest)
+	admin.Put("/users/:id/scopes", adminScopeService.SetAdminScopesRequest)
+
+	// Verification chains: stages that approve achievements per category and competition level
+	admin.Get("/verification-chains", verificationChainService.GetVerificationChainsRequest)
+	admin.Put("/verification-chains", verificationChainService.SaveVerificationChainRequest)
+	admin.Delete("/verification-chains/:id", verificationChainService.DeleteVerificationChainRequest)
+
+	// Permission catalogue (resource:action)
+	admin.Get("/permissions", roleService.GetPermissionsRequest)
+	admin.Post("/permissions", roleService.CreatePermissionRequest)
+	admin.Put("/permissions/:id", roleService.UpdatePermissionRequest)
+	admin.Delete("/permissions/:id", roleService.DeletePermissionRequest)
+
 	// System statistics (admin only)
 	admin.Get("/stats", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{
@@ -2,16 +2,67 @@ package route
 
 import (
 	"UASBE/app/service"
+	"UASBE/middleware"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-func SetupAuthRoutes(app *fiber.App, authService *service.AuthService) {
+func SetupAuthRoutes(app *fiber.App, authService *service.AuthService, twoFactorService *service.TwoFactorService, ssoService *service.SSOService, sessionService *service.SessionService, accountService *service.AccountService) {
 	auth := app.Group("/api/auth")
 
+	// Public verification keys for services that validate our tokens
+	app.Get("/.well-known/jwks.json", authService.JWKSRequest)
+
 	// Login
 	auth.Post("/login", authService.LoginRequest)
 
+	// Single sign-on with the university identity provider (OpenID Connect)
+	auth.Get("/sso/login", ssoService.SSOLoginRequest)
+	auth.Get("/sso/callback", ssoService.SSOCallbackRequest)
+
 	// Register
 	auth.Post("/register", authService.RegisterRequest)
+
+	// Exchange a refresh token for a new token pair (rotates the refresh token)
+	auth.Post("/refresh", authService.RefreshTokenRequest)
+
+	// Rules a new password must meet
+	auth.Get("/password-policy", authService.PasswordPolicyRequest)
+
+	// Password reset - email a single-use link, then set a new password with it
+	auth.Post("/forgot-password", authService.ForgotPasswordRequest)
+	auth.Post("/reset-password", authService.ResetPasswordRequest)
+
+	// Email verification - confirm the address, or get a new link
+	auth.Post("/verify-email", authService.VerifyEmailRequest)
+	auth.Post("/verify-email/resend", authService.ResendVerificationRequest)
+
+	// Logout - revokes the current token (and optionally its refresh token)
+	auth.Post("/logout", middleware.AuthMiddleware(authService), authService.LogoutRequest)
+
+	// Self-service account: own profile and password
+	auth.Get("/me", middleware.AuthMiddleware(authService), accountService.GetMeRequest)
+	auth.Put("/me", middleware.AuthMiddleware(authService), accountService.UpdateMeRequest)
+	auth.Put("/me/password", middleware.AuthMiddleware(authService), accountService.ChangePasswordRequest)
+
+	// Active sessions (logins) of the current user
+	auth.Get("/sessions", middleware.AuthMiddleware(authService), sessionService.GetMySessionsRequest)
+	auth.Delete("/sessions/:id", middleware.AuthMiddleware(authService), sessionService.RevokeMySessionRequest)
+
+	// Login attempts of the current user (successful and failed)
+	auth.Get("/login-history", middleware.AuthMiddleware(authService), sessionService.GetMyLoginHistoryRequest)
+
+	// Two-factor authentication (TOTP)
+	twoFactor := auth.Group("/2fa")
+
+	// Second login step - exchange the login challenge and a code for tokens
+	twoFactor.Post("/verify", twoFactorService.VerifyRequest)
+
+	// Enrolment - also reachable with the setup token returned at login
+	twoFactor.Post("/setup", middleware.TwoFactorSetupMiddleware(authService), twoFactorService.SetupRequest)
+	twoFactor.Post("/enable", middleware.TwoFactorSetupMiddleware(authService), twoFactorService.EnableRequest)
+
+	twoFactor.Get("/", middleware.AuthMiddleware(authService), twoFactorService.StatusRequest)
+	twoFactor.Post("/disable", middleware.AuthMiddleware(authService), twoFactorService.DisableRequest)
+	twoFactor.Post("/recovery-codes", middleware.AuthMiddleware(authService), twoFactorService.RecoveryCodesRequest)
 }
\ No newline at end of file
@@ -0,0 +1,25 @@
+package route
+
+import (
+	"UASBE/app/service"
+	"UASBE/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupDelegationRoutes(app *fiber.App, delegationService *service.DelegationService, authService *service.AuthService) {
+	api := app.Group("/api/delegations")
+
+	// Lecturers (and admins on their behalf) delegate their verification rights
+	api.Use(middleware.AuthMiddleware(authService))
+	api.Use(middleware.PermissionMiddleware(authService, "achievements", "verify"))
+
+	// Delegations given and received (admins: all)
+	api.Get("/", delegationService.GetDelegationsRequest)
+
+	// Delegate verification to another lecturer for a period
+	api.Post("/", delegationService.CreateDelegationRequest)
+
+	// End a delegation early
+	api.Delete("/:id", delegationService.RevokeDelegationRequest)
+}
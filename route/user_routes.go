@@ -7,17 +7,18 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func SetupUserRoutes(app *fiber.App, userService *service.UserService, authService *service.AuthService) {
+func SetupUserRoutes(app *fiber.App, userService *service.UserService, authService *service.AuthService, sessionService *service.SessionService, adminScopeService *service.AdminScopeService) {
 	api := app.Group("/api/users")
 	
 	// Apply auth middleware to all user routes
 	api.Use(middleware.AuthMiddleware(authService))
 
-	// FR-009: Manage Users - Admin only endpoints
+	// FR-009: Manage Users - Admin only endpoints. Department admins may manage
+	// the students and lecturers of their scopes (ScopedAdminMiddleware).
 	
 	// Get available advisors (lecturers)
 	api.Get("/advisors", 
-		middleware.AdminOnlyMiddleware(),
+		middleware.ScopedAdminMiddleware(adminScopeService),
 		userService.GetAvailableAdvisorsRequest)
 	
 	// Create default lecturer for testing
@@ -52,26 +53,40 @@ func SetupUserRoutes(app *fiber.App, userService *service.UserService, authServi
 	
 	// Get all users
 	api.Get("/", 
-		middleware.AdminOnlyMiddleware(),
+		middleware.ScopedAdminMiddleware(adminScopeService),
 		userService.GetAllUsersRequest)
 
 	// Get user by ID
 	api.Get("/:id", 
-		middleware.AdminOnlyMiddleware(),
+		middleware.ScopedAdminMiddleware(adminScopeService),
 		userService.GetUserByIDRequest)
 
 	// Create new user
 	api.Post("/", 
-		middleware.AdminOnlyMiddleware(),
+		middleware.ScopedAdminMiddleware(adminScopeService),
 		userService.CreateUserRequest)
 
 	// Update user
 	api.Put("/:id", 
-		middleware.AdminOnlyMiddleware(),
+		middleware.ScopedAdminMiddleware(adminScopeService),
 		userService.UpdateUserRequest)
 
 	// Delete user
 	api.Delete("/:id", 
-		middleware.AdminOnlyMiddleware(),
+		middleware.ScopedAdminMiddleware(adminScopeService),
 		userService.DeleteUserRequest)
+
+	// Unlock an account locked out by failed logins
+	api.Post("/:id/unlock",
+		middleware.ScopedAdminMiddleware(adminScopeService),
+		userService.UnlockUserRequest)
+
+	// Sessions of a user - list and force logout of one session
+	api.Get("/:id/sessions",
+		middleware.AdminOnlyMiddleware(),
+		sessionService.GetUserSessionsRequest)
+
+	api.Delete("/:id/sessions/:session_id",
+		middleware.AdminOnlyMiddleware(),
+		sessionService.RevokeUserSessionRequest)
 }
\ No newline at end of file
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"UASBE/app/service"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestGenerateAPIKey menguji format API key dan prefix yang disimpan
+func TestGenerateAPIKey(t *testing.T) {
+	key, prefix, err := service.GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("GenerateAPIKey: %v", err)
+	}
+
+	if !strings.HasPrefix(key, "uask_") {
+		t.Errorf("key %q must start with uask_", key)
+	}
+	if len(prefix) != 12 || !strings.HasPrefix(key, prefix) {
+		t.Errorf("prefix %q must be the first 12 characters of the key", prefix)
+	}
+
+	other, _, _ := service.GenerateAPIKey()
+	if other == key {
+		t.Error("keys must be random")
+	}
+}
+
+// TestNormalizeAPIKeyScopes menguji validasi scope API key
+func TestNormalizeAPIKeyScopes(t *testing.T) {
+	scopes, err := service.NormalizeAPIKeyScopes([]string{" Achievements:Export ", "achievements:export", "", "achievements:read"})
+	if err != nil {
+		t.Fatalf("NormalizeAPIKeyScopes: %v", err)
+	}
+	if want := []string{"achievements:export", "achievements:read"}; !reflect.DeepEqual(scopes, want) {
+		t.Errorf("scopes = %v, want %v", scopes, want)
+	}
+
+	for _, invalid := range [][]string{nil, {"", " "}, {"achievements"}, {"achievements:*"}, {"a:b:c"}} {
+		if _, err := service.NormalizeAPIKeyScopes(invalid); err == nil {
+			t.Errorf("scopes %q should be rejected", invalid)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"UASBE/app/service"
+	"testing"
+)
+
+// TestDescribeUserAgent menguji label perangkat pada daftar sesi
+func TestDescribeUserAgent(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		want      string
+	}{
+		{"", "Unknown device"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36", "Chrome on Windows"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0", "Edge on Windows"},
+		{"Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0", "Firefox on Linux"},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_2) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Safari/605.1.15", "Safari on macOS"},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Mobile/15E148 Safari/604.1", "Safari on iOS"},
+		{"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36", "Chrome on Android"},
+		{"PostmanRuntime/7.36.0", "Postman"},
+		{"curl/8.4.0", "curl"},
+		{"SomethingElse/1.0", "Unknown device"},
+		{"Mozilla/5.0 (X11; Linux x86_64) Gecko Custom/1.0", "Browser on Linux"},
+	}
+
+	for _, tt := range tests {
+		if got := service.DescribeUserAgent(tt.userAgent); got != tt.want {
+			t.Errorf("DescribeUserAgent(%q) = %q, want %q", tt.userAgent, got, tt.want)
+		}
+	}
+}
+
+// TestIPRange menguji jaringan IP yang dipakai untuk mendeteksi login dari tempat baru
+func TestIPRange(t *testing.T) {
+	tests := map[string]string{
+		"10.1.2.3":                "10.1.2.0/24",
+		"10.1.2.250":              "10.1.2.0/24",
+		"10.1.3.3":                "10.1.3.0/24",
+		"::ffff:192.168.1.20":     "192.168.1.0/24",
+		"2001:db8:abcd:12::1":     "2001:db8:abcd::/48",
+		"2001:db8:abcd:ffff::bad": "2001:db8:abcd::/48",
+		"":                        "",
+		"not-an-ip":               "not-an-ip",
+	}
+
+	for ip, want := range tests {
+		if got := service.IPRange(ip); got != want {
+			t.Errorf("IPRange(%q) = %q, want %q", ip, got, want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"UASBE/app/service"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret adalah secret SHA1 dari RFC 6238 Appendix B ("12345678901234567890") dalam base32
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// TestTOTPCode menguji kode TOTP terhadap test vector RFC 6238 (6 digit terakhir)
+func TestTOTPCode(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		t.Run(time.Unix(tt.unix, 0).UTC().Format(time.RFC3339), func(t *testing.T) {
+			got, err := service.TOTPCode(rfc6238Secret, service.TOTPStep(time.Unix(tt.unix, 0)))
+			if err != nil {
+				t.Fatalf("TOTPCode() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("TOTPCode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestValidateTOTP menguji toleransi clock drift dan penolakan kode yang salah
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := service.TOTPStep(now)
+
+	previous, _ := service.TOTPCode(rfc6238Secret, step-1)
+	current, _ := service.TOTPCode(rfc6238Secret, step)
+	tooOld, _ := service.TOTPCode(rfc6238Secret, step-2)
+
+	tests := []struct {
+		name     string
+		code     string
+		wantOK   bool
+		wantStep int64
+	}{
+		{"Current step", current, true, step},
+		{"Previous step within skew", previous, true, step - 1},
+		{"Code with spaces", current[:3] + " " + current[3:], true, step},
+		{"Outside skew window", tooOld, false, 0},
+		{"Wrong length", "12345", false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := service.ValidateTOTP(rfc6238Secret, tt.code, now)
+			if ok != tt.wantOK {
+				t.Fatalf("ValidateTOTP(%q) ok = %v, want %v", tt.code, ok, tt.wantOK)
+			}
+			if ok && gotStep != tt.wantStep {
+				t.Errorf("ValidateTOTP(%q) step = %v, want %v", tt.code, gotStep, tt.wantStep)
+			}
+		})
+	}
+}
+
+// TestTOTPProvisioningURI menguji URI otpauth:// untuk QR code
+func TestTOTPProvisioningURI(t *testing.T) {
+	secret, err := service.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret() error = %v", err)
+	}
+	if len(secret) != 32 {
+		t.Errorf("secret length = %d, want 32 base32 characters (160 bits)", len(secret))
+	}
+
+	uri := service.TOTPProvisioningURI("UAS Achievement System", "dosen01", secret)
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("invalid URI %q: %v", uri, err)
+	}
+
+	if parsed.Scheme != "otpauth" || parsed.Host != "totp" {
+		t.Errorf("URI = %q, want otpauth://totp/...", uri)
+	}
+	if !strings.HasSuffix(parsed.Path, "UAS Achievement System:dosen01") {
+		t.Errorf("label = %q, want issuer:account", parsed.Path)
+	}
+	if parsed.Query().Get("secret") != secret || parsed.Query().Get("issuer") != "UAS Achievement System" {
+		t.Errorf("query = %v, want secret and issuer", parsed.Query())
+	}
+}
+
+// TestRecoveryCodes menguji format dan normalisasi recovery code
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := service.GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes() error = %v", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("code %q, want format xxxxx-xxxxx", code)
+		}
+		if seen[code] {
+			t.Errorf("duplicate recovery code %q", code)
+		}
+		seen[code] = true
+
+		typed := " " + strings.ToUpper(code[:5]) + " " + code[6:]
+		if service.NormalizeRecoveryCode(typed) != service.NormalizeRecoveryCode(code) {
+			t.Errorf("NormalizeRecoveryCode(%q) does not match %q", typed, code)
+		}
+	}
+}
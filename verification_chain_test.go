@@ -0,0 +1,125 @@
+package main
+
+import (
+	"UASBE/app/model"
+	"UASBE/app/policy"
+	"UASBE/app/repository"
+	"UASBE/app/service"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestSelectVerificationChain menguji pemilihan rantai verifikasi berdasarkan tingkat kompetisi
+func TestSelectVerificationChain(t *testing.T) {
+	chains := []model.VerificationChain{
+		{Category: "competition", CompetitionLevel: "", Stages: []string{"advisor", "student_affairs"}},
+		{Category: "competition", CompetitionLevel: "international", Stages: []string{"advisor", "student_affairs", "university_verifier"}},
+	}
+
+	tests := []struct {
+		level string
+		want  []string
+	}{
+		{"international", []string{"advisor", "student_affairs", "university_verifier"}},
+		{" International ", []string{"advisor", "student_affairs", "university_verifier"}},
+		{"national", []string{"advisor", "student_affairs"}},
+		{"", []string{"advisor", "student_affairs"}},
+	}
+
+	for _, tt := range tests {
+		if got := service.SelectVerificationChain(chains, tt.level); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SelectVerificationChain(%q) = %v, want %v", tt.level, got, tt.want)
+		}
+	}
+
+	if got := service.SelectVerificationChain(nil, "international"); !reflect.DeepEqual(got, []string{policy.StageAdvisor}) {
+		t.Errorf("without chains the advisor alone should verify, got %v", got)
+	}
+}
+
+// TestNormalizeStages menguji validasi tahap rantai verifikasi
+func TestNormalizeStages(t *testing.T) {
+	stages, err := service.NormalizeStages([]string{" Advisor ", "", "student_affairs"})
+	if err != nil {
+		t.Fatalf("NormalizeStages returned error: %v", err)
+	}
+	if want := []string{"advisor", "student_affairs"}; !reflect.DeepEqual(stages, want) {
+		t.Errorf("NormalizeStages = %v, want %v", stages, want)
+	}
+
+	if _, err := service.NormalizeStages([]string{"advisor", "ADVISOR"}); err == nil {
+		t.Error("a stage appearing twice should be rejected")
+	}
+	if _, err := service.NormalizeStages([]string{" "}); err == nil {
+		t.Error("a chain without stages should be rejected")
+	}
+
+	for _, builtIn := range []string{"admin", "lecturer", "Dosen", "student", "service"} {
+		if _, err := service.NormalizeStages([]string{"advisor", builtIn}); err == nil {
+			t.Errorf("a chain with the built-in role %q should be rejected", builtIn)
+		}
+	}
+	if _, err := service.NormalizeStages([]string{"advisor", "department_admin", "student_affairs"}); err != nil {
+		t.Errorf("department_admin and verifier roles should be allowed, got %v", err)
+	}
+}
+
+// TestSaveChainRequiresVerifyPermission menguji bahwa setiap tahap selain advisor
+// harus role yang memiliki permission achievements:verify
+func TestSaveChainRequiresVerifyPermission(t *testing.T) {
+	f := newAuthFixture(t)
+	read := f.store.addPermission("achievements", "read")
+	verify := f.store.addPermission("achievements", "verify")
+	f.store.addRole("department_admin", true, read, f.store.addPermission("users", "manage"))
+	f.store.addRole("student_affairs", false, read, verify)
+
+	f.db.On("INSERT INTO verification_chains", func(args []driver.Value) fakeResult {
+		return fakeRows([]string{"id", "category", "competition_level", "stages", "updated_by", "created_at", "updated_at"},
+			[]driver.Value{"chain-1", args[0], args[1], args[2], "", time.Now(), time.Now()})
+	})
+
+	chains := service.NewVerificationChainService(repository.NewVerificationChainRepository(), repository.NewRoleRepository())
+
+	_, err := chains.SaveChain(&service.SaveVerificationChainRequest{Category: "competition", Stages: []string{"advisor", "department_admin"}}, "")
+	if want := "stage 'department_admin' cannot verify: the role lacks the achievements:verify permission"; err == nil || err.Error() != want {
+		t.Errorf("SaveChain() with a stage lacking achievements:verify error = %v, want %s", err, want)
+	}
+	if _, err := chains.SaveChain(&service.SaveVerificationChainRequest{Category: "competition", Stages: []string{"advisor", "bem"}}, ""); err == nil || err.Error() != "stage 'bem' is not a role" {
+		t.Errorf("SaveChain() with an unknown role error = %v, want stage 'bem' is not a role", err)
+	}
+	if len(f.db.Statements("INSERT INTO verification_chains")) != 0 {
+		t.Fatalf("a rejected chain was saved")
+	}
+
+	chain, err := chains.SaveChain(&service.SaveVerificationChainRequest{Category: "Competition", Stages: []string{"advisor", "student_affairs"}}, "")
+	if err != nil {
+		t.Fatalf("SaveChain() error = %v", err)
+	}
+	if want := []string{"advisor", "student_affairs"}; chain.Category != "competition" || !reflect.DeepEqual(chain.Stages, want) {
+		t.Errorf("saved chain = %+v, want category competition with stages %v", chain, want)
+	}
+}
+
+// TestVerificationStage menguji tahap yang sedang ditunggu sebuah referensi
+func TestVerificationStage(t *testing.T) {
+	reference := &model.AchievementReference{
+		Status:            policy.StatusSubmitted,
+		VerificationChain: []string{"advisor", "student_affairs"},
+		CurrentStage:      1,
+	}
+	if got := service.VerificationStage(reference); got != "student_affairs" {
+		t.Errorf("VerificationStage = %q, want student_affairs", got)
+	}
+
+	legacy := &model.AchievementReference{Status: policy.StatusSubmitted}
+	if got := service.VerificationStage(legacy); got != policy.StageAdvisor {
+		t.Errorf("a reference without chain should wait for the advisor, got %q", got)
+	}
+
+	verified := &model.AchievementReference{Status: policy.StatusVerified, VerificationChain: []string{"advisor"}, CurrentStage: 1}
+	if got := service.VerificationStage(verified); got != "" {
+		t.Errorf("a verified reference waits for no stage, got %q", got)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"UASBE/app/model"
+	"UASBE/app/service"
+	"testing"
+	"time"
+)
+
+var testSLAConfig = service.VerificationSLAConfig{
+	Enabled:       true,
+	CheckInterval: time.Hour,
+	RemindAfter:   72 * time.Hour,
+	EscalateAfter: 168 * time.Hour,
+}
+
+// TestWaitingSince menguji awal waktu tunggu tahap verifikasi
+func TestWaitingSince(t *testing.T) {
+	submitted := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	approved := submitted.Add(48 * time.Hour)
+
+	ref := &model.AchievementReference{Status: "submitted", SubmittedAt: submitted}
+	if got := service.WaitingSince(ref); !got.Equal(submitted) {
+		t.Errorf("without approvals: got %v, want %v", got, submitted)
+	}
+
+	ref.Approvals = []model.StageApproval{{Stage: "advisor", Round: 1, At: approved}}
+	if got := service.WaitingSince(ref); !got.Equal(approved) {
+		t.Errorf("after an approval: got %v, want %v", got, approved)
+	}
+}
+
+// TestVerificationSLADue menguji pengingat dan eskalasi yang jatuh tempo
+func TestVerificationSLADue(t *testing.T) {
+	submitted := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		ref          model.AchievementReference
+		now          time.Time
+		wantRemind   bool
+		wantEscalate bool
+	}{
+		{"within the SLA", model.AchievementReference{Status: "submitted", SubmittedAt: submitted}, submitted.Add(24 * time.Hour), false, false},
+		{"reminder due", model.AchievementReference{Status: "submitted", SubmittedAt: submitted}, submitted.Add(80 * time.Hour), true, false},
+		{"already reminded", model.AchievementReference{Status: "submitted", SubmittedAt: submitted, RemindedAt: submitted.Add(73 * time.Hour)}, submitted.Add(80 * time.Hour), false, false},
+		{"escalation due", model.AchievementReference{Status: "submitted", SubmittedAt: submitted, RemindedAt: submitted.Add(73 * time.Hour)}, submitted.Add(170 * time.Hour), false, true},
+		{"already escalated", model.AchievementReference{Status: "submitted", SubmittedAt: submitted, RemindedAt: submitted.Add(73 * time.Hour), EscalatedAt: submitted.Add(169 * time.Hour)}, submitted.Add(200 * time.Hour), false, false},
+		{"both due after downtime", model.AchievementReference{Status: "submitted", SubmittedAt: submitted}, submitted.Add(200 * time.Hour), true, true},
+		{"reminder of an earlier submission", model.AchievementReference{Status: "submitted", SubmittedAt: submitted, RemindedAt: submitted.Add(-24 * time.Hour)}, submitted.Add(80 * time.Hour), true, false},
+		{"next stage waits anew", model.AchievementReference{Status: "submitted", SubmittedAt: submitted, RemindedAt: submitted.Add(73 * time.Hour),
+			Approvals: []model.StageApproval{{Stage: "advisor", Round: 1, At: submitted.Add(100 * time.Hour)}}}, submitted.Add(120 * time.Hour), false, false},
+		{"verified", model.AchievementReference{Status: "verified", SubmittedAt: submitted}, submitted.Add(200 * time.Hour), false, false},
+	}
+
+	for _, tt := range tests {
+		remind, escalate := testSLAConfig.Due(&tt.ref, tt.now)
+		if remind != tt.wantRemind || escalate != tt.wantEscalate {
+			t.Errorf("%s: got remind=%v escalate=%v, want remind=%v escalate=%v", tt.name, remind, escalate, tt.wantRemind, tt.wantEscalate)
+		}
+	}
+}
+
+// TestVerificationSLASummary menguji jumlah pelanggaran SLA pada statistik
+func TestVerificationSLASummary(t *testing.T) {
+	now := time.Date(2026, 3, 20, 8, 0, 0, 0, time.UTC)
+
+	refs := []model.AchievementReference{
+		{Status: "submitted", SubmittedAt: now.Add(-24 * time.Hour)},
+		{Status: "submitted", SubmittedAt: now.Add(-100 * time.Hour)},
+		{Status: "submitted", SubmittedAt: now.Add(-200 * time.Hour), EscalatedAt: now.Add(-20 * time.Hour)},
+		{Status: "submitted", SubmittedAt: now.Add(-300 * time.Hour)},
+		{Status: "verified", SubmittedAt: now.Add(-300 * time.Hour)},
+		{Status: "draft"},
+	}
+
+	summary := testSLAConfig.Summarize(refs, now)
+	if summary.Pending != 4 || summary.Overdue != 3 || summary.Breached != 2 || summary.Escalated != 1 {
+		t.Errorf("got %+v, want pending=4 overdue=3 breached=2 escalated=1", summary)
+	}
+	if summary.RemindAfterHours != 72 || summary.EscalateAfterHours != 168 {
+		t.Errorf("thresholds: got %v/%v hours, want 72/168", summary.RemindAfterHours, summary.EscalateAfterHours)
+	}
+}
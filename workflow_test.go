@@ -0,0 +1,146 @@
+package main
+
+import (
+	"UASBE/app/model"
+	"UASBE/app/policy"
+	"UASBE/app/service"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestDefaultWorkflow menguji transisi status prestasi yang diizinkan beserta aksi policy-nya
+func TestDefaultWorkflow(t *testing.T) {
+	w := policy.DefaultWorkflow()
+
+	tests := []struct {
+		from   string
+		to     string
+		action policy.Action
+	}{
+		{policy.StatusDraft, policy.StatusSubmitted, policy.ActionSubmit},
+		{policy.StatusDraft, policy.StatusDeleted, policy.ActionDelete},
+		{policy.StatusSubmitted, policy.StatusVerified, policy.ActionVerify},
+		{policy.StatusSubmitted, policy.StatusRejected, policy.ActionVerify},
+		{policy.StatusSubmitted, policy.StatusRevisionRequested, policy.ActionVerify},
+		{policy.StatusRejected, policy.StatusSubmitted, policy.ActionSubmit},
+		{policy.StatusRevisionRequested, policy.StatusSubmitted, policy.ActionSubmit},
+	}
+
+	for _, tt := range tests {
+		transition, err := w.Check(tt.from, tt.to, "note")
+		if err != nil {
+			t.Errorf("Check(%q, %q) returned error: %v", tt.from, tt.to, err)
+			continue
+		}
+		if transition.Action != tt.action {
+			t.Errorf("Check(%q, %q) action = %q, want %q", tt.from, tt.to, transition.Action, tt.action)
+		}
+	}
+}
+
+// TestWorkflowInvalidTransition menguji penolakan transisi yang tidak ada
+func TestWorkflowInvalidTransition(t *testing.T) {
+	w := policy.DefaultWorkflow()
+
+	invalid := [][2]string{
+		{policy.StatusDraft, policy.StatusVerified},
+		{policy.StatusSubmitted, policy.StatusDeleted},
+		{policy.StatusVerified, policy.StatusRejected},
+		{policy.StatusDeleted, policy.StatusDraft},
+		{policy.StatusRevisionRequested, policy.StatusVerified},
+		{policy.StatusVerified, policy.StatusSubmitted},
+		{"unknown", policy.StatusSubmitted},
+	}
+
+	for _, pair := range invalid {
+		_, err := w.Check(pair[0], pair[1], "note")
+		var transitionErr *policy.TransitionError
+		if !errors.As(err, &transitionErr) {
+			t.Errorf("Check(%q, %q) should return a TransitionError, got %v", pair[0], pair[1], err)
+		}
+	}
+
+	_, err := w.Check(policy.StatusSubmitted, policy.StatusDraft, "")
+	var transitionErr *policy.TransitionError
+	if !errors.As(err, &transitionErr) {
+		t.Fatalf("expected TransitionError, got %v", err)
+	}
+	want := []string{policy.StatusVerified, policy.StatusRejected, policy.StatusRevisionRequested}
+	if !reflect.DeepEqual(transitionErr.Allowed, want) {
+		t.Errorf("Allowed = %v, want %v", transitionErr.Allowed, want)
+	}
+}
+
+// TestWorkflowRequiresNote menguji catatan wajib saat menolak prestasi
+func TestWorkflowRequiresNote(t *testing.T) {
+	w := policy.DefaultWorkflow()
+
+	if _, err := w.Check(policy.StatusSubmitted, policy.StatusRejected, "  "); !errors.Is(err, policy.ErrNoteRequired) {
+		t.Errorf("rejecting without a note should return ErrNoteRequired, got %v", err)
+	}
+
+	transition, err := w.Check(policy.StatusSubmitted, policy.StatusRejected, "Sertifikat tidak terbaca")
+	if err != nil {
+		t.Fatalf("rejecting with a note returned error: %v", err)
+	}
+	if !transition.RequiresNote {
+		t.Error("rejection should be marked as requiring a note")
+	}
+
+	if _, err := w.Check(policy.StatusSubmitted, policy.StatusRevisionRequested, ""); !errors.Is(err, policy.ErrNoteRequired) {
+		t.Errorf("requesting a revision without feedback should return ErrNoteRequired, got %v", err)
+	}
+
+	if _, err := w.Check(policy.StatusSubmitted, policy.StatusVerified, ""); err != nil {
+		t.Errorf("verifying should not need a note, got %v", err)
+	}
+}
+
+// TestWorkflowEditable menguji status prestasi yang masih boleh diedit mahasiswa
+func TestWorkflowEditable(t *testing.T) {
+	w := policy.DefaultWorkflow()
+
+	tests := map[string]bool{
+		policy.StatusDraft:             true,
+		policy.StatusRejected:          true,
+		policy.StatusRevisionRequested: true,
+		policy.StatusSubmitted:         false,
+		policy.StatusVerified:          false,
+		policy.StatusDeleted:           false,
+	}
+
+	for status, want := range tests {
+		if got := w.Editable(status); got != want {
+			t.Errorf("Editable(%q) = %v, want %v", status, got, want)
+		}
+	}
+}
+
+// TestFeedbackRounds menguji pengelompokan feedback per putaran pengajuan
+func TestFeedbackRounds(t *testing.T) {
+	history := []model.StatusChange{
+		{To: policy.StatusDraft, Actor: "student-1"},
+		{From: policy.StatusDraft, To: policy.StatusSubmitted, Actor: "student-1"},
+		{From: policy.StatusSubmitted, To: policy.StatusRevisionRequested, Actor: "lecturer-1", Feedback: []string{"Lampirkan sertifikat"}},
+		{From: policy.StatusRevisionRequested, To: policy.StatusSubmitted, Actor: "student-1"},
+		{From: policy.StatusSubmitted, To: policy.StatusRejected, Actor: "lecturer-1", Note: "Bukan prestasi tingkat nasional"},
+		{From: policy.StatusRejected, To: policy.StatusSubmitted, Actor: "student-1"},
+		{From: policy.StatusSubmitted, To: policy.StatusVerified, Actor: "lecturer-1"},
+	}
+
+	rounds := service.FeedbackRounds(history)
+	if len(rounds) != 2 {
+		t.Fatalf("expected 2 rounds, got %d", len(rounds))
+	}
+	if rounds[0].Round != 1 || rounds[0].Status != policy.StatusRevisionRequested || !reflect.DeepEqual(rounds[0].Feedback, []string{"Lampirkan sertifikat"}) {
+		t.Errorf("unexpected first round: %+v", rounds[0])
+	}
+	if rounds[1].Round != 2 || rounds[1].Status != policy.StatusRejected || rounds[1].Note != "Bukan prestasi tingkat nasional" {
+		t.Errorf("unexpected second round: %+v", rounds[1])
+	}
+
+	if got := service.NormalizeFeedback([]string{" Perbaiki tanggal ", "", "  "}); !reflect.DeepEqual(got, []string{"Perbaiki tanggal"}) {
+		t.Errorf("NormalizeFeedback = %v", got)
+	}
+}